@@ -3,49 +3,395 @@ package main
 import (
 	"context"
 	"errors"
-	"log"
+	"flag"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	"backend/internal/adapters/cache"
+	"backend/internal/adapters/db/postgres"
+	llmadapter "backend/internal/adapters/llm"
+	"backend/internal/adapters/mailer"
+	"backend/internal/adapters/search"
+	"backend/internal/domain"
+	"backend/internal/logging"
+	"backend/internal/metrics"
+	"backend/internal/tracing"
+	transport "backend/internal/transport/http"
 	"backend/internal/transport/http/handlers"
+	"backend/internal/usecase/auth"
+	"backend/internal/usecase/authz"
+	"backend/internal/usecase/export"
+	"backend/internal/usecase/importer"
+	"backend/internal/usecase/jobs"
+	"backend/internal/usecase/llm"
+	"backend/internal/usecase/org"
+	"backend/internal/usecase/pii"
+	"backend/internal/usecase/share"
+	"backend/internal/usecase/usage"
+	"backend/internal/usecase/users"
+	"backend/migrations"
+
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 func main() {
+	healthcheck := flag.Bool("healthcheck", false, "check a health endpoint of a running server and exit")
+	healthcheckPath := flag.String("healthcheck-path", "/health/live", "endpoint for -healthcheck: /health/live or /health/ready")
+	flag.Parse()
+
+	if *healthcheck {
+		os.Exit(runHealthcheck(serverAddr(), *healthcheckPath))
+	}
+
+	logger, err := logging.New(os.Stdout, logging.Config{
+		Level:      envString("LOG_LEVEL", "info"),
+		Format:     envString("LOG_FORMAT", "json"),
+		LogContent: !envBool("LOG_REDACT_CONTENT", true),
+	})
+	if err != nil {
+		slog.Error("failed to setup logger", slog.Any("error", err))
+		os.Exit(1)
+	}
+
 	addr := serverAddr()
-	mux := http.NewServeMux()
 
-	healthHandler := handlers.NewHealthHandler()
-	mux.HandleFunc("/health", healthHandler.Health)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		ServiceName: envString("OTEL_SERVICE_NAME", "backend"),
+		Exporter:    envString("OTEL_TRACES_EXPORTER", tracing.ExporterNone),
+		SampleRatio: envFloat64("OTEL_TRACES_SAMPLE_RATIO", 1),
+	})
+	if err != nil {
+		fatal(logger, "failed to setup tracing", err)
+	}
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			logger.Error("failed to flush traces", slog.Any("error", err))
+		}
+	}()
+
+	pool, err := postgres.NewPool(ctx)
+	if err != nil {
+		fatal(logger, "failed to connect to postgres", err)
+	}
+	defer pool.Close()
+
+	if err := metrics.RegisterPool(pool); err != nil {
+		fatal(logger, "failed to register pool metrics", err)
+	}
+
+	limits := defaultLimits()
+
+	chatRepo := postgres.NewChatRepo(pool)
+	msgRepo := postgres.NewMessageRepo(pool)
+	userRepo := postgres.NewUserRepo(pool)
+	usageRepo := postgres.NewUsageRepo(pool)
+	orgRepo := postgres.NewOrgRepo(pool)
+
+	authorizer, err := authz.NewService(orgRepo)
+	if err != nil {
+		fatal(logger, "failed to create authorizer", err)
+	}
+
+	llmConfig := llmadapter.Config{
+		BaseURL:     envString("OLLAMA_BASE_URL", "http://localhost:11434"),
+		Model:       envString("OLLAMA_MODEL", "mistral"),
+		Temperature: 0.3,
+		TopP:        0.9,
+		MaxTokens:   limits.MaxOutputTokens,
+		Timeout:     envDuration("OLLAMA_TIMEOUT", 120*time.Second),
+		// Один лимит на все клиенты: копии llmConfig для классификаторов делят те же слоты
+		Limiter: llmadapter.NewLimiter(limits.MaxConcurrentLLM),
+	}
+	httpClient := &http.Client{
+		Timeout:   llmConfig.Timeout,
+		Transport: tracing.Transport(http.DefaultTransport),
+	}
+	ollama := llmadapter.NewOllamaClient(httpClient, llmConfig)
+
+	usageService, err := usage.NewService(usageRepo, &domain.Quota{
+		DailyTokens:          envInt64("QUOTA_DAILY_TOKENS", 0),
+		MonthlyTokens:        envInt64("QUOTA_MONTHLY_TOKENS", 0),
+		DailyRequests:        envInt64("QUOTA_DAILY_REQUESTS", 0),
+		MonthlyRequests:      envInt64("QUOTA_MONTHLY_REQUESTS", 0),
+		DailyDocumentBytes:   envInt64("QUOTA_DAILY_DOCUMENT_BYTES", 0),
+		MonthlyDocumentBytes: envInt64("QUOTA_MONTHLY_DOCUMENT_BYTES", 0),
+	})
+	if err != nil {
+		fatal(logger, "failed to create usage service", err)
+	}
+
+	llmOpts := []llm.Option{
+		llm.WithUsageTracker(usageService),
+		llm.WithTxManager(postgres.NewTxManager(pool)),
+		llm.WithAuthorizer(authorizer),
+	}
+
+	piiPolicy := pii.DefaultPolicy
+	if v := os.Getenv("PII_POLICY"); v != "" {
+		piiPolicy, err = pii.ParsePolicy(v)
+		if err != nil {
+			fatal(logger, "failed to parse PII_POLICY", err)
+		}
+	}
+	llmOpts = append(llmOpts, llm.WithPIIPolicy(piiPolicy))
+
+	enableTools := envBool("LLM_ENABLE_TOOLS", false)
+	tools := []llm.Tool{llm.CalculatorTool(), llm.DocumentLookupTool()}
+
+	if envBool("LLM_ENABLE_WEB_SEARCH", true) {
+		webCache, err := newWebCache(ctx, logger, pool)
+		if err != nil {
+			fatal(logger, "failed to configure web search cache", err)
+		}
+		cacheTTL := search.CacheTTL{
+			Volatile: envDuration("WEB_CACHE_TTL_VOLATILE", search.DefaultCacheTTL.Volatile),
+			Default:  envDuration("WEB_CACHE_TTL_DEFAULT", search.DefaultCacheTTL.Default),
+			Stable:   envDuration("WEB_CACHE_TTL_STABLE", search.DefaultCacheTTL.Stable),
+			Page:     envDuration("WEB_CACHE_TTL_PAGE", search.DefaultCacheTTL.Page),
+		}
+
+		webSearch, err := search.New(&http.Client{
+			Timeout:   10 * time.Second,
+			Transport: tracing.Transport(http.DefaultTransport),
+		}, search.Config{
+			Providers:   envList("WEB_SEARCH_PROVIDERS", []string{"duckduckgo"}),
+			SearXNGURL:  os.Getenv("SEARXNG_URL"),
+			BraveAPIKey: os.Getenv("BRAVE_API_KEY"),
+			FixturePath: os.Getenv("WEB_SEARCH_FIXTURE"),
+			Cache:       webCache,
+			CacheTTL:    cacheTTL,
+		})
+		if err != nil {
+			fatal(logger, "failed to configure web search", err)
+		}
+
+		var pages domain.PageFetcher
+		if envBool("WEB_FETCH_ENABLED", true) {
+			// Страницы из выдачи - произвольные адреса, поэтому во внутреннюю сеть не ходим
+			pages = search.NewPageFetcher(&http.Client{
+				Transport: tracing.Transport(search.PublicOnlyTransport()),
+			}, search.FetcherConfig{
+				MaxBytes:     envInt64("WEB_FETCH_MAX_BYTES", 1<<20),
+				Timeout:      envDuration("WEB_FETCH_TIMEOUT", 5*time.Second),
+				AllowDomains: envList("WEB_FETCH_ALLOW_DOMAINS", nil),
+				DenyDomains:  envList("WEB_FETCH_DENY_DOMAINS", nil),
+			})
+			if webCache != nil {
+				pages = search.NewCachedFetcher(pages, webCache, cacheTTL.Page)
+			}
+			llmOpts = append(llmOpts, llm.WithPageFetcher(pages))
+		}
+
+		if enableTools {
+			// С инструментами модель сама решает, когда искать
+			tools = append(tools, llm.WebSearchTool(webSearch, pages))
+		} else {
+			// Классификатор веб-поиска - короткий JSON-ответ, можно отдать более лёгкой модели
+			classifierConfig := llmConfig
+			classifierConfig.Model = envString("OLLAMA_SEARCH_CLASSIFIER_MODEL", llmConfig.Model)
+			classifierConfig.Temperature = 0
+			classifierConfig.MaxTokens = 128
+			classifierConfig.JSONOutput = true
+
+			llmOpts = append(llmOpts, llm.WithWebSearch(webSearch, llmadapter.NewOllamaClient(httpClient, classifierConfig)))
+		}
+	}
+
+	if envBool("LLM_MODERATION", false) {
+		moderationConfig := llmConfig
+		moderationConfig.Model = envString("OLLAMA_MODERATION_MODEL", llmConfig.Model)
+		moderationConfig.Temperature = 0
+		moderationConfig.MaxTokens = 128
+		moderationConfig.JSONOutput = true
+
+		llmOpts = append(llmOpts, llm.WithModerator(llm.NewLLMModerator(llmadapter.NewOllamaClient(httpClient, moderationConfig))))
+	}
+
+	if enableTools {
+		registry, err := llm.NewToolRegistry(tools...)
+		if err != nil {
+			fatal(logger, "failed to register tools", err)
+		}
+		llmOpts = append(llmOpts, llm.WithTools(ollama, registry))
+	}
+
+	llmService, err := llm.NewChatService(chatRepo, msgRepo, ollama, &limits, llmOpts...)
+	if err != nil {
+		fatal(logger, "failed to create chat service", err)
+	}
+
+	healthChecks := []handlers.HealthCheck{
+		{
+			Name: "postgres",
+			Check: func(ctx context.Context) (string, error) {
+				return "", pool.Ping(ctx)
+			},
+		},
+		{
+			Name: "ollama",
+			Check: func(ctx context.Context) (string, error) {
+				return "model " + llmConfig.Model, ollama.CheckModel(ctx)
+			},
+		},
+		{
+			Name:  "migrations",
+			Check: migrationsCheck(pool),
+		},
+	}
+
+	exporter, err := export.NewExporter(chatRepo, msgRepo, export.WithAuthorizer(authorizer))
+	if err != nil {
+		fatal(logger, "failed to create chat exporter", err)
+	}
+
+	importLimits := importer.DefaultLimits
+	importLimits.MaxBytes = envInt64("IMPORT_MAX_BYTES", importLimits.MaxBytes)
+	chatImporter, err := importer.NewImporter(chatRepo, msgRepo, postgres.NewTxManager(pool), importLimits)
+	if err != nil {
+		fatal(logger, "failed to create chat importer", err)
+	}
+
+	shareService, err := share.NewService(postgres.NewShareRepo(pool), chatRepo, msgRepo, authorizer)
+	if err != nil {
+		fatal(logger, "failed to create share service", err)
+	}
+
+	// Без SMTP_ADDR письма с приглашениями только пишутся в лог
+	var mail domain.Mailer = mailer.NewLogMailer()
+	if smtpAddr := os.Getenv("SMTP_ADDR"); smtpAddr != "" {
+		mail, err = mailer.NewSMTPMailer(mailer.Config{
+			Addr:     smtpAddr,
+			From:     envString("SMTP_FROM", "Alfa Copilot <noreply@localhost>"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			Timeout:  envDuration("SMTP_TIMEOUT", 10*time.Second),
+		})
+		if err != nil {
+			fatal(logger, "failed to configure smtp", err)
+		}
+	}
+
+	orgService, err := org.NewService(orgRepo, postgres.NewInviteRepo(pool), userRepo, mail, postgres.NewTxManager(pool), authorizer,
+		org.WithInviteURL(envString("INVITE_URL", "http://localhost:3000/invite")),
+		org.WithInviteTTL(envDuration("INVITE_TTL", org.DefaultInviteTTL)),
+	)
+	if err != nil {
+		fatal(logger, "failed to create organization service", err)
+	}
+
+	userService, err := users.NewService(userRepo, usageService, postgres.NewTxManager(pool))
+	if err != nil {
+		fatal(logger, "failed to create user service", err)
+	}
+
+	// Без AUTH_SECRET остаётся режим разработки: токены не проверяются, все запросы - от dev-пользователя
+	var tokens *auth.Tokens
+	if secret := os.Getenv("AUTH_SECRET"); secret != "" {
+		tokens, err = auth.NewTokens([]byte(secret), envDuration("AUTH_TOKEN_TTL", auth.DefaultTokenTTL))
+		if err != nil {
+			fatal(logger, "failed to configure auth tokens", err)
+		}
+	} else {
+		logger.Warn("AUTH_SECRET is not set, authentication is disabled")
+	}
+
+	var jobQueue *jobs.Queue
+	queueDone := make(chan struct{})
+	if envBool("JOBS_ENABLED", true) {
+		jobRepo := postgres.NewJobRepo(pool)
+		if err := metrics.RegisterJobQueue(jobRepo); err != nil {
+			fatal(logger, "failed to register job queue metrics", err)
+		}
+
+		jobQueue, err = jobs.NewQueue(jobRepo,
+			jobs.WithWorkers(int(envInt64("JOB_WORKERS", 2))),
+			jobs.WithPollInterval(envDuration("JOB_POLL_INTERVAL", time.Second)),
+			// Аренда с запасом на самую долгую генерацию
+			jobs.WithLease(envDuration("JOB_LEASE", 2*llmConfig.Timeout)),
+			jobs.WithMaxAttempts(int(envInt64("JOB_MAX_ATTEMPTS", 3))),
+		)
+		if err != nil {
+			fatal(logger, "failed to create job queue", err)
+		}
+
+		jobQueue.Register(jobs.KindReply, jobs.ReplyHandler(llmService, nil, jobQueue))
+
+		// Название и пересказ - короткие служебные ответы, модель та же, но с меньшим лимитом токенов
+		if envBool("CHAT_TITLES", true) {
+			titleConfig := llmConfig
+			titleConfig.Temperature = 0.2
+			titleConfig.MaxTokens = 64
+			jobQueue.Register(jobs.KindChatTitle, jobs.TitleHandler(chatRepo, msgRepo, llmadapter.NewOllamaClient(httpClient, titleConfig), authorizer))
+		}
+		if envBool("CHAT_SUMMARIES", false) {
+			summaryConfig := llmConfig
+			summaryConfig.Temperature = 0.2
+			summaryConfig.MaxTokens = 512
+			jobQueue.Register(jobs.KindChatSummary, jobs.SummaryHandler(chatRepo, msgRepo, llmadapter.NewOllamaClient(httpClient, summaryConfig), authorizer))
+		}
+
+		go func() {
+			defer close(queueDone)
+			jobQueue.Run(ctx)
+		}()
+	} else {
+		close(queueDone)
+	}
+
+	router := transport.NewRouter(chatRepo, msgRepo, userRepo, postgres.NewChatSearchRepo(pool), llmService, exporter, chatImporter, shareService, orgService, authorizer, usageService, userService, tokens, nil, jobQueue, limits, healthChecks)
 
 	srv := &http.Server{
 		Addr:         addr,
-		Handler:      mux,
+		Handler:      router.SetupRoutes(),
 		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
+		WriteTimeout: llmConfig.Timeout + 15*time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
-	defer stop()
-
-	errCh := make(chan error, 1)
+	errCh := make(chan error, 2)
 	go func() {
-		log.Printf("HTTP server is starting on %s", addr)
+		logger.Info("HTTP server is starting", slog.String("addr", addr))
 		if err := srv.ListenAndServe(); err != nil {
 			errCh <- err
 		}
 	}()
 
+	// Метрики отдаются на отдельном адресе, который не публикуется наружу вместе с API
+	metricsAddr := envString("METRICS_ADDR", ":9090")
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/metrics", metrics.Handler())
+	metricsSrv := &http.Server{
+		Addr:         metricsAddr,
+		Handler:      metricsMux,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 15 * time.Second,
+	}
+
+	go func() {
+		logger.Info("metrics server is starting", slog.String("addr", metricsAddr))
+		if err := metricsSrv.ListenAndServe(); err != nil {
+			errCh <- err
+		}
+	}()
+
 	select {
 	case <-ctx.Done():
-		log.Println("shutdown signal received")
+		logger.Info("shutdown signal received")
 	case err := <-errCh:
 		if err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Fatalf("http server failed: %v", err)
+			fatal(logger, "http server failed", err)
 		}
 		return
 	}
@@ -54,10 +400,117 @@ func main() {
 	defer cancel()
 
 	if err := srv.Shutdown(shutdownCtx); err != nil {
-		log.Fatalf("graceful shutdown failed: %v", err)
+		fatal(logger, "graceful shutdown failed", err)
+	}
+	if err := metricsSrv.Shutdown(shutdownCtx); err != nil {
+		logger.Error("metrics server shutdown failed", slog.Any("error", err))
+	}
+
+	// Незавершённые задачи после аренды заберёт другой экземпляр или этот после перезапуска
+	select {
+	case <-queueDone:
+	case <-shutdownCtx.Done():
+		logger.Warn("job workers did not stop in time")
+	}
+
+	logger.Info("server stopped gracefully")
+}
+
+// newWebCache создаёт кэш веб-поиска по WEB_CACHE_BACKEND: memory - LRU в процессе,
+// postgres - LRU перед общей таблицей app.cache, none - без кэша (nil)
+func newWebCache(ctx context.Context, logger *slog.Logger, pool *pgxpool.Pool) (domain.Cache, error) {
+	backend := envString("WEB_CACHE_BACKEND", "memory")
+	lru := cache.NewLRU(int(envInt64("WEB_CACHE_SIZE", 1000)))
+
+	switch backend {
+	case "none":
+		return nil, nil
+	case "memory":
+		return lru, nil
+	case "postgres":
+		repo := postgres.NewCacheRepo(pool)
+
+		// Истёкшие записи не читаются, но занимают место - чистим их раз в час
+		go func() {
+			ticker := time.NewTicker(time.Hour)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					deleted, err := repo.DeleteExpired(ctx, time.Now())
+					if err != nil {
+						logger.Warn("failed to delete expired cache entries", slog.Any("error", err))
+						continue
+					}
+					logger.Debug("expired cache entries deleted", slog.Int64("count", deleted))
+				}
+			}
+		}()
+
+		return cache.NewLayered(lru, repo), nil
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", backend)
+	}
+}
+
+// migrationsCheck сравнивает версию схемы в базе с последней встроенной миграцией
+func migrationsCheck(pool *pgxpool.Pool) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		want, err := migrations.LatestVersion()
+		if err != nil {
+			return "", err
+		}
+
+		version, dirty, err := postgres.MigrationVersion(ctx, pool)
+		if errors.Is(err, postgres.ErrNoMigrationTable) {
+			return fmt.Sprintf("expected version %d", want), fmt.Errorf("%w: %v", handlers.ErrHealthUnknown, err)
+		}
+		if err != nil {
+			return "", err
+		}
+
+		detail := fmt.Sprintf("version %d, expected %d", version, want)
+		switch {
+		case dirty:
+			return detail, errors.New("schema is dirty after a failed migration")
+		case version < want:
+			return detail, errors.New("schema is behind the service")
+		}
+
+		return detail, nil
+	}
+}
+
+// runHealthcheck опрашивает эндпоинт здоровья запущенного сервиса - для HEALTHCHECK в docker без curl.
+// По умолчанию это /health/live: /health/ready ждёт и загрузки модели в Ollama, и контейнер,
+// проверяемый по нему, не становится healthy до ollama pull
+func runHealthcheck(addr, path string) int {
+	if strings.HasPrefix(addr, ":") {
+		addr = "127.0.0.1" + addr
 	}
 
-	log.Println("server stopped gracefully")
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get("http://" + addr + path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintln(os.Stderr, path, "failed:", resp.Status)
+		return 1
+	}
+
+	return 0
+}
+
+// fatal логирует ошибку запуска и завершает процесс
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, slog.Any("error", err))
+	os.Exit(1)
 }
 
 func serverAddr() string {
@@ -74,3 +527,96 @@ func serverAddr() string {
 
 	return ":8080"
 }
+
+func defaultLimits() domain.Limits {
+	return domain.Limits{
+		MaxPromptChars:    12000,
+		MaxOutputTokens:   1024,
+		MaxFileSizeBytes:  10 * 1024 * 1024,
+		MaxFileTextChars:  20000,
+		MaxHistoryChars:   6000,
+		MaxRequestChars:   4000,
+		MaxSearchChars:    6000,
+		MaxRequestsPerMin: 30,
+		MaxConcurrentLLM:  int(envInt64("LLM_MAX_CONCURRENT", 2)),
+	}
+}
+
+func envString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func envInt64(key string, def int64) int64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		slog.Warn("invalid env value, using default", slog.String("key", key), slog.String("value", v), slog.Any("default", def))
+		return def
+	}
+	return n
+}
+
+// envList читает список через запятую, пустые элементы отбрасываются
+func envList(key string, def []string) []string {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func envBool(key string, def bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		slog.Warn("invalid env value, using default", slog.String("key", key), slog.String("value", v), slog.Any("default", def))
+		return def
+	}
+	return b
+}
+
+func envDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		slog.Warn("invalid env value, using default", slog.String("key", key), slog.String("value", v), slog.Any("default", def))
+		return def
+	}
+	return d
+}
+
+func envFloat64(key string, def float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		slog.Warn("invalid env value, using default", slog.String("key", key), slog.String("value", v), slog.Any("default", def))
+		return def
+	}
+	return f
+}
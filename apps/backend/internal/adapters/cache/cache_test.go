@@ -0,0 +1,121 @@
+package cache
+
+import (
+	"backend/internal/domain"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func entry(value string, expiresAt time.Time) *domain.CacheEntry {
+	return &domain.CacheEntry{Value: []byte(value), ExpiresAt: expiresAt}
+}
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2)
+	expires := time.Now().Add(time.Hour)
+
+	c.Set(ctx, "a", entry("1", expires))
+	c.Set(ctx, "b", entry("2", expires))
+
+	// Обращение к "a" делает её свежей, вытесняется "b"
+	if got, _ := c.Get(ctx, "a"); got == nil {
+		t.Fatalf("a should be cached")
+	}
+	c.Set(ctx, "c", entry("3", expires))
+
+	if got, _ := c.Get(ctx, "b"); got != nil {
+		t.Fatalf("b should be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if got, _ := c.Get(ctx, key); got == nil {
+			t.Fatalf("%s should be cached", key)
+		}
+	}
+	if c.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", c.Len())
+	}
+}
+
+func TestLRU_Expiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := NewLRU(10)
+	c.now = func() time.Time { return now }
+
+	c.Set(ctx, "rate", entry("16%", now.Add(15*time.Minute)))
+
+	if got, _ := c.Get(ctx, "rate"); got == nil || string(got.Value) != "16%" {
+		t.Fatalf("Get() = %v, want fresh entry", got)
+	}
+
+	now = now.Add(15 * time.Minute)
+	if got, _ := c.Get(ctx, "rate"); got != nil {
+		t.Fatalf("expired entry returned: %v", got)
+	}
+	if c.Len() != 0 {
+		t.Fatalf("expired entry should be removed, Len() = %d", c.Len())
+	}
+}
+
+func TestLRU_Overwrite(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2)
+	expires := time.Now().Add(time.Hour)
+
+	c.Set(ctx, "a", entry("old", expires))
+	c.Set(ctx, "a", entry("new", expires))
+
+	got, _ := c.Get(ctx, "a")
+	if got == nil || string(got.Value) != "new" {
+		t.Fatalf("Get() = %v, want new value", got)
+	}
+	if c.Len() != 1 {
+		t.Fatalf("Len() = %d, want 1", c.Len())
+	}
+}
+
+// failingCache - хранилище, которое всегда отвечает ошибкой
+type failingCache struct{}
+
+func (failingCache) Get(context.Context, string) (*domain.CacheEntry, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (failingCache) Set(context.Context, string, *domain.CacheEntry) error {
+	return errors.New("connection refused")
+}
+
+func TestLayered(t *testing.T) {
+	ctx := context.Background()
+	expires := time.Now().Add(time.Hour)
+
+	front, back := NewLRU(10), NewLRU(10)
+	l := NewLayered(front, back)
+
+	// Запись попадает в оба уровня
+	if err := l.Set(ctx, "k", entry("v", expires)); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := back.Get(ctx, "k"); got == nil {
+		t.Fatalf("value should reach the back cache")
+	}
+
+	// Попадание во втором уровне поднимается в первый
+	back.Set(ctx, "only-back", entry("b", expires))
+	got, err := l.Get(ctx, "only-back")
+	if err != nil || got == nil || string(got.Value) != "b" {
+		t.Fatalf("Get() = %v, %v", got, err)
+	}
+	if got, _ := front.Get(ctx, "only-back"); got == nil || !got.ExpiresAt.Equal(expires) {
+		t.Fatalf("back hit should be copied to front with the same expiry, got %v", got)
+	}
+
+	// Ошибка второго уровня не прячется
+	l = NewLayered(NewLRU(10), failingCache{})
+	if _, err := l.Get(ctx, "k"); err == nil {
+		t.Fatalf("expected error from back cache")
+	}
+}
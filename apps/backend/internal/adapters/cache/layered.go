@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"backend/internal/domain"
+	"context"
+)
+
+// Layered - быстрый кэш (обычно LRU) перед общим хранилищем (например Postgres).
+// Попадание во втором уровне копируется в первый с тем же сроком жизни
+type Layered struct {
+	front domain.Cache
+	back  domain.Cache
+}
+
+func NewLayered(front, back domain.Cache) *Layered {
+	return &Layered{front: front, back: back}
+}
+
+func (l *Layered) Get(ctx context.Context, key string) (*domain.CacheEntry, error) {
+	entry, err := l.front.Get(ctx, key)
+	if err != nil || entry != nil {
+		return entry, err
+	}
+
+	entry, err = l.back.Get(ctx, key)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+
+	if err := l.front.Set(ctx, key, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (l *Layered) Set(ctx context.Context, key string, entry *domain.CacheEntry) error {
+	if err := l.front.Set(ctx, key, entry); err != nil {
+		return err
+	}
+	return l.back.Set(ctx, key, entry)
+}
+
+var _ domain.Cache = (*Layered)(nil)
@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"backend/internal/domain"
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// LRU - кэш в памяти процесса с ограничением по числу записей.
+// При переполнении вытесняется запись, к которой дольше всего не обращались
+type LRU struct {
+	capacity int
+
+	mu    sync.Mutex
+	items map[string]*list.Element
+	order *list.List // от недавних к давним
+	now   func() time.Time
+}
+
+type lruItem struct {
+	key   string
+	entry domain.CacheEntry
+}
+
+func NewLRU(capacity int) *LRU {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &LRU{
+		capacity: capacity,
+		items:    make(map[string]*list.Element, capacity),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+func (c *LRU) Get(_ context.Context, key string) (*domain.CacheEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, nil
+	}
+
+	item := el.Value.(*lruItem)
+	if !c.now().Before(item.entry.ExpiresAt) {
+		c.order.Remove(el)
+		delete(c.items, key)
+		return nil, nil
+	}
+
+	c.order.MoveToFront(el)
+	entry := item.entry
+	return &entry, nil
+}
+
+func (c *LRU) Set(_ context.Context, key string, entry *domain.CacheEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		el.Value.(*lruItem).entry = *entry
+		c.order.MoveToFront(el)
+		return nil
+	}
+
+	c.items[key] = c.order.PushFront(&lruItem{key: key, entry: *entry})
+
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruItem).key)
+	}
+
+	return nil
+}
+
+// Len - сколько записей сейчас в кэше (включая ещё не удалённые истёкшие)
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+var _ domain.Cache = (*LRU)(nil)
@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"backend/internal/domain"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// CacheRepo - общий для всех инстансов backend кэш в таблице app.cache
+type CacheRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewCacheRepo(pool *pgxpool.Pool) *CacheRepo {
+	return &CacheRepo{pool: pool}
+}
+
+func (c *CacheRepo) Get(ctx context.Context, key string) (*domain.CacheEntry, error) {
+	const q = `
+	SELECT value, expires_at
+	FROM app.cache
+	WHERE key = $1
+	  AND expires_at > now();
+	`
+
+	var entry domain.CacheEntry
+	err := conn(ctx, c.pool).QueryRow(ctx, q, key).Scan(&entry.Value, &entry.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+func (c *CacheRepo) Set(ctx context.Context, key string, entry *domain.CacheEntry) error {
+	const q = `
+	INSERT INTO app.cache (key, value, expires_at)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (key) DO UPDATE
+	SET value      = EXCLUDED.value,
+	    expires_at = EXCLUDED.expires_at,
+	    created_at = now();
+	`
+
+	_, err := conn(ctx, c.pool).Exec(ctx, q, key, entry.Value, entry.ExpiresAt)
+	return err
+}
+
+// DeleteExpired удаляет записи, истёкшие до before, и возвращает их количество
+func (c *CacheRepo) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	const q = `DELETE FROM app.cache WHERE expires_at <= $1;`
+
+	tag, err := conn(ctx, c.pool).Exec(ctx, q, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+var _ domain.Cache = (*CacheRepo)(nil)
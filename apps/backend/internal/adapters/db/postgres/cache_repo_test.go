@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"backend/internal/domain"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheRepo_SetGet(t *testing.T) {
+	ctx := context.Background()
+	repo := NewCacheRepo(testPool)
+
+	_, err := testPool.Exec(ctx, "TRUNCATE app.cache")
+	require.NoError(t, err)
+
+	expires := time.Now().Add(time.Hour).UTC().Truncate(time.Microsecond)
+	err = repo.Set(ctx, "search:ddg:5:курс доллара", &domain.CacheEntry{Value: []byte(`[{"Title":"ЦБ"}]`), ExpiresAt: expires})
+	require.NoError(t, err)
+
+	entry, err := repo.Get(ctx, "search:ddg:5:курс доллара")
+	require.NoError(t, err)
+	require.NotNil(t, entry)
+	require.Equal(t, `[{"Title":"ЦБ"}]`, string(entry.Value))
+	require.True(t, entry.ExpiresAt.Equal(expires))
+
+	// Перезапись
+	err = repo.Set(ctx, "search:ddg:5:курс доллара", &domain.CacheEntry{Value: []byte(`[]`), ExpiresAt: expires})
+	require.NoError(t, err)
+	entry, err = repo.Get(ctx, "search:ddg:5:курс доллара")
+	require.NoError(t, err)
+	require.Equal(t, `[]`, string(entry.Value))
+
+	missing, err := repo.Get(ctx, "page:https://example.com")
+	require.NoError(t, err)
+	require.Nil(t, missing)
+}
+
+func TestCacheRepo_Expired(t *testing.T) {
+	ctx := context.Background()
+	repo := NewCacheRepo(testPool)
+
+	_, err := testPool.Exec(ctx, "TRUNCATE app.cache")
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Set(ctx, "old", &domain.CacheEntry{Value: []byte("1"), ExpiresAt: time.Now().Add(-time.Minute)}))
+	require.NoError(t, repo.Set(ctx, "fresh", &domain.CacheEntry{Value: []byte("2"), ExpiresAt: time.Now().Add(time.Hour)}))
+
+	entry, err := repo.Get(ctx, "old")
+	require.NoError(t, err)
+	require.Nil(t, entry, "expired entry must not be returned")
+
+	deleted, err := repo.DeleteExpired(ctx, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, int64(1), deleted)
+
+	entry, err = repo.Get(ctx, "fresh")
+	require.NoError(t, err)
+	require.NotNil(t, entry)
+}
@@ -7,7 +7,6 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -21,24 +20,24 @@ func NewChatRepo(pool *pgxpool.Pool) *ChatRepo {
 
 func (c *ChatRepo) Create(ctx context.Context, chat *domain.Chat) error {
 	const q = `
-    INSERT INTO app.chats (id, title, user_id, created_at, updated_at)
-    VALUES ($1, $2, $3, now(), now())
+    INSERT INTO app.chats (id, title, user_id, org_id, created_at, updated_at)
+    VALUES ($1, $2, $3, $4, COALESCE($5, now()), now())
     RETURNING created_at, updated_at;
     `
 
-	return c.pool.QueryRow(ctx, q, chat.ID, chat.Title, chat.UserID).
+	return conn(ctx, c.pool).QueryRow(ctx, q, chat.ID, chat.Title, chat.UserID, chat.OrgID, nullTime(chat.CreatedAt)).
 		Scan(&chat.CreatedAt, &chat.UpdatedAt)
 }
 
 func (c *ChatRepo) GetByID(ctx context.Context, chatID uuid.UUID) (*domain.Chat, error) {
 	const q = `
-    SELECT id, title, user_id, created_at, updated_at
+    SELECT id, title, user_id, org_id, summary, active_message_id, created_at, updated_at
     FROM app.chats
     WHERE id = $1;
     `
 
 	var chat domain.Chat
-	err := c.pool.QueryRow(ctx, q, chatID).Scan(&chat.ID, &chat.Title, &chat.UserID, &chat.CreatedAt, &chat.UpdatedAt)
+	err := conn(ctx, c.pool).QueryRow(ctx, q, chatID).Scan(&chat.ID, &chat.Title, &chat.UserID, &chat.OrgID, &chat.Summary, &chat.ActiveMessageID, &chat.CreatedAt, &chat.UpdatedAt)
 
 	if err != nil {
 		return nil, err
@@ -49,13 +48,28 @@ func (c *ChatRepo) GetByID(ctx context.Context, chatID uuid.UUID) (*domain.Chat,
 
 func (c *ChatRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]*domain.Chat, error) {
 	const q = `
-	SELECT * 
+	SELECT id, title, user_id, org_id, summary, active_message_id, created_at, updated_at
 	FROM app.chats
-	WHERE user_id = $1
+	WHERE user_id = $1 AND org_id IS NULL
 	ORDER BY updated_at DESC;
 	`
 
-	rows, err := c.pool.Query(ctx, q, userID)
+	return c.list(ctx, q, userID)
+}
+
+func (c *ChatRepo) ListByOrg(ctx context.Context, orgID uuid.UUID) ([]*domain.Chat, error) {
+	const q = `
+	SELECT id, title, user_id, org_id, summary, active_message_id, created_at, updated_at
+	FROM app.chats
+	WHERE org_id = $1
+	ORDER BY updated_at DESC;
+	`
+
+	return c.list(ctx, q, orgID)
+}
+
+func (c *ChatRepo) list(ctx context.Context, q string, args ...any) ([]*domain.Chat, error) {
+	rows, err := conn(ctx, c.pool).Query(ctx, q, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -64,7 +78,7 @@ func (c *ChatRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]*domain.
 	var chats []*domain.Chat
 	for rows.Next() {
 		var chat domain.Chat
-		err := rows.Scan(&chat.ID, &chat.Title, &chat.UserID, &chat.CreatedAt, &chat.UpdatedAt, nil)
+		err := rows.Scan(&chat.ID, &chat.Title, &chat.UserID, &chat.OrgID, &chat.Summary, &chat.ActiveMessageID, &chat.CreatedAt, &chat.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -88,7 +102,17 @@ func (c *ChatRepo) UpdateTitle(ctx context.Context, chat *domain.Chat) error {
 	RETURNING title, updated_at;
 	`
 
-	return c.pool.QueryRow(ctx, q, chat.Title, chat.ID).Scan(&chat.Title, &chat.UpdatedAt)
+	return conn(ctx, c.pool).QueryRow(ctx, q, chat.Title, chat.ID).Scan(&chat.Title, &chat.UpdatedAt)
+}
+
+func (c *ChatRepo) UpdateSummary(ctx context.Context, chatID uuid.UUID, summary string) error {
+	const q = `
+	UPDATE app.chats
+	SET summary = $1
+	WHERE id = $2;
+	`
+	_, err := conn(ctx, c.pool).Exec(ctx, q, summary, chatID)
+	return err
 }
 
 func (c *ChatRepo) Touch(ctx context.Context, chatID uuid.UUID, t time.Time) error {
@@ -98,45 +122,32 @@ func (c *ChatRepo) Touch(ctx context.Context, chatID uuid.UUID, t time.Time) err
     	updated_at       = now()
 	WHERE id = $2;
 	`
-	_, err := c.pool.Exec(ctx, q, t, chatID)
+	_, err := conn(ctx, c.pool).Exec(ctx, q, t, chatID)
 	return err
 }
 
 // Delete удаляет чат и все связанные с ним сообщения в одной транзакции.
 func (c *ChatRepo) Delete(ctx context.Context, chatID uuid.UUID) error {
-	tx, err := c.pool.BeginTx(ctx, pgx.TxOptions{})
-	if err != nil {
-		return fmt.Errorf("begin tx: %w", err)
-	}
-	// Если где-то ошибка — откат
-	defer func() {
-		if err != nil {
-			_ = tx.Rollback(ctx)
-		}
-	}()
-
-	// 1. Удаляем сообщения чата
-	const deleteMsgs = `
+	return NewTxManager(c.pool).WithinTx(ctx, func(ctx context.Context) error {
+		// 1. Удаляем сообщения чата
+		const deleteMsgs = `
 		DELETE FROM app.messages
 		WHERE chat_id = $1;
 	`
 
-	if _, err = tx.Exec(ctx, deleteMsgs, chatID); err != nil {
-		return fmt.Errorf("delete messages: %w", err)
-	}
+		if _, err := conn(ctx, c.pool).Exec(ctx, deleteMsgs, chatID); err != nil {
+			return fmt.Errorf("delete messages: %w", err)
+		}
 
-	const deleteChat = `
+		const deleteChat = `
 		DELETE FROM app.chats
 		WHERE id = $1;
 	`
 
-	if _, err = tx.Exec(ctx, deleteChat, chatID); err != nil {
-		return fmt.Errorf("delete chat: %w", err)
-	}
-
-	if err = tx.Commit(ctx); err != nil {
-		return fmt.Errorf("commit tx: %w", err)
-	}
+		if _, err := conn(ctx, c.pool).Exec(ctx, deleteChat, chatID); err != nil {
+			return fmt.Errorf("delete chat: %w", err)
+		}
 
-	return nil
+		return nil
+	})
 }
@@ -23,6 +23,18 @@ func TestMain(m *testing.M) {
 			"../../../../migrations/0001_init_schemas.up.sql",
 			"../../../../migrations/0002_init_app_tables.up.sql",
 			"../../../../migrations/0003_init_auth_tables.up.sql",
+			"../../../../migrations/0004_init_usage_tables.up.sql",
+			"../../../../migrations/0005_add_message_metadata.up.sql",
+			"../../../../migrations/0006_init_cache_table.up.sql",
+			"../../../../migrations/0007_add_message_branches.up.sql",
+			"../../../../migrations/0008_add_message_client_id.up.sql",
+			"../../../../migrations/0009_add_message_status.up.sql",
+			"../../../../migrations/0010_init_jobs_table.up.sql",
+			"../../../../migrations/0011_add_search_index.up.sql",
+			"../../../../migrations/0012_init_chat_shares.up.sql",
+			"../../../../migrations/0013_init_orgs.up.sql",
+			"../../../../migrations/0014_add_user_sessions.up.sql",
+			"../../../../migrations/0015_add_users_email_lower_index.up.sql",
 		),
 		postgres.WithDatabase("app_test"),
 		postgres.WithUsername("postgres"),
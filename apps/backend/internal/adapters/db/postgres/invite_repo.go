@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"backend/internal/domain"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type InviteRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewInviteRepo(pool *pgxpool.Pool) *InviteRepo {
+	return &InviteRepo{pool: pool}
+}
+
+const inviteColumns = `id, org_id, email, role, token_hash, invited_by, expires_at, accepted_at, created_at`
+
+func scanInvite(row pgx.Row) (*domain.OrgInvite, error) {
+	var invite domain.OrgInvite
+	err := row.Scan(&invite.ID, &invite.OrgID, &invite.Email, &invite.Role, &invite.TokenHash,
+		&invite.InvitedBy, &invite.ExpiresAt, &invite.AcceptedAt, &invite.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+func (i *InviteRepo) Create(ctx context.Context, invite *domain.OrgInvite) error {
+	const q = `
+	INSERT INTO app.org_invites (id, org_id, email, role, token_hash, invited_by, expires_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING created_at;
+	`
+
+	return conn(ctx, i.pool).QueryRow(ctx, q, invite.ID, invite.OrgID, invite.Email, invite.Role,
+		invite.TokenHash, invite.InvitedBy, invite.ExpiresAt).Scan(&invite.CreatedAt)
+}
+
+func (i *InviteRepo) GetByTokenHash(ctx context.Context, tokenHash []byte) (*domain.OrgInvite, error) {
+	q := `SELECT ` + inviteColumns + ` FROM app.org_invites WHERE token_hash = $1;`
+
+	invite, err := scanInvite(conn(ctx, i.pool).QueryRow(ctx, q, tokenHash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return invite, err
+}
+
+func (i *InviteRepo) ListByOrg(ctx context.Context, orgID uuid.UUID) ([]*domain.OrgInvite, error) {
+	q := `SELECT ` + inviteColumns + ` FROM app.org_invites WHERE org_id = $1 ORDER BY created_at DESC;`
+
+	rows, err := conn(ctx, i.pool).Query(ctx, q, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invites []*domain.OrgInvite
+	for rows.Next() {
+		invite, err := scanInvite(rows)
+		if err != nil {
+			return nil, err
+		}
+		invites = append(invites, invite)
+	}
+
+	return invites, rows.Err()
+}
+
+func (i *InviteRepo) MarkAccepted(ctx context.Context, inviteID uuid.UUID, at time.Time) error {
+	const q = `
+	UPDATE app.org_invites
+	SET accepted_at = $2
+	WHERE id = $1;
+	`
+
+	_, err := conn(ctx, i.pool).Exec(ctx, q, inviteID, at)
+	return err
+}
+
+func (i *InviteRepo) Delete(ctx context.Context, inviteID uuid.UUID) error {
+	const q = `DELETE FROM app.org_invites WHERE id = $1;`
+
+	_, err := conn(ctx, i.pool).Exec(ctx, q, inviteID)
+	return err
+}
@@ -0,0 +1,169 @@
+package postgres
+
+import (
+	"backend/internal/domain"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type JobRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewJobRepo(pool *pgxpool.Pool) *JobRepo {
+	return &JobRepo{pool: pool}
+}
+
+const jobColumns = `id, kind, user_id, payload, status, result, error, attempts, max_attempts,
+	COALESCE(dedupe_key, ''), run_at, locked_until, created_at, updated_at`
+
+func scanJob(row pgx.Row) (*domain.Job, error) {
+	var job domain.Job
+	err := row.Scan(&job.ID, &job.Kind, &job.UserID, &job.Payload, &job.Status, &job.Result, &job.Error,
+		&job.Attempts, &job.MaxAttempts, &job.DedupeKey, &job.RunAt, &job.LockedUntil, &job.CreatedAt, &job.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (j *JobRepo) Enqueue(ctx context.Context, job *domain.Job) error {
+	// При совпадении ключа с ожидающей задачей "обновление" ничего не меняет, но возвращает её строку
+	const q = `
+	INSERT INTO app.jobs (id, kind, user_id, payload, max_attempts, dedupe_key, run_at)
+	VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), COALESCE($7, now()))
+	ON CONFLICT (dedupe_key) WHERE status = 'pending'
+	DO UPDATE SET dedupe_key = EXCLUDED.dedupe_key
+	RETURNING ` + jobColumns + `;
+	`
+
+	var runAt *time.Time
+	if !job.RunAt.IsZero() {
+		runAt = &job.RunAt
+	}
+
+	saved, err := scanJob(conn(ctx, j.pool).QueryRow(ctx, q,
+		job.ID, job.Kind, job.UserID, job.Payload, job.MaxAttempts, job.DedupeKey, runAt))
+	if err != nil {
+		return err
+	}
+
+	*job = *saved
+	return nil
+}
+
+func (j *JobRepo) GetByID(ctx context.Context, jobID uuid.UUID) (*domain.Job, error) {
+	q := `SELECT ` + jobColumns + ` FROM app.jobs WHERE id = $1;`
+	return scanJob(conn(ctx, j.pool).QueryRow(ctx, q, jobID))
+}
+
+func (j *JobRepo) Claim(ctx context.Context, kinds []string, lease time.Duration) (*domain.Job, error) {
+	// SKIP LOCKED: воркеры не ждут друг друга и не берут одну задачу дважды
+	const q = `
+	UPDATE app.jobs
+	SET status       = 'running',
+	    attempts     = attempts + 1,
+	    locked_until = now() + make_interval(secs => $2),
+	    updated_at   = now()
+	WHERE id = (
+		SELECT id
+		FROM app.jobs
+		WHERE kind = ANY($1)
+		  AND ((status = 'pending' AND run_at <= now())
+		    OR (status = 'running' AND locked_until < now()))
+		ORDER BY run_at
+		FOR UPDATE SKIP LOCKED
+		LIMIT 1
+	)
+	RETURNING ` + jobColumns + `;
+	`
+
+	return scanJob(conn(ctx, j.pool).QueryRow(ctx, q, kinds, lease.Seconds()))
+}
+
+func (j *JobRepo) Complete(ctx context.Context, jobID uuid.UUID, attempt int, result json.RawMessage) error {
+	const q = `
+	UPDATE app.jobs
+	SET status       = 'done',
+	    result       = $3,
+	    error        = '',
+	    locked_until = NULL,
+	    updated_at   = now()
+	WHERE id = $1 AND status = 'running' AND attempts = $2;
+	`
+
+	tag, err := conn(ctx, j.pool).Exec(ctx, q, jobID, attempt, result)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrJobLeaseLost
+	}
+	return nil
+}
+
+func (j *JobRepo) Fail(ctx context.Context, jobID uuid.UUID, attempt int, errText string, retryAt *time.Time) error {
+	// Повтор не нужен, если за время выполнения уже поставлена такая же задача (тот же dedupe_key)
+	const q = `
+	UPDATE app.jobs j
+	SET status       = CASE
+	                       WHEN $3::timestamptz IS NULL THEN 'failed'
+	                       WHEN EXISTS (SELECT 1
+	                                    FROM app.jobs o
+	                                    WHERE o.dedupe_key = j.dedupe_key
+	                                      AND o.status = 'pending'
+	                                      AND o.id <> j.id) THEN 'failed'
+	                       ELSE 'pending'
+	                   END,
+	    run_at       = COALESCE($3, run_at),
+	    error        = $2,
+	    locked_until = NULL,
+	    updated_at   = now()
+	WHERE id = $1 AND status = 'running' AND attempts = $4;
+	`
+
+	tag, err := conn(ctx, j.pool).Exec(ctx, q, jobID, errText, retryAt, attempt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrJobLeaseLost
+	}
+	return nil
+}
+
+// CountActive считает ожидающие и выполняющиеся задачи по статусам - для метрик очереди
+func (j *JobRepo) CountActive(ctx context.Context) (map[domain.JobStatus]int, error) {
+	const q = `
+	SELECT status, count(*)
+	FROM app.jobs
+	WHERE status IN ('pending', 'running')
+	GROUP BY status;
+	`
+
+	rows, err := conn(ctx, j.pool).Query(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[domain.JobStatus]int)
+	for rows.Next() {
+		var status domain.JobStatus
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[status] = n
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"backend/internal/domain"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobRepo_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	repo := NewJobRepo(testPool)
+
+	_, err := testPool.Exec(ctx, "TRUNCATE app.jobs")
+	require.NoError(t, err)
+	userID := insertTestUser(t, ctx)
+
+	job := &domain.Job{ID: uuid.New(), Kind: "reply", UserID: userID, Payload: json.RawMessage(`{"a":1}`), MaxAttempts: 3, DedupeKey: "k"}
+	require.NoError(t, repo.Enqueue(ctx, job))
+	require.Equal(t, domain.JobPending, job.Status)
+
+	// Ожидающая задача с тем же ключом не дублируется
+	duplicate := &domain.Job{ID: uuid.New(), Kind: "reply", UserID: userID, Payload: json.RawMessage(`{}`), MaxAttempts: 3, DedupeKey: "k"}
+	require.NoError(t, repo.Enqueue(ctx, duplicate))
+	require.Equal(t, job.ID, duplicate.ID)
+
+	none, err := repo.Claim(ctx, []string{"chat_title"}, time.Minute)
+	require.NoError(t, err)
+	require.Nil(t, none)
+
+	claimed, err := repo.Claim(ctx, []string{"reply"}, time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	require.Equal(t, job.ID, claimed.ID)
+	require.Equal(t, domain.JobRunning, claimed.Status)
+	require.Equal(t, 1, claimed.Attempts)
+	require.NotNil(t, claimed.LockedUntil)
+
+	// Арендованную задачу второй воркер не получает
+	none, err = repo.Claim(ctx, []string{"reply"}, time.Minute)
+	require.NoError(t, err)
+	require.Nil(t, none)
+
+	retryAt := time.Now().Add(-time.Second)
+	require.NoError(t, repo.Fail(ctx, job.ID, claimed.Attempts, "timeout", &retryAt))
+
+	claimed, err = repo.Claim(ctx, []string{"reply"}, time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	require.Equal(t, 2, claimed.Attempts)
+	require.Equal(t, "timeout", claimed.Error)
+
+	// Завершить задачу может только воркер с последней арендой
+	require.ErrorIs(t, repo.Complete(ctx, job.ID, 1, json.RawMessage(`{}`)), domain.ErrJobLeaseLost)
+	require.NoError(t, repo.Complete(ctx, job.ID, claimed.Attempts, json.RawMessage(`{"message_id":"x"}`)))
+	require.ErrorIs(t, repo.Fail(ctx, job.ID, claimed.Attempts, "late", nil), domain.ErrJobLeaseLost)
+
+	got, err := repo.GetByID(ctx, job.ID)
+	require.NoError(t, err)
+	require.Equal(t, domain.JobDone, got.Status)
+	require.JSONEq(t, `{"message_id":"x"}`, string(got.Result))
+	require.True(t, got.Finished())
+
+	missing, err := repo.GetByID(ctx, uuid.New())
+	require.NoError(t, err)
+	require.Nil(t, missing)
+}
+
+func TestJobRepo_ClaimExpiredLease(t *testing.T) {
+	ctx := context.Background()
+	repo := NewJobRepo(testPool)
+
+	_, err := testPool.Exec(ctx, "TRUNCATE app.jobs")
+	require.NoError(t, err)
+
+	job := &domain.Job{ID: uuid.New(), Kind: "reply", UserID: insertTestUser(t, ctx), Payload: json.RawMessage(`{}`), MaxAttempts: 3}
+	require.NoError(t, repo.Enqueue(ctx, job))
+
+	stale, err := repo.Claim(ctx, []string{"reply"}, time.Millisecond)
+	require.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+
+	// Воркер пропал, не завершив задачу, - её забирает другой
+	claimed, err := repo.Claim(ctx, []string{"reply"}, time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	require.Equal(t, 2, claimed.Attempts)
+
+	// Первый воркер вернулся: его результат не перезаписывает задачу второго
+	require.ErrorIs(t, repo.Complete(ctx, job.ID, stale.Attempts, json.RawMessage(`{}`)), domain.ErrJobLeaseLost)
+	require.ErrorIs(t, repo.Fail(ctx, job.ID, stale.Attempts, "timeout", nil), domain.ErrJobLeaseLost)
+
+	got, err := repo.GetByID(ctx, job.ID)
+	require.NoError(t, err)
+	require.Equal(t, domain.JobRunning, got.Status)
+}
+
+func TestJobRepo_CountActive(t *testing.T) {
+	ctx := context.Background()
+	repo := NewJobRepo(testPool)
+
+	_, err := testPool.Exec(ctx, "TRUNCATE app.jobs")
+	require.NoError(t, err)
+	userID := insertTestUser(t, ctx)
+
+	for range 3 {
+		require.NoError(t, repo.Enqueue(ctx, &domain.Job{ID: uuid.New(), Kind: "reply", UserID: userID, Payload: json.RawMessage(`{}`), MaxAttempts: 3}))
+	}
+	running, err := repo.Claim(ctx, []string{"reply"}, time.Minute)
+	require.NoError(t, err)
+	done, err := repo.Claim(ctx, []string{"reply"}, time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, repo.Complete(ctx, done.ID, done.Attempts, json.RawMessage(`{}`)))
+	require.NotNil(t, running)
+
+	// Завершённые задачи не считаются
+	counts, err := repo.CountActive(ctx)
+	require.NoError(t, err)
+	require.Equal(t, map[domain.JobStatus]int{domain.JobPending: 1, domain.JobRunning: 1}, counts)
+}
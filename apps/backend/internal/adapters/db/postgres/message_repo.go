@@ -3,8 +3,13 @@ package postgres
 import (
 	"backend/internal/domain"
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -16,40 +21,189 @@ func NewMessageRepo(pool *pgxpool.Pool) *MessageRepo {
 	return &MessageRepo{pool: pool}
 }
 
+// branchQuery поднимается по parent_id от стартового сообщения (его выбирает %s) не глубже $2 сообщений
+// и возвращает ветку от старых к новым
+const branchQuery = `
+	WITH RECURSIVE branch AS (
+		SELECT m.id, m.chat_id, m.parent_id, m.role, m.content, m.status, m.metadata, m.created_at, 1 AS depth
+		FROM app.messages m
+		WHERE m.id = (%s)
+		UNION ALL
+		SELECT p.id, p.chat_id, p.parent_id, p.role, p.content, p.status, p.metadata, p.created_at, b.depth + 1
+		FROM app.messages p
+		JOIN branch b ON p.id = b.parent_id
+		WHERE b.depth < $2
+	)
+	SELECT id, chat_id, parent_id, role, content, status, metadata, created_at
+	FROM branch
+	ORDER BY depth DESC;
+	`
+
 func (m *MessageRepo) Append(ctx context.Context, msg *domain.Message) error {
+	// Новое сообщение сразу становится концом активной ветки чата
+	const q = `
+	WITH inserted AS (
+		INSERT INTO app.messages (id, chat_id, parent_id, role, content, status, metadata, client_message_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, COALESCE(NULLIF($6, ''), 'complete'), $7, NULLIF($8, ''), COALESCE($9, now()))
+		RETURNING created_at, status
+	), activated AS (
+		UPDATE app.chats
+		SET active_message_id = $1
+		WHERE id = $2
+	)
+	SELECT created_at, status FROM inserted;
+	`
+
+	err := conn(ctx, m.pool).QueryRow(ctx, q, msg.ID, msg.ChatID, msg.ParentID, msg.Role, msg.Content, msg.Status, msg.Metadata, msg.ClientMessageID, nullTime(msg.CreatedAt)).
+		Scan(&msg.CreatedAt, &msg.Status)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "idx_messages_chat_client_message_id" {
+		return domain.ErrDuplicateMessage
+	}
+	return err
+}
+
+func (m *MessageRepo) GetByID(ctx context.Context, messageID uuid.UUID) (*domain.Message, error) {
+	const q = `
+	SELECT id, chat_id, parent_id, role, content, status, metadata, COALESCE(client_message_id, ''), created_at
+	FROM app.messages
+	WHERE id = $1;
+	`
+
+	return m.queryMessage(ctx, q, messageID)
+}
+
+func (m *MessageRepo) GetByClientID(ctx context.Context, chatID uuid.UUID, clientMessageID string) (*domain.Message, error) {
+	const q = `
+	SELECT id, chat_id, parent_id, role, content, status, metadata, client_message_id, created_at
+	FROM app.messages
+	WHERE chat_id = $1
+	  AND client_message_id = $2;
+	`
+
+	return m.queryMessage(ctx, q, chatID, clientMessageID)
+}
+
+func (m *MessageRepo) GetAnswer(ctx context.Context, questionID uuid.UUID) (*domain.Message, error) {
+	// Спускаемся от вопроса только через вызовы инструментов и их результаты: следующий вопрос ветки уже не наш
 	const q = `
-	INSERT INTO app.messages (id, chat_id, role, content, created_at)
-	VALUES ($1, $2, $3, $4, now())
-	RETURNING created_at;
+	WITH RECURSIVE chain AS (
+		SELECT id, role, metadata
+		FROM app.messages
+		WHERE parent_id = $1
+		UNION ALL
+		SELECT m.id, m.role, m.metadata
+		FROM app.messages m
+		JOIN chain c ON m.parent_id = c.id
+		WHERE c.role = 'tool' OR c.metadata ? 'tool_calls'
+	)
+	SELECT m.id, m.chat_id, m.parent_id, m.role, m.content, m.status, m.metadata, COALESCE(m.client_message_id, ''), m.created_at
+	FROM app.messages m
+	JOIN chain c ON c.id = m.id
+	WHERE m.role = 'assistant'
+	  AND NOT m.metadata ? 'tool_calls'
+	ORDER BY m.created_at, m.id
+	LIMIT 1;
 	`
 
-	return m.pool.QueryRow(ctx, q, msg.ID, msg.ChatID, msg.Role, msg.Content).Scan(&msg.CreatedAt)
+	return m.queryMessage(ctx, q, questionID)
 }
 
 func (m *MessageRepo) GetLastN(ctx context.Context, chatID uuid.UUID, n int) ([]*domain.Message, error) {
+	q := fmt.Sprintf(branchQuery, `SELECT active_message_id FROM app.chats WHERE id = $1`)
+	return m.queryMessages(ctx, q, chatID, n)
+}
+
+func (m *MessageRepo) GetBranch(ctx context.Context, messageID uuid.UUID, n int) ([]*domain.Message, error) {
+	q := fmt.Sprintf(branchQuery, `SELECT $1::uuid`)
+	return m.queryMessages(ctx, q, messageID, n)
+}
+
+func (m *MessageRepo) GetBranchIDs(ctx context.Context, messageID uuid.UUID) ([]uuid.UUID, error) {
 	const q = `
-	SELECT id, chat_id, role, content, created_at
-	FROM (
-		SELECT id, chat_id, role, content, created_at
+	WITH RECURSIVE branch AS (
+		SELECT id, parent_id, 1 AS depth
 		FROM app.messages
-		WHERE chat_id = $1
-		ORDER BY created_at DESC
-		LIMIT $2
-	) AS latest
-	ORDER BY created_at ASC;
+		WHERE id = $1
+		UNION ALL
+		SELECT p.id, p.parent_id, b.depth + 1
+		FROM app.messages p
+		JOIN branch b ON p.id = b.parent_id
+	)
+	SELECT id
+	FROM branch
+	ORDER BY depth DESC;
 	`
 
-	rows, err := m.pool.Query(ctx, q, chatID, n)
+	rows, err := conn(ctx, m.pool).Query(ctx, q, messageID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var messages []*domain.Message
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func (m *MessageRepo) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*domain.Message, error) {
+	const q = `
+	SELECT m.id, m.chat_id, m.parent_id, m.role, m.content, m.status, m.metadata, m.created_at
+	FROM unnest($1::uuid[]) WITH ORDINALITY AS ids(id, ord)
+	JOIN app.messages m ON m.id = ids.id
+	ORDER BY ids.ord;
+	`
 
+	return m.queryMessages(ctx, q, ids)
+}
+
+func (m *MessageRepo) ListByChat(ctx context.Context, chatID uuid.UUID, before *uuid.UUID, limit int) ([]*domain.Message, error) {
+	// Поднимаемся от конца активной ветки (или от родителя before) не глубже limit сообщений;
+	// у каждого сообщения - все ответы на тот же родитель
+	const q = `
+	WITH RECURSIVE branch AS (
+		SELECT m.id, m.chat_id, m.parent_id, m.role, m.content, m.status, m.metadata, m.created_at, 1 AS depth
+		FROM app.messages m
+		WHERE m.chat_id = $1
+		  AND m.id = CASE
+		                 WHEN $2::uuid IS NULL THEN (SELECT active_message_id FROM app.chats WHERE id = $1)
+		                 ELSE (SELECT parent_id FROM app.messages WHERE id = $2 AND chat_id = $1)
+		             END
+		UNION ALL
+		SELECT p.id, p.chat_id, p.parent_id, p.role, p.content, p.status, p.metadata, p.created_at, b.depth + 1
+		FROM app.messages p
+		JOIN branch b ON p.id = b.parent_id
+		WHERE b.depth < $3
+	)
+	SELECT b.id, b.chat_id, b.parent_id, b.role, b.content, b.status, b.metadata, b.created_at,
+	       ARRAY(
+	           SELECT s.id
+	           FROM app.messages s
+	           WHERE s.chat_id = b.chat_id
+	             AND s.parent_id IS NOT DISTINCT FROM b.parent_id
+	           ORDER BY s.created_at, s.id
+	       ) AS sibling_ids
+	FROM branch b
+	ORDER BY b.depth DESC
+	`
+
+	rows, err := conn(ctx, m.pool).Query(ctx, q, chatID, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []*domain.Message
 	for rows.Next() {
 		var msg domain.Message
-		err := rows.Scan(&msg.ID, &msg.ChatID, &msg.Role, &msg.Content, &msg.CreatedAt)
+		err := rows.Scan(&msg.ID, &msg.ChatID, &msg.ParentID, &msg.Role, &msg.Content, &msg.Status, &msg.Metadata, &msg.CreatedAt, &msg.SiblingIDs)
 		if err != nil {
 			return nil, err
 		}
@@ -64,17 +218,61 @@ func (m *MessageRepo) GetLastN(ctx context.Context, chatID uuid.UUID, n int) ([]
 	return messages, err
 }
 
-func (m *MessageRepo) ListByChat(ctx context.Context, chatID uuid.UUID, limit, offset int) ([]*domain.Message, error) {
+func (m *MessageRepo) UpdateStatus(ctx context.Context, messageID uuid.UUID, status domain.MessageStatus) error {
 	const q = `
-	SELECT id, chat_id, role, content, created_at 
-	FROM app.messages
-	WHERE chat_id = $1
-	ORDER BY created_at ASC
-	LIMIT $2
-	OFFSET $3
+	UPDATE app.messages
+	SET status = $2
+	WHERE id = $1;
+	`
+
+	_, err := conn(ctx, m.pool).Exec(ctx, q, messageID, status)
+	return err
+}
+
+func (m *MessageRepo) ActivateBranch(ctx context.Context, messageID uuid.UUID) error {
+	// От выбранного сообщения спускаемся по самым новым ответам до листа и делаем его концом активной ветки
+	const q = `
+	WITH RECURSIVE leaf AS (
+		SELECT id, chat_id, 1 AS depth
+		FROM app.messages
+		WHERE id = $1
+		UNION ALL
+		SELECT c.id, c.chat_id, l.depth + 1
+		FROM leaf l
+		CROSS JOIN LATERAL (
+			SELECT id, chat_id
+			FROM app.messages
+			WHERE parent_id = l.id
+			ORDER BY created_at DESC, id DESC
+			LIMIT 1
+		) c
+	)
+	UPDATE app.chats
+	SET active_message_id = (SELECT id FROM leaf ORDER BY depth DESC LIMIT 1)
+	WHERE id = (SELECT chat_id FROM leaf WHERE depth = 1);
 	`
 
-	rows, err := m.pool.Query(ctx, q, chatID, limit, offset)
+	_, err := conn(ctx, m.pool).Exec(ctx, q, messageID)
+	return err
+}
+
+// queryMessage читает одно сообщение; если строки нет - nil, nil
+func (m *MessageRepo) queryMessage(ctx context.Context, q string, args ...any) (*domain.Message, error) {
+	var msg domain.Message
+	err := conn(ctx, m.pool).QueryRow(ctx, q, args...).
+		Scan(&msg.ID, &msg.ChatID, &msg.ParentID, &msg.Role, &msg.Content, &msg.Status, &msg.Metadata, &msg.ClientMessageID, &msg.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &msg, nil
+}
+
+func (m *MessageRepo) queryMessages(ctx context.Context, q string, args ...any) ([]*domain.Message, error) {
+	rows, err := conn(ctx, m.pool).Query(ctx, q, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -83,7 +281,7 @@ func (m *MessageRepo) ListByChat(ctx context.Context, chatID uuid.UUID, limit, o
 	var messages []*domain.Message
 	for rows.Next() {
 		var msg domain.Message
-		err := rows.Scan(&msg.ID, &msg.ChatID, &msg.Role, &msg.Content, &msg.CreatedAt)
+		err := rows.Scan(&msg.ID, &msg.ChatID, &msg.ParentID, &msg.Role, &msg.Content, &msg.Status, &msg.Metadata, &msg.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -95,5 +293,13 @@ func (m *MessageRepo) ListByChat(ctx context.Context, chatID uuid.UUID, limit, o
 		return nil, err
 	}
 
-	return messages, err
+	return messages, nil
+}
+
+// nullTime - NULL вместо нулевого времени, чтобы в запросе сработало значение по умолчанию
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
 }
@@ -0,0 +1,185 @@
+package postgres
+
+import (
+	"backend/internal/domain"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// appendTestMessage сохраняет сообщение после parentID
+func appendTestMessage(t *testing.T, ctx context.Context, repo *MessageRepo, chatID uuid.UUID, parentID *uuid.UUID, role, content string) *domain.Message {
+	t.Helper()
+	msg := &domain.Message{
+		ID:       uuid.New(),
+		ChatID:   chatID,
+		ParentID: parentID,
+		Role:     role,
+		Content:  content,
+	}
+	require.NoError(t, repo.Append(ctx, msg))
+	return msg
+}
+
+func contents(messages []*domain.Message) []string {
+	out := make([]string, len(messages))
+	for i, msg := range messages {
+		out[i] = msg.Content
+	}
+	return out
+}
+
+func TestMessageRepo_Branches(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMessageRepo(testPool)
+	chatRepo := NewChatRepo(testPool)
+
+	_, err := testPool.Exec(ctx, "TRUNCATE app.chats CASCADE")
+	require.NoError(t, err)
+	_, err = testPool.Exec(ctx, "TRUNCATE app.users CASCADE")
+	require.NoError(t, err)
+
+	chat := &domain.Chat{ID: uuid.New(), Title: "branches", UserID: insertTestUser(t, ctx)}
+	require.NoError(t, chatRepo.Create(ctx, chat))
+
+	// q1 -> a1 -> q2 -> a2, затем альтернативный ответ a2' на q2
+	q1 := appendTestMessage(t, ctx, repo, chat.ID, nil, "user", "q1")
+	a1 := appendTestMessage(t, ctx, repo, chat.ID, &q1.ID, "assistant", "a1")
+	q2 := appendTestMessage(t, ctx, repo, chat.ID, &a1.ID, "user", "q2")
+	a2 := appendTestMessage(t, ctx, repo, chat.ID, &q2.ID, "assistant", "a2")
+	a2alt := appendTestMessage(t, ctx, repo, chat.ID, &q2.ID, "assistant", "a2'")
+
+	got, err := chatRepo.GetByID(ctx, chat.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got.ActiveMessageID)
+	require.Equal(t, a2alt.ID, *got.ActiveMessageID)
+
+	last, err := repo.GetLastN(ctx, chat.ID, 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"q1", "a1", "q2", "a2'"}, contents(last))
+
+	last, err = repo.GetLastN(ctx, chat.ID, 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"q2", "a2'"}, contents(last))
+
+	branch, err := repo.GetBranch(ctx, a2.ID, 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"q1", "a1", "q2", "a2"}, contents(branch))
+
+	listed, err := repo.ListByChat(ctx, chat.ID, nil, 100)
+	require.NoError(t, err)
+	require.Equal(t, []string{"q1", "a1", "q2", "a2'"}, contents(listed))
+	require.Equal(t, []uuid.UUID{q1.ID}, listed[0].SiblingIDs)
+	require.Equal(t, []uuid.UUID{a2.ID, a2alt.ID}, listed[3].SiblingIDs)
+
+	// Страницы идут от конца ветки к началу
+	listed, err = repo.ListByChat(ctx, chat.ID, nil, 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"q2", "a2'"}, contents(listed))
+	listed, err = repo.ListByChat(ctx, chat.ID, &listed[0].ID, 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"q1", "a1"}, contents(listed))
+	listed, err = repo.ListByChat(ctx, chat.ID, &q1.ID, 2)
+	require.NoError(t, err)
+	require.Empty(t, listed)
+	listed, err = repo.ListByChat(ctx, uuid.New(), &q2.ID, 2)
+	require.NoError(t, err)
+	require.Empty(t, listed)
+
+	ids, err := repo.GetBranchIDs(ctx, a2.ID)
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{q1.ID, a1.ID, q2.ID, a2.ID}, ids)
+
+	byIDs, err := repo.GetByIDs(ctx, []uuid.UUID{q2.ID, uuid.New(), q1.ID})
+	require.NoError(t, err)
+	require.Equal(t, []string{"q2", "q1"}, contents(byIDs))
+
+	// Переключение на старый ответ
+	require.NoError(t, repo.ActivateBranch(ctx, a2.ID))
+	last, err = repo.GetLastN(ctx, chat.ID, 10)
+	require.NoError(t, err)
+	require.Equal(t, []string{"q1", "a1", "q2", "a2"}, contents(last))
+
+	// Активация вопроса продолжает ветку самым новым ответом
+	require.NoError(t, repo.ActivateBranch(ctx, q2.ID))
+	got, err = chatRepo.GetByID(ctx, chat.ID)
+	require.NoError(t, err)
+	require.Equal(t, a2alt.ID, *got.ActiveMessageID)
+}
+
+func TestMessageRepo_GetByID_NotFound(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMessageRepo(testPool)
+
+	msg, err := repo.GetByID(ctx, uuid.New())
+	require.NoError(t, err)
+	require.Nil(t, msg)
+}
+
+func TestMessageRepo_ClientMessageID(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMessageRepo(testPool)
+	chatRepo := NewChatRepo(testPool)
+
+	_, err := testPool.Exec(ctx, "TRUNCATE app.chats CASCADE")
+	require.NoError(t, err)
+	_, err = testPool.Exec(ctx, "TRUNCATE app.users CASCADE")
+	require.NoError(t, err)
+
+	chat := &domain.Chat{ID: uuid.New(), Title: "idempotency", UserID: insertTestUser(t, ctx)}
+	require.NoError(t, chatRepo.Create(ctx, chat))
+
+	question := &domain.Message{ID: uuid.New(), ChatID: chat.ID, Role: "user", Content: "q", ClientMessageID: "key-1"}
+	require.NoError(t, repo.Append(ctx, question))
+
+	duplicate := &domain.Message{ID: uuid.New(), ChatID: chat.ID, Role: "user", Content: "q", ClientMessageID: "key-1"}
+	require.ErrorIs(t, repo.Append(ctx, duplicate), domain.ErrDuplicateMessage)
+
+	found, err := repo.GetByClientID(ctx, chat.ID, "key-1")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	require.Equal(t, question.ID, found.ID)
+
+	answer, err := repo.GetAnswer(ctx, question.ID)
+	require.NoError(t, err)
+	require.Nil(t, answer)
+
+	// Ответ после вызова инструмента находится через цепочку вызовов
+	call := &domain.Message{ID: uuid.New(), ChatID: chat.ID, ParentID: &question.ID, Role: "assistant",
+		Metadata: domain.MessageMetadata{ToolCalls: []domain.ToolCall{{Name: "calculator"}}}}
+	require.NoError(t, repo.Append(ctx, call))
+	result := appendTestMessage(t, ctx, repo, chat.ID, &call.ID, "tool", "1200")
+	final := appendTestMessage(t, ctx, repo, chat.ID, &result.ID, "assistant", "a")
+
+	answer, err = repo.GetAnswer(ctx, question.ID)
+	require.NoError(t, err)
+	require.NotNil(t, answer)
+	require.Equal(t, final.ID, answer.ID)
+}
+
+func TestMessageRepo_AppendKeepsCreatedAt(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMessageRepo(testPool)
+	chatRepo := NewChatRepo(testPool)
+
+	_, err := testPool.Exec(ctx, "TRUNCATE app.chats CASCADE")
+	require.NoError(t, err)
+	_, err = testPool.Exec(ctx, "TRUNCATE app.users CASCADE")
+	require.NoError(t, err)
+
+	// Импорт переносит исходное время чата и сообщений
+	importedAt := time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC)
+	chat := &domain.Chat{ID: uuid.New(), Title: "imported", UserID: insertTestUser(t, ctx), CreatedAt: importedAt}
+	require.NoError(t, chatRepo.Create(ctx, chat))
+	require.True(t, chat.CreatedAt.Equal(importedAt))
+
+	msg := &domain.Message{ID: uuid.New(), ChatID: chat.ID, Role: "user", Content: "q", CreatedAt: importedAt}
+	require.NoError(t, repo.Append(ctx, msg))
+	require.True(t, msg.CreatedAt.Equal(importedAt))
+
+	fresh := appendTestMessage(t, ctx, repo, chat.ID, &msg.ID, "assistant", "a")
+	require.True(t, fresh.CreatedAt.After(importedAt))
+}
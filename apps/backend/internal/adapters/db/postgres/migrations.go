@@ -0,0 +1,32 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrNoMigrationTable - в базе нет таблицы schema_migrations (миграции применялись вручную)
+var ErrNoMigrationTable = errors.New("schema_migrations table not found")
+
+// MigrationVersion читает версию схемы из таблицы schema_migrations в формате golang-migrate
+func MigrationVersion(ctx context.Context, pool *pgxpool.Pool) (version int64, dirty bool, err error) {
+	const q = `
+	SELECT version, dirty
+	FROM schema_migrations
+	LIMIT 1;
+	`
+
+	err = pool.QueryRow(ctx, q).Scan(&version, &dirty)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "42P01" { // undefined_table
+			return 0, false, ErrNoMigrationTable
+		}
+		return 0, false, err
+	}
+
+	return version, dirty, nil
+}
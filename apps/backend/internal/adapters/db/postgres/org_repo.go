@@ -0,0 +1,202 @@
+package postgres
+
+import (
+	"backend/internal/domain"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type OrgRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewOrgRepo(pool *pgxpool.Pool) *OrgRepo {
+	return &OrgRepo{pool: pool}
+}
+
+// Create сохраняет организацию и её владельца в одной транзакции
+func (o *OrgRepo) Create(ctx context.Context, org *domain.Org, ownerID uuid.UUID) error {
+	return NewTxManager(o.pool).WithinTx(ctx, func(ctx context.Context) error {
+		const insertOrg = `
+		INSERT INTO app.orgs (id, name)
+		VALUES ($1, $2)
+		RETURNING created_at;
+		`
+
+		if err := conn(ctx, o.pool).QueryRow(ctx, insertOrg, org.ID, org.Name).Scan(&org.CreatedAt); err != nil {
+			return fmt.Errorf("insert org: %w", err)
+		}
+
+		owner := &domain.Membership{OrgID: org.ID, UserID: ownerID, Role: domain.OrgRoleOwner}
+		if err := o.AddMember(ctx, owner); err != nil {
+			return fmt.Errorf("insert owner: %w", err)
+		}
+
+		org.Role = domain.OrgRoleOwner
+		return nil
+	})
+}
+
+func (o *OrgRepo) GetByID(ctx context.Context, orgID uuid.UUID) (*domain.Org, error) {
+	const q = `
+	SELECT id, name, created_at
+	FROM app.orgs
+	WHERE id = $1;
+	`
+
+	var org domain.Org
+	err := conn(ctx, o.pool).QueryRow(ctx, q, orgID).Scan(&org.ID, &org.Name, &org.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &org, nil
+}
+
+func (o *OrgRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]*domain.Org, error) {
+	const q = `
+	SELECT o.id, o.name, m.role, o.created_at
+	FROM app.orgs o
+	JOIN app.memberships m ON m.org_id = o.id
+	WHERE m.user_id = $1
+	ORDER BY o.name;
+	`
+
+	rows, err := conn(ctx, o.pool).Query(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orgs []*domain.Org
+	for rows.Next() {
+		var org domain.Org
+		if err := rows.Scan(&org.ID, &org.Name, &org.Role, &org.CreatedAt); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, &org)
+	}
+
+	return orgs, rows.Err()
+}
+
+func (o *OrgRepo) GetMembership(ctx context.Context, orgID, userID uuid.UUID) (*domain.Membership, error) {
+	const q = `
+	SELECT org_id, user_id, role, created_at
+	FROM app.memberships
+	WHERE org_id = $1 AND user_id = $2;
+	`
+
+	var m domain.Membership
+	err := conn(ctx, o.pool).QueryRow(ctx, q, orgID, userID).Scan(&m.OrgID, &m.UserID, &m.Role, &m.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+func (o *OrgRepo) ListMembers(ctx context.Context, orgID uuid.UUID) ([]*domain.Membership, error) {
+	const q = `
+	SELECT m.org_id, m.user_id, u.email, m.role, m.created_at
+	FROM app.memberships m
+	JOIN app.users u ON u.id = m.user_id
+	WHERE m.org_id = $1
+	ORDER BY m.created_at;
+	`
+
+	rows, err := conn(ctx, o.pool).Query(ctx, q, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []*domain.Membership
+	for rows.Next() {
+		var m domain.Membership
+		if err := rows.Scan(&m.OrgID, &m.UserID, &m.Email, &m.Role, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, &m)
+	}
+
+	return members, rows.Err()
+}
+
+func (o *OrgRepo) AddMember(ctx context.Context, m *domain.Membership) error {
+	const q = `
+	INSERT INTO app.memberships (org_id, user_id, role)
+	VALUES ($1, $2, $3)
+	RETURNING created_at;
+	`
+
+	err := conn(ctx, o.pool).QueryRow(ctx, q, m.OrgID, m.UserID, m.Role).Scan(&m.CreatedAt)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return domain.ErrAlreadyMember
+	}
+	return err
+}
+
+func (o *OrgRepo) UpdateMemberRole(ctx context.Context, orgID, userID uuid.UUID, role domain.OrgRole) error {
+	const q = `
+	UPDATE app.memberships
+	SET role = $3
+	WHERE org_id = $1 AND user_id = $2;
+	`
+
+	_, err := conn(ctx, o.pool).Exec(ctx, q, orgID, userID, role)
+	return err
+}
+
+// LockOwners блокирует записи владельцев в порядке user_id, чтобы параллельные транзакции не взаимоблокировались.
+// Транзакция, ждавшая блокировку, видит владельцев уже после чужого изменения
+func (o *OrgRepo) LockOwners(ctx context.Context, orgID uuid.UUID) ([]uuid.UUID, error) {
+	const q = `
+	SELECT user_id
+	FROM app.memberships
+	WHERE org_id = $1 AND role = 'owner'
+	ORDER BY user_id
+	FOR UPDATE;
+	`
+
+	rows, err := conn(ctx, o.pool).Query(ctx, q, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var owners []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		owners = append(owners, id)
+	}
+
+	return owners, rows.Err()
+}
+
+func (o *OrgRepo) RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error {
+	const q = `
+	DELETE FROM app.memberships
+	WHERE org_id = $1 AND user_id = $2;
+	`
+
+	_, err := conn(ctx, o.pool).Exec(ctx, q, orgID, userID)
+	return err
+}
@@ -0,0 +1,168 @@
+package postgres
+
+import (
+	"backend/internal/domain"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrgRepo_Memberships(t *testing.T) {
+	ctx := context.Background()
+	repo := NewOrgRepo(testPool)
+	chatRepo := NewChatRepo(testPool)
+
+	_, err := testPool.Exec(ctx, "TRUNCATE app.orgs, app.chats, app.users CASCADE")
+	require.NoError(t, err)
+
+	owner := insertTestUser(t, ctx)
+	viewer := insertTestUser(t, ctx)
+
+	org := &domain.Org{ID: uuid.New(), Name: "Кофейня"}
+	require.NoError(t, repo.Create(ctx, org, owner))
+	require.False(t, org.CreatedAt.IsZero())
+
+	require.NoError(t, repo.AddMember(ctx, &domain.Membership{OrgID: org.ID, UserID: viewer, Role: domain.OrgRoleViewer}))
+	err = repo.AddMember(ctx, &domain.Membership{OrgID: org.ID, UserID: viewer, Role: domain.OrgRoleMember})
+	require.ErrorIs(t, err, domain.ErrAlreadyMember)
+
+	orgs, err := repo.ListByUser(ctx, viewer)
+	require.NoError(t, err)
+	require.Len(t, orgs, 1)
+	require.Equal(t, domain.OrgRoleViewer, orgs[0].Role)
+
+	members, err := repo.ListMembers(ctx, org.ID)
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+	require.Equal(t, owner, members[0].UserID)
+	require.Equal(t, domain.OrgRoleOwner, members[0].Role)
+	require.Contains(t, members[1].Email, "@example.com")
+
+	require.NoError(t, repo.UpdateMemberRole(ctx, org.ID, viewer, domain.OrgRoleMember))
+	m, err := repo.GetMembership(ctx, org.ID, viewer)
+	require.NoError(t, err)
+	require.Equal(t, domain.OrgRoleMember, m.Role)
+
+	// Чат организации не попадает в личные чаты автора
+	orgChat := &domain.Chat{ID: uuid.New(), Title: "План закупок", UserID: viewer, OrgID: &org.ID}
+	require.NoError(t, chatRepo.Create(ctx, orgChat))
+	personal, err := chatRepo.ListByUser(ctx, viewer)
+	require.NoError(t, err)
+	require.Empty(t, personal)
+	orgChats, err := chatRepo.ListByOrg(ctx, org.ID)
+	require.NoError(t, err)
+	require.Len(t, orgChats, 1)
+	require.Equal(t, org.ID, *orgChats[0].OrgID)
+
+	// После исключения чаты участника остаются в организации
+	require.NoError(t, repo.RemoveMember(ctx, org.ID, viewer))
+	m, err = repo.GetMembership(ctx, org.ID, viewer)
+	require.NoError(t, err)
+	require.Nil(t, m)
+	orgChats, err = chatRepo.ListByOrg(ctx, org.ID)
+	require.NoError(t, err)
+	require.Len(t, orgChats, 1)
+}
+
+func TestInviteRepo_CreateAccept(t *testing.T) {
+	ctx := context.Background()
+	orgRepo := NewOrgRepo(testPool)
+	repo := NewInviteRepo(testPool)
+
+	_, err := testPool.Exec(ctx, "TRUNCATE app.orgs, app.users CASCADE")
+	require.NoError(t, err)
+
+	owner := insertTestUser(t, ctx)
+	org := &domain.Org{ID: uuid.New(), Name: "Кофейня"}
+	require.NoError(t, orgRepo.Create(ctx, org, owner))
+
+	invite := &domain.OrgInvite{
+		ID:        uuid.New(),
+		OrgID:     org.ID,
+		Email:     "anna@example.com",
+		Role:      domain.OrgRoleMember,
+		TokenHash: []byte("invite-hash"),
+		InvitedBy: owner,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	require.NoError(t, repo.Create(ctx, invite))
+
+	got, err := repo.GetByTokenHash(ctx, []byte("invite-hash"))
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	require.True(t, got.Pending(time.Now()))
+
+	missing, err := repo.GetByTokenHash(ctx, []byte("unknown"))
+	require.NoError(t, err)
+	require.Nil(t, missing)
+
+	require.NoError(t, repo.MarkAccepted(ctx, invite.ID, time.Now()))
+	invites, err := repo.ListByOrg(ctx, org.ID)
+	require.NoError(t, err)
+	require.Len(t, invites, 1)
+	require.NotNil(t, invites[0].AcceptedAt)
+	require.False(t, invites[0].Pending(time.Now()))
+
+	require.NoError(t, repo.Delete(ctx, invite.ID))
+	invites, err = repo.ListByOrg(ctx, org.ID)
+	require.NoError(t, err)
+	require.Empty(t, invites)
+}
+
+func TestOrgRepo_LockOwners(t *testing.T) {
+	ctx := context.Background()
+	repo := NewOrgRepo(testPool)
+	txManager := NewTxManager(testPool)
+
+	_, err := testPool.Exec(ctx, "TRUNCATE app.orgs, app.users CASCADE")
+	require.NoError(t, err)
+
+	first := insertTestUser(t, ctx)
+	second := insertTestUser(t, ctx)
+	org := &domain.Org{ID: uuid.New(), Name: "Кофейня"}
+	require.NoError(t, repo.Create(ctx, org, first))
+	require.NoError(t, repo.AddMember(ctx, &domain.Membership{OrgID: org.ID, UserID: second, Role: domain.OrgRoleOwner}))
+
+	// Первая транзакция держит блокировку владельцев и понижает second
+	locked := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- txManager.WithinTx(ctx, func(ctx context.Context) error {
+			owners, err := repo.LockOwners(ctx, org.ID)
+			if err != nil {
+				return err
+			}
+			if len(owners) != 2 {
+				return fmt.Errorf("owners = %v, want 2", owners)
+			}
+			close(locked)
+			<-release
+			return repo.UpdateMemberRole(ctx, org.ID, second, domain.OrgRoleMember)
+		})
+	}()
+	<-locked
+
+	// Вторая ждёт первую и видит владельцев уже после её изменения
+	owners := make(chan []uuid.UUID, 1)
+	go func() {
+		_ = txManager.WithinTx(ctx, func(ctx context.Context) error {
+			ids, err := repo.LockOwners(ctx, org.ID)
+			owners <- ids
+			return err
+		})
+	}()
+
+	select {
+	case <-owners:
+		t.Fatal("LockOwners() did not wait for the concurrent transaction")
+	case <-time.After(200 * time.Millisecond):
+	}
+	close(release)
+	require.NoError(t, <-done)
+	require.Equal(t, []uuid.UUID{first}, <-owners)
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"backend/internal/domain"
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// headlineOptions - параметры ts_headline: до двух фрагментов по 10-30 слов
+var headlineOptions = fmt.Sprintf(`StartSel="%s", StopSel="%s", MinWords=10, MaxWords=30, MaxFragments=2, FragmentDelimiter=" … "`,
+	domain.HighlightStart, domain.HighlightEnd)
+
+type ChatSearchRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewChatSearchRepo(pool *pgxpool.Pool) *ChatSearchRepo {
+	return &ChatSearchRepo{pool: pool}
+}
+
+func (s *ChatSearchRepo) Search(ctx context.Context, scope domain.ChatScope, query string, limit, offset int) ([]*domain.ChatSearchHit, error) {
+	// Совпавшие сообщения проверяются на активную ветку только в чатах, где они есть.
+	// Сниппеты строятся только для страницы выдачи: ts_headline заново разбирает текст и дорог
+	const q = `
+	WITH RECURSIVE query AS (
+		SELECT websearch_to_tsquery('russian', $2) AS q
+	),
+	scoped AS (
+		SELECT c.id, c.title, c.active_message_id, c.search_vector, c.updated_at
+		FROM app.chats c
+		WHERE c.org_id IS NULL AND c.user_id = $1
+		   OR c.org_id = ANY($6)
+	),
+	matched AS (
+		SELECT c.id AS chat_id, c.title, m.id, m.role, m.content, ts_rank(m.search_vector, query.q) AS rank, m.created_at
+		FROM app.messages m
+		JOIN scoped c ON c.id = m.chat_id, query
+		WHERE m.role IN ('user', 'assistant')
+		  AND m.status = 'complete'
+		  AND m.search_vector @@ query.q
+	),
+	active AS (
+		SELECT m.id, m.parent_id
+		FROM app.messages m
+		JOIN scoped c ON c.active_message_id = m.id
+		WHERE c.id IN (SELECT chat_id FROM matched)
+		UNION ALL
+		SELECT p.id, p.parent_id
+		FROM app.messages p
+		JOIN active a ON p.id = a.parent_id
+	),
+	hits AS (
+		SELECT c.id AS chat_id, c.title, NULL::uuid AS message_id, '' AS role, c.title AS body,
+		       ts_rank(c.search_vector, query.q) AS rank, c.updated_at AS created_at
+		FROM scoped c, query
+		WHERE c.search_vector @@ query.q
+		UNION ALL
+		SELECT m.chat_id, m.title, m.id, m.role, m.content, m.rank, m.created_at
+		FROM matched m
+		JOIN active a ON a.id = m.id
+		ORDER BY rank DESC, created_at DESC
+		LIMIT $3 OFFSET $4
+	)
+	SELECT hits.chat_id, hits.title, hits.message_id, hits.role,
+	       ts_headline('russian', hits.body, query.q, $5),
+	       hits.rank, hits.created_at
+	FROM hits, query
+	ORDER BY hits.rank DESC, hits.created_at DESC;
+	`
+
+	rows, err := conn(ctx, s.pool).Query(ctx, q, scope.UserID, query, limit, offset, headlineOptions, scope.OrgIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hits []*domain.ChatSearchHit
+	for rows.Next() {
+		var hit domain.ChatSearchHit
+		var rank float32
+		if err := rows.Scan(&hit.ChatID, &hit.ChatTitle, &hit.MessageID, &hit.Role, &hit.Snippet, &rank, &hit.CreatedAt); err != nil {
+			return nil, err
+		}
+		hit.Rank = float64(rank)
+		hits = append(hits, &hit)
+	}
+
+	return hits, rows.Err()
+}
@@ -0,0 +1,101 @@
+package postgres
+
+import (
+	"backend/internal/domain"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatSearchRepo_Search(t *testing.T) {
+	ctx := context.Background()
+	repo := NewChatSearchRepo(testPool)
+	chatRepo := NewChatRepo(testPool)
+	msgRepo := NewMessageRepo(testPool)
+
+	_, err := testPool.Exec(ctx, "TRUNCATE app.chats CASCADE")
+	require.NoError(t, err)
+	_, err = testPool.Exec(ctx, "TRUNCATE app.users CASCADE")
+	require.NoError(t, err)
+
+	userID := insertTestUser(t, ctx)
+	lease := &domain.Chat{ID: uuid.New(), Title: "Аренда офиса", UserID: userID}
+	other := &domain.Chat{ID: uuid.New(), Title: "Маркетинг", UserID: userID}
+	foreign := &domain.Chat{ID: uuid.New(), Title: "Аренда склада", UserID: insertTestUser(t, ctx)}
+	for _, chat := range []*domain.Chat{lease, other, foreign} {
+		require.NoError(t, chatRepo.Create(ctx, chat))
+	}
+
+	question := appendTestMessage(t, ctx, msgRepo, other.ID, nil, "user", "Можно ли досрочно расторгнуть договор аренды?")
+	// Ответ из неактивной ветки и вопрос без сгенерированного ответа не ищутся
+	appendTestMessage(t, ctx, msgRepo, other.ID, &question.ID, "assistant", "Аренду расторгнуть нельзя.")
+	answer := appendTestMessage(t, ctx, msgRepo, other.ID, &question.ID, "assistant", "Да, если это предусмотрено договором.")
+	pending := appendTestMessage(t, ctx, msgRepo, other.ID, &answer.ID, "user", "А аренду склада?")
+	require.NoError(t, msgRepo.UpdateStatus(ctx, pending.ID, domain.MessageStatusPending))
+	appendTestMessage(t, ctx, msgRepo, foreign.ID, nil, "user", "Арендатор просит скидку")
+
+	scope := domain.ChatScope{UserID: userID}
+
+	// Морфология: "аренду" находит "аренды" и "Аренда"
+	hits, err := repo.Search(ctx, scope, "аренду", 10, 0)
+	require.NoError(t, err)
+	require.Len(t, hits, 2)
+
+	// Название чата весит больше текста сообщения
+	require.Equal(t, lease.ID, hits[0].ChatID)
+	require.Nil(t, hits[0].MessageID)
+	require.Equal(t, other.ID, hits[1].ChatID)
+	require.NotNil(t, hits[1].MessageID)
+	require.Equal(t, question.ID, *hits[1].MessageID)
+	require.Equal(t, "user", hits[1].Role)
+	require.True(t, strings.Contains(hits[1].Snippet, domain.HighlightStart+"аренды"+domain.HighlightEnd), hits[1].Snippet)
+
+	page, err := repo.Search(ctx, scope, "аренду", 1, 1)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	require.Equal(t, other.ID, page[0].ChatID)
+
+	none, err := repo.Search(ctx, scope, "неустойка", 10, 0)
+	require.NoError(t, err)
+	require.Empty(t, none)
+}
+
+func TestChatSearchRepo_SearchOrgChats(t *testing.T) {
+	ctx := context.Background()
+	repo := NewChatSearchRepo(testPool)
+	chatRepo := NewChatRepo(testPool)
+	orgRepo := NewOrgRepo(testPool)
+
+	_, err := testPool.Exec(ctx, "TRUNCATE app.orgs, app.chats, app.users CASCADE")
+	require.NoError(t, err)
+
+	owner := insertTestUser(t, ctx)
+	viewer := insertTestUser(t, ctx)
+	org := &domain.Org{ID: uuid.New(), Name: "Кофейня"}
+	require.NoError(t, orgRepo.Create(ctx, org, owner))
+	require.NoError(t, orgRepo.AddMember(ctx, &domain.Membership{OrgID: org.ID, UserID: viewer, Role: domain.OrgRoleViewer}))
+
+	orgChat := &domain.Chat{ID: uuid.New(), Title: "Аренда зала", UserID: owner, OrgID: &org.ID}
+	personal := &domain.Chat{ID: uuid.New(), Title: "Аренда квартиры", UserID: owner}
+	for _, chat := range []*domain.Chat{orgChat, personal} {
+		require.NoError(t, chatRepo.Create(ctx, chat))
+	}
+
+	// Участник находит чаты организации, но не личные чаты других участников
+	hits, err := repo.Search(ctx, domain.ChatScope{UserID: viewer, OrgIDs: []uuid.UUID{org.ID}}, "аренда", 10, 0)
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+	require.Equal(t, orgChat.ID, hits[0].ChatID)
+
+	hits, err = repo.Search(ctx, domain.ChatScope{UserID: owner, OrgIDs: []uuid.UUID{org.ID}}, "аренда", 10, 0)
+	require.NoError(t, err)
+	require.Len(t, hits, 2)
+
+	// Организация вне scope не ищется, даже если пользователь в ней состоит
+	hits, err = repo.Search(ctx, domain.ChatScope{UserID: viewer}, "аренда", 10, 0)
+	require.NoError(t, err)
+	require.Empty(t, hits)
+}
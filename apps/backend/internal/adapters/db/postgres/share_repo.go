@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"backend/internal/domain"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ShareRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewShareRepo(pool *pgxpool.Pool) *ShareRepo {
+	return &ShareRepo{pool: pool}
+}
+
+const shareColumns = `id, chat_id, user_id, token_hash, message_id, mask_pii, expires_at, revoked_at, created_at`
+
+func scanShare(row pgx.Row) (*domain.ChatShare, error) {
+	var share domain.ChatShare
+	err := row.Scan(&share.ID, &share.ChatID, &share.UserID, &share.TokenHash, &share.MessageID,
+		&share.MaskPII, &share.ExpiresAt, &share.RevokedAt, &share.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+func (s *ShareRepo) Create(ctx context.Context, share *domain.ChatShare) error {
+	const q = `
+	INSERT INTO app.chat_shares (id, chat_id, user_id, token_hash, message_id, mask_pii, expires_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING created_at;
+	`
+
+	return conn(ctx, s.pool).QueryRow(ctx, q, share.ID, share.ChatID, share.UserID, share.TokenHash,
+		share.MessageID, share.MaskPII, share.ExpiresAt).Scan(&share.CreatedAt)
+}
+
+func (s *ShareRepo) GetByID(ctx context.Context, shareID uuid.UUID) (*domain.ChatShare, error) {
+	q := `SELECT ` + shareColumns + ` FROM app.chat_shares WHERE id = $1;`
+
+	share, err := scanShare(conn(ctx, s.pool).QueryRow(ctx, q, shareID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return share, err
+}
+
+func (s *ShareRepo) GetByTokenHash(ctx context.Context, tokenHash []byte) (*domain.ChatShare, error) {
+	q := `SELECT ` + shareColumns + ` FROM app.chat_shares WHERE token_hash = $1;`
+
+	share, err := scanShare(conn(ctx, s.pool).QueryRow(ctx, q, tokenHash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return share, err
+}
+
+func (s *ShareRepo) ListByChat(ctx context.Context, chatID uuid.UUID) ([]*domain.ChatShare, error) {
+	q := `SELECT ` + shareColumns + ` FROM app.chat_shares WHERE chat_id = $1 ORDER BY created_at DESC;`
+
+	rows, err := conn(ctx, s.pool).Query(ctx, q, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shares []*domain.ChatShare
+	for rows.Next() {
+		share, err := scanShare(rows)
+		if err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+
+	return shares, rows.Err()
+}
+
+func (s *ShareRepo) Revoke(ctx context.Context, shareID uuid.UUID, at time.Time) error {
+	const q = `
+	UPDATE app.chat_shares
+	SET revoked_at = COALESCE(revoked_at, $2)
+	WHERE id = $1;
+	`
+
+	_, err := conn(ctx, s.pool).Exec(ctx, q, shareID, at)
+	return err
+}
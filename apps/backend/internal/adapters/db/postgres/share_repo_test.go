@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"backend/internal/domain"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShareRepo_CreateGetRevoke(t *testing.T) {
+	ctx := context.Background()
+	repo := NewShareRepo(testPool)
+	chatRepo := NewChatRepo(testPool)
+	msgRepo := NewMessageRepo(testPool)
+
+	_, err := testPool.Exec(ctx, "TRUNCATE app.chats CASCADE")
+	require.NoError(t, err)
+	_, err = testPool.Exec(ctx, "TRUNCATE app.users CASCADE")
+	require.NoError(t, err)
+
+	userID := insertTestUser(t, ctx)
+	chat := &domain.Chat{ID: uuid.New(), Title: "Договор", UserID: userID}
+	require.NoError(t, chatRepo.Create(ctx, chat))
+	msg := appendTestMessage(t, ctx, msgRepo, chat.ID, nil, "user", "вопрос")
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Microsecond)
+	share := &domain.ChatShare{
+		ID:        uuid.New(),
+		ChatID:    chat.ID,
+		UserID:    userID,
+		TokenHash: []byte("hash-1"),
+		MessageID: &msg.ID,
+		MaskPII:   true,
+		ExpiresAt: &expiresAt,
+	}
+	require.NoError(t, repo.Create(ctx, share))
+	require.False(t, share.CreatedAt.IsZero())
+
+	got, err := repo.GetByTokenHash(ctx, []byte("hash-1"))
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	require.Equal(t, share.ID, got.ID)
+	require.Equal(t, msg.ID, *got.MessageID)
+	require.True(t, got.MaskPII)
+	require.True(t, expiresAt.Equal(*got.ExpiresAt))
+	require.True(t, got.Active(time.Now()))
+
+	missing, err := repo.GetByTokenHash(ctx, []byte("unknown"))
+	require.NoError(t, err)
+	require.Nil(t, missing)
+
+	// Повторный отзыв не сдвигает время первого
+	revokedAt := time.Now().UTC().Truncate(time.Microsecond)
+	require.NoError(t, repo.Revoke(ctx, share.ID, revokedAt))
+	require.NoError(t, repo.Revoke(ctx, share.ID, revokedAt.Add(time.Hour)))
+
+	got, err = repo.GetByID(ctx, share.ID)
+	require.NoError(t, err)
+	require.True(t, revokedAt.Equal(*got.RevokedAt))
+	require.False(t, got.Active(time.Now()))
+
+	shares, err := repo.ListByChat(ctx, chat.ID)
+	require.NoError(t, err)
+	require.Len(t, shares, 1)
+
+	// Ссылки удаляются вместе с чатом
+	_, err = testPool.Exec(ctx, "DELETE FROM app.chats WHERE id = $1", chat.ID)
+	require.NoError(t, err)
+	got, err = repo.GetByID(ctx, share.ID)
+	require.NoError(t, err)
+	require.Nil(t, got)
+}
@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"backend/internal/domain"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxManager_WithinTx(t *testing.T) {
+	ctx := context.Background()
+	txManager := NewTxManager(testPool)
+	msgRepo := NewMessageRepo(testPool)
+	chatRepo := NewChatRepo(testPool)
+
+	_, err := testPool.Exec(ctx, "TRUNCATE app.chats CASCADE")
+	require.NoError(t, err)
+	_, err = testPool.Exec(ctx, "TRUNCATE app.users CASCADE")
+	require.NoError(t, err)
+
+	chat := &domain.Chat{ID: uuid.New(), Title: "tx", UserID: insertTestUser(t, ctx)}
+	require.NoError(t, chatRepo.Create(ctx, chat))
+
+	question := &domain.Message{ID: uuid.New(), ChatID: chat.ID, Role: "user", Content: "q", Status: domain.MessageStatusPending}
+	require.NoError(t, msgRepo.Append(ctx, question))
+
+	// Ошибка внутри транзакции откатывает и ответ, и смену статуса
+	failed := errors.New("touch failed")
+	answer := &domain.Message{ID: uuid.New(), ChatID: chat.ID, ParentID: &question.ID, Role: "assistant", Content: "a"}
+	err = txManager.WithinTx(ctx, func(ctx context.Context) error {
+		require.NoError(t, msgRepo.Append(ctx, answer))
+		require.NoError(t, msgRepo.UpdateStatus(ctx, question.ID, domain.MessageStatusComplete))
+		return failed
+	})
+	require.ErrorIs(t, err, failed)
+
+	got, err := msgRepo.GetByID(ctx, answer.ID)
+	require.NoError(t, err)
+	require.Nil(t, got)
+	got, err = msgRepo.GetByID(ctx, question.ID)
+	require.NoError(t, err)
+	require.Equal(t, domain.MessageStatusPending, got.Status)
+
+	// Успешная транзакция сохраняет всё
+	touchTime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.Local)
+	err = txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := msgRepo.Append(ctx, answer); err != nil {
+			return err
+		}
+		if err := msgRepo.UpdateStatus(ctx, question.ID, domain.MessageStatusComplete); err != nil {
+			return err
+		}
+		return chatRepo.Touch(ctx, chat.ID, touchTime)
+	})
+	require.NoError(t, err)
+
+	got, err = msgRepo.GetByID(ctx, question.ID)
+	require.NoError(t, err)
+	require.Equal(t, domain.MessageStatusComplete, got.Status)
+
+	var lastMessageAt time.Time
+	require.NoError(t, testPool.QueryRow(ctx, `SELECT last_message_at FROM app.chats WHERE id = $1`, chat.ID).Scan(&lastMessageAt))
+	require.Equal(t, touchTime, lastMessageAt)
+}
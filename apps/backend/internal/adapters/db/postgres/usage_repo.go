@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"backend/internal/domain"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type UsageRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewUsageRepo(pool *pgxpool.Pool) *UsageRepo {
+	return &UsageRepo{pool: pool}
+}
+
+func (u *UsageRepo) Add(ctx context.Context, usage *domain.Usage) error {
+	const q = `
+	INSERT INTO app.usage (user_id, day, requests, prompt_tokens, completion_tokens, document_bytes)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (user_id, day) DO UPDATE
+	SET requests          = app.usage.requests + EXCLUDED.requests,
+	    prompt_tokens     = app.usage.prompt_tokens + EXCLUDED.prompt_tokens,
+	    completion_tokens = app.usage.completion_tokens + EXCLUDED.completion_tokens,
+	    document_bytes    = app.usage.document_bytes + EXCLUDED.document_bytes;
+	`
+
+	_, err := u.pool.Exec(ctx, q,
+		usage.UserID,
+		usage.Day,
+		usage.Requests,
+		usage.PromptTokens,
+		usage.CompletionTokens,
+		usage.DocumentBytes,
+	)
+	return err
+}
+
+func (u *UsageRepo) Sum(ctx context.Context, userID uuid.UUID, from, to time.Time) (*domain.Usage, error) {
+	const q = `
+	SELECT COALESCE(SUM(requests), 0),
+	       COALESCE(SUM(prompt_tokens), 0),
+	       COALESCE(SUM(completion_tokens), 0),
+	       COALESCE(SUM(document_bytes), 0)
+	FROM app.usage
+	WHERE user_id = $1
+	  AND day BETWEEN $2 AND $3;
+	`
+
+	usage := domain.Usage{UserID: userID, Day: from}
+	err := u.pool.QueryRow(ctx, q, userID, from, to).Scan(
+		&usage.Requests,
+		&usage.PromptTokens,
+		&usage.CompletionTokens,
+		&usage.DocumentBytes,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &usage, nil
+}
+
+func (u *UsageRepo) GetQuota(ctx context.Context, userID uuid.UUID) (*domain.Quota, error) {
+	const q = `
+	SELECT user_id, daily_tokens, monthly_tokens, daily_requests, monthly_requests,
+	       daily_document_bytes, monthly_document_bytes, updated_at
+	FROM app.quotas
+	WHERE user_id = $1;
+	`
+
+	var quota domain.Quota
+	err := u.pool.QueryRow(ctx, q, userID).Scan(
+		&quota.UserID,
+		&quota.DailyTokens,
+		&quota.MonthlyTokens,
+		&quota.DailyRequests,
+		&quota.MonthlyRequests,
+		&quota.DailyDocumentBytes,
+		&quota.MonthlyDocumentBytes,
+		&quota.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &quota, nil
+}
+
+func (u *UsageRepo) SetQuota(ctx context.Context, quota *domain.Quota) error {
+	const q = `
+	INSERT INTO app.quotas (user_id, daily_tokens, monthly_tokens, daily_requests, monthly_requests,
+	                        daily_document_bytes, monthly_document_bytes, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, now())
+	ON CONFLICT (user_id) DO UPDATE
+	SET daily_tokens           = EXCLUDED.daily_tokens,
+	    monthly_tokens         = EXCLUDED.monthly_tokens,
+	    daily_requests         = EXCLUDED.daily_requests,
+	    monthly_requests       = EXCLUDED.monthly_requests,
+	    daily_document_bytes   = EXCLUDED.daily_document_bytes,
+	    monthly_document_bytes = EXCLUDED.monthly_document_bytes,
+	    updated_at             = now()
+	RETURNING updated_at;
+	`
+
+	return u.pool.QueryRow(ctx, q,
+		quota.UserID,
+		quota.DailyTokens,
+		quota.MonthlyTokens,
+		quota.DailyRequests,
+		quota.MonthlyRequests,
+		quota.DailyDocumentBytes,
+		quota.MonthlyDocumentBytes,
+	).Scan(&quota.UpdatedAt)
+}
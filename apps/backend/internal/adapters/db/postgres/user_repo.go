@@ -3,10 +3,13 @@ package postgres
 import (
 	"backend/internal/domain"
 	"context"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -18,11 +21,12 @@ type UserRepo struct {
 	pool *pgxpool.Pool
 }
 
+// GetByEmail ищет пользователя по email без учёта регистра
 func (u *UserRepo) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
 	const q = `
-		SELECT id, email, password_hash, is_active, created_at, last_login_at
+		SELECT id, email, password_hash, is_active, role, token_version, must_change_password, created_at, last_login_at
 		FROM auth.users
-		WHERE email = $1;
+		WHERE lower(email) = lower($1);
 	`
 
 	var user domain.User
@@ -33,6 +37,42 @@ func (u *UserRepo) GetByEmail(ctx context.Context, email string) (*domain.User,
 		&user.Email,
 		&user.PasswordHash,
 		&user.IsActive,
+		&user.Role,
+		&user.TokenVersion,
+		&user.MustChangePassword,
+		&user.CreatedAt,
+		&lastLoginAt,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	user.LastLoginAt = lastLoginAt
+	return &user, nil
+}
+
+func (u *UserRepo) GetByID(ctx context.Context, userID uuid.UUID) (*domain.User, error) {
+	const q = `
+		SELECT id, email, password_hash, is_active, role, token_version, must_change_password, created_at, last_login_at
+		FROM auth.users
+		WHERE id = $1;
+	`
+
+	var user domain.User
+	var lastLoginAt *time.Time
+
+	err := u.pool.QueryRow(ctx, q, userID).Scan(
+		&user.ID,
+		&user.Email,
+		&user.PasswordHash,
+		&user.IsActive,
+		&user.Role,
+		&user.TokenVersion,
+		&user.MustChangePassword,
 		&user.CreatedAt,
 		&lastLoginAt,
 	)
@@ -58,3 +98,147 @@ func (u *UserRepo) UpdateLastLogin(ctx context.Context, userID uuid.UUID) error
 	_, err := u.pool.Exec(ctx, q, userID)
 	return err
 }
+
+// Create сохраняет пользователя в auth.users и в app.users, на который ссылаются чаты и потребление
+func (u *UserRepo) Create(ctx context.Context, user *domain.User) error {
+	const q = `
+		WITH created AS (
+			INSERT INTO auth.users (id, email, password_hash, is_active, role, must_change_password)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id, email, created_at
+		)
+		INSERT INTO app.users (id, email, created_at)
+		SELECT id, email, created_at FROM created
+		RETURNING created_at;
+	`
+
+	err := conn(ctx, u.pool).QueryRow(ctx, q,
+		user.ID,
+		user.Email,
+		user.PasswordHash,
+		user.IsActive,
+		user.Role,
+		user.MustChangePassword,
+	).Scan(&user.CreatedAt)
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return domain.ErrUserExists
+	}
+	return err
+}
+
+// overviewColumns - пользователь без хэша пароля и число его чатов
+const overviewColumns = `
+	u.id, u.email, u.is_active, u.role, u.token_version, u.must_change_password, u.created_at, u.last_login_at,
+	(SELECT count(*) FROM app.chats c WHERE c.user_id = u.id)
+`
+
+func (u *UserRepo) List(ctx context.Context, query string, limit, offset int) ([]*domain.UserOverview, error) {
+	const q = `
+		SELECT ` + overviewColumns + `
+		FROM auth.users u
+		WHERE $1 = '' OR u.email ILIKE '%' || $1 || '%' ESCAPE '\'
+		ORDER BY u.created_at DESC, u.id
+		LIMIT $2 OFFSET $3;
+	`
+
+	rows, err := conn(ctx, u.pool).Query(ctx, q, escapeLike(query), limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*domain.UserOverview
+	for rows.Next() {
+		user, err := scanOverview(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+func (u *UserRepo) GetOverview(ctx context.Context, userID uuid.UUID) (*domain.UserOverview, error) {
+	const q = `
+		SELECT ` + overviewColumns + `
+		FROM auth.users u
+		WHERE u.id = $1;
+	`
+
+	user, err := scanOverview(conn(ctx, u.pool).QueryRow(ctx, q, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (u *UserRepo) SetActive(ctx context.Context, userID uuid.UUID, active bool) error {
+	const q = `
+		UPDATE auth.users
+		SET is_active = $2
+		WHERE id = $1;
+	`
+
+	return u.update(ctx, q, userID, active)
+}
+
+func (u *UserRepo) UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash string, temporary bool) error {
+	const q = `
+		UPDATE auth.users
+		SET password_hash = $2, must_change_password = $3
+		WHERE id = $1;
+	`
+
+	return u.update(ctx, q, userID, passwordHash, temporary)
+}
+
+func (u *UserRepo) RevokeTokens(ctx context.Context, userID uuid.UUID) error {
+	const q = `
+		UPDATE auth.users
+		SET token_version = token_version + 1
+		WHERE id = $1;
+	`
+
+	return u.update(ctx, q, userID)
+}
+
+// update выполняет UPDATE одного пользователя; если строка не найдена - domain.ErrUserNotFound
+func (u *UserRepo) update(ctx context.Context, q string, args ...any) error {
+	tag, err := conn(ctx, u.pool).Exec(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+func scanOverview(row pgx.Row) (*domain.UserOverview, error) {
+	var user domain.UserOverview
+	err := row.Scan(
+		&user.ID,
+		&user.Email,
+		&user.IsActive,
+		&user.Role,
+		&user.TokenVersion,
+		&user.MustChangePassword,
+		&user.CreatedAt,
+		&user.LastLoginAt,
+		&user.Chats,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// escapeLike экранирует спецсимволы LIKE, чтобы query искался как подстрока
+func escapeLike(query string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(query)
+}
@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"backend/internal/domain"
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserRepo_AdminOperations(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepo(testPool)
+	chatRepo := NewChatRepo(testPool)
+
+	_, err := testPool.Exec(ctx, "TRUNCATE auth.users, app.users CASCADE")
+	require.NoError(t, err)
+
+	anna := &domain.User{ID: uuid.New(), Email: "anna_b@example.com", PasswordHash: "hash-1", IsActive: true,
+		Role: domain.UserRoleUser, MustChangePassword: true}
+	require.NoError(t, repo.Create(ctx, anna))
+	require.False(t, anna.CreatedAt.IsZero())
+
+	boris := &domain.User{ID: uuid.New(), Email: "boris@example.com", PasswordHash: "hash-2", IsActive: true, Role: domain.UserRoleAdmin}
+	require.NoError(t, repo.Create(ctx, boris))
+
+	err = repo.Create(ctx, &domain.User{ID: uuid.New(), Email: "anna_b@example.com", PasswordHash: "x", Role: domain.UserRoleUser})
+	require.ErrorIs(t, err, domain.ErrUserExists)
+	err = repo.Create(ctx, &domain.User{ID: uuid.New(), Email: "Anna_B@Example.com", PasswordHash: "x", Role: domain.UserRoleUser})
+	require.ErrorIs(t, err, domain.ErrUserExists, "email is unique regardless of case")
+
+	byEmail, err := repo.GetByEmail(ctx, "ANNA_B@example.com")
+	require.NoError(t, err)
+	require.Equal(t, anna.ID, byEmail.ID)
+
+	// Созданный пользователь может владеть чатами: запись в app.users появилась вместе с auth.users
+	require.NoError(t, chatRepo.Create(ctx, &domain.Chat{ID: uuid.New(), Title: "Налоги", UserID: anna.ID}))
+
+	all, err := repo.List(ctx, "", 10, 0)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	require.Equal(t, boris.ID, all[0].ID, "newest first")
+
+	found, err := repo.List(ctx, "ANNA", 10, 0)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	require.Equal(t, 1, found[0].Chats)
+	require.True(t, found[0].MustChangePassword)
+	require.Empty(t, found[0].PasswordHash)
+
+	// Спецсимволы LIKE ищутся как обычные символы
+	found, err = repo.List(ctx, "a_b", 10, 0)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	found, err = repo.List(ctx, "%", 10, 0)
+	require.NoError(t, err)
+	require.Empty(t, found)
+
+	require.NoError(t, repo.SetActive(ctx, anna.ID, false))
+	require.NoError(t, repo.UpdatePassword(ctx, anna.ID, "hash-3", false))
+	require.NoError(t, repo.RevokeTokens(ctx, anna.ID))
+
+	got, err := repo.GetByID(ctx, anna.ID)
+	require.NoError(t, err)
+	require.False(t, got.IsActive)
+	require.Equal(t, "hash-3", got.PasswordHash)
+	require.False(t, got.MustChangePassword)
+	require.Equal(t, 1, got.TokenVersion)
+
+	overview, err := repo.GetOverview(ctx, anna.ID)
+	require.NoError(t, err)
+	require.Equal(t, 1, overview.Chats)
+
+	missing := uuid.New()
+	overview, err = repo.GetOverview(ctx, missing)
+	require.NoError(t, err)
+	require.Nil(t, overview)
+	require.ErrorIs(t, repo.SetActive(ctx, missing, true), domain.ErrUserNotFound)
+	require.ErrorIs(t, repo.RevokeTokens(ctx, missing), domain.ErrUserNotFound)
+}
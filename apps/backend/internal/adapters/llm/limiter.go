@@ -0,0 +1,45 @@
+package llm
+
+import (
+	"backend/internal/metrics"
+	"context"
+	"fmt"
+)
+
+// Limiter ограничивает число одновременных запросов к Ollama. Один Limiter
+// передаётся всем клиентам через Config, чтобы основная модель и классификаторы
+// делили общий лимит, а не получали каждый свой
+type Limiter struct {
+	slots chan struct{}
+}
+
+// NewLimiter создаёт лимит на n одновременных генераций; при n <= 0 возвращает nil - без ограничений
+func NewLimiter(n int) *Limiter {
+	if n <= 0 {
+		return nil
+	}
+
+	metrics.SetLLMSlots(n)
+	return &Limiter{slots: make(chan struct{}, n)}
+}
+
+// acquire ждёт свободный слот; возвращаемая функция освобождает его
+func (l *Limiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	waited := metrics.LLMSlotWaiting()
+	select {
+	case l.slots <- struct{}{}:
+		waited()
+		released := metrics.LLMSlotAcquired()
+		return func() {
+			<-l.slots
+			released()
+		}, nil
+	case <-ctx.Done():
+		waited()
+		return nil, fmt.Errorf("waiting for LLM slot: %w", ctx.Err())
+	}
+}
@@ -2,6 +2,7 @@ package llm
 
 import (
 	"backend/internal/domain"
+	"backend/internal/metrics"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -10,157 +11,329 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("backend/internal/adapters/llm")
+
 type Config struct {
-	BaseURL         string
-	Model           string
-	Temperature     float32
-	TopP            float32
-	MaxTokens       int
-	Timeout         time.Duration
-	EnableWebSearch bool
+	BaseURL     string
+	Model       string
+	Temperature float32
+	TopP        float32
+	MaxTokens   int
+	Timeout     time.Duration
+	// JSONOutput - просить модель отвечать валидным JSON (format: "json"), например для классификаторов
+	JSONOutput bool
+	// Limiter - общий лимит одновременных генераций, nil - без ограничений
+	Limiter *Limiter
 }
 
 type OllamaClient struct {
-	client    *http.Client
-	config    Config
-	webSearch *WebSearch
+	client *http.Client
+	config Config
 }
 
 func NewOllamaClient(client *http.Client, config Config) *OllamaClient {
-	oc := &OllamaClient{
+	return &OllamaClient{
 		client: client,
 		config: config,
 	}
+}
+
+func (c *OllamaClient) Generate(ctx context.Context, prompt []byte) (*domain.Generation, error) {
+	ctx, span := tracer.Start(ctx, "ollama.Generate", trace.WithAttributes(
+		attribute.String("llm.model", c.config.Model),
+		attribute.Int("llm.prompt_bytes", len(prompt)),
+	))
+	defer span.End()
+
+	return c.observe(ctx, span, func(ctx context.Context) (*domain.Generation, error) {
+		return c.generate(ctx, prompt)
+	})
+}
 
-	if config.EnableWebSearch {
-		oc.webSearch = NewWebSearch(client)
+// Chat отправляет диалог в /api/chat вместе с описанием инструментов
+func (c *OllamaClient) Chat(ctx context.Context, messages []domain.ChatMessage, tools []domain.ToolSpec) (*domain.Generation, error) {
+	ctx, span := tracer.Start(ctx, "ollama.Chat", trace.WithAttributes(
+		attribute.String("llm.model", c.config.Model),
+		attribute.Int("llm.messages", len(messages)),
+		attribute.Int("llm.tools", len(tools)),
+	))
+	defer span.End()
+
+	generation, err := c.observe(ctx, span, func(ctx context.Context) (*domain.Generation, error) {
+		return c.chat(ctx, messages, tools)
+	})
+	if err == nil {
+		span.SetAttributes(attribute.Int("llm.tool_calls", len(generation.ToolCalls)))
 	}
 
-	return oc
+	return generation, err
 }
 
-func (c *OllamaClient) Generate(ctx context.Context, prompt []byte) (string, error) {
-	promptStr := string(prompt)
+// observe занимает слот в общем лимите и пишет метрики и атрибуты спана вокруг вызова Ollama
+func (c *OllamaClient) observe(ctx context.Context, span trace.Span, call func(ctx context.Context) (*domain.Generation, error)) (*domain.Generation, error) {
+	release, err := c.config.Limiter.acquire(ctx)
+	if err != nil {
+		recordSpanError(span, err)
+		return nil, err
+	}
+	defer release()
 
-	if c.config.EnableWebSearch && c.webSearch != nil && c.needsWebSearch(promptStr) {
-		webResults, err := c.performWebSearch(ctx, promptStr)
-		if err == nil && len(webResults) > 0 {
-			promptStr = c.enrichPromptWithWebSearch(promptStr, webResults)
-		}
+	defer metrics.LLMStarted(c.config.Model)()
+
+	start := time.Now()
+	generation, err := call(ctx)
+	if err != nil {
+		metrics.ObserveLLMGeneration(c.config.Model, time.Since(start), 0, 0, err)
+		recordSpanError(span, err)
+		return nil, err
 	}
 
+	metrics.ObserveLLMGeneration(c.config.Model, time.Since(start), generation.PromptTokens, generation.CompletionTokens, nil)
+	span.SetAttributes(
+		attribute.Int("llm.prompt_tokens", generation.PromptTokens),
+		attribute.Int("llm.completion_tokens", generation.CompletionTokens),
+	)
+	return generation, nil
+}
+
+func (c *OllamaClient) generate(ctx context.Context, prompt []byte) (*domain.Generation, error) {
 	url := fmt.Sprintf("%s/api/generate", c.config.BaseURL)
 
 	requestBody := map[string]interface{}{
-		"model":       c.config.Model,
-		"prompt":      promptStr,
-		"stream":      false,
+		"model":   c.config.Model,
+		"prompt":  string(prompt),
+		"stream":  false,
+		"options": c.options(),
+	}
+	if c.config.JSONOutput {
+		requestBody["format"] = "json"
+	}
+
+	var response struct {
+		Model           string `json:"model"`
+		Response        string `json:"response"`
+		PromptEvalCount int    `json:"prompt_eval_count"`
+		EvalCount       int    `json:"eval_count"`
+	}
+
+	if err := c.post(ctx, url, requestBody, &response); err != nil {
+		return nil, err
+	}
+
+	return &domain.Generation{
+		Content:          response.Response,
+		Model:            response.Model,
+		PromptTokens:     response.PromptEvalCount,
+		CompletionTokens: response.EvalCount,
+	}, nil
+}
+
+// options - параметры генерации. Ollama читает их только из поля options, на верхнем уровне запроса они игнорируются
+func (c *OllamaClient) options() map[string]interface{} {
+	return map[string]interface{}{
 		"temperature": c.config.Temperature,
 		"top_p":       c.config.TopP,
 		"num_predict": c.config.MaxTokens,
 	}
+}
+
+// ollamaChatMessage - сообщение в формате /api/chat
+type ollamaChatMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaTool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description"`
+		Parameters  json.RawMessage `json:"parameters"`
+	} `json:"function"`
+}
+
+func (c *OllamaClient) chat(ctx context.Context, messages []domain.ChatMessage, tools []domain.ToolSpec) (*domain.Generation, error) {
+	url := fmt.Sprintf("%s/api/chat", c.config.BaseURL)
+
+	chatMessages := make([]ollamaChatMessage, 0, len(messages))
+	for _, m := range messages {
+		msg := ollamaChatMessage{
+			Role:     string(m.Role),
+			Content:  m.Content,
+			ToolName: m.ToolName,
+		}
+		for _, call := range m.ToolCalls {
+			var tc ollamaToolCall
+			tc.Function.Name = call.Name
+			tc.Function.Arguments = call.Arguments
+			msg.ToolCalls = append(msg.ToolCalls, tc)
+		}
+		chatMessages = append(chatMessages, msg)
+	}
+
+	chatTools := make([]ollamaTool, 0, len(tools))
+	for _, t := range tools {
+		tool := ollamaTool{Type: "function"}
+		tool.Function.Name = t.Name
+		tool.Function.Description = t.Description
+		tool.Function.Parameters = t.Parameters
+		chatTools = append(chatTools, tool)
+	}
 
-	jsonData, err := json.Marshal(requestBody)
+	requestBody := map[string]interface{}{
+		"model":    c.config.Model,
+		"messages": chatMessages,
+		"stream":   false,
+		"options":  c.options(),
+	}
+	if len(chatTools) > 0 {
+		requestBody["tools"] = chatTools
+	}
+	if c.config.JSONOutput {
+		requestBody["format"] = "json"
+	}
+
+	var response struct {
+		Model           string            `json:"model"`
+		Message         ollamaChatMessage `json:"message"`
+		PromptEvalCount int               `json:"prompt_eval_count"`
+		EvalCount       int               `json:"eval_count"`
+	}
+
+	if err := c.post(ctx, url, requestBody, &response); err != nil {
+		return nil, err
+	}
+
+	generation := &domain.Generation{
+		Content:          response.Message.Content,
+		Model:            response.Model,
+		PromptTokens:     response.PromptEvalCount,
+		CompletionTokens: response.EvalCount,
+	}
+	for _, tc := range response.Message.ToolCalls {
+		generation.ToolCalls = append(generation.ToolCalls, domain.ToolCall{
+			Name:      tc.Function.Name,
+			Arguments: tc.Function.Arguments,
+		})
+	}
+
+	return generation, nil
+}
+
+// post отправляет JSON-запрос в Ollama и декодирует ответ в out
+func (c *OllamaClient) post(ctx context.Context, url string, body interface{}, out interface{}) error {
+	jsonData, err := json.Marshal(body)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := c.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+		return fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("ollama API returned status %d: %s", resp.StatusCode, string(body))
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("ollama API returned status %d: %s", resp.StatusCode, string(respBody))
 	}
 
-	var response struct {
-		Response string `json:"response"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	return response.Response, nil
+	return nil
 }
 
-func (c *OllamaClient) needsWebSearch(prompt string) bool {
-	lowerPrompt := strings.ToLower(prompt)
+// ListModels возвращает имена моделей, загруженных в Ollama (GET /api/tags)
+func (c *OllamaClient) ListModels(ctx context.Context) ([]string, error) {
+	url := fmt.Sprintf("%s/api/tags", c.config.BaseURL)
 
-	searchKeywords := []string{
-		"текущий", "актуальный", "сегодня", "сейчас", "последний",
-		"новости", "события", "курс", "цена", "погода",
-		"когда", "где находится", "адрес", "расписание",
-		"что происходит", "что случилось", "когда будет",
-		"проверь в интернете", "найди в интернете", "поищи",
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	for _, keyword := range searchKeywords {
-		if strings.Contains(lowerPrompt, keyword) {
-			return true
-		}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
+	defer resp.Body.Close()
 
-	// Проверяем, есть ли явная инструкция о веб-поиске
-	if strings.Contains(lowerPrompt, "[web_search]") || strings.Contains(lowerPrompt, "[поиск]") {
-		return true
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ollama API returned status %d", resp.StatusCode)
 	}
 
-	return false
-}
-
-func (c *OllamaClient) performWebSearch(ctx context.Context, prompt string) ([]SearchResult, error) {
-	parts := strings.Split(prompt, "USER:")
-	query := prompt
-	if len(parts) > 1 {
-		query = strings.TrimSpace(parts[len(parts)-1])
+	var response struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
 	}
 
-	// Удаляем метки веб-поиска
-	query = strings.ReplaceAll(query, "[web_search]", "")
-	query = strings.ReplaceAll(query, "[поиск]", "")
-	query = strings.TrimSpace(query)
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
 
-	if query == "" {
-		return nil, fmt.Errorf("empty search query")
+	names := make([]string, 0, len(response.Models))
+	for _, m := range response.Models {
+		names = append(names, m.Name)
 	}
 
-	return c.webSearch.Search(ctx, query, 5)
+	return names, nil
 }
 
-func (c *OllamaClient) enrichPromptWithWebSearch(prompt string, results []SearchResult) string {
-	var webInfo strings.Builder
-	webInfo.WriteString("\n\n[WEB_SEARCH_RESULTS]\n")
-	webInfo.WriteString("Я выполнил поиск в интернете по вашему запросу. Вот найденная информация:\n\n")
-
-	for i, result := range results {
-		webInfo.WriteString(fmt.Sprintf("Результат %d:\n", i+1))
-		webInfo.WriteString(result.Format())
-		webInfo.WriteString("\n\n")
+// CheckModel проверяет, что Ollama доступна и модель из Config.Model уже загружена
+func (c *OllamaClient) CheckModel(ctx context.Context) error {
+	models, err := c.ListModels(ctx)
+	if err != nil {
+		return err
 	}
 
-	webInfo.WriteString("Используйте эту информацию для ответа на вопрос пользователя. Если информация не найдена, используйте свои знания.\n")
+	want := c.config.Model
+	if !strings.Contains(want, ":") {
+		// Ollama хранит модели с тегом, "mistral" == "mistral:latest"
+		want += ":latest"
+	}
 
-	parts := strings.Split(prompt, "USER:")
-	if len(parts) > 1 {
-		prompt = parts[0] + webInfo.String() + "\nUSER:\n" + parts[len(parts)-1]
-	} else {
-		prompt = prompt + webInfo.String()
+	for _, name := range models {
+		if name == want || name == c.config.Model {
+			return nil
+		}
 	}
 
-	return prompt
+	return fmt.Errorf("model %q is not pulled", c.config.Model)
+}
+
+// recordSpanError помечает спан ошибкой
+func recordSpanError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
 }
 
-var _ domain.LLM = (*OllamaClient)(nil)
+var (
+	_ domain.LLM     = (*OllamaClient)(nil)
+	_ domain.ToolLLM = (*OllamaClient)(nil)
+)
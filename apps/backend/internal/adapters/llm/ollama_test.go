@@ -0,0 +1,83 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Параметры генерации уходят в options: на верхнем уровне запроса Ollama их не читает
+func TestOllamaClient_SendsSamplingOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		call func(c *OllamaClient) error
+	}{
+		{
+			name: "generate",
+			path: "/api/generate",
+			call: func(c *OllamaClient) error {
+				_, err := c.Generate(context.Background(), []byte("нужен ли поиск?"))
+				return err
+			},
+		},
+		{
+			name: "chat",
+			path: "/api/chat",
+			call: func(c *OllamaClient) error {
+				_, err := c.Chat(context.Background(), nil, nil)
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]any
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tt.path {
+					t.Errorf("path = %q, want %q", r.URL.Path, tt.path)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("request is not JSON: %v", err)
+				}
+				_, _ = w.Write([]byte(`{"model": "classifier", "response": "{}", "message": {"role": "assistant", "content": "{}"}}`))
+			}))
+			defer server.Close()
+
+			client := NewOllamaClient(server.Client(), Config{
+				BaseURL:     server.URL,
+				Model:       "classifier",
+				Temperature: 0,
+				TopP:        0.5,
+				MaxTokens:   128,
+				JSONOutput:  true,
+			})
+			if err := tt.call(client); err != nil {
+				t.Fatalf("call error = %v", err)
+			}
+
+			for _, key := range []string{"temperature", "top_p", "num_predict"} {
+				if _, ok := body[key]; ok {
+					t.Fatalf("%s is sent at the top level:\n%v", key, body)
+				}
+			}
+
+			options, ok := body["options"].(map[string]any)
+			if !ok {
+				t.Fatalf("request has no options:\n%v", body)
+			}
+			want := map[string]float64{"temperature": 0, "top_p": 0.5, "num_predict": 128}
+			for key, value := range want {
+				if got, ok := options[key].(float64); !ok || got != value {
+					t.Fatalf("options[%s] = %v, want %v", key, options[key], value)
+				}
+			}
+			if body["format"] != "json" {
+				t.Fatalf("format = %v, want json", body["format"])
+			}
+		})
+	}
+}
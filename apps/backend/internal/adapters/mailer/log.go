@@ -0,0 +1,24 @@
+package mailer
+
+import (
+	"backend/internal/domain"
+	"backend/internal/logging"
+	"context"
+	"log/slog"
+)
+
+// LogMailer не отправляет письма, а отмечает их в логе - для разработки без SMTP-сервера.
+// Адрес и текст письма в лог не попадают: в тексте приглашения - секретная ссылка
+type LogMailer struct{}
+
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+func (LogMailer) Send(ctx context.Context, email *domain.Email) error {
+	logging.FromContext(ctx).InfoContext(ctx, "email is not sent: SMTP is not configured",
+		slog.String("subject", email.Subject),
+		slog.Int("text_bytes", len(email.Text)),
+	)
+	return nil
+}
@@ -0,0 +1,35 @@
+package mailer
+
+import (
+	"backend/internal/domain"
+	"backend/internal/logging"
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogMailer_DoesNotLogSecrets(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := logging.WithLogger(context.Background(), slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	err := NewLogMailer().Send(ctx, &domain.Email{
+		To:      "anna@example.com",
+		Subject: "Приглашение в «Кофейня»",
+		Text:    "Откройте ссылку:\nhttps://copilot.example.com/invite?token=secret-token",
+	})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	out := buf.String()
+	for _, secret := range []string{"anna@example.com", "secret-token"} {
+		if strings.Contains(out, secret) {
+			t.Fatalf("log contains %q: %s", secret, out)
+		}
+	}
+	if !strings.Contains(out, "Кофейня") {
+		t.Fatalf("log has no subject: %s", out)
+	}
+}
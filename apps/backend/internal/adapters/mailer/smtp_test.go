@@ -0,0 +1,59 @@
+package mailer
+
+import (
+	"backend/internal/domain"
+	"bytes"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildMessage(t *testing.T) {
+	from := &mail.Address{Name: "Alfa Copilot", Address: "noreply@copilot.example.com"}
+	to := &mail.Address{Address: "anna@example.com"}
+	email := &domain.Email{
+		To:      to.Address,
+		Subject: "Приглашение в «Кофейня»",
+		Text:    "Откройте ссылку:\nhttps://copilot.example.com/invite?token=abc",
+	}
+
+	raw, err := buildMessage(from, to, email, time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("buildMessage() error = %v", err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("message is not parsed: %v", err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != email.Subject {
+		t.Fatalf("subject = %q, %v", subject, err)
+	}
+	if msg.Header.Get("To") != "<anna@example.com>" {
+		t.Fatalf("to = %q", msg.Header.Get("To"))
+	}
+
+	body, err := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	if err != nil {
+		t.Fatalf("body is not decoded: %v", err)
+	}
+	if got := strings.ReplaceAll(string(body), "\r\n", "\n"); got != email.Text {
+		t.Fatalf("body = %q", got)
+	}
+}
+
+func TestBuildMessage_RejectsHeaderInjection(t *testing.T) {
+	from := &mail.Address{Address: "noreply@copilot.example.com"}
+	to := &mail.Address{Address: "anna@example.com"}
+
+	_, err := buildMessage(from, to, &domain.Email{Subject: "Привет\r\nBcc: evil@example.com"}, time.Now())
+	if err == nil {
+		t.Fatalf("multiline subject is accepted")
+	}
+}
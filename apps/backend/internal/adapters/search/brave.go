@@ -0,0 +1,99 @@
+package search
+
+import (
+	"backend/internal/domain"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const braveSearchURL = "https://api.search.brave.com/res/v1/web/search"
+
+// Brave - поиск через Brave Search API (нужен ключ подписки)
+type Brave struct {
+	client  *http.Client
+	apiKey  string
+	baseURL string
+}
+
+func NewBrave(client *http.Client, apiKey string) *Brave {
+	return &Brave{
+		client:  client,
+		apiKey:  apiKey,
+		baseURL: braveSearchURL,
+	}
+}
+
+func (b *Brave) Name() string {
+	return "brave"
+}
+
+func (b *Brave) Search(ctx context.Context, query string, maxResults int) ([]domain.SearchResult, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("count", strconv.Itoa(maxResults))
+	params.Set("search_lang", "ru")
+
+	req, err := http.NewRequestWithContext(ctx, "GET", b.baseURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Subscription-Token", b.apiKey)
+
+	resp, err := doRequest(b.client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var data struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	results := make([]domain.SearchResult, 0, maxResults)
+	for _, r := range data.Web.Results {
+		if len(results) >= maxResults {
+			break
+		}
+		results = append(results, domain.SearchResult{
+			Title:   stripTags(r.Title),
+			URL:     r.URL,
+			Snippet: stripTags(r.Description),
+		})
+	}
+
+	return results, nil
+}
+
+// stripTags убирает разметку подсветки (<strong>) из текстов Brave
+func stripTags(s string) string {
+	nodes, err := html.ParseFragment(strings.NewReader(s), nil)
+	if err != nil {
+		return s
+	}
+
+	var b strings.Builder
+	for _, n := range nodes {
+		b.WriteString(nodeText(n))
+		b.WriteString(" ")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+var _ Provider = (*Brave)(nil)
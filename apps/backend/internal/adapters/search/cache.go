@@ -0,0 +1,193 @@
+package search
+
+import (
+	"backend/internal/domain"
+	"backend/internal/logging"
+	"backend/internal/metrics"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// QueryClass - насколько быстро устаревает ответ на запрос
+type QueryClass string
+
+const (
+	// QueryVolatile - курсы, ставки, цены, новости: меняются в течение дня
+	QueryVolatile QueryClass = "volatile"
+	// QueryStable - адреса, реквизиты, контакты: меняются редко
+	QueryStable QueryClass = "stable"
+	// QueryDefault - всё остальное
+	QueryDefault QueryClass = "default"
+)
+
+// volatileMarkers и stableMarkers - начала слов, по которым определяется класс запроса,
+// stableCodes - короткие обозначения реквизитов, совпадают только целым словом
+var (
+	volatileMarkers = []string{
+		"курс", "ставк", "цен", "стоимост", "котировк", "бирж", "акци",
+		"новост", "сегодня", "сейчас", "вчера", "погод", "пробк", "инфляц",
+	}
+	stableMarkers = []string{
+		"адрес", "где находится", "как добраться", "реквизит",
+		"телефон", "часы работы", "режим работы", "контакт",
+	}
+	stableCodes = []string{"инн", "огрн", "кпп", "бик", "оквэд"}
+)
+
+// ClassifyQuery определяет класс запроса по ключевым словам. Изменчивость важнее:
+// "курс доллара в банке по адресу" кэшируется как volatile
+func ClassifyQuery(query string) QueryClass {
+	q := " " + strings.ToLower(query)
+
+	for _, m := range volatileMarkers {
+		if strings.Contains(q, " "+m) {
+			return QueryVolatile
+		}
+	}
+	for _, m := range stableMarkers {
+		if strings.Contains(q, " "+m) {
+			return QueryStable
+		}
+	}
+	for _, word := range strings.FieldsFunc(q, func(r rune) bool { return !unicode.IsLetter(r) }) {
+		for _, code := range stableCodes {
+			if word == code {
+				return QueryStable
+			}
+		}
+	}
+	return QueryDefault
+}
+
+// CacheTTL - сроки жизни записей по классам запросов и для скачанных страниц
+type CacheTTL struct {
+	Volatile time.Duration
+	Default  time.Duration
+	Stable   time.Duration
+	Page     time.Duration
+}
+
+// DefaultCacheTTL - значения по умолчанию
+var DefaultCacheTTL = CacheTTL{
+	Volatile: 15 * time.Minute,
+	Default:  6 * time.Hour,
+	Stable:   7 * 24 * time.Hour,
+	Page:     time.Hour,
+}
+
+// ForQuery возвращает срок жизни результатов поиска по запросу
+func (t CacheTTL) ForQuery(query string) time.Duration {
+	switch ClassifyQuery(query) {
+	case QueryVolatile:
+		return t.Volatile
+	case QueryStable:
+		return t.Stable
+	default:
+		return t.Default
+	}
+}
+
+// CachedProvider - кэш перед поисковым бэкендом. Ключ - имя бэкенда и нормализованный запрос.
+// Кэшируются только непустые успешные ответы; сбой хранилища кэша не мешает поиску
+type CachedProvider struct {
+	Provider
+	cache domain.Cache
+	ttl   CacheTTL
+	now   func() time.Time
+}
+
+func NewCachedProvider(p Provider, cache domain.Cache, ttl CacheTTL) *CachedProvider {
+	return &CachedProvider{Provider: p, cache: cache, ttl: ttl, now: time.Now}
+}
+
+func (c *CachedProvider) Search(ctx context.Context, query string, maxResults int) ([]domain.SearchResult, error) {
+	key := fmt.Sprintf("search:%s:%d:%s", c.Name(), maxResults, normalizeQuery(query))
+
+	var cached []domain.SearchResult
+	if readCache(ctx, c.cache, "search", key, &cached) {
+		return cached, nil
+	}
+
+	results, err := c.Provider.Search(ctx, query, maxResults)
+	if err != nil || len(results) == 0 {
+		return results, err
+	}
+
+	writeCache(ctx, c.cache, key, results, c.now().Add(c.ttl.ForQuery(query)))
+	return results, nil
+}
+
+// CachedFetcher - кэш скачанных страниц по URL
+type CachedFetcher struct {
+	fetcher domain.PageFetcher
+	cache   domain.Cache
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+func NewCachedFetcher(fetcher domain.PageFetcher, cache domain.Cache, ttl time.Duration) *CachedFetcher {
+	return &CachedFetcher{fetcher: fetcher, cache: cache, ttl: ttl, now: time.Now}
+}
+
+func (c *CachedFetcher) Fetch(ctx context.Context, url string) (*domain.Page, error) {
+	key := "page:" + url
+
+	var cached domain.Page
+	if readCache(ctx, c.cache, "page", key, &cached) {
+		return &cached, nil
+	}
+
+	page, err := c.fetcher.Fetch(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	writeCache(ctx, c.cache, key, page, c.now().Add(c.ttl))
+	return page, nil
+}
+
+// normalizeQuery приводит запрос к виду для ключа кэша: регистр, пробелы, пунктуация по краям
+func normalizeQuery(query string) string {
+	q := strings.Join(strings.Fields(strings.ToLower(query)), " ")
+	q = strings.Trim(q, " ?!.,;:")
+	return strings.ReplaceAll(q, "ё", "е")
+}
+
+// readCache достаёт значение и декодирует его в out, считая ошибки хранилища промахом
+func readCache(ctx context.Context, cache domain.Cache, name, key string, out interface{}) bool {
+	entry, err := cache.Get(ctx, key)
+	if err == nil && entry != nil {
+		err = json.Unmarshal(entry.Value, out)
+	}
+
+	metrics.ObserveCache(name, err == nil && entry != nil, err)
+	if err != nil {
+		logging.FromContext(ctx).WarnContext(ctx, "cache read failed",
+			slog.String("cache", name),
+			slog.Any("error", err),
+		)
+		return false
+	}
+
+	return entry != nil
+}
+
+func writeCache(ctx context.Context, cache domain.Cache, key string, value interface{}, expiresAt time.Time) {
+	data, err := json.Marshal(value)
+	if err == nil {
+		err = cache.Set(ctx, key, &domain.CacheEntry{Value: data, ExpiresAt: expiresAt})
+	}
+	if err != nil {
+		logging.FromContext(ctx).WarnContext(ctx, "cache write failed", slog.Any("error", err))
+	}
+}
+
+var (
+	_ Provider           = (*CachedProvider)(nil)
+	_ domain.PageFetcher = (*CachedFetcher)(nil)
+)
@@ -0,0 +1,160 @@
+package search
+
+import (
+	"backend/internal/adapters/cache"
+	"backend/internal/domain"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestClassifyQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  QueryClass
+	}{
+		{query: "курс доллара", want: QueryVolatile},
+		{query: "Ключевая ставка ЦБ", want: QueryVolatile},
+		{query: "новости малого бизнеса", want: QueryVolatile},
+		{query: "адрес налоговой инспекции 46", want: QueryStable},
+		{query: "реквизиты ФНС", want: QueryStable},
+		{query: "ИНН ООО Ромашка", want: QueryStable},
+		{query: "курс юаня в банке по адресу Ленина 1", want: QueryVolatile},
+		{query: "как составить договор аренды", want: QueryDefault},
+		{query: "инновации в торговле", want: QueryDefault},
+	}
+
+	for _, tt := range tests {
+		if got := ClassifyQuery(tt.query); got != tt.want {
+			t.Errorf("ClassifyQuery(%q) = %s, want %s", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestCachedProvider(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	inner := &stubProvider{name: "ddg", results: []domain.SearchResult{{Title: "ЦБ", URL: "https://cbr.ru"}}}
+	lru := cache.NewLRU(10)
+	p := NewCachedProvider(inner, lru, DefaultCacheTTL)
+	p.now = func() time.Time { return now }
+
+	for _, q := range []string{"Курс доллара?", "  курс   доллара "} {
+		results, err := p.Search(ctx, q, 5)
+		if err != nil || len(results) != 1 || results[0].URL != "https://cbr.ru" {
+			t.Fatalf("Search(%q) = %v, %v", q, results, err)
+		}
+	}
+	if inner.calls != 1 {
+		t.Fatalf("provider calls = %d, want 1 (second query should hit the cache)", inner.calls)
+	}
+
+	entry, _ := lru.Get(ctx, "search:ddg:5:курс доллара")
+	if entry == nil || !entry.ExpiresAt.Equal(now.Add(DefaultCacheTTL.Volatile)) {
+		t.Fatalf("volatile query should use short TTL, got %v", entry)
+	}
+}
+
+func TestCachedProvider_DoesNotCacheFailures(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		inner *stubProvider
+	}{
+		{name: "empty", inner: &stubProvider{name: "ddg"}},
+		{name: "error", inner: &stubProvider{name: "ddg", err: errors.New("blocked")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewCachedProvider(tt.inner, cache.NewLRU(10), DefaultCacheTTL)
+			p.Search(ctx, "адрес ФНС", 5)
+			p.Search(ctx, "адрес ФНС", 5)
+			if tt.inner.calls != 2 {
+				t.Fatalf("provider calls = %d, want 2", tt.inner.calls)
+			}
+		})
+	}
+}
+
+// countingFetcher считает скачивания
+type countingFetcher struct {
+	calls int
+}
+
+func (f *countingFetcher) Fetch(_ context.Context, url string) (*domain.Page, error) {
+	f.calls++
+	return &domain.Page{URL: url, Title: "Заголовок", Text: "Текст"}, nil
+}
+
+func TestCachedFetcher(t *testing.T) {
+	ctx := context.Background()
+	inner := &countingFetcher{}
+	f := NewCachedFetcher(inner, cache.NewLRU(10), time.Hour)
+
+	for i := 0; i < 2; i++ {
+		page, err := f.Fetch(ctx, "https://cbr.ru/keyrate")
+		if err != nil || page.Title != "Заголовок" || page.Text != "Текст" {
+			t.Fatalf("Fetch() = %+v, %v", page, err)
+		}
+	}
+	if inner.calls != 1 {
+		t.Fatalf("fetch calls = %d, want 1", inner.calls)
+	}
+}
+
+// counterValue читает значение счётчика из реестра по умолчанию, 0 - если серии ещё нет
+func counterValue(t *testing.T, name string, labels map[string]string) float64 {
+	t.Helper()
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if labels[label.GetName()] != label.GetValue() {
+					continue metrics
+				}
+			}
+			return m.GetCounter().GetValue()
+		}
+	}
+	return 0
+}
+
+// Попадание в кэш не считается запросом к бэкенду: успешных запросов столько же, сколько промахов
+func TestNew_CacheHitsAreNotProviderRequests(t *testing.T) {
+	chain, err := New(nil, Config{Providers: []string{"fixture"}, FixturePath: "testdata/fixture.json", Cache: cache.NewLRU(10), CacheTTL: DefaultCacheTTL})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	providerOK := map[string]string{"provider": "fixture", "result": "ok"}
+	cacheHit := map[string]string{"cache": "search", "result": "hit"}
+	okBefore := counterValue(t, "backend_web_search_requests_total", providerOK)
+	hitsBefore := counterValue(t, "backend_cache_requests_total", cacheHit)
+
+	for range 3 {
+		if _, err := chain.Search(context.Background(), "курс доллара", 5); err != nil {
+			t.Fatalf("Search() error = %v", err)
+		}
+	}
+
+	if got := counterValue(t, "backend_web_search_requests_total", providerOK) - okBefore; got != 1 {
+		t.Fatalf("provider requests = %v, want 1", got)
+	}
+	if got := counterValue(t, "backend_cache_requests_total", cacheHit) - hitsBefore; got != 2 {
+		t.Fatalf("cache hits = %v, want 2", got)
+	}
+}
@@ -0,0 +1,250 @@
+package search
+
+import (
+	"backend/internal/domain"
+	"backend/internal/logging"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	duckDuckGoHTMLURL    = "https://html.duckduckgo.com/html/"
+	duckDuckGoInstantURL = "https://api.duckduckgo.com/"
+)
+
+// DuckDuckGo - поиск через HTML-версию DuckDuckGo (без ключа) с добором из Instant Answer API
+type DuckDuckGo struct {
+	client     *http.Client
+	htmlURL    string
+	instantURL string
+}
+
+func NewDuckDuckGo(client *http.Client) *DuckDuckGo {
+	return &DuckDuckGo{
+		client:     client,
+		htmlURL:    duckDuckGoHTMLURL,
+		instantURL: duckDuckGoInstantURL,
+	}
+}
+
+func (d *DuckDuckGo) Name() string {
+	return "duckduckgo"
+}
+
+func (d *DuckDuckGo) Search(ctx context.Context, query string, maxResults int) ([]domain.SearchResult, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", d.htmlURL+"?q="+url.QueryEscape(query), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
+
+	resp, err := doRequest(d.client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	results, err := parseDuckDuckGoHTML(resp.Body, maxResults)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) < maxResults {
+		// Instant Answer даёт справку и связанные темы - полезный добор, но не обязательный
+		iaResults, err := d.searchInstant(ctx, query)
+		if err != nil {
+			logging.FromContext(ctx).WarnContext(ctx, "duckduckgo instant answer failed", slog.Any("error", err))
+		}
+		results = append(results, iaResults...)
+	}
+
+	if len(results) > maxResults {
+		results = results[:maxResults]
+	}
+
+	return results, nil
+}
+
+// searchInstant использует Instant Answer API
+func (d *DuckDuckGo) searchInstant(ctx context.Context, query string) ([]domain.SearchResult, error) {
+	apiURL := d.instantURL + "?format=json&no_html=1&skip_disambig=1&q=" + url.QueryEscape(query)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := doRequest(d.client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var data struct {
+		Heading       string `json:"Heading"`
+		AbstractText  string `json:"AbstractText"`
+		AbstractURL   string `json:"AbstractURL"`
+		Answer        string `json:"Answer"`
+		RelatedTopics []struct {
+			Text     string `json:"Text"`
+			FirstURL string `json:"FirstURL"`
+		} `json:"RelatedTopics"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, err
+	}
+
+	var results []domain.SearchResult
+
+	if data.AbstractText != "" {
+		results = append(results, domain.SearchResult{
+			Title:   data.Heading,
+			URL:     data.AbstractURL,
+			Snippet: data.AbstractText,
+		})
+	}
+
+	if data.Answer != "" {
+		results = append(results, domain.SearchResult{
+			Title:   "Instant Answer",
+			Snippet: data.Answer,
+		})
+	}
+
+	for _, topic := range data.RelatedTopics {
+		if topic.Text != "" && len(results) < defaultMaxResults {
+			results = append(results, domain.SearchResult{
+				Title: topic.Text,
+				URL:   topic.FirstURL,
+			})
+		}
+	}
+
+	return results, nil
+}
+
+// parseDuckDuckGoHTML разбирает выдачу html.duckduckgo.com: каждый результат - блок .result,
+// внутри заголовок со ссылкой a.result__a и описание .result__snippet. Реклама (.result--ad) пропускается
+func parseDuckDuckGoHTML(r io.Reader, maxResults int) ([]domain.SearchResult, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse html: %w", err)
+	}
+
+	var results []domain.SearchResult
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if len(results) >= maxResults {
+			return
+		}
+
+		if n.Type == html.ElementNode && hasClass(n, "result") {
+			if hasClass(n, "result--ad") {
+				return
+			}
+
+			link := findFirst(n, func(n *html.Node) bool { return n.Data == "a" && hasClass(n, "result__a") })
+			if link == nil {
+				return
+			}
+
+			result := domain.SearchResult{
+				Title: nodeText(link),
+				URL:   decodeDuckDuckGoURL(attr(link, "href")),
+			}
+			if snippet := findFirst(n, func(n *html.Node) bool { return hasClass(n, "result__snippet") }); snippet != nil {
+				result.Snippet = nodeText(snippet)
+			}
+
+			results = append(results, result)
+			return
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	return results, nil
+}
+
+// findFirst ищет в глубину первый элемент, подходящий под match
+func findFirst(n *html.Node, match func(n *html.Node) bool) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && match(c) {
+			return c
+		}
+		if found := findFirst(c, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// decodeDuckDuckGoURL достаёт настоящий адрес из редиректа вида //duckduckgo.com/l/?uddg=...
+func decodeDuckDuckGoURL(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+
+	if strings.HasPrefix(u.Path, "/l/") {
+		if target := u.Query().Get("uddg"); target != "" {
+			return target
+		}
+	}
+
+	if u.Scheme == "" && strings.HasPrefix(href, "//") {
+		return "https:" + href
+	}
+
+	return href
+}
+
+func hasClass(n *html.Node, class string) bool {
+	for _, field := range strings.Fields(attr(n, "class")) {
+		if field == class {
+			return true
+		}
+	}
+	return false
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// nodeText склеивает текст узла и потомков, схлопывая пробелы
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			b.WriteString(" ")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+var _ Provider = (*DuckDuckGo)(nil)
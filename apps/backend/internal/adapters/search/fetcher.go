@@ -0,0 +1,313 @@
+package search
+
+import (
+	"backend/internal/domain"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/html"
+)
+
+const (
+	defaultFetchMaxBytes = 1 << 20
+	defaultFetchTimeout  = 5 * time.Second
+	defaultUserAgent     = "MicroBizAssistantBot/1.0"
+)
+
+var (
+	ErrDomainNotAllowed = errors.New("domain is not allowed")
+	ErrRobotsDisallowed = errors.New("disallowed by robots.txt")
+	ErrUnsupportedPage  = errors.New("unsupported page content type")
+	ErrPrivateAddress   = errors.New("private network address")
+)
+
+// FetcherConfig - ограничения при скачивании страниц из выдачи
+type FetcherConfig struct {
+	// MaxBytes - сколько байт страницы читать, остальное отбрасывается
+	MaxBytes int64
+	// Timeout - время на скачивание одной страницы (включая robots.txt)
+	Timeout time.Duration
+	// UserAgent - как представляемся сайтам, по нему же ищутся правила в robots.txt
+	UserAgent string
+	// AllowDomains - если не пуст, скачиваются только эти домены и их поддомены
+	AllowDomains []string
+	// DenyDomains - эти домены и поддомены не скачиваются никогда
+	DenyDomains []string
+}
+
+// PageFetcher скачивает страницы из поисковой выдачи с учётом robots.txt и списков доменов
+type PageFetcher struct {
+	client *http.Client
+	config FetcherConfig
+	robots *robotsCache
+}
+
+func NewPageFetcher(client *http.Client, config FetcherConfig) *PageFetcher {
+	if client == nil {
+		client = &http.Client{}
+	}
+	if config.MaxBytes <= 0 {
+		config.MaxBytes = defaultFetchMaxBytes
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultFetchTimeout
+	}
+	if config.UserAgent == "" {
+		config.UserAgent = defaultUserAgent
+	}
+
+	f := &PageFetcher{config: config}
+
+	// Редирект не должен уводить на запрещённый домен
+	c := *client
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return errors.New("too many redirects")
+		}
+		return f.checkDomain(req.URL)
+	}
+	f.client = &c
+	f.robots = newRobotsCache(f.client, config.UserAgent)
+
+	return f
+}
+
+func (f *PageFetcher) Fetch(ctx context.Context, rawURL string) (_ *domain.Page, err error) {
+	ctx, span := tracer.Start(ctx, "web_fetch.Fetch", trace.WithAttributes(
+		attribute.String("url", rawURL),
+	))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, f.config.Timeout)
+	defer cancel()
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("%w: scheme %q", ErrDomainNotAllowed, u.Scheme)
+	}
+	if err := f.checkDomain(u); err != nil {
+		return nil, err
+	}
+
+	allowed, err := f.robots.allowed(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrRobotsDisallowed
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", f.config.UserAgent)
+	req.Header.Set("Accept", "text/html,text/plain;q=0.9")
+
+	resp, err := doRequest(f.client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	body := io.LimitReader(resp.Body, f.config.MaxBytes)
+
+	var page *domain.Page
+	switch mediaType {
+	case "text/html", "application/xhtml+xml", "":
+		page, err = extractPage(body)
+		if err != nil {
+			return nil, err
+		}
+	case "text/plain":
+		text, err := io.ReadAll(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read page: %w", err)
+		}
+		page = &domain.Page{Text: strings.TrimSpace(strings.ToValidUTF8(string(text), ""))}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedPage, mediaType)
+	}
+
+	page.URL = resp.Request.URL.String()
+	span.SetAttributes(attribute.Int("web_fetch.text_chars", len(page.Text)))
+
+	return page, nil
+}
+
+// checkDomain применяет списки разрешённых и запрещённых доменов
+func (f *PageFetcher) checkDomain(u *url.URL) error {
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return fmt.Errorf("%w: empty host", ErrDomainNotAllowed)
+	}
+
+	if matchDomain(host, f.config.DenyDomains) {
+		return fmt.Errorf("%w: %s", ErrDomainNotAllowed, host)
+	}
+	if len(f.config.AllowDomains) > 0 && !matchDomain(host, f.config.AllowDomains) {
+		return fmt.Errorf("%w: %s", ErrDomainNotAllowed, host)
+	}
+
+	return nil
+}
+
+// matchDomain - host совпадает с одним из доменов или является его поддоменом
+func matchDomain(host string, domains []string) bool {
+	for _, d := range domains {
+		d = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(d), "."))
+		if d == "" {
+			continue
+		}
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// PublicOnlyTransport - транспорт, который не соединяется с локальными и частными адресами.
+// Адреса проверяются после DNS-резолва, поэтому ссылка из выдачи не достучится до внутренней сети
+func PublicOnlyTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   5 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+				ip.IsLinkLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast() {
+				return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// skipTags - элементы, в которых не бывает основного текста
+var skipTags = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true,
+	"nav": true, "header": true, "footer": true, "aside": true,
+	"form": true, "button": true, "iframe": true, "svg": true, "select": true,
+}
+
+// blockTags - элементы, после которых начинается новый абзац
+var blockTags = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "main": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"li": true, "ul": true, "ol": true, "tr": true, "table": true,
+	"blockquote": true, "pre": true, "br": true, "dd": true, "dt": true,
+}
+
+// extractPage достаёт заголовок и основной текст страницы.
+// Если есть <article> или <main>, берётся только он, иначе весь <body> без меню, шапки и подвала
+func extractPage(r io.Reader) (*domain.Page, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse html: %w", err)
+	}
+
+	page := &domain.Page{}
+	if title := findFirst(doc, func(n *html.Node) bool { return n.Data == "title" }); title != nil {
+		page.Title = nodeText(title)
+	}
+
+	root := findFirst(doc, func(n *html.Node) bool { return n.Data == "article" })
+	if root == nil {
+		root = findFirst(doc, func(n *html.Node) bool { return n.Data == "main" })
+	}
+	if root == nil {
+		root = findFirst(doc, func(n *html.Node) bool { return n.Data == "body" })
+	}
+	if root == nil {
+		root = doc
+	}
+
+	page.Text = readableText(root)
+	return page, nil
+}
+
+// readableText собирает текст узла по абзацам, пропуская служебные элементы
+func readableText(root *html.Node) string {
+	var paragraphs []string
+	var current strings.Builder
+
+	flush := func() {
+		if text := strings.Join(strings.Fields(current.String()), " "); text != "" {
+			paragraphs = append(paragraphs, text)
+		}
+		current.Reset()
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.ElementNode:
+			if skipTags[n.Data] || hasAttr(n, "hidden") || attr(n, "aria-hidden") == "true" {
+				return
+			}
+			if blockTags[n.Data] {
+				flush()
+				defer flush()
+			}
+			if n.Data == "td" || n.Data == "th" {
+				// Ячейки таблицы остаются в одной строке, но не слипаются
+				current.WriteString(" ")
+			}
+		case html.TextNode:
+			// Пробелы берём из самого текста, чтобы не разрывать "<b>25 октября</b>."
+			current.WriteString(n.Data)
+		case html.CommentNode:
+			return
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+	flush()
+
+	return strings.Join(paragraphs, "\n\n")
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+var _ domain.PageFetcher = (*PageFetcher)(nil)
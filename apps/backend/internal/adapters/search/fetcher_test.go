@@ -0,0 +1,256 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const articlePage = `<!DOCTYPE html>
+<html>
+<head><title>Ключевая ставка | Банк России</title><script>var tracking = "ключевая";</script></head>
+<body>
+  <header><nav><a href="/">Главная</a> <a href="/about">О банке</a></nav></header>
+  <aside>Подпишитесь на рассылку</aside>
+  <article>
+    <h1>Ключевая ставка</h1>
+    <p>Совет директоров Банка России принял решение сохранить ключевую ставку.</p>
+    <p>Следующее заседание запланировано на <b>25 октября</b>.</p>
+    <div hidden>скрытый блок</div>
+    <style>.x { color: red }</style>
+  </article>
+  <footer>© Банк России</footer>
+</body>
+</html>`
+
+func newTestSite(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\nDisallow: /private\n\nUser-agent: OtherBot\nDisallow: /\n"))
+	})
+	mux.HandleFunc("/article", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(articlePage))
+	})
+	mux.HandleFunc("/private/page", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("page disallowed by robots.txt was fetched")
+	})
+	mux.HandleFunc("/file.pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte("%PDF-1.4"))
+	})
+	mux.HandleFunc("/big", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(strings.Repeat("а", 10000)))
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://denied.example.com/", http.StatusFound)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestPageFetcher_Fetch(t *testing.T) {
+	srv := newTestSite(t)
+	f := NewPageFetcher(srv.Client(), FetcherConfig{DenyDomains: []string{"example.com"}})
+
+	page, err := f.Fetch(context.Background(), srv.URL+"/article")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+
+	if page.Title != "Ключевая ставка | Банк России" {
+		t.Fatalf("Title = %q", page.Title)
+	}
+	wantText := "Ключевая ставка\n\nСовет директоров Банка России принял решение сохранить ключевую ставку.\n\nСледующее заседание запланировано на 25 октября."
+	if page.Text != wantText {
+		t.Fatalf("Text = %q, want %q", page.Text, wantText)
+	}
+	for _, junk := range []string{"Главная", "рассылку", "tracking", "color", "скрытый", "©"} {
+		if strings.Contains(page.Text, junk) {
+			t.Fatalf("text contains %q", junk)
+		}
+	}
+}
+
+func TestPageFetcher_Errors(t *testing.T) {
+	srv := newTestSite(t)
+	host, _ := url.Parse(srv.URL)
+
+	tests := []struct {
+		name    string
+		config  FetcherConfig
+		path    string
+		wantErr error
+	}{
+		{name: "robots disallow", path: "/private/page", wantErr: ErrRobotsDisallowed},
+		{name: "deny list", config: FetcherConfig{DenyDomains: []string{host.Hostname()}}, path: "/article", wantErr: ErrDomainNotAllowed},
+		{name: "not in allow list", config: FetcherConfig{AllowDomains: []string{"cbr.ru"}}, path: "/article", wantErr: ErrDomainNotAllowed},
+		{name: "redirect to denied domain", config: FetcherConfig{DenyDomains: []string{"example.com"}}, path: "/redirect", wantErr: ErrDomainNotAllowed},
+		{name: "unsupported content", path: "/file.pdf", wantErr: ErrUnsupportedPage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewPageFetcher(srv.Client(), tt.config)
+			_, err := f.Fetch(context.Background(), srv.URL+tt.path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPageFetcher_MaxBytes(t *testing.T) {
+	srv := newTestSite(t)
+	f := NewPageFetcher(srv.Client(), FetcherConfig{MaxBytes: 101})
+
+	page, err := f.Fetch(context.Background(), srv.URL+"/big")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	// 101 байт - 50 двухбайтовых букв и обрезанный хвост, который отбрасывается
+	if len([]rune(page.Text)) != 50 {
+		t.Fatalf("text runes = %d, want 50", len([]rune(page.Text)))
+	}
+}
+
+func TestPageFetcher_SchemeNotAllowed(t *testing.T) {
+	f := NewPageFetcher(nil, FetcherConfig{})
+	if _, err := f.Fetch(context.Background(), "file:///etc/passwd"); !errors.Is(err, ErrDomainNotAllowed) {
+		t.Fatalf("error = %v, want ErrDomainNotAllowed", err)
+	}
+}
+
+func TestParseRobots(t *testing.T) {
+	robots := `# comment
+User-agent: Googlebot
+Disallow: /
+
+User-agent: MicroBizAssistantBot
+User-agent: SomeOtherBot
+Disallow: /search
+Allow: /search/about
+Disallow: /*.pdf$
+Disallow:
+
+User-agent: *
+Disallow: /admin
+`
+
+	tests := []struct {
+		name      string
+		userAgent string
+		path      string
+		want      bool
+	}{
+		{name: "specific group allows root", userAgent: "MicroBizAssistantBot/1.0", path: "/", want: true},
+		{name: "specific group disallows prefix", userAgent: "MicroBizAssistantBot/1.0", path: "/search?q=1", want: false},
+		{name: "longer allow wins", userAgent: "MicroBizAssistantBot/1.0", path: "/search/about", want: true},
+		{name: "wildcard with end anchor", userAgent: "MicroBizAssistantBot/1.0", path: "/docs/report.pdf", want: false},
+		{name: "end anchor does not match longer path", userAgent: "MicroBizAssistantBot/1.0", path: "/docs/report.pdf.html", want: true},
+		{name: "specific group ignores star group", userAgent: "MicroBizAssistantBot/1.0", path: "/admin", want: true},
+		{name: "unknown agent falls back to star", userAgent: "NoNameBot/2.0", path: "/admin", want: false},
+		{name: "unknown agent allowed elsewhere", userAgent: "NoNameBot/2.0", path: "/search", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := parseRobots(strings.NewReader(robots), tt.userAgent)
+			if got := rules.allowed(tt.path); got != tt.want {
+				t.Fatalf("allowed(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRobotsCache_Status(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		want   bool
+	}{
+		{name: "missing robots allows all", status: http.StatusNotFound, want: true},
+		{name: "server error disallows all", status: http.StatusServiceUnavailable, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			u, _ := url.Parse(srv.URL + "/page")
+			got, err := newRobotsCache(srv.Client(), defaultUserAgent).allowed(context.Background(), u)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("allowed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// roundTripFunc отвечает на запросы без сети
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestRobotsCache_EvictsLeastRecentHost(t *testing.T) {
+	fetches := make(map[string]int)
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		fetches[r.URL.Host]++
+		return &http.Response{StatusCode: http.StatusNotFound, Body: http.NoBody, Request: r}, nil
+	})}
+
+	cache := newRobotsCache(client, defaultUserAgent)
+	cache.maxHosts = 2
+
+	for _, host := range []string{"a.ru", "b.ru", "a.ru", "c.ru", "a.ru", "b.ru"} {
+		u, _ := url.Parse("https://" + host + "/page")
+		if _, err := cache.allowed(context.Background(), u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// c.ru вытеснил b.ru, к которому дольше не обращались, а a.ru остался в кэше
+	want := map[string]int{"a.ru": 1, "b.ru": 2, "c.ru": 1}
+	if !reflect.DeepEqual(fetches, want) {
+		t.Fatalf("robots.txt fetches = %v, want %v", fetches, want)
+	}
+	if got := cache.order.Len(); got != cache.maxHosts {
+		t.Fatalf("cached hosts = %d, want %d", got, cache.maxHosts)
+	}
+}
+
+func TestMatchDomain(t *testing.T) {
+	domains := []string{"cbr.ru", ".nalog.gov.ru"}
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{host: "cbr.ru", want: true},
+		{host: "www.cbr.ru", want: true},
+		{host: "notcbr.ru", want: false},
+		{host: "lkfl2.nalog.gov.ru", want: true},
+		{host: "gov.ru", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := matchDomain(tt.host, domains); got != tt.want {
+			t.Errorf("matchDomain(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package search
+
+import (
+	"backend/internal/domain"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// fixtureDefaultKey - результаты для запросов, которых нет в файле
+const fixtureDefaultKey = "*"
+
+// Fixture отдаёт заготовленные результаты из JSON-файла - для офлайн-разработки и тестов.
+// Формат файла: {"запрос": [{"title": "...", "url": "...", "snippet": "..."}], "*": [...]}
+type Fixture struct {
+	results map[string][]domain.SearchResult
+}
+
+func NewFixture(path string) (*Fixture, error) {
+	if path == "" {
+		return nil, errors.New("fixture: file path should be provided")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("fixture: failed to read file: %w", err)
+	}
+
+	var raw map[string][]domain.SearchResult
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("fixture: failed to decode file: %w", err)
+	}
+
+	f := &Fixture{results: make(map[string][]domain.SearchResult, len(raw))}
+	for query, results := range raw {
+		f.results[normalizeFixtureQuery(query)] = results
+	}
+
+	return f, nil
+}
+
+func (f *Fixture) Name() string {
+	return "fixture"
+}
+
+func (f *Fixture) Search(_ context.Context, query string, maxResults int) ([]domain.SearchResult, error) {
+	results, ok := f.results[normalizeFixtureQuery(query)]
+	if !ok {
+		results = f.results[fixtureDefaultKey]
+	}
+
+	if len(results) > maxResults {
+		results = results[:maxResults]
+	}
+
+	// Копия, чтобы вызывающий код не менял фикстуру
+	return append([]domain.SearchResult(nil), results...), nil
+}
+
+func normalizeFixtureQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
+
+var _ Provider = (*Fixture)(nil)
@@ -0,0 +1,185 @@
+package search
+
+import (
+	"backend/internal/domain"
+	"backend/internal/logging"
+	"backend/internal/metrics"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("backend/internal/adapters/search")
+
+const defaultMaxResults = 5
+
+// ErrAllProvidersFailed - ни один поисковый бэкенд не ответил
+var ErrAllProvidersFailed = errors.New("all search providers failed")
+
+// Provider - отдельный поисковый бэкенд (DuckDuckGo, SearXNG, Brave, фикстура)
+type Provider interface {
+	domain.SearchProvider
+	// Name - имя бэкенда для логов, метрик и конфигурации
+	Name() string
+}
+
+// Config - какие бэкенды использовать и в каком порядке
+type Config struct {
+	// Providers - имена бэкендов в порядке опроса: duckduckgo, searxng, brave, fixture
+	Providers []string
+	// SearXNGURL - адрес своего инстанса SearXNG (нужен включённый JSON-формат)
+	SearXNGURL string
+	// BraveAPIKey - ключ Brave Search API
+	BraveAPIKey string
+	// FixturePath - JSON-файл с заготовленными результатами для офлайн-режима и тестов
+	FixturePath string
+	// Cache - если задан, ответы каждого бэкенда кэшируются со сроками из CacheTTL
+	Cache    domain.Cache
+	CacheTTL CacheTTL
+}
+
+// New собирает цепочку бэкендов по конфигурации
+func New(client *http.Client, cfg Config) (*Chain, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	providers := make([]Provider, 0, len(cfg.Providers))
+	for _, name := range cfg.Providers {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "":
+			continue
+		case "duckduckgo", "ddg":
+			providers = append(providers, NewDuckDuckGo(client))
+		case "searxng":
+			if cfg.SearXNGURL == "" {
+				return nil, errors.New("searxng: base URL should be provided")
+			}
+			providers = append(providers, NewSearXNG(client, cfg.SearXNGURL))
+		case "brave":
+			if cfg.BraveAPIKey == "" {
+				return nil, errors.New("brave: API key should be provided")
+			}
+			providers = append(providers, NewBrave(client, cfg.BraveAPIKey))
+		case "fixture":
+			fixture, err := NewFixture(cfg.FixturePath)
+			if err != nil {
+				return nil, err
+			}
+			providers = append(providers, fixture)
+		default:
+			return nil, fmt.Errorf("unknown search provider %q", name)
+		}
+	}
+
+	// Метрики пишутся под кэшем: попадание в кэш не запрос к бэкенду и считается в metrics.ObserveCache
+	for i, p := range providers {
+		providers[i] = observedProvider{p}
+		if cfg.Cache != nil {
+			providers[i] = NewCachedProvider(providers[i], cfg.Cache, cfg.CacheTTL)
+		}
+	}
+
+	return NewChain(providers...)
+}
+
+// Chain опрашивает бэкенды по порядку и переходит к следующему при ошибке или пустой выдаче
+type Chain struct {
+	providers []Provider
+}
+
+func NewChain(providers ...Provider) (*Chain, error) {
+	if len(providers) == 0 {
+		return nil, errors.New("at least one search provider should be provided")
+	}
+	return &Chain{providers: providers}, nil
+}
+
+// Search возвращает результаты первого бэкенда, который ответил непустой выдачей.
+// Если все ответили пусто - пустой список без ошибки, если все упали - ErrAllProvidersFailed
+func (c *Chain) Search(ctx context.Context, query string, maxResults int) ([]domain.SearchResult, error) {
+	if maxResults <= 0 {
+		maxResults = defaultMaxResults
+	}
+
+	var errs []error
+	for _, p := range c.providers {
+		results, err := c.searchOne(ctx, p, query, maxResults)
+		if err != nil {
+			logging.FromContext(ctx).WarnContext(ctx, "search provider failed, trying next",
+				slog.String("provider", p.Name()),
+				slog.Any("error", err),
+			)
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+			continue
+		}
+
+		if len(results) > 0 {
+			if len(results) > maxResults {
+				results = results[:maxResults]
+			}
+			return results, nil
+		}
+	}
+
+	if len(errs) == len(c.providers) {
+		return nil, fmt.Errorf("%w: %w", ErrAllProvidersFailed, errors.Join(errs...))
+	}
+
+	return nil, nil
+}
+
+func (c *Chain) searchOne(ctx context.Context, p Provider, query string, maxResults int) ([]domain.SearchResult, error) {
+	ctx, span := tracer.Start(ctx, "web_search.Search", trace.WithAttributes(
+		attribute.String("web_search.provider", p.Name()),
+		attribute.Int("web_search.query_chars", len(query)),
+	))
+	defer span.End()
+
+	results, err := p.Search(ctx, query, maxResults)
+	span.SetAttributes(attribute.Int("web_search.results", len(results)))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// observedProvider пишет метрики каждого запроса к поисковому бэкенду
+type observedProvider struct {
+	Provider
+}
+
+func (p observedProvider) Search(ctx context.Context, query string, maxResults int) ([]domain.SearchResult, error) {
+	results, err := p.Provider.Search(ctx, query, maxResults)
+	metrics.ObserveWebSearch(p.Name(), len(results), err)
+	return results, err
+}
+
+// doRequest отправляет запрос и проверяет статус ответа, при ошибке тело уже закрыто
+func doRequest(client *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return resp, nil
+}
+
+var _ domain.SearchProvider = (*Chain)(nil)
@@ -0,0 +1,256 @@
+package search
+
+import (
+	"bufio"
+	"container/list"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	robotsMaxBytes   = 512 << 10
+	robotsTTL        = time.Hour
+	robotsFailureTTL = 10 * time.Minute
+	// robotsMaxHosts - сколько хостов помнит кэш; дольше всего не встречавшиеся вытесняются
+	robotsMaxHosts = 1024
+)
+
+// robotsCache скачивает robots.txt один раз на хост и хранит правила robotsTTL.
+// Число хостов ограничено maxHosts, как в cache.LRU: выдача поиска приносит всё новые хосты
+type robotsCache struct {
+	client    *http.Client
+	userAgent string
+	maxHosts  int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // от недавних к давним
+	now     func() time.Time
+}
+
+type robotsEntry struct {
+	key     string
+	rules   *robotsRules
+	expires time.Time
+}
+
+func newRobotsCache(client *http.Client, userAgent string) *robotsCache {
+	return &robotsCache{
+		client:    client,
+		userAgent: userAgent,
+		maxHosts:  robotsMaxHosts,
+		entries:   make(map[string]*list.Element),
+		order:     list.New(),
+		now:       time.Now,
+	}
+}
+
+// allowed - можно ли нашему агенту скачивать u
+func (c *robotsCache) allowed(ctx context.Context, u *url.URL) (bool, error) {
+	key := u.Scheme + "://" + u.Host
+
+	entry, ok := c.get(key)
+	if !ok {
+		rules, ttl := c.fetch(ctx, key)
+		if ctx.Err() != nil {
+			// Отмена запроса - не повод запоминать хост как недоступный
+			return false, ctx.Err()
+		}
+
+		entry = &robotsEntry{key: key, rules: rules, expires: c.now().Add(ttl)}
+		c.put(entry)
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+
+	return entry.rules.allowed(path), nil
+}
+
+// get возвращает неистёкшие правила хоста; истёкшая запись удаляется
+func (c *robotsCache) get(key string) (*robotsEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := el.Value.(*robotsEntry)
+	if c.now().After(entry.expires) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.order.MoveToFront(el)
+	return entry, true
+}
+
+// put запоминает правила хоста и вытесняет самые давние хосты сверх maxHosts
+func (c *robotsCache) put(entry *robotsEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[entry.key]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.entries[entry.key] = c.order.PushFront(entry)
+
+	for c.order.Len() > c.maxHosts {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*robotsEntry).key)
+	}
+}
+
+// fetch скачивает robots.txt по правилам RFC 9309: нет файла (4xx) - можно всё,
+// сервер недоступен (5xx, сетевая ошибка) - нельзя ничего, пока не получится перепроверить
+func (c *robotsCache) fetch(ctx context.Context, origin string) (*robotsRules, time.Duration) {
+	req, err := http.NewRequestWithContext(ctx, "GET", origin+"/robots.txt", nil)
+	if err != nil {
+		return disallowAll(), robotsFailureTTL
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return disallowAll(), robotsFailureTTL
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return parseRobots(io.LimitReader(resp.Body, robotsMaxBytes), c.userAgent), robotsTTL
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return &robotsRules{}, robotsTTL
+	default:
+		return disallowAll(), robotsFailureTTL
+	}
+}
+
+type robotsRule struct {
+	pattern string
+	allow   bool
+}
+
+// robotsRules - правила группы robots.txt, подходящей нашему агенту
+type robotsRules struct {
+	rules []robotsRule
+}
+
+func disallowAll() *robotsRules {
+	return &robotsRules{rules: []robotsRule{{pattern: "/", allow: false}}}
+}
+
+// allowed выбирает самое длинное совпавшее правило, при равной длине побеждает Allow
+func (r *robotsRules) allowed(path string) bool {
+	best := -1
+	allow := true
+	for _, rule := range r.rules {
+		if !matchRobotsPattern(rule.pattern, path) {
+			continue
+		}
+		if len(rule.pattern) > best || (len(rule.pattern) == best && rule.allow) {
+			best = len(rule.pattern)
+			allow = rule.allow
+		}
+	}
+	return allow
+}
+
+// parseRobots берёт группу с нашим User-agent, а если её нет - группу "*"
+func parseRobots(r io.Reader, userAgent string) *robotsRules {
+	token := strings.ToLower(userAgent)
+	if i := strings.IndexByte(token, '/'); i >= 0 {
+		token = token[:i]
+	}
+
+	var (
+		specific, wildcard []robotsRule
+		foundSpecific      bool
+		groupAgents        []string
+		inRules            bool
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// User-agent после правил начинает новую группу
+			if inRules {
+				groupAgents = nil
+				inRules = false
+			}
+			groupAgents = append(groupAgents, strings.ToLower(value))
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				// Пустой Disallow ничего не запрещает
+				continue
+			}
+			rule := robotsRule{pattern: value, allow: key == "allow"}
+			for _, agent := range groupAgents {
+				switch {
+				case agent == "*":
+					wildcard = append(wildcard, rule)
+				case agent != "" && strings.Contains(token, agent):
+					specific = append(specific, rule)
+					foundSpecific = true
+				}
+			}
+		}
+	}
+
+	if foundSpecific {
+		return &robotsRules{rules: specific}
+	}
+	return &robotsRules{rules: wildcard}
+}
+
+// matchRobotsPattern сопоставляет путь с шаблоном robots.txt: префикс, * - любая строка, $ - конец пути
+func matchRobotsPattern(pattern, path string) bool {
+	if !strings.ContainsAny(pattern, "*$") {
+		return strings.HasPrefix(path, pattern)
+	}
+
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+	if anchored {
+		expr += "$"
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return false
+	}
+	return re.MatchString(path)
+}
@@ -0,0 +1,272 @@
+package search
+
+import (
+	"backend/internal/domain"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseDuckDuckGoHTML(t *testing.T) {
+	f, err := os.Open("testdata/duckduckgo.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	results, err := parseDuckDuckGoHTML(f, 5)
+	if err != nil {
+		t.Fatalf("parseDuckDuckGoHTML() error = %v", err)
+	}
+
+	want := []domain.SearchResult{
+		{
+			Title:   "Ключевая ставка Банка России",
+			URL:     "https://www.cbr.ru/hd_base/KeyRate/",
+			Snippet: "Динамика ключевой ставки и решения совета директоров.",
+		},
+		{
+			Title: "Что такое ключевая ставка",
+			URL:   "https://www.banki.ru/news/keyrate/",
+		},
+		{
+			Title:   "Третий результат",
+			URL:     "https://example.com/a?b=1&c=2",
+			Snippet: "Описание & детали",
+		},
+	}
+
+	if len(results) != len(want) {
+		t.Fatalf("results = %d, want %d: %+v", len(results), len(want), results)
+	}
+	for i := range want {
+		if results[i] != want[i] {
+			t.Fatalf("result %d = %+v, want %+v", i, results[i], want[i])
+		}
+	}
+}
+
+func TestParseDuckDuckGoHTML_MaxResults(t *testing.T) {
+	f, err := os.Open("testdata/duckduckgo.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	results, err := parseDuckDuckGoHTML(f, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("results = %d, want 1", len(results))
+	}
+}
+
+func TestDecodeDuckDuckGoURL(t *testing.T) {
+	tests := []struct {
+		href string
+		want string
+	}{
+		{href: "//duckduckgo.com/l/?uddg=https%3A%2F%2Fnalog.gov.ru%2Frn77%2F&rut=x", want: "https://nalog.gov.ru/rn77/"},
+		{href: "/l/?uddg=https%3A%2F%2Fexample.com%2F%3Fq%3D%D0%BA%D1%83%D1%80%D1%81", want: "https://example.com/?q=курс"},
+		{href: "https://example.com/page", want: "https://example.com/page"},
+		{href: "//example.com/page", want: "https://example.com/page"},
+	}
+
+	for _, tt := range tests {
+		if got := decodeDuckDuckGoURL(tt.href); got != tt.want {
+			t.Errorf("decodeDuckDuckGoURL(%q) = %q, want %q", tt.href, got, tt.want)
+		}
+	}
+}
+
+func TestSearXNG_Search(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search" || r.URL.Query().Get("format") != "json" || r.URL.Query().Get("q") != "ставка НДС" {
+			t.Errorf("unexpected request: %s", r.URL)
+		}
+		w.Write([]byte(`{"results": [
+			{"title": " НДС в 2025 году ", "url": "https://nalog.gov.ru/nds", "content": "Ставки 0, 10 и 20%"},
+			{"title": "Второй", "url": "https://example.com/2", "content": ""}
+		]}`))
+	}))
+	defer srv.Close()
+
+	results, err := NewSearXNG(srv.Client(), srv.URL+"/").Search(context.Background(), "ставка НДС", 1)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Title != "НДС в 2025 году" || results[0].Snippet != "Ставки 0, 10 и 20%" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+}
+
+func TestBrave_Search(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Subscription-Token") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"web": {"results": [
+			{"title": "Упрощённая <strong>система</strong>", "url": "https://example.com/usn", "description": "Ставка <strong>6%</strong> или 15%"}
+		]}}`))
+	}))
+	defer srv.Close()
+
+	b := NewBrave(srv.Client(), "secret")
+	b.baseURL = srv.URL
+
+	results, err := b.Search(context.Background(), "УСН", 5)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Title != "Упрощённая система" || results[0].Snippet != "Ставка 6% или 15%" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+
+	b.apiKey = "wrong"
+	if _, err := b.Search(context.Background(), "УСН", 5); err == nil {
+		t.Fatalf("expected error on unauthorized response")
+	}
+}
+
+func TestFixture_Search(t *testing.T) {
+	f, err := NewFixture("testdata/fixture.json")
+	if err != nil {
+		t.Fatalf("NewFixture() error = %v", err)
+	}
+
+	results, err := f.Search(context.Background(), "  курс   ДОЛЛАРА ", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].URL != "https://www.cbr.ru/currency_base/daily/" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+
+	results, _ = f.Search(context.Background(), "что-то другое", 5)
+	if len(results) != 1 || results[0].Title != "Заглушка" {
+		t.Fatalf("expected default results, got %+v", results)
+	}
+
+	if _, err := NewFixture("testdata/missing.json"); err == nil {
+		t.Fatalf("expected error for missing file")
+	}
+}
+
+// stubProvider - бэкенд с фиксированным ответом
+type stubProvider struct {
+	name    string
+	results []domain.SearchResult
+	err     error
+	calls   int
+}
+
+func (s *stubProvider) Name() string { return s.name }
+
+func (s *stubProvider) Search(context.Context, string, int) ([]domain.SearchResult, error) {
+	s.calls++
+	return s.results, s.err
+}
+
+func TestChain_Failover(t *testing.T) {
+	found := []domain.SearchResult{{Title: "ok", URL: "https://example.com"}}
+
+	tests := []struct {
+		name        string
+		providers   []*stubProvider
+		wantResults int
+		wantErr     error
+		wantCalls   []int
+	}{
+		{
+			name:        "first provider answers",
+			providers:   []*stubProvider{{name: "a", results: found}, {name: "b", results: found}},
+			wantResults: 1,
+			wantCalls:   []int{1, 0},
+		},
+		{
+			name:        "error fails over to next",
+			providers:   []*stubProvider{{name: "a", err: errors.New("blocked")}, {name: "b", results: found}},
+			wantResults: 1,
+			wantCalls:   []int{1, 1},
+		},
+		{
+			name:        "empty result fails over to next",
+			providers:   []*stubProvider{{name: "a"}, {name: "b", results: found}},
+			wantResults: 1,
+			wantCalls:   []int{1, 1},
+		},
+		{
+			name:      "all empty is not an error",
+			providers: []*stubProvider{{name: "a"}, {name: "b", err: errors.New("timeout")}},
+			wantCalls: []int{1, 1},
+		},
+		{
+			name:      "all failed",
+			providers: []*stubProvider{{name: "a", err: errors.New("blocked")}, {name: "b", err: errors.New("timeout")}},
+			wantErr:   ErrAllProvidersFailed,
+			wantCalls: []int{1, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			providers := make([]Provider, 0, len(tt.providers))
+			for _, p := range tt.providers {
+				providers = append(providers, p)
+			}
+			chain, err := NewChain(providers...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			results, err := chain.Search(context.Background(), "q", 5)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if len(results) != tt.wantResults {
+				t.Fatalf("results = %d, want %d", len(results), tt.wantResults)
+			}
+			for i, p := range tt.providers {
+				if p.calls != tt.wantCalls[i] {
+					t.Fatalf("provider %s calls = %d, want %d", p.name, p.calls, tt.wantCalls[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNew_Config(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{name: "default duckduckgo", cfg: Config{Providers: []string{"duckduckgo"}}},
+		{name: "order with spaces", cfg: Config{Providers: []string{"fixture", " duckduckgo"}, FixturePath: "testdata/fixture.json"}},
+		{name: "searxng without url", cfg: Config{Providers: []string{"searxng"}}, wantErr: "base URL"},
+		{name: "brave without key", cfg: Config{Providers: []string{"brave"}}, wantErr: "API key"},
+		{name: "unknown", cfg: Config{Providers: []string{"google"}}, wantErr: "unknown search provider"},
+		{name: "empty", cfg: Config{}, wantErr: "at least one"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(nil, tt.cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
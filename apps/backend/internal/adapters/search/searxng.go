@@ -0,0 +1,74 @@
+package search
+
+import (
+	"backend/internal/domain"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SearXNG - поиск через свой инстанс SearXNG (в settings.yml должен быть включён формат json)
+type SearXNG struct {
+	client  *http.Client
+	baseURL string
+}
+
+func NewSearXNG(client *http.Client, baseURL string) *SearXNG {
+	return &SearXNG{
+		client:  client,
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}
+}
+
+func (s *SearXNG) Name() string {
+	return "searxng"
+}
+
+func (s *SearXNG) Search(ctx context.Context, query string, maxResults int) ([]domain.SearchResult, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("format", "json")
+	params.Set("language", "ru")
+
+	req, err := http.NewRequestWithContext(ctx, "GET", s.baseURL+"/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := doRequest(s.client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var data struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	results := make([]domain.SearchResult, 0, maxResults)
+	for _, r := range data.Results {
+		if len(results) >= maxResults {
+			break
+		}
+		results = append(results, domain.SearchResult{
+			Title:   strings.TrimSpace(r.Title),
+			URL:     r.URL,
+			Snippet: strings.TrimSpace(r.Content),
+		})
+	}
+
+	return results, nil
+}
+
+var _ Provider = (*SearXNG)(nil)
@@ -0,0 +1,9 @@
+package domain
+
+import "time"
+
+// CacheEntry - закэшированное значение и момент, когда оно перестаёт быть актуальным
+type CacheEntry struct {
+	Value     []byte
+	ExpiresAt time.Time
+}
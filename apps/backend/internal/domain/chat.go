@@ -1,11 +1,18 @@
 package domain
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// ErrAccessDenied - у пользователя нет прав на чат, сообщение, документ или организацию
+var ErrAccessDenied = errors.New("access denied")
+
+// DefaultChatTitle - название чата, созданного без названия; после первого ответа его заменяет сгенерированное
+const DefaultChatTitle = "Новый чат"
+
 type ChatStatus string
 
 const (
@@ -17,12 +24,41 @@ type Chat struct {
 	ID       uuid.UUID         `json:"id"`
 	Title    string            `json:"title,omitempty"`
 	UserID   uuid.UUID         `json:"user_id,omitempty"`
-	Model    *string           `json:"model,omitempty"` // log
+	OrgID    *uuid.UUID        `json:"org_id,omitempty"` // nil - личный чат UserID
+	Model    *string           `json:"model,omitempty"`  // log
 	Summary  *string           `json:"summary,omitempty"`
 	Metadata map[string]string `json:"metadata,omitempty"`
 	Status   ChatStatus        `json:"status"`
+	// ActiveMessageID - последнее сообщение активной ветки; от него строится история и выдача GetMessages
+	ActiveMessageID *uuid.UUID `json:"active_message_id,omitempty"`
 
 	CreatedAt     time.Time `json:"created_at"`
 	UpdatedAt     time.Time `json:"updated_at"`
 	LastMessageAt time.Time `json:"last_message_at"`
 }
+
+// ChatScope - чаты, над которыми пользователь может выполнить действие: его личные чаты
+// (UserID, uuid.Nil - ни одного) и все чаты организаций OrgIDs. Строится authz для выборок по многим чатам
+type ChatScope struct {
+	UserID uuid.UUID
+	OrgIDs []uuid.UUID
+}
+
+// Границы совпадения в ChatSearchHit.Snippet. Управляющие символы не встречаются в тексте сообщений,
+// поэтому транспорт может безопасно экранировать сниппет и заменить их своей разметкой
+const (
+	HighlightStart = "\x02"
+	HighlightEnd   = "\x03"
+)
+
+// ChatSearchHit - совпадение полнотекстового поиска: название чата (MessageID == nil) или сообщение в нём
+type ChatSearchHit struct {
+	ChatID    uuid.UUID
+	ChatTitle string
+	MessageID *uuid.UUID
+	Role      string
+	// Snippet - фрагмент текста с совпадениями между HighlightStart и HighlightEnd
+	Snippet   string
+	Rank      float64
+	CreatedAt time.Time
+}
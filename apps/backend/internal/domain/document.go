@@ -5,6 +5,7 @@ import "time"
 type Document struct {
 	ID         string
 	UserID     string
+	OrgID      *string // nil - личный документ UserID
 	ChatID     *string
 	Name       string
 	MimeType   string
@@ -13,4 +14,3 @@ type Document struct {
 
 	CreatedAt time.Time
 }
-
@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobLeaseLost - аренда задачи истекла и её забрал другой воркер (или задача уже завершена)
+	ErrJobLeaseLost = errors.New("job lease lost")
+)
+
+type JobStatus string
+
+const (
+	JobPending JobStatus = "pending" // ждёт воркера (в том числе повтора после ошибки)
+	JobRunning JobStatus = "running" // выполняется; если воркер пропал, задачу заберёт другой после LockedUntil
+	JobDone    JobStatus = "done"
+	JobFailed  JobStatus = "failed" // ошибка без повтора или попытки исчерпаны
+)
+
+// Job - фоновая задача из очереди app.jobs
+type Job struct {
+	ID      uuid.UUID
+	Kind    string
+	UserID  uuid.UUID
+	Payload json.RawMessage
+	Status  JobStatus
+	// Result - результат обработчика (JSON), Error - текст последней ошибки
+	Result      json.RawMessage
+	Error       string
+	Attempts    int
+	MaxAttempts int
+	// DedupeKey - пока задача с этим ключом ждёт выполнения, такая же новая не создаётся
+	DedupeKey string
+
+	RunAt       time.Time
+	LockedUntil *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Finished - задача больше не изменится
+func (j *Job) Finished() bool {
+	return j.Status == JobDone || j.Status == JobFailed
+}
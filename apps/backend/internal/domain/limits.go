@@ -7,6 +7,7 @@ type Limits struct {
 	MaxFileTextChars  int
 	MaxHistoryChars   int
 	MaxRequestChars   int
+	MaxSearchChars    int
 	MaxRequestsPerMin int
 	MaxConcurrentLLM  int
 }
@@ -1,7 +1,42 @@
 package domain
 
+import "encoding/json"
+
 type GenerateParams struct {
 	System  string
 	Context []string
 	User    string
 }
+
+// Generation - результат вызова LLM вместе с расходом токенов
+type Generation struct {
+	Content          string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	// ToolCalls - инструменты, которые модель попросила вызвать (только для ToolLLM.Chat)
+	ToolCalls []ToolCall
+}
+
+// ToolSpec - описание инструмента для модели: имя, назначение и JSON Schema аргументов
+type ToolSpec struct {
+	Name        string
+	Description string
+	Parameters  json.RawMessage
+}
+
+// ToolCall - вызов инструмента, запрошенный моделью
+type ToolCall struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// ChatMessage - сообщение диалога с моделью в формате чата
+type ChatMessage struct {
+	Role    Role
+	Content string
+	// ToolCalls - вызовы инструментов в сообщении ассистента
+	ToolCalls []ToolCall
+	// ToolName - какой инструмент вернул результат (для RoleTool)
+	ToolName string
+}
@@ -0,0 +1,9 @@
+package domain
+
+// Email - письмо для отправки через Mailer
+type Email struct {
+	To      string
+	Subject string
+	// Text - тело письма, обычный текст
+	Text string
+}
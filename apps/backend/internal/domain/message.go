@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -13,6 +14,29 @@ const (
 	RoleSystem    Role = "system" // используем для системных сообщений (например установить поведение)
 	RoleUser      Role = "user"
 	RoleAssistant Role = "assistant"
+	RoleTool      Role = "tool" // результат вызова инструмента (калькулятор, поиск и т.д.)
+)
+
+var (
+	ErrMessageNotFound = errors.New("message not found")
+	// ErrWrongMessageRole - операция неприменима к сообщению этой роли (например, правка ответа ассистента)
+	ErrWrongMessageRole = errors.New("operation is not allowed for this message role")
+	// ErrDuplicateMessage - в чате уже есть сообщение с таким ключом идемпотентности клиента
+	ErrDuplicateMessage = errors.New("message with this client id already exists")
+	// ErrIdempotencyKeyReused - ключ идемпотентности повторно прислан с другим текстом
+	ErrIdempotencyKeyReused = errors.New("idempotency key is already used for a different message")
+	// ErrReplyInProgress - ответ на сообщение с этим ключом ещё генерируется другим запросом
+	ErrReplyInProgress = errors.New("reply is still being generated")
+)
+
+// MessageStatus - состояние генерации ответа на сообщение пользователя.
+// У остальных сообщений всегда complete
+type MessageStatus string
+
+const (
+	MessageStatusPending  MessageStatus = "pending"  // ответ генерируется
+	MessageStatusComplete MessageStatus = "complete" // ответ сохранён
+	MessageStatusFailed   MessageStatus = "failed"   // генерация или сохранение ответа не удались; можно перегенерировать
 )
 
 type Message struct {
@@ -20,10 +44,52 @@ type Message struct {
 	ChatID  uuid.UUID `json:"conversation_id"`
 	Role    string    `json:"role"`
 	Content string    `json:"content"`
+	// Status - для сообщений пользователя: сгенерирован ли ответ
+	Status MessageStatus `json:"status,omitempty"`
+	// ParentID - предыдущее сообщение ветки; nil у первого сообщения чата.
+	// Сообщения чата образуют дерево: перегенерация и правка добавляют новую ветку рядом со старой
+	ParentID *uuid.UUID `json:"parent_id,omitempty"`
+	// SiblingIDs - альтернативы этого сообщения (включая его самого) по порядку создания.
+	// Заполняется только в ListByChat
+	SiblingIDs []uuid.UUID `json:"sibling_ids,omitempty"`
+	// ClientMessageID - ключ идемпотентности от клиента (Idempotency-Key): повтор запроса
+	// с тем же ключом возвращает уже сгенерированный ответ
+	ClientMessageID string `json:"client_message_id,omitempty"`
 
 	CreatedAt time.Time `json:"created_at"`
 	LatencyMs *int64
 	Truncated bool // флаг, что часть запроса/документа была обрезана по лимиту
+
+	Metadata MessageMetadata `json:"metadata"`
+}
+
+// MessageMetadata - служебные сведения о том, как было получено сообщение.
+// Хранится в app.messages.metadata (JSONB)
+type MessageMetadata struct {
+	// DocumentIDs и Scenario - с какими документами и сценарием был задан вопрос (у сообщений пользователя).
+	// Нужны, чтобы перегенерировать ответ в том же контексте
+	DocumentIDs []uuid.UUID `json:"document_ids,omitempty"`
+	Scenario    string      `json:"scenario,omitempty"`
+
+	Searches []SearchRecord `json:"searches,omitempty"`
+	// ToolCalls - инструменты, вызванные моделью в этом сообщении ассистента
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// ToolName и ToolError - для сообщений с ролью tool
+	ToolName  string `json:"tool_name,omitempty"`
+	ToolError string `json:"tool_error,omitempty"`
+	// Injections - признаки prompt-injection в документах, веб-результатах и ответах инструментов
+	Injections []InjectionFlag `json:"injections,omitempty"`
+	// PII - персональные данные, скрытые от модели при подготовке ответа
+	PII *PIIReport `json:"pii,omitempty"`
+	// Guardrails - проверки ответа, которые не прошли, с причиной и принятым решением
+	Guardrails []GuardrailFlag `json:"guardrails,omitempty"`
+}
+
+// InjectionFlag - недоверенный источник, в тексте которого найдены попытки управлять моделью.
+// Source: "document:<id>", "web_search" или "tool:<name>"; Signals - коды сработавших правил
+type InjectionFlag struct {
+	Source  string   `json:"source"`
+	Signals []string `json:"signals"`
 }
 
 func (m *Message) String() string {
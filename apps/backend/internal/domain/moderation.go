@@ -0,0 +1,18 @@
+package domain
+
+// Moderation - результат проверки ответа классификатором недопустимого содержимого
+type Moderation struct {
+	Flagged bool
+	// Categories - причины, например violence, self_harm, illegal
+	Categories []string
+}
+
+// GuardrailFlag - проверка ответа модели, которая не прошла, и что с этим сделано
+type GuardrailFlag struct {
+	// Check - empty, garbage, language, echo или moderation
+	Check  string `json:"check"`
+	Reason string `json:"reason"`
+	// Action - retried (ответ сгенерирован заново), fixed (исправлен), replaced (заменён заглушкой)
+	// или kept (оставлен как есть)
+	Action string `json:"action"`
+}
@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrOrgNotFound - организации нет или пользователь в ней не состоит
+	ErrOrgNotFound = errors.New("organization not found")
+	// ErrAlreadyMember - пользователь уже состоит в организации
+	ErrAlreadyMember = errors.New("already a member")
+	// ErrLastOwner - у организации не останется владельца
+	ErrLastOwner = errors.New("organization must keep an owner")
+	// ErrInviteNotFound - приглашения нет, оно принято, истекло или выписано на другой email
+	ErrInviteNotFound = errors.New("invite not found")
+)
+
+// OrgRole - роль участника организации
+type OrgRole string
+
+const (
+	// OrgRoleOwner - управляет участниками, приглашениями и любыми чатами организации
+	OrgRoleOwner OrgRole = "owner"
+	// OrgRoleMember - создаёт чаты и пишет в чаты организации
+	OrgRoleMember OrgRole = "member"
+	// OrgRoleViewer - только читает чаты организации
+	OrgRoleViewer OrgRole = "viewer"
+)
+
+func (r OrgRole) Valid() bool {
+	switch r {
+	case OrgRoleOwner, OrgRoleMember, OrgRoleViewer:
+		return true
+	}
+	return false
+}
+
+type Org struct {
+	ID   uuid.UUID
+	Name string
+	// Role - роль текущего пользователя; заполняется только OrgRepo.ListByUser
+	Role OrgRole
+
+	CreatedAt time.Time
+}
+
+type Membership struct {
+	OrgID  uuid.UUID
+	UserID uuid.UUID
+	// Email - заполняется только OrgRepo.ListMembers
+	Email string
+	Role  OrgRole
+
+	CreatedAt time.Time
+}
+
+// OrgInvite - приглашение в организацию по email
+type OrgInvite struct {
+	ID    uuid.UUID
+	OrgID uuid.UUID
+	Email string
+	Role  OrgRole
+	// TokenHash - SHA-256 токена из письма; сам токен уходит только в письмо
+	TokenHash  []byte
+	InvitedBy  uuid.UUID
+	ExpiresAt  time.Time
+	AcceptedAt *time.Time
+
+	CreatedAt time.Time
+}
+
+// Pending - приглашение можно принять в момент now
+func (i *OrgInvite) Pending(now time.Time) bool {
+	return i.AcceptedAt == nil && now.Before(i.ExpiresAt)
+}
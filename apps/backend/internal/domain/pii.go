@@ -0,0 +1,15 @@
+package domain
+
+import "errors"
+
+// ErrSensitiveData - в запросе найдены персональные данные, а политика сценария запрещает их отправку в модель
+var ErrSensitiveData = errors.New("sensitive data is not allowed")
+
+// PIIReport - сколько персональных данных найдено в запросе и что с ними сделано.
+// Сами значения не хранятся - только количество по видам
+type PIIReport struct {
+	// Action - mask, tokenize или block
+	Action string `json:"action"`
+	// Counts - количество разных найденных значений по видам: passport, inn, snils, phone, card, account, email, iban
+	Counts map[string]int `json:"counts"`
+}
@@ -2,20 +2,31 @@ package domain
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// TxManager - единица работы: операции репозиториев внутри fn выполняются атомарно.
+// Транзакция передаётся через ctx, поэтому репозитории должны получать именно его
+type TxManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
 type ChatRepo interface {
-	// Create - сохранить новый чат в БД
+	// Create - сохранить новый чат в БД. Заданный chat.CreatedAt сохраняется (импорт), иначе - текущее время
 	Create(ctx context.Context, chat *Chat) error
 	// Get - получить чат по ID
 	GetByID(ctx context.Context, chatID uuid.UUID) (*Chat, error)
-	// ListByUser - получить все чаты пользователя по его ID
+	// ListByUser - получить личные чаты пользователя по его ID (без чатов организаций)
 	ListByUser(ctx context.Context, userID uuid.UUID) ([]*Chat, error)
+	// ListByOrg - получить все чаты организации
+	ListByOrg(ctx context.Context, orgID uuid.UUID) ([]*Chat, error)
 	// Update - Обновить существующий чат
 	UpdateTitle(ctx context.Context, chat *Chat) error
+	// UpdateSummary - сохранить краткое содержание чата
+	UpdateSummary(ctx context.Context, chatID uuid.UUID, summary string) error
 	//Touch - обновление времени последнего сообщения в чате (last_message_at)
 	Touch(ctx context.Context, chatID uuid.UUID, t time.Time) error
 	// Delete - удалить чат из БД по ID
@@ -23,26 +34,179 @@ type ChatRepo interface {
 }
 
 type MessageRepo interface {
-	// Append — сохранить новое сообщение в конец чата.
-	// Предполагаем, что msg.ChatID уже заполнен.
+	// Append — сохранить новое сообщение после msg.ParentID и сделать его концом активной ветки чата.
+	// Предполагаем, что msg.ChatID уже заполнен. Повтор msg.ClientMessageID в чате - ErrDuplicateMessage.
+	// Заданный msg.CreatedAt сохраняется (импорт), иначе - текущее время
 	Append(ctx context.Context, msg *Message) error
-	// GetLastN — взять последние n сообщений чата.
+	// GetByID — сообщение по ID. Если не найдено - nil, nil
+	GetByID(ctx context.Context, messageID uuid.UUID) (*Message, error)
+	// GetByClientID — сообщение чата с ключом идемпотентности клиента. Если не найдено - nil, nil
+	GetByClientID(ctx context.Context, chatID uuid.UUID, clientMessageID string) (*Message, error)
+	// GetAnswer — первый итоговый ответ ассистента на сообщение пользователя
+	// (без промежуточных вызовов инструментов). Если ответа нет - nil, nil
+	GetAnswer(ctx context.Context, questionID uuid.UUID) (*Message, error)
+	// GetLastN — взять последние n сообщений активной ветки чата.
 	// Порядок - от старых к новым
 	GetLastN(ctx context.Context, chatID uuid.UUID, n int) ([]*Message, error)
-	// ListByChat — история чата для UI с пагинацией.
+	// GetBranch — последние n сообщений ветки, которая заканчивается messageID (включая его).
+	// Порядок - от старых к новым
+	GetBranch(ctx context.Context, messageID uuid.UUID, n int) ([]*Message, error)
+	// GetBranchIDs — ID всех сообщений ветки, которая заканчивается messageID (включая его).
+	// Порядок - от старых к новым
+	GetBranchIDs(ctx context.Context, messageID uuid.UUID) ([]uuid.UUID, error)
+	// GetByIDs — сообщения по ID в порядке ids; ненайденные пропускаются
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*Message, error)
+	// ListByChat — страница активной ветки чата для UI с альтернативами каждого сообщения: последние limit сообщений
+	// перед before (nil - до конца ветки). before из другого чата - пустая страница.
 	// Порядок - от старых к новым
-	ListByChat(ctx context.Context, chatID uuid.UUID, limit, offset int) ([]*Message, error)
+	ListByChat(ctx context.Context, chatID uuid.UUID, before *uuid.UUID, limit int) ([]*Message, error)
+	// UpdateStatus — сменить статус генерации ответа на сообщение пользователя
+	UpdateStatus(ctx context.Context, messageID uuid.UUID, status MessageStatus) error
+	// ActivateBranch — сделать активной ветку, проходящую через messageID
+	// (дальше ветка продолжается по самым новым ответам)
+	ActivateBranch(ctx context.Context, messageID uuid.UUID) error
+}
+
+type ChatSearchRepo interface {
+	// Search — полнотекстовый поиск по названиям чатов из scope и по завершённым сообщениям
+	// их активных веток. Порядок - от более релевантных к менее
+	Search(ctx context.Context, scope ChatScope, query string, limit, offset int) ([]*ChatSearchHit, error)
+}
+
+type ShareRepo interface {
+	// Create — сохранить ссылку на чат
+	Create(ctx context.Context, share *ChatShare) error
+	// GetByID — ссылка по ID. Если не найдена - nil, nil
+	GetByID(ctx context.Context, shareID uuid.UUID) (*ChatShare, error)
+	// GetByTokenHash — ссылка по хэшу токена. Если не найдена - nil, nil
+	GetByTokenHash(ctx context.Context, tokenHash []byte) (*ChatShare, error)
+	// ListByChat — все ссылки чата, включая отозванные и истёкшие. Порядок - от новых к старым
+	ListByChat(ctx context.Context, chatID uuid.UUID) ([]*ChatShare, error)
+	// Revoke — отозвать ссылку в момент at. Повторный отзыв не меняет время
+	Revoke(ctx context.Context, shareID uuid.UUID, at time.Time) error
+}
+
+type OrgRepo interface {
+	// Create — сохранить организацию вместе с владельцем ownerID
+	Create(ctx context.Context, org *Org, ownerID uuid.UUID) error
+	// GetByID — организация по ID. Если не найдена - nil, nil
+	GetByID(ctx context.Context, orgID uuid.UUID) (*Org, error)
+	// ListByUser — организации, в которых состоит пользователь, с его ролью в Org.Role
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]*Org, error)
+	// GetMembership — участие пользователя в организации. Если не состоит - nil, nil
+	GetMembership(ctx context.Context, orgID, userID uuid.UUID) (*Membership, error)
+	// ListMembers — участники организации с email. Порядок - по времени вступления
+	ListMembers(ctx context.Context, orgID uuid.UUID) ([]*Membership, error)
+	// AddMember — добавить участника. Если он уже состоит в организации - ErrAlreadyMember
+	AddMember(ctx context.Context, m *Membership) error
+	// UpdateMemberRole — сменить роль участника
+	UpdateMemberRole(ctx context.Context, orgID, userID uuid.UUID, role OrgRole) error
+	// RemoveMember — исключить участника. Его чаты остаются в организации
+	RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error
+	// LockOwners — ID владельцев организации. Внутри транзакции их записи блокируются до её конца,
+	// чтобы параллельные понижения и выходы владельцев не оставили организацию без владельца
+	LockOwners(ctx context.Context, orgID uuid.UUID) ([]uuid.UUID, error)
+}
+
+type InviteRepo interface {
+	// Create — сохранить приглашение
+	Create(ctx context.Context, invite *OrgInvite) error
+	// GetByTokenHash — приглашение по хэшу токена. Если не найдено - nil, nil
+	GetByTokenHash(ctx context.Context, tokenHash []byte) (*OrgInvite, error)
+	// ListByOrg — приглашения организации, включая принятые и истёкшие. Порядок - от новых к старым
+	ListByOrg(ctx context.Context, orgID uuid.UUID) ([]*OrgInvite, error)
+	// MarkAccepted — отметить приглашение принятым в момент at
+	MarkAccepted(ctx context.Context, inviteID uuid.UUID, at time.Time) error
+	// Delete — удалить приглашение, письмо с которым не удалось отправить
+	Delete(ctx context.Context, inviteID uuid.UUID) error
+}
+
+type Mailer interface {
+	// Send - отправить письмо
+	Send(ctx context.Context, email *Email) error
+}
+
+type JobRepo interface {
+	// Enqueue — поставить задачу в очередь. Если задача с тем же DedupeKey ещё ждёт выполнения,
+	// новая не создаётся, а job заполняется существующей
+	Enqueue(ctx context.Context, job *Job) error
+	// GetByID — задача по ID. Если не найдена - nil, nil
+	GetByID(ctx context.Context, jobID uuid.UUID) (*Job, error)
+	// Claim — забрать одну готовую к запуску задачу указанных видов (или зависшую с истёкшей арендой)
+	// и арендовать её на lease. Конкурирующие воркеры не получают одну задачу дважды. Нет задач - nil, nil
+	Claim(ctx context.Context, kinds []string, lease time.Duration) (*Job, error)
+	// Complete — завершить задачу с результатом. attempt - Attempts из Claim: каждый Claim увеличивает его,
+	// поэтому он служит токеном аренды. Если задачу с тех пор забрал другой воркер - ErrJobLeaseLost
+	Complete(ctx context.Context, jobID uuid.UUID, attempt int, result json.RawMessage) error
+	// Fail — записать ошибку: при retryAt != nil задача будет повторена в это время, иначе завершается как failed.
+	// Аренда проверяется так же, как в Complete
+	Fail(ctx context.Context, jobID uuid.UUID, attempt int, errText string, retryAt *time.Time) error
 }
 
 type LLM interface {
-	// Generate - отправить запрос к LLM. Возвращает ответ в виде string
+	// Generate - отправить запрос к LLM. Возвращает ответ и расход токенов
 	// Для хендлеров стоит в main.go создать новый сервис
-	Generate(ctx context.Context, prompt []byte) (string, error)
+	Generate(ctx context.Context, prompt []byte) (*Generation, error)
+}
+
+type ToolLLM interface {
+	// Chat - отправить диалог вместе с описанием доступных инструментов.
+	// Если модель решила вызвать инструменты, они возвращаются в Generation.ToolCalls
+	Chat(ctx context.Context, messages []ChatMessage, tools []ToolSpec) (*Generation, error)
+}
+
+type Moderator interface {
+	// Moderate - проверить текст ответа на недопустимое содержимое
+	Moderate(ctx context.Context, text string) (*Moderation, error)
+}
+
+type SearchProvider interface {
+	// Search - найти в интернете до maxResults результатов по запросу
+	Search(ctx context.Context, query string, maxResults int) ([]SearchResult, error)
+}
+
+type PageFetcher interface {
+	// Fetch - скачать страницу и извлечь из неё читаемый текст
+	Fetch(ctx context.Context, url string) (*Page, error)
+}
+
+type Cache interface {
+	// Get - получить неистёкшее значение по ключу, nil если его нет
+	Get(ctx context.Context, key string) (*CacheEntry, error)
+	// Set - сохранить значение до entry.ExpiresAt
+	Set(ctx context.Context, key string, entry *CacheEntry) error
 }
 
 type UserRepo interface {
 	// GetByEmail - получить пользователя по email
 	GetByEmail(ctx context.Context, email string) (*User, error)
+	// GetByID - получить пользователя по ID
+	GetByID(ctx context.Context, userID uuid.UUID) (*User, error)
 	// UpdateLastLogin - обновить время последнего входа
 	UpdateLastLogin(ctx context.Context, userID uuid.UUID) error
+	// Create - сохранить нового пользователя. Если email занят - ErrUserExists
+	Create(ctx context.Context, user *User) error
+	// List - пользователи, email которых содержит query (без учёта регистра; пустой query - все),
+	// с числом чатов. Порядок - от новых к старым
+	List(ctx context.Context, query string, limit, offset int) ([]*UserOverview, error)
+	// GetOverview - пользователь с числом чатов. Если не найден - nil, nil
+	GetOverview(ctx context.Context, userID uuid.UUID) (*UserOverview, error)
+	// SetActive - включить или отключить пользователя. Если не найден - ErrUserNotFound
+	SetActive(ctx context.Context, userID uuid.UUID, active bool) error
+	// UpdatePassword - сменить хэш пароля; temporary - пароль выдан администратором и должен быть сменён.
+	// Если не найден - ErrUserNotFound
+	UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash string, temporary bool) error
+	// RevokeTokens - увеличить версию токенов, отозвав все выданные. Если не найден - ErrUserNotFound
+	RevokeTokens(ctx context.Context, userID uuid.UUID) error
+}
+
+type UsageRepo interface {
+	// Add - прибавить потребление к дневной записи пользователя (u.UserID, u.Day)
+	Add(ctx context.Context, u *Usage) error
+	// Sum - суммарное потребление пользователя за дни в диапазоне [from, to]
+	Sum(ctx context.Context, userID uuid.UUID, from, to time.Time) (*Usage, error)
+	// GetQuota - персональная квота пользователя, nil если не задана
+	GetQuota(ctx context.Context, userID uuid.UUID) (*Quota, error)
+	// SetQuota - создать или обновить персональную квоту пользователя
+	SetQuota(ctx context.Context, q *Quota) error
 }
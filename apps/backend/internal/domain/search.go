@@ -0,0 +1,29 @@
+package domain
+
+// SearchResult - один результат веб-поиска
+type SearchResult struct {
+	Title   string
+	URL     string
+	Snippet string
+	// Excerpt - выдержка из полного текста страницы (если её удалось скачать)
+	Excerpt string
+}
+
+// Page - читаемый текст веб-страницы без разметки, меню и скриптов
+type Page struct {
+	URL   string
+	Title string
+	// Text - основной текст, абзацы разделены пустой строкой
+	Text string
+}
+
+// SearchRecord - сведения о поиске, выполненном при подготовке ответа
+type SearchRecord struct {
+	Query   string   `json:"query"`
+	Source  string   `json:"source,omitempty"` // кто решил искать: llm, heuristic, explicit
+	Results int      `json:"results"`
+	URLs    []string `json:"urls,omitempty"`
+	// FetchedURLs - страницы, чей текст попал в промпт
+	FetchedURLs []string `json:"fetched_urls,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
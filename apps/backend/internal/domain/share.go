@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrShareNotFound - ссылки нет, она отозвана или истекла
+var ErrShareNotFound = errors.New("share not found")
+
+// ChatShare - ссылка на снимок чата только для чтения
+type ChatShare struct {
+	ID     uuid.UUID
+	ChatID uuid.UUID
+	UserID uuid.UUID
+	// TokenHash - SHA-256 токена из ссылки; сам токен показывается владельцу только при создании
+	TokenHash []byte
+	// MessageID - последнее сообщение снимка; nil - чат был пуст
+	MessageID *uuid.UUID
+	MaskPII   bool
+	ExpiresAt *time.Time
+	RevokedAt *time.Time
+
+	CreatedAt time.Time
+}
+
+// Active - по ссылке можно открыть чат в момент now
+func (s *ChatShare) Active(now time.Time) bool {
+	if s.RevokedAt != nil {
+		return false
+	}
+	return s.ExpiresAt == nil || now.Before(*s.ExpiresAt)
+}
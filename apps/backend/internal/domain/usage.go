@@ -0,0 +1,39 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrQuotaExceeded - пользователь исчерпал дневную или месячную квоту
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Usage - потребление пользователя за день (или сумма за период)
+type Usage struct {
+	UserID           uuid.UUID
+	Day              time.Time
+	Requests         int64
+	PromptTokens     int64
+	CompletionTokens int64
+	DocumentBytes    int64
+}
+
+// Tokens возвращает суммарное количество токенов (запрос + ответ)
+func (u Usage) Tokens() int64 {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// Quota - лимиты потребления пользователя. Значение 0 означает отсутствие лимита
+type Quota struct {
+	UserID               uuid.UUID
+	DailyTokens          int64
+	MonthlyTokens        int64
+	DailyRequests        int64
+	MonthlyRequests      int64
+	DailyDocumentBytes   int64
+	MonthlyDocumentBytes int64
+
+	UpdatedAt time.Time
+}
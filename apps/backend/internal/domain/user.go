@@ -1,18 +1,49 @@
 package domain
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+var (
+	// ErrUserNotFound - пользователя с таким ID нет
+	ErrUserNotFound = errors.New("user not found")
+	// ErrUserExists - пользователь с таким email уже есть
+	ErrUserExists = errors.New("user already exists")
+)
+
+type UserRole string
+
+const (
+	UserRoleUser  UserRole = "user"
+	UserRoleAdmin UserRole = "admin"
+)
+
+func (r UserRole) Valid() bool {
+	return r == UserRoleUser || r == UserRoleAdmin
+}
+
 type User struct {
 	ID           uuid.UUID
 	Email        string
 	Name         string
 	PasswordHash string
 	IsActive     bool
+	Role         UserRole
+	// TokenVersion - версия выданных токенов; токены с другой версией отозваны
+	TokenVersion int
+	// MustChangePassword - пароль временный, выданный администратором
+	MustChangePassword bool
 
 	CreatedAt   time.Time
 	LastLoginAt *time.Time
 }
+
+// UserOverview - пользователь для администратора вместе с числом его чатов
+type UserOverview struct {
+	User
+	// Chats - чаты, созданные пользователем, включая чаты организаций
+	Chats int
+}
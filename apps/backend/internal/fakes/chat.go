@@ -0,0 +1,91 @@
+package fakes
+
+import (
+	"backend/internal/domain"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ChatRepo хранит чаты в памяти
+type ChatRepo struct {
+	Chats map[uuid.UUID]*domain.Chat
+	// Touched - время последнего сообщения, переданное в Touch, по чатам
+	Touched map[uuid.UUID]time.Time
+	// TouchErr - ошибка Touch (сбой сохранения ответа)
+	TouchErr error
+}
+
+func NewChatRepo(chats ...*domain.Chat) *ChatRepo {
+	f := &ChatRepo{Chats: make(map[uuid.UUID]*domain.Chat), Touched: make(map[uuid.UUID]time.Time)}
+	for _, chat := range chats {
+		f.Chats[chat.ID] = chat
+	}
+	return f
+}
+
+func (f *ChatRepo) Create(_ context.Context, chat *domain.Chat) error {
+	if chat.CreatedAt.IsZero() {
+		chat.CreatedAt = time.Now()
+	}
+	f.Chats[chat.ID] = chat
+	return nil
+}
+
+// GetByID для неизвестного чата возвращает ошибку, как настоящий репозиторий
+func (f *ChatRepo) GetByID(_ context.Context, chatID uuid.UUID) (*domain.Chat, error) {
+	chat, ok := f.Chats[chatID]
+	if !ok {
+		return nil, fmt.Errorf("chat %s not found", chatID)
+	}
+	return chat, nil
+}
+
+func (f *ChatRepo) ListByUser(_ context.Context, userID uuid.UUID) ([]*domain.Chat, error) {
+	var out []*domain.Chat
+	for _, chat := range f.Chats {
+		if chat.UserID == userID && chat.OrgID == nil {
+			out = append(out, chat)
+		}
+	}
+	return out, nil
+}
+
+func (f *ChatRepo) ListByOrg(_ context.Context, orgID uuid.UUID) ([]*domain.Chat, error) {
+	var out []*domain.Chat
+	for _, chat := range f.Chats {
+		if chat.OrgID != nil && *chat.OrgID == orgID {
+			out = append(out, chat)
+		}
+	}
+	return out, nil
+}
+
+func (f *ChatRepo) UpdateTitle(_ context.Context, chat *domain.Chat) error {
+	if stored, ok := f.Chats[chat.ID]; ok {
+		stored.Title = chat.Title
+	}
+	return nil
+}
+
+func (f *ChatRepo) UpdateSummary(_ context.Context, chatID uuid.UUID, summary string) error {
+	if chat, ok := f.Chats[chatID]; ok {
+		chat.Summary = &summary
+	}
+	return nil
+}
+
+func (f *ChatRepo) Touch(_ context.Context, chatID uuid.UUID, t time.Time) error {
+	if f.TouchErr != nil {
+		return f.TouchErr
+	}
+	f.Touched[chatID] = t
+	return nil
+}
+
+func (f *ChatRepo) Delete(_ context.Context, chatID uuid.UUID) error {
+	delete(f.Chats, chatID)
+	return nil
+}
@@ -0,0 +1,42 @@
+// Package fakes - реализации портов domain в памяти для тестов сценариев и HTTP-слоя.
+// Поведение повторяет контракты из domain/ports.go: ненайденное - nil, nil, дубликаты - доменные ошибки.
+// Фейки не потокобезопасны, кроме JobRepo, с которым работают параллельные воркеры
+package fakes
+
+import (
+	"context"
+)
+
+// Snapshotter - репозиторий, состояние которого Tx умеет откатить
+type Snapshotter interface {
+	// Snapshot запоминает текущее состояние и возвращает функцию, которая его восстанавливает
+	Snapshot() (restore func())
+}
+
+// Tx выполняет fn без настоящей транзакции. Если fn вернула ошибку,
+// репозитории Rollback возвращаются к состоянию до её вызова, как при откате
+type Tx struct {
+	Rollback []Snapshotter
+	// Calls - сколько раз вызван WithinTx
+	Calls int
+}
+
+func NewTx(rollback ...Snapshotter) *Tx {
+	return &Tx{Rollback: rollback}
+}
+
+func (f *Tx) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	f.Calls++
+
+	restore := make([]func(), 0, len(f.Rollback))
+	for _, repo := range f.Rollback {
+		restore = append(restore, repo.Snapshot())
+	}
+	if err := fn(ctx); err != nil {
+		for _, r := range restore {
+			r()
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package fakes
+
+import (
+	"backend/internal/domain"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// JobRepo - очередь в памяти; Claim берёт первую ожидающую задачу без учёта времени повтора и вида.
+// Аренда проверяется по Attempts, как в настоящем репозитории
+type JobRepo struct {
+	mu   sync.Mutex
+	Jobs []*domain.Job
+}
+
+// Update меняет сохранённую задачу под блокировкой, например чтобы её забрал другой воркер
+func (f *JobRepo) Update(jobID uuid.UUID, fn func(job *domain.Job)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, job := range f.Jobs {
+		if job.ID == jobID {
+			fn(job)
+		}
+	}
+}
+
+func (f *JobRepo) Enqueue(_ context.Context, job *domain.Job) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, existing := range f.Jobs {
+		if job.DedupeKey != "" && existing.DedupeKey == job.DedupeKey && existing.Status == domain.JobPending {
+			*job = *existing
+			return nil
+		}
+	}
+	job.Status = domain.JobPending
+	saved := *job
+	f.Jobs = append(f.Jobs, &saved)
+	return nil
+}
+
+func (f *JobRepo) GetByID(_ context.Context, jobID uuid.UUID) (*domain.Job, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, job := range f.Jobs {
+		if job.ID == jobID {
+			copied := *job
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *JobRepo) Claim(_ context.Context, _ []string, _ time.Duration) (*domain.Job, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, job := range f.Jobs {
+		if job.Status == domain.JobPending {
+			job.Status = domain.JobRunning
+			job.Attempts++
+			copied := *job
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *JobRepo) Complete(_ context.Context, jobID uuid.UUID, attempt int, result json.RawMessage) error {
+	return f.save(jobID, attempt, func(job *domain.Job) {
+		job.Status, job.Result, job.Error = domain.JobDone, result, ""
+	})
+}
+
+func (f *JobRepo) Fail(_ context.Context, jobID uuid.UUID, attempt int, errText string, retryAt *time.Time) error {
+	return f.save(jobID, attempt, func(job *domain.Job) {
+		job.Status, job.Error = domain.JobFailed, errText
+		if retryAt != nil {
+			job.Status, job.RunAt = domain.JobPending, *retryAt
+		}
+	})
+}
+
+func (f *JobRepo) save(jobID uuid.UUID, attempt int, fn func(job *domain.Job)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, job := range f.Jobs {
+		if job.ID == jobID {
+			if job.Status != domain.JobRunning || job.Attempts != attempt {
+				return domain.ErrJobLeaseLost
+			}
+			fn(job)
+			return nil
+		}
+	}
+	return domain.ErrJobNotFound
+}
@@ -0,0 +1,21 @@
+package fakes
+
+import (
+	"backend/internal/domain"
+	"context"
+)
+
+// LLM - поддельная модель: запоминает промпты и отдаёт заранее заданный ответ
+type LLM struct {
+	Response string
+	Err      error
+	Prompts  []string
+}
+
+func (f *LLM) Generate(_ context.Context, prompt []byte) (*domain.Generation, error) {
+	f.Prompts = append(f.Prompts, string(prompt))
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	return &domain.Generation{Content: f.Response, Model: "fake"}, nil
+}
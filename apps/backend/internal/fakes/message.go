@@ -0,0 +1,225 @@
+package fakes
+
+import (
+	"backend/internal/domain"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MessageRepo хранит дерево сообщений в памяти по порядку сохранения. Если задан Chats,
+// Append делает сообщение концом активной ветки его чата, как настоящий репозиторий
+type MessageRepo struct {
+	Chats    *ChatRepo
+	Messages []*domain.Message
+	// AppendErr - ошибка Append (сбой сохранения)
+	AppendErr error
+}
+
+func NewMessageRepo(chats *ChatRepo) *MessageRepo {
+	return &MessageRepo{Chats: chats}
+}
+
+// AppendBranch сохраняет сообщения одной веткой после активного сообщения чата в порядке перечисления
+func (f *MessageRepo) AppendBranch(chat *domain.Chat, messages ...*domain.Message) {
+	for _, msg := range messages {
+		msg.ChatID = chat.ID
+		msg.ParentID = chat.ActiveMessageID
+		f.Messages = append(f.Messages, msg)
+		id := msg.ID
+		chat.ActiveMessageID = &id
+	}
+}
+
+func (f *MessageRepo) Append(ctx context.Context, msg *domain.Message) error {
+	if f.AppendErr != nil {
+		return f.AppendErr
+	}
+	if msg.ClientMessageID != "" {
+		if existing, _ := f.GetByClientID(ctx, msg.ChatID, msg.ClientMessageID); existing != nil {
+			return domain.ErrDuplicateMessage
+		}
+	}
+	if msg.CreatedAt.IsZero() {
+		msg.CreatedAt = time.Now()
+	}
+	f.Messages = append(f.Messages, msg)
+
+	if chat := f.chat(msg.ChatID); chat != nil {
+		id := msg.ID
+		chat.ActiveMessageID = &id
+	}
+	return nil
+}
+
+func (f *MessageRepo) GetByID(_ context.Context, messageID uuid.UUID) (*domain.Message, error) {
+	for _, msg := range f.Messages {
+		if msg.ID == messageID {
+			return msg, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *MessageRepo) GetByClientID(_ context.Context, chatID uuid.UUID, clientMessageID string) (*domain.Message, error) {
+	for _, msg := range f.Messages {
+		if msg.ChatID == chatID && msg.ClientMessageID == clientMessageID {
+			return msg, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *MessageRepo) GetAnswer(_ context.Context, questionID uuid.UUID) (*domain.Message, error) {
+	parent := questionID
+	for _, msg := range f.Messages {
+		if msg.ParentID == nil || *msg.ParentID != parent {
+			continue
+		}
+		if msg.Role == string(domain.RoleAssistant) && len(msg.Metadata.ToolCalls) == 0 {
+			return msg, nil
+		}
+		parent = msg.ID
+	}
+	return nil, nil
+}
+
+func (f *MessageRepo) GetLastN(ctx context.Context, chatID uuid.UUID, n int) ([]*domain.Message, error) {
+	chat := f.chat(chatID)
+	if chat == nil || chat.ActiveMessageID == nil {
+		return nil, nil
+	}
+	return f.GetBranch(ctx, *chat.ActiveMessageID, n)
+}
+
+func (f *MessageRepo) GetBranch(ctx context.Context, messageID uuid.UUID, n int) ([]*domain.Message, error) {
+	var branch []*domain.Message
+	for msg, _ := f.GetByID(ctx, messageID); msg != nil && len(branch) < n; {
+		branch = append(branch, msg)
+		if msg.ParentID == nil {
+			break
+		}
+		msg, _ = f.GetByID(ctx, *msg.ParentID)
+	}
+	slices.Reverse(branch)
+	return branch, nil
+}
+
+func (f *MessageRepo) GetBranchIDs(ctx context.Context, messageID uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for msg, _ := f.GetByID(ctx, messageID); msg != nil; {
+		ids = append(ids, msg.ID)
+		if msg.ParentID == nil {
+			break
+		}
+		msg, _ = f.GetByID(ctx, *msg.ParentID)
+	}
+	slices.Reverse(ids)
+	return ids, nil
+}
+
+func (f *MessageRepo) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*domain.Message, error) {
+	var out []*domain.Message
+	for _, id := range ids {
+		if msg, _ := f.GetByID(ctx, id); msg != nil {
+			out = append(out, msg)
+		}
+	}
+	return out, nil
+}
+
+// ListByChat отдаёт копии сообщений с заполненными SiblingIDs
+func (f *MessageRepo) ListByChat(ctx context.Context, chatID uuid.UUID, before *uuid.UUID, limit int) ([]*domain.Message, error) {
+	var end *uuid.UUID
+	if before == nil {
+		if chat := f.chat(chatID); chat != nil {
+			end = chat.ActiveMessageID
+		}
+	} else {
+		msg, _ := f.GetByID(ctx, *before)
+		if msg == nil || msg.ChatID != chatID {
+			return nil, nil
+		}
+		end = msg.ParentID
+	}
+	if end == nil {
+		return nil, nil
+	}
+
+	branch, err := f.GetBranch(ctx, *end, limit)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*domain.Message, 0, len(branch))
+	for _, msg := range branch {
+		copied := *msg
+		copied.SiblingIDs = f.siblings(msg)
+		out = append(out, &copied)
+	}
+	return out, nil
+}
+
+func (f *MessageRepo) UpdateStatus(ctx context.Context, messageID uuid.UUID, status domain.MessageStatus) error {
+	if msg, _ := f.GetByID(ctx, messageID); msg != nil {
+		msg.Status = status
+	}
+	return nil
+}
+
+func (f *MessageRepo) ActivateBranch(ctx context.Context, messageID uuid.UUID) error {
+	msg, _ := f.GetByID(ctx, messageID)
+	if msg == nil {
+		return nil
+	}
+	// Спускаемся по самым новым ответам
+	for next := msg; next != nil; {
+		msg, next = next, nil
+		for _, child := range f.Messages {
+			if child.ParentID != nil && *child.ParentID == msg.ID {
+				next = child
+			}
+		}
+	}
+	if chat := f.chat(msg.ChatID); chat != nil {
+		id := msg.ID
+		chat.ActiveMessageID = &id
+	}
+	return nil
+}
+
+// Snapshot откатывает сохранённые сообщения и активные ветки чатов
+func (f *MessageRepo) Snapshot() func() {
+	saved := len(f.Messages)
+	active := make(map[uuid.UUID]*uuid.UUID)
+	if f.Chats != nil {
+		for id, chat := range f.Chats.Chats {
+			active[id] = chat.ActiveMessageID
+		}
+	}
+	return func() {
+		f.Messages = f.Messages[:saved]
+		for id, messageID := range active {
+			f.Chats.Chats[id].ActiveMessageID = messageID
+		}
+	}
+}
+
+func (f *MessageRepo) chat(chatID uuid.UUID) *domain.Chat {
+	if f.Chats == nil {
+		return nil
+	}
+	return f.Chats.Chats[chatID]
+}
+
+func (f *MessageRepo) siblings(msg *domain.Message) []uuid.UUID {
+	var ids []uuid.UUID
+	for _, other := range f.Messages {
+		if other.ChatID == msg.ChatID && ((other.ParentID == nil && msg.ParentID == nil) ||
+			(other.ParentID != nil && msg.ParentID != nil && *other.ParentID == *msg.ParentID)) {
+			ids = append(ids, other.ID)
+		}
+	}
+	return ids
+}
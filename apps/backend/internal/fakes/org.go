@@ -0,0 +1,154 @@
+package fakes
+
+import (
+	"backend/internal/domain"
+	"bytes"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OrgRepo хранит организации и участников в памяти
+type OrgRepo struct {
+	Orgs    map[uuid.UUID]*domain.Org
+	Members []*domain.Membership
+}
+
+func NewOrgRepo() *OrgRepo {
+	return &OrgRepo{Orgs: make(map[uuid.UUID]*domain.Org)}
+}
+
+func (f *OrgRepo) Create(_ context.Context, org *domain.Org, ownerID uuid.UUID) error {
+	org.CreatedAt = time.Now()
+	org.Role = domain.OrgRoleOwner
+	f.Orgs[org.ID] = org
+	f.Members = append(f.Members, &domain.Membership{OrgID: org.ID, UserID: ownerID, Role: domain.OrgRoleOwner, CreatedAt: org.CreatedAt})
+	return nil
+}
+
+func (f *OrgRepo) GetByID(_ context.Context, orgID uuid.UUID) (*domain.Org, error) {
+	return f.Orgs[orgID], nil
+}
+
+func (f *OrgRepo) ListByUser(_ context.Context, userID uuid.UUID) ([]*domain.Org, error) {
+	var out []*domain.Org
+	for _, m := range f.Members {
+		if org, ok := f.Orgs[m.OrgID]; ok && m.UserID == userID {
+			copied := *org
+			copied.Role = m.Role
+			out = append(out, &copied)
+		}
+	}
+	return out, nil
+}
+
+func (f *OrgRepo) GetMembership(_ context.Context, orgID, userID uuid.UUID) (*domain.Membership, error) {
+	for _, m := range f.Members {
+		if m.OrgID == orgID && m.UserID == userID {
+			return m, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *OrgRepo) ListMembers(_ context.Context, orgID uuid.UUID) ([]*domain.Membership, error) {
+	var out []*domain.Membership
+	for _, m := range f.Members {
+		if m.OrgID == orgID {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func (f *OrgRepo) AddMember(ctx context.Context, m *domain.Membership) error {
+	if existing, _ := f.GetMembership(ctx, m.OrgID, m.UserID); existing != nil {
+		return domain.ErrAlreadyMember
+	}
+	m.CreatedAt = time.Now()
+	f.Members = append(f.Members, m)
+	return nil
+}
+
+func (f *OrgRepo) UpdateMemberRole(ctx context.Context, orgID, userID uuid.UUID, role domain.OrgRole) error {
+	if m, _ := f.GetMembership(ctx, orgID, userID); m != nil {
+		m.Role = role
+	}
+	return nil
+}
+
+func (f *OrgRepo) RemoveMember(_ context.Context, orgID, userID uuid.UUID) error {
+	f.Members = slices.DeleteFunc(f.Members, func(m *domain.Membership) bool { return m.OrgID == orgID && m.UserID == userID })
+	return nil
+}
+
+func (f *OrgRepo) LockOwners(_ context.Context, orgID uuid.UUID) ([]uuid.UUID, error) {
+	var owners []uuid.UUID
+	for _, m := range f.Members {
+		if m.OrgID == orgID && m.Role == domain.OrgRoleOwner {
+			owners = append(owners, m.UserID)
+		}
+	}
+	return owners, nil
+}
+
+// InviteRepo хранит приглашения в памяти
+type InviteRepo struct {
+	Invites []*domain.OrgInvite
+}
+
+func (f *InviteRepo) Create(_ context.Context, invite *domain.OrgInvite) error {
+	invite.CreatedAt = time.Now()
+	f.Invites = append(f.Invites, invite)
+	return nil
+}
+
+func (f *InviteRepo) GetByTokenHash(_ context.Context, tokenHash []byte) (*domain.OrgInvite, error) {
+	for _, invite := range f.Invites {
+		if bytes.Equal(invite.TokenHash, tokenHash) {
+			return invite, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *InviteRepo) ListByOrg(_ context.Context, orgID uuid.UUID) ([]*domain.OrgInvite, error) {
+	var out []*domain.OrgInvite
+	for _, invite := range slices.Backward(f.Invites) {
+		if invite.OrgID == orgID {
+			out = append(out, invite)
+		}
+	}
+	return out, nil
+}
+
+func (f *InviteRepo) MarkAccepted(_ context.Context, inviteID uuid.UUID, at time.Time) error {
+	for _, invite := range f.Invites {
+		if invite.ID == inviteID {
+			invite.AcceptedAt = &at
+		}
+	}
+	return nil
+}
+
+func (f *InviteRepo) Delete(_ context.Context, inviteID uuid.UUID) error {
+	f.Invites = slices.DeleteFunc(f.Invites, func(invite *domain.OrgInvite) bool { return invite.ID == inviteID })
+	return nil
+}
+
+// Mailer запоминает отправленные письма
+type Mailer struct {
+	Sent []*domain.Email
+	// Err - ошибка отправки
+	Err error
+}
+
+func (f *Mailer) Send(_ context.Context, email *domain.Email) error {
+	if f.Err != nil {
+		return f.Err
+	}
+	f.Sent = append(f.Sent, email)
+	return nil
+}
@@ -0,0 +1,59 @@
+package fakes
+
+import (
+	"backend/internal/domain"
+	"bytes"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ShareRepo хранит ссылки на чаты в памяти
+type ShareRepo struct {
+	Shares []*domain.ChatShare
+}
+
+func (f *ShareRepo) Create(_ context.Context, share *domain.ChatShare) error {
+	share.CreatedAt = time.Now()
+	f.Shares = append(f.Shares, share)
+	return nil
+}
+
+func (f *ShareRepo) GetByID(_ context.Context, shareID uuid.UUID) (*domain.ChatShare, error) {
+	for _, share := range f.Shares {
+		if share.ID == shareID {
+			return share, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *ShareRepo) GetByTokenHash(_ context.Context, tokenHash []byte) (*domain.ChatShare, error) {
+	for _, share := range f.Shares {
+		if bytes.Equal(share.TokenHash, tokenHash) {
+			return share, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *ShareRepo) ListByChat(_ context.Context, chatID uuid.UUID) ([]*domain.ChatShare, error) {
+	var out []*domain.ChatShare
+	for _, share := range slices.Backward(f.Shares) {
+		if share.ChatID == chatID {
+			out = append(out, share)
+		}
+	}
+	return out, nil
+}
+
+func (f *ShareRepo) Revoke(_ context.Context, shareID uuid.UUID, at time.Time) error {
+	for _, share := range f.Shares {
+		if share.ID == shareID && share.RevokedAt == nil {
+			share.RevokedAt = &at
+		}
+	}
+	return nil
+}
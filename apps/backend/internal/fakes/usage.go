@@ -0,0 +1,57 @@
+package fakes
+
+import (
+	"backend/internal/domain"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UsageRepo хранит дневное потребление и квоты в памяти
+type UsageRepo struct {
+	Days   []*domain.Usage
+	Quotas map[uuid.UUID]*domain.Quota
+}
+
+func NewUsageRepo() *UsageRepo {
+	return &UsageRepo{Quotas: make(map[uuid.UUID]*domain.Quota)}
+}
+
+func (f *UsageRepo) Add(_ context.Context, u *domain.Usage) error {
+	for _, day := range f.Days {
+		if day.UserID == u.UserID && day.Day.Equal(u.Day) {
+			day.Requests += u.Requests
+			day.PromptTokens += u.PromptTokens
+			day.CompletionTokens += u.CompletionTokens
+			day.DocumentBytes += u.DocumentBytes
+			return nil
+		}
+	}
+	copied := *u
+	f.Days = append(f.Days, &copied)
+	return nil
+}
+
+func (f *UsageRepo) Sum(_ context.Context, userID uuid.UUID, from, to time.Time) (*domain.Usage, error) {
+	sum := domain.Usage{UserID: userID, Day: from}
+	for _, u := range f.Days {
+		if u.UserID != userID || u.Day.Before(from) || u.Day.After(to) {
+			continue
+		}
+		sum.Requests += u.Requests
+		sum.PromptTokens += u.PromptTokens
+		sum.CompletionTokens += u.CompletionTokens
+		sum.DocumentBytes += u.DocumentBytes
+	}
+	return &sum, nil
+}
+
+func (f *UsageRepo) GetQuota(_ context.Context, userID uuid.UUID) (*domain.Quota, error) {
+	return f.Quotas[userID], nil
+}
+
+func (f *UsageRepo) SetQuota(_ context.Context, q *domain.Quota) error {
+	f.Quotas[q.UserID] = q
+	return nil
+}
@@ -0,0 +1,123 @@
+package fakes
+
+import (
+	"backend/internal/domain"
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserRepo хранит пользователей в памяти
+type UserRepo struct {
+	Users map[uuid.UUID]*domain.User
+	// Chats - число чатов пользователя для List и GetOverview
+	Chats map[uuid.UUID]int
+	// RevokeErr - ошибка RevokeTokens, чтобы проверить откат сброса пароля
+	RevokeErr error
+}
+
+func NewUserRepo(users ...*domain.User) *UserRepo {
+	f := &UserRepo{Users: make(map[uuid.UUID]*domain.User), Chats: make(map[uuid.UUID]int)}
+	for _, u := range users {
+		f.Users[u.ID] = u
+	}
+	return f
+}
+
+// GetByEmail сравнивает адрес точно: нормализовать его должен вызывающий код
+func (f *UserRepo) GetByEmail(_ context.Context, email string) (*domain.User, error) {
+	for _, u := range f.Users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *UserRepo) GetByID(_ context.Context, userID uuid.UUID) (*domain.User, error) {
+	return f.Users[userID], nil
+}
+
+func (f *UserRepo) UpdateLastLogin(_ context.Context, userID uuid.UUID) error {
+	if u, ok := f.Users[userID]; ok {
+		now := time.Now()
+		u.LastLoginAt = &now
+	}
+	return nil
+}
+
+func (f *UserRepo) Create(ctx context.Context, user *domain.User) error {
+	if existing, _ := f.GetByEmail(ctx, user.Email); existing != nil {
+		return domain.ErrUserExists
+	}
+	user.CreatedAt = time.Now()
+	f.Users[user.ID] = user
+	return nil
+}
+
+func (f *UserRepo) List(_ context.Context, query string, limit, offset int) ([]*domain.UserOverview, error) {
+	var out []*domain.UserOverview
+	for _, u := range f.Users {
+		if strings.Contains(strings.ToLower(u.Email), strings.ToLower(query)) {
+			out = append(out, &domain.UserOverview{User: *u, Chats: f.Chats[u.ID]})
+		}
+	}
+	slices.SortFunc(out, func(a, b *domain.UserOverview) int { return b.CreatedAt.Compare(a.CreatedAt) })
+
+	out = out[min(offset, len(out)):]
+	return out[:min(limit, len(out))], nil
+}
+
+func (f *UserRepo) GetOverview(_ context.Context, userID uuid.UUID) (*domain.UserOverview, error) {
+	u, ok := f.Users[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &domain.UserOverview{User: *u, Chats: f.Chats[userID]}, nil
+}
+
+func (f *UserRepo) SetActive(_ context.Context, userID uuid.UUID, active bool) error {
+	u, ok := f.Users[userID]
+	if !ok {
+		return domain.ErrUserNotFound
+	}
+	u.IsActive = active
+	return nil
+}
+
+func (f *UserRepo) UpdatePassword(_ context.Context, userID uuid.UUID, hash string, temporary bool) error {
+	u, ok := f.Users[userID]
+	if !ok {
+		return domain.ErrUserNotFound
+	}
+	u.PasswordHash, u.MustChangePassword = hash, temporary
+	return nil
+}
+
+func (f *UserRepo) RevokeTokens(_ context.Context, userID uuid.UUID) error {
+	if f.RevokeErr != nil {
+		return f.RevokeErr
+	}
+	u, ok := f.Users[userID]
+	if !ok {
+		return domain.ErrUserNotFound
+	}
+	u.TokenVersion++
+	return nil
+}
+
+// Snapshot откатывает изменения сохранённых пользователей
+func (f *UserRepo) Snapshot() func() {
+	saved := make(map[uuid.UUID]domain.User, len(f.Users))
+	for id, u := range f.Users {
+		saved[id] = *u
+	}
+	return func() {
+		for id, u := range saved {
+			*f.Users[id] = u
+		}
+	}
+}
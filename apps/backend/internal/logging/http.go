@@ -0,0 +1,80 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware кладёт в контекст логгер с request_id (и trace_id, если запрос трассируется)
+// и пишет одну строку на каждый обработанный запрос. Заменяет middleware.Logger из chi.
+// В строку попадает шаблон маршрута, а не путь: в пути бывают секреты, например токен в /shared/{token}
+func Middleware(base *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			logger := base.With(slog.String("request_id", middleware.GetReqID(r.Context())))
+			if sc := trace.SpanContextFromContext(r.Context()); sc.HasTraceID() {
+				logger = logger.With(slog.String("trace_id", sc.TraceID().String()))
+			}
+
+			info := &requestInfo{}
+			ctx := context.WithValue(WithLogger(r.Context(), logger), requestInfoKey{}, info)
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			route := ""
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				route = rctx.RoutePattern()
+			}
+
+			level := slog.LevelInfo
+			switch {
+			case status >= http.StatusInternalServerError:
+				level = slog.LevelError
+			case status >= http.StatusBadRequest:
+				level = slog.LevelWarn
+			}
+
+			if info.userID != "" {
+				logger = logger.With(slog.String("user_id", info.userID))
+			}
+
+			logger.LogAttrs(r.Context(), level, "http request",
+				slog.String("method", r.Method),
+				slog.String("route", route),
+				slog.Int("status", status),
+				slog.Int("bytes", ww.BytesWritten()),
+				slog.Duration("duration", time.Since(start)),
+			)
+		})
+	}
+}
+
+type requestInfoKey struct{}
+
+// requestInfo - данные, которые становятся известны внутри цепочки middleware,
+// но нужны в итоговой строке access-лога
+type requestInfo struct {
+	userID string
+}
+
+// WithUserID добавляет user_id в логгер запроса и в итоговую строку access-лога
+func WithUserID(ctx context.Context, userID string) context.Context {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		info.userID = userID
+	}
+	return With(ctx, slog.String("user_id", userID))
+}
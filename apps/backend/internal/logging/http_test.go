@@ -0,0 +1,41 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Токен из /shared/{token} открывает чат, поэтому в access-лог попадает только шаблон маршрута
+func TestMiddleware_LogsRoutePatternNotPath(t *testing.T) {
+	const token = "s3cr3t-share-token"
+
+	var buf bytes.Buffer
+	logger, err := New(&buf, Config{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	r := chi.NewRouter()
+	r.Use(Middleware(logger))
+	r.Get("/shared/{token}", func(w http.ResponseWriter, r *http.Request) {})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/shared/"+token, nil))
+
+	if strings.Contains(buf.String(), token) {
+		t.Fatalf("access log leaks share token:\n%s", buf.String())
+	}
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("log is not JSON: %v", err)
+	}
+	if entry["route"] != "/shared/{token}" {
+		t.Fatalf("route = %v, want /shared/{token}", entry["route"])
+	}
+}
@@ -0,0 +1,81 @@
+// Package logging настраивает структурированный JSON-логгер (log/slog) и
+// передаёт логгер с request_id/user_id через context.Context.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+)
+
+type Config struct {
+	// Level - debug, info, warn или error
+	Level string
+	// Format - json или text
+	Format string
+	// LogContent - писать в логи тексты сообщений, документов и поисковых запросов.
+	// По умолчанию вместо них пишется только длина
+	LogContent bool
+}
+
+// logContent - нулевое значение скрывает тексты и до вызова New
+var logContent atomic.Bool
+
+// New создаёт логгер по конфигу и делает его логгером по умолчанию (slog.Default, log.Printf)
+func New(w io.Writer, cfg Config) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil && cfg.Level != "" {
+		return nil, fmt.Errorf("invalid log level %q: %w", cfg.Level, err)
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "", "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", cfg.Format)
+	}
+
+	logContent.Store(cfg.LogContent)
+
+	logger := slog.New(handler)
+	slog.SetDefault(logger)
+
+	return logger, nil
+}
+
+type ctxKey struct{}
+
+// WithLogger кладёт логгер в контекст
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, logger)
+}
+
+// FromContext возвращает логгер запроса или slog.Default, если его нет
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// With добавляет атрибуты к логгеру из контекста и возвращает новый контекст
+func With(ctx context.Context, args ...any) context.Context {
+	return WithLogger(ctx, FromContext(ctx).With(args...))
+}
+
+// Content возвращает атрибут с пользовательским текстом.
+// Если Config.LogContent не включён, в лог попадает только длина текста
+func Content(key, value string) slog.Attr {
+	if !logContent.Load() {
+		return slog.String(key, fmt.Sprintf("[redacted %d chars]", len([]rune(value))))
+	}
+	return slog.String(key, value)
+}
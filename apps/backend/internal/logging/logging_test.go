@@ -0,0 +1,74 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestContent_Redaction(t *testing.T) {
+	tests := []struct {
+		name       string
+		logContent bool
+		value      string
+		want       string
+	}{
+		{name: "redacted by default", value: "паспорт 4510 123456", want: "[redacted 19 chars]"},
+		{name: "plain", logContent: true, value: "паспорт 4510 123456", want: "паспорт 4510 123456"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger, err := New(&buf, Config{Level: "info", Format: "json", LogContent: tt.logContent})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			logger.Info("msg", Content("content", tt.value))
+
+			var entry map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("log is not JSON: %v\n%s", err, buf.String())
+			}
+			if entry["content"] != tt.want {
+				t.Fatalf("content = %q, want %q", entry["content"], tt.want)
+			}
+		})
+	}
+}
+
+func TestWithUserID_AddsAttributeToContextLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, Config{})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	info := &requestInfo{}
+	ctx := context.WithValue(WithLogger(context.Background(), logger), requestInfoKey{}, info)
+	ctx = WithUserID(ctx, "user-1")
+
+	FromContext(ctx).Info("hello")
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("log is not JSON: %v", err)
+	}
+	if entry["user_id"] != "user-1" {
+		t.Fatalf("user_id = %v, want user-1", entry["user_id"])
+	}
+	if info.userID != "user-1" {
+		t.Fatalf("request info user id = %q, want user-1", info.userID)
+	}
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, Config{Level: "loud"}); err == nil {
+		t.Fatal("expected error for unknown level")
+	}
+	if _, err := New(&bytes.Buffer{}, Config{Format: "xml"}); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
@@ -0,0 +1,26 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var cacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "cache",
+	Name:      "requests_total",
+	Help:      "Cache lookups by cache name (search/page) and result (hit/miss/error).",
+}, []string{"cache", "result"})
+
+// ObserveCache фиксирует обращение к кэшу: попадание, промах или ошибку хранилища
+func ObserveCache(cache string, hit bool, err error) {
+	result := "miss"
+	switch {
+	case err != nil:
+		result = "error"
+	case hit:
+		result = "hit"
+	}
+
+	cacheRequests.WithLabelValues(cache, result).Inc()
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"backend/internal/domain"
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// jobsScrapeTimeout - сколько ждать подсчёта задач при scrape
+const jobsScrapeTimeout = 5 * time.Second
+
+// JobCounter считает незавершённые задачи очереди по статусам
+type JobCounter interface {
+	CountActive(ctx context.Context) (map[domain.JobStatus]int, error)
+}
+
+// jobsCollector считает ожидающие и выполняющиеся задачи в момент scrape: очередь живёт в базе
+// и общая для всех инстансов, поэтому счётчики в памяти процесса её не отражают
+type jobsCollector struct {
+	counter JobCounter
+	jobs    *prometheus.Desc
+}
+
+// RegisterJobQueue регистрирует метрики очереди фоновых задач
+func RegisterJobQueue(counter JobCounter) error {
+	return prometheus.Register(&jobsCollector{
+		counter: counter,
+		jobs: prometheus.NewDesc(prometheus.BuildFQName(namespace, "jobs", "queued"),
+			"Background jobs by status (pending/running).", []string{"status"}, nil),
+	})
+}
+
+func (c *jobsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.jobs
+}
+
+func (c *jobsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), jobsScrapeTimeout)
+	defer cancel()
+
+	counts, err := c.counter.CountActive(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(c.jobs, err)
+		return
+	}
+
+	for _, status := range []domain.JobStatus{domain.JobPending, domain.JobRunning} {
+		ch <- prometheus.MustNewConstMetric(c.jobs, prometheus.GaugeValue, float64(counts[status]), string(status))
+	}
+}
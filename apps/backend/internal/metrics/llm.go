@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	llmRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "requests_total",
+		Help:      "LLM generation requests by model and result (ok/error).",
+	}, []string{"model", "result"})
+
+	llmDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "generation_duration_seconds",
+		Help:      "LLM generation latency by model.",
+		Buckets:   []float64{0.5, 1, 2, 5, 10, 20, 30, 45, 60, 90, 120},
+	}, []string{"model"})
+
+	llmTokens = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "tokens_total",
+		Help:      "Tokens processed by model and type (prompt/completion).",
+	}, []string{"model", "type"})
+
+	llmInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "requests_in_flight",
+		Help:      "LLM generations currently running.",
+	}, []string{"model"})
+
+	llmSlotsLimit = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "slots_limit",
+		Help:      "Size of the shared LLM concurrency limit, 0 if unlimited.",
+	})
+
+	llmSlotsInUse = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "slots_in_use",
+		Help:      "Shared LLM concurrency slots currently taken.",
+	})
+
+	llmSlotsWaiting = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "slots_waiting",
+		Help:      "Callers waiting for a shared LLM concurrency slot.",
+	})
+
+	webSearchRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "web_search",
+		Name:      "requests_total",
+		Help:      "Web search requests by provider and result (ok/empty/error).",
+	}, []string{"provider", "result"})
+)
+
+// ObserveLLMGeneration фиксирует результат одного вызова модели
+func ObserveLLMGeneration(model string, duration time.Duration, promptTokens, completionTokens int, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+
+	llmRequests.WithLabelValues(model, result).Inc()
+	llmDuration.WithLabelValues(model).Observe(duration.Seconds())
+
+	if err == nil {
+		llmTokens.WithLabelValues(model, "prompt").Add(float64(promptTokens))
+		llmTokens.WithLabelValues(model, "completion").Add(float64(completionTokens))
+	}
+}
+
+// LLMStarted отмечает начало генерации; возвращаемая функция вызывается по её завершении
+func LLMStarted(model string) func() {
+	llmInFlight.WithLabelValues(model).Inc()
+
+	return func() {
+		llmInFlight.WithLabelValues(model).Dec()
+	}
+}
+
+// SetLLMSlots запоминает размер общего лимита генераций
+func SetLLMSlots(n int) {
+	llmSlotsLimit.Set(float64(n))
+}
+
+// LLMSlotWaiting отмечает вызов, ждущий слот общего лимита; возвращаемая функция вызывается, когда ожидание закончилось
+func LLMSlotWaiting() func() {
+	llmSlotsWaiting.Inc()
+
+	return func() {
+		llmSlotsWaiting.Dec()
+	}
+}
+
+// LLMSlotAcquired отмечает занятый слот общего лимита; возвращаемая функция вызывается при его освобождении
+func LLMSlotAcquired() func() {
+	llmSlotsInUse.Inc()
+
+	return func() {
+		llmSlotsInUse.Dec()
+	}
+}
+
+// ObserveWebSearch фиксирует результат запроса к поисковому провайдеру
+func ObserveWebSearch(provider string, results int, err error) {
+	result := "ok"
+	switch {
+	case err != nil:
+		result = "error"
+	case results == 0:
+		result = "empty"
+	}
+
+	webSearchRequests.WithLabelValues(provider, result).Inc()
+}
@@ -0,0 +1,16 @@
+// Package metrics содержит Prometheus-метрики сервиса и хелперы для их обновления.
+// Все метрики регистрируются в prometheus.DefaultRegisterer и отдаются через Handler.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "backend"
+
+// Handler отдаёт метрики в формате Prometheus для эндпоинта /metrics
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
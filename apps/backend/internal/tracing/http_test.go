@@ -0,0 +1,197 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// setupRecorder подменяет глобальный провайдер на записывающий спаны в память
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+		_ = provider.Shutdown(t.Context())
+	})
+
+	return recorder
+}
+
+func spanAttr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestHTTPMiddleware_ServerSpan(t *testing.T) {
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+
+	tests := []struct {
+		name        string
+		traceparent string
+		status      int
+		wantError   bool
+	}{
+		{name: "new trace", status: http.StatusOK},
+		{name: "continues incoming trace", traceparent: "00-" + traceID + "-" + spanID + "-01", status: http.StatusOK},
+		{name: "server error", status: http.StatusBadGateway, wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := setupRecorder(t)
+
+			var handlerSpan trace.SpanContext
+			r := chi.NewRouter()
+			r.Use(HTTPMiddleware)
+			r.Get("/chats/{chatID}", func(w http.ResponseWriter, r *http.Request) {
+				handlerSpan = trace.SpanContextFromContext(r.Context())
+				w.WriteHeader(tt.status)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/chats/42", nil)
+			if tt.traceparent != "" {
+				req.Header.Set("traceparent", tt.traceparent)
+			}
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("spans = %d, want 1", len(spans))
+			}
+			span := spans[0]
+
+			if span.Name() != "GET /chats/{chatID}" {
+				t.Fatalf("span name = %q", span.Name())
+			}
+			if span.SpanKind() != trace.SpanKindServer {
+				t.Fatalf("span kind = %v, want server", span.SpanKind())
+			}
+			if got := spanAttr(span, "http.route").AsString(); got != "/chats/{chatID}" {
+				t.Fatalf("http.route = %q", got)
+			}
+			if got := spanAttr(span, "http.response.status_code").AsInt64(); got != int64(tt.status) {
+				t.Fatalf("http.response.status_code = %d, want %d", got, tt.status)
+			}
+			if gotError := span.Status().Code == codes.Error; gotError != tt.wantError {
+				t.Fatalf("span status = %v, want error %v", span.Status().Code, tt.wantError)
+			}
+
+			// Обработчик получает контекст с серверным спаном
+			if handlerSpan.SpanID() != span.SpanContext().SpanID() {
+				t.Fatalf("handler span = %s, want %s", handlerSpan.SpanID(), span.SpanContext().SpanID())
+			}
+
+			if tt.traceparent != "" {
+				if got := span.SpanContext().TraceID().String(); got != traceID {
+					t.Fatalf("trace id = %s, want %s from traceparent", got, traceID)
+				}
+				if got := span.Parent().SpanID().String(); got != spanID {
+					t.Fatalf("parent span id = %s, want %s", got, spanID)
+				}
+			} else if span.Parent().IsValid() {
+				t.Fatalf("span without traceparent has parent %s", span.Parent().SpanID())
+			}
+		})
+	}
+}
+
+// Токен из /shared/{token} открывает чат, поэтому в спан попадает только шаблон маршрута
+func TestHTTPMiddleware_SpanHasNoSharedToken(t *testing.T) {
+	const token = "s3cr3t-share-token"
+	recorder := setupRecorder(t)
+
+	r := chi.NewRouter()
+	r.Use(HTTPMiddleware)
+	r.Get("/shared/{token}", func(w http.ResponseWriter, r *http.Request) {})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/shared/"+token, nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("spans = %d, want 1", len(spans))
+	}
+	if strings.Contains(spans[0].Name(), token) {
+		t.Fatalf("span name leaks share token: %q", spans[0].Name())
+	}
+	for _, kv := range spans[0].Attributes() {
+		if strings.Contains(kv.Value.Emit(), token) {
+			t.Fatalf("span attribute %s leaks share token: %q", kv.Key, kv.Value.Emit())
+		}
+	}
+}
+
+// Исходящий запрос из обработчика продолжает трейс входящего и передаёт traceparent дальше
+func TestTransport_PropagatesServerSpan(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	var downstreamHeader string
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downstreamHeader = r.Header.Get("traceparent")
+	}))
+	defer downstream.Close()
+
+	client := &http.Client{Transport: Transport(nil)}
+
+	r := chi.NewRouter()
+	r.Use(HTTPMiddleware)
+	r.Get("/reply", func(w http.ResponseWriter, r *http.Request) {
+		req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, downstream.URL+"/api/generate", nil)
+		if err != nil {
+			t.Fatalf("NewRequest() error = %v", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+		resp.Body.Close()
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/reply", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("spans = %d, want 2", len(spans))
+	}
+	// Клиентский спан заканчивается раньше серверного
+	clientSpan, serverSpan := spans[0], spans[1]
+
+	if clientSpan.SpanKind() != trace.SpanKindClient || serverSpan.SpanKind() != trace.SpanKindServer {
+		t.Fatalf("span kinds = %v, %v", clientSpan.SpanKind(), serverSpan.SpanKind())
+	}
+	if clientSpan.Parent().SpanID() != serverSpan.SpanContext().SpanID() {
+		t.Fatalf("client span parent = %s, want server span %s", clientSpan.Parent().SpanID(), serverSpan.SpanContext().SpanID())
+	}
+	if clientSpan.SpanContext().TraceID() != serverSpan.SpanContext().TraceID() {
+		t.Fatalf("client and server spans are in different traces")
+	}
+
+	want := "00-" + clientSpan.SpanContext().TraceID().String() + "-" + clientSpan.SpanContext().SpanID().String() + "-01"
+	if downstreamHeader != want {
+		t.Fatalf("downstream traceparent = %q, want %q", downstreamHeader, want)
+	}
+}
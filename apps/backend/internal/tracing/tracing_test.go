@@ -0,0 +1,50 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+)
+
+func TestSetup_SampleRatio(t *testing.T) {
+	tests := []struct {
+		name        string
+		ratio       float64
+		wantErr     bool
+		wantSampled bool
+	}{
+		{name: "zero samples nothing", ratio: 0},
+		{name: "one samples everything", ratio: 1, wantSampled: true},
+		{name: "negative is rejected", ratio: -0.1, wantErr: true},
+		{name: "above one is rejected", ratio: 1.5, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+			t.Cleanup(func() {
+				otel.SetTracerProvider(prevProvider)
+				otel.SetTextMapPropagator(prevPropagator)
+			})
+
+			shutdown, err := Setup(context.Background(), Config{ServiceName: "test", Exporter: ExporterStdout, SampleRatio: tt.ratio})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Setup() error = nil, want error for ratio %v", tt.ratio)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Setup() error = %v", err)
+			}
+			t.Cleanup(func() { _ = shutdown(context.Background()) })
+
+			_, span := otel.Tracer("test").Start(context.Background(), "request")
+			span.End()
+			if got := span.SpanContext().IsSampled(); got != tt.wantSampled {
+				t.Fatalf("sampled = %v, want %v", got, tt.wantSampled)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package dto
+
+import "time"
+
+type AdminUserResponse struct {
+	ID                 string     `json:"id"`
+	Email              string     `json:"email"`
+	Role               string     `json:"role"`
+	IsActive           bool       `json:"is_active"`
+	MustChangePassword bool       `json:"must_change_password"`
+	ChatCount          int        `json:"chat_count"`
+	CreatedAt          time.Time  `json:"created_at"`
+	LastLoginAt        *time.Time `json:"last_login_at,omitempty"`
+}
+
+type AdminUsersListResponse struct {
+	Users []AdminUserResponse `json:"users"`
+	// NextOffset - смещение следующей страницы; отсутствует на последней
+	NextOffset *int `json:"next_offset,omitempty"`
+}
+
+type AdminUserDetailResponse struct {
+	AdminUserResponse
+	Usage UsageResponse `json:"usage"`
+}
+
+type CreateUserRequest struct {
+	Email string `json:"email"`
+	// Role - user (по умолчанию) или admin
+	Role string `json:"role,omitempty"`
+}
+
+// CreateUserResponse - временный пароль показывается только в этом ответе
+type CreateUserResponse struct {
+	User              AdminUserResponse `json:"user"`
+	TemporaryPassword string            `json:"temporary_password"`
+}
+
+type UpdateUserStatusRequest struct {
+	IsActive *bool `json:"is_active"`
+}
+
+type PasswordResetResponse struct {
+	TemporaryPassword string `json:"temporary_password"`
+}
@@ -6,9 +6,22 @@ type HealthResponse struct {
 	Status string `json:"status"`
 }
 
+type DependencyStatus struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+	Detail    string `json:"detail,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+type ReadinessResponse struct {
+	Status string                      `json:"status"`
+	Checks map[string]DependencyStatus `json:"checks"`
+}
+
 type ChatResponse struct {
 	ID        string    `json:"id"`
 	Title     string    `json:"title"`
+	OrgID     *string   `json:"org_id,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -20,30 +33,58 @@ type ChatsListResponse struct {
 type CreateChatRequest struct {
 	Title        string  `json:"title"`
 	ScenarioCode *string `json:"scenario_code,omitempty"`
+	// OrgID - создать чат в организации; без него чат личный
+	OrgID *string `json:"org_id,omitempty"`
 }
 
 type CreateChatResponse struct {
-	ID    string `json:"id"`
-	Title string `json:"title"`
+	ID    string  `json:"id"`
+	Title string  `json:"title"`
+	OrgID *string `json:"org_id,omitempty"`
 }
 
 type MessageResponse struct {
 	ID        string    `json:"id"`
+	ParentID  *string   `json:"parent_id,omitempty"`
 	Role      string    `json:"role"`
 	Content   string    `json:"content"`
 	CreatedAt time.Time `json:"created_at"`
+	// Status - для вопросов пользователя: pending, complete или failed (ответ можно перегенерировать)
+	Status string `json:"status,omitempty"`
+	// SiblingIDs - альтернативные версии сообщения (перегенерации, правки), включая его само
+	SiblingIDs []string `json:"sibling_ids,omitempty"`
 }
 
 type MessagesListResponse struct {
 	Messages []MessageResponse `json:"messages"`
+	// NextBefore - значение ?before= для более старой страницы; нет - история закончилась
+	NextBefore *string `json:"next_before,omitempty"`
 }
 
 type SendMessageRequest struct {
 	Content      string   `json:"content"`
 	DocumentIDs  []string `json:"document_ids,omitempty"`
 	ScenarioCode *string  `json:"scenario_code,omitempty"`
+	// ClientMessageID - ключ идемпотентности, если клиент не передаёт заголовок Idempotency-Key
+	ClientMessageID string `json:"client_message_id,omitempty"`
+}
+
+type EditMessageRequest struct {
+	Content string `json:"content"`
 }
 
 type SendMessageResponse struct {
 	Message MessageResponse `json:"message"`
 }
+
+type ImportedChatResponse struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Messages int    `json:"messages"`
+	// Skipped - не перенесённые сообщения: системные, скрытые, пустые, неизвестных ролей
+	Skipped int `json:"skipped"`
+}
+
+type ImportChatsResponse struct {
+	Chats []ImportedChatResponse `json:"chats"`
+}
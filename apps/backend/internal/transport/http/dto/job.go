@@ -0,0 +1,25 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type JobResponse struct {
+	ID       string `json:"id"`
+	Kind     string `json:"kind"`
+	Status   string `json:"status"`
+	Attempts int    `json:"attempts"`
+	// Result - результат задачи (для reply - message_id и content), только для done
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// JobAcceptedResponse - ответ 202 на асинхронную отправку сообщения
+type JobAcceptedResponse struct {
+	JobID  string `json:"job_id"`
+	Status string `json:"status"`
+}
@@ -0,0 +1,56 @@
+package dto
+
+import "time"
+
+type CreateOrgRequest struct {
+	Name string `json:"name"`
+}
+
+type OrgResponse struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Role - роль текущего пользователя: owner, member или viewer
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type OrgsListResponse struct {
+	Orgs []OrgResponse `json:"orgs"`
+}
+
+type MemberResponse struct {
+	UserID   string    `json:"user_id"`
+	Email    string    `json:"email,omitempty"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+type MembersListResponse struct {
+	Members []MemberResponse `json:"members"`
+}
+
+type UpdateMemberRequest struct {
+	Role string `json:"role"`
+}
+
+type CreateInviteRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type InviteResponse struct {
+	ID         string     `json:"id"`
+	Email      string     `json:"email"`
+	Role       string     `json:"role"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type InvitesListResponse struct {
+	Invites []InviteResponse `json:"invites"`
+}
+
+type AcceptInviteRequest struct {
+	Token string `json:"token"`
+}
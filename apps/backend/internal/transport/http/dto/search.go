@@ -0,0 +1,20 @@
+package dto
+
+import "time"
+
+type SearchHitResponse struct {
+	ChatID    string  `json:"chat_id"`
+	ChatTitle string  `json:"chat_title"`
+	MessageID *string `json:"message_id,omitempty"` // нет - совпало название чата
+	Role      string  `json:"role,omitempty"`
+	// Snippet - экранированный HTML, совпадения обёрнуты в <mark>
+	Snippet   string    `json:"snippet"`
+	Rank      float64   `json:"rank"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SearchResponse struct {
+	Results []SearchHitResponse `json:"results"`
+	// NextOffset - offset следующей страницы; нет - результаты закончились
+	NextOffset *int `json:"next_offset,omitempty"`
+}
@@ -0,0 +1,41 @@
+package dto
+
+import "time"
+
+type CreateShareRequest struct {
+	// ExpiresInHours - срок действия ссылки; 0 или пусто - бессрочная
+	ExpiresInHours int  `json:"expires_in_hours,omitempty"`
+	MaskPII        bool `json:"mask_pii"`
+}
+
+type ShareResponse struct {
+	ID        string     `json:"id"`
+	MessageID *string    `json:"message_id,omitempty"`
+	MaskPII   bool       `json:"mask_pii"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// CreateShareResponse - токен возвращается только при создании: сервер хранит лишь его хеш
+type CreateShareResponse struct {
+	ShareResponse
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+type SharesListResponse struct {
+	Shares []ShareResponse `json:"shares"`
+}
+
+type SharedMessageResponse struct {
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SharedChatResponse struct {
+	Title    string                  `json:"title"`
+	SharedAt time.Time               `json:"shared_at"`
+	Messages []SharedMessageResponse `json:"messages"`
+}
@@ -0,0 +1,49 @@
+package dto
+
+import "time"
+
+type UsageCounters struct {
+	Requests         int64 `json:"requests"`
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+	DocumentBytes    int64 `json:"document_bytes"`
+}
+
+// UsageLimits - лимиты периода, 0 означает отсутствие лимита
+type UsageLimits struct {
+	Tokens        int64 `json:"tokens"`
+	Requests      int64 `json:"requests"`
+	DocumentBytes int64 `json:"document_bytes"`
+}
+
+type UsagePeriod struct {
+	From   time.Time     `json:"from"`
+	Used   UsageCounters `json:"used"`
+	Limits UsageLimits   `json:"limits"`
+}
+
+type UsageResponse struct {
+	Daily   UsagePeriod `json:"daily"`
+	Monthly UsagePeriod `json:"monthly"`
+}
+
+type QuotaRequest struct {
+	DailyTokens          int64 `json:"daily_tokens"`
+	MonthlyTokens        int64 `json:"monthly_tokens"`
+	DailyRequests        int64 `json:"daily_requests"`
+	MonthlyRequests      int64 `json:"monthly_requests"`
+	DailyDocumentBytes   int64 `json:"daily_document_bytes"`
+	MonthlyDocumentBytes int64 `json:"monthly_document_bytes"`
+}
+
+type QuotaResponse struct {
+	UserID               string    `json:"user_id"`
+	DailyTokens          int64     `json:"daily_tokens"`
+	MonthlyTokens        int64     `json:"monthly_tokens"`
+	DailyRequests        int64     `json:"daily_requests"`
+	MonthlyRequests      int64     `json:"monthly_requests"`
+	DailyDocumentBytes   int64     `json:"daily_document_bytes"`
+	MonthlyDocumentBytes int64     `json:"monthly_document_bytes"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
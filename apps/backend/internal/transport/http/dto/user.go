@@ -10,6 +10,13 @@ type LoginResponse struct {
 	User  struct {
 		ID    string `json:"id"`
 		Email string `json:"email"`
+		Role  string `json:"role"`
+		// MustChangePassword - вход по временному паролю, клиент должен предложить сменить его
+		MustChangePassword bool `json:"must_change_password"`
 	} `json:"user"`
 }
 
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
@@ -2,10 +2,14 @@ package handlers
 
 import (
 	"backend/internal/domain"
+	"backend/internal/logging"
 	"backend/internal/transport/http/dto"
+	"backend/internal/usecase/auth"
+	"backend/internal/usecase/authz"
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
@@ -17,16 +21,25 @@ import (
 // ContextKey - тип для ключей контекста
 type ContextKey string
 
-const UserIDKey ContextKey = "user_id"
+const (
+	UserIDKey ContextKey = "user_id"
+	// UserRoleKey - роль пользователя из БД на момент запроса (domain.UserRole); без проверки токенов отсутствует
+	UserRoleKey ContextKey = "user_role"
+	// mustChangePasswordKey - пользователь вошёл по временному паролю; без проверки токенов отсутствует
+	mustChangePasswordKey ContextKey = "must_change_password"
+)
 
 // AuthHandler - handler для аутентификации
 type AuthHandler struct {
 	userRepo domain.UserRepo
+	// tokens - выпуск подписанных токенов; nil - режим разработки с токеном-заглушкой
+	tokens *auth.Tokens
 }
 
-func NewAuthHandler(userRepo domain.UserRepo) *AuthHandler {
+func NewAuthHandler(userRepo domain.UserRepo, tokens *auth.Tokens) *AuthHandler {
 	return &AuthHandler{
 		userRepo: userRepo,
+		tokens:   tokens,
 	}
 }
 
@@ -38,7 +51,8 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	req.Email = strings.TrimSpace(req.Email)
+	// Адреса хранятся в нижнем регистре, как их сохраняет users.Service.Create
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
 	if req.Email == "" {
 		http.Error(w, "email is required", http.StatusBadRequest)
 		return
@@ -52,6 +66,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	// Получаем пользователя по email
 	user, err := h.userRepo.GetByEmail(r.Context(), req.Email)
 	if err != nil {
+		logging.FromContext(r.Context()).ErrorContext(r.Context(), "failed to get user by email", slog.Any("error", err))
 		http.Error(w, "failed to authenticate", http.StatusInternalServerError)
 		return
 	}
@@ -75,25 +90,36 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	// Обновляем время последнего входа
 	if err := h.userRepo.UpdateLastLogin(r.Context(), user.ID); err != nil {
 		// Логируем ошибку, но не прерываем процесс аутентификации
-		_ = err
+		logging.FromContext(r.Context()).ErrorContext(r.Context(), "failed to update last login",
+			slog.String("user_id", user.ID.String()),
+			slog.Any("error", err),
+		)
 	}
 
-	// Генерируем простой токен (в реальности здесь должен быть JWT)
-	// TODO: заменить на JWT токен
+	// Без ключа подписи выдаём токен-заглушку: AuthMiddleware его всё равно не проверяет
 	token := generateSimpleToken(user.ID)
+	if h.tokens != nil {
+		token, err = h.tokens.Issue(user)
+		if err != nil {
+			logging.FromContext(r.Context()).ErrorContext(r.Context(), "failed to issue token", slog.Any("error", err))
+			http.Error(w, "failed to authenticate", http.StatusInternalServerError)
+			return
+		}
+	}
 
 	response := dto.LoginResponse{
 		Token: token,
 	}
 	response.User.ID = user.ID.String()
 	response.User.Email = user.Email
+	response.User.Role = string(user.Role)
+	response.User.MustChangePassword = user.MustChangePassword
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-// generateSimpleToken генерирует простой токен для аутентификации
-// TODO: заменить на JWT токен
+// generateSimpleToken генерирует неподписанный токен для режима разработки
 func generateSimpleToken(userID uuid.UUID) string {
 	tokenData := map[string]interface{}{
 		"user_id": userID.String(),
@@ -104,20 +130,113 @@ func generateSimpleToken(userID uuid.UUID) string {
 	return base64.URLEncoding.EncodeToString(tokenJSON)
 }
 
-// AuthMiddleware - middleware для аутентификации
-// TODO: реализовать проверку JWT токена
-func AuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// TODO: извлечь токен из заголовка Authorization
-		// TODO: проверить токен и извлечь userID
-		// Пока используем заглушку
-		userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+// devUserID - пользователь, от имени которого выполняются запросы, когда токены не проверяются
+var devUserID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+// AuthMiddleware проверяет токен из заголовка Authorization: Bearer. Токен принимается, если верны
+// подпись и срок действия, пользователь активен и версия токена не отозвана. В контекст кладётся
+// текущая роль пользователя из БД, а не роль из токена: понижение роли действует сразу.
+// Без tokens (AUTH_SECRET не задан) токены не проверяются и все запросы выполняются
+// от имени devUserID - режим локальной разработки
+func AuthMiddleware(tokens *auth.Tokens, userRepo domain.UserRepo) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tokens == nil {
+				next.ServeHTTP(w, r.WithContext(withUserID(r.Context(), devUserID)))
+				return
+			}
+
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" {
+				writeUnauthorized(w)
+				return
+			}
+			claims, err := tokens.Parse(strings.TrimSpace(token))
+			if err != nil {
+				writeUnauthorized(w)
+				return
+			}
+
+			user, err := userRepo.GetByID(r.Context(), claims.UserID)
+			if err != nil {
+				logging.FromContext(r.Context()).ErrorContext(r.Context(), "failed to get user by id", slog.Any("error", err))
+				http.Error(w, "failed to authenticate", http.StatusInternalServerError)
+				return
+			}
+			// Отключённый пользователь и отозванные токены (принудительный выход, сброс пароля)
+			if user == nil || !user.IsActive || user.TokenVersion != claims.Version {
+				writeUnauthorized(w)
+				return
+			}
+
+			ctx := withUserID(r.Context(), user.ID)
+			ctx = context.WithValue(ctx, UserRoleKey, user.Role)
+			ctx = context.WithValue(ctx, mustChangePasswordKey, user.MustChangePassword)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
 
-		ctx := context.WithValue(r.Context(), UserIDKey, userID)
-		next.ServeHTTP(w, r.WithContext(ctx))
+// PasswordChangeMiddleware не пускает пользователя с временным паролем никуда, кроме смены пароля:
+// маршрут PUT /me/password регистрируется без этого middleware. Должен стоять после AuthMiddleware
+func PasswordChangeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if must, _ := r.Context().Value(mustChangePasswordKey).(bool); must {
+			http.Error(w, "password change required", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
 	})
 }
 
+// AdminMiddleware пропускает пользователей, которым политика authz.AdminAccess разрешает
+// административные действия. Роль берётся из контекста AuthMiddleware; без проверки токенов - из БД.
+// Должен стоять после AuthMiddleware
+func AdminMiddleware(userRepo domain.UserRepo, az *authz.Service) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, err := getUserIDFromContext(r.Context())
+			if err != nil {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			subject := authz.User(userID)
+			if role, ok := r.Context().Value(UserRoleKey).(domain.UserRole); ok {
+				subject.Role = role
+			} else {
+				user, err := userRepo.GetByID(r.Context(), userID)
+				if err != nil {
+					http.Error(w, "failed to authorize", http.StatusInternalServerError)
+					return
+				}
+				if user == nil || !user.IsActive {
+					http.Error(w, "access denied", http.StatusForbidden)
+					return
+				}
+				subject.Role = user.Role
+			}
+
+			if err := az.Can(r.Context(), subject, authz.AdminAccess, authz.System); err != nil {
+				writeAccessError(w, err)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func withUserID(ctx context.Context, userID uuid.UUID) context.Context {
+	ctx = context.WithValue(ctx, UserIDKey, userID)
+	return logging.WithUserID(ctx, userID.String())
+}
+
+func writeUnauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	http.Error(w, "unauthorized", http.StatusUnauthorized)
+}
+
 // getUserIDFromContext извлекает userID из контекста
 func getUserIDFromContext(ctx context.Context) (uuid.UUID, error) {
 	userID, ok := ctx.Value(UserIDKey).(uuid.UUID)
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"backend/internal/domain"
+	"backend/internal/fakes"
+	"backend/internal/usecase/auth"
+	"backend/internal/usecase/authz"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAuthHandler_LoginNormalizesEmail(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("секретный-пароль"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword() error = %v", err)
+	}
+	user := &domain.User{ID: uuid.New(), Email: "anna@example.com", PasswordHash: string(hash), IsActive: true, Role: domain.UserRoleUser}
+	h := NewAuthHandler(fakes.NewUserRepo(user), nil)
+
+	body := `{"email":"  Anna@Example.COM ","password":"секретный-пароль"}`
+	rec := httptest.NewRecorder()
+	h.Login(rec, httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+}
+
+func TestAuthMiddleware_Tokens(t *testing.T) {
+	tokens, err := auth.NewTokens([]byte("0123456789abcdef0123456789abcdef"), time.Hour)
+	if err != nil {
+		t.Fatalf("NewTokens() error = %v", err)
+	}
+	user := &domain.User{ID: uuid.New(), Role: domain.UserRoleAdmin}
+	repo := fakes.NewUserRepo(user)
+
+	token, err := tokens.Issue(user)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+
+	var gotID uuid.UUID
+	var gotRole domain.UserRole
+	handler := AuthMiddleware(tokens, repo)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotID, _ = getUserIDFromContext(r.Context())
+		gotRole, _ = r.Context().Value(UserRoleKey).(domain.UserRole)
+	}))
+
+	tests := []struct {
+		name     string
+		header   string
+		inactive bool
+		version  int
+		want     int
+	}{
+		{name: "valid token", header: "Bearer " + token, want: http.StatusOK},
+		{name: "no header", want: http.StatusUnauthorized},
+		{name: "not bearer", header: "Basic " + token, want: http.StatusUnauthorized},
+		{name: "garbage", header: "Bearer abc", want: http.StatusUnauthorized},
+		{name: "inactive user", header: "Bearer " + token, inactive: true, want: http.StatusUnauthorized},
+		{name: "revoked token", header: "Bearer " + token, version: 1, want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user.IsActive, user.TokenVersion = !tt.inactive, tt.version
+			gotID, gotRole = uuid.Nil, ""
+
+			req := httptest.NewRequest(http.MethodGet, "/chats", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want != http.StatusOK {
+				if rec.Header().Get("WWW-Authenticate") != "Bearer" {
+					t.Fatalf("WWW-Authenticate = %q, want Bearer", rec.Header().Get("WWW-Authenticate"))
+				}
+				return
+			}
+			if gotID != user.ID || gotRole != domain.UserRoleAdmin {
+				t.Fatalf("context user = %s, role = %q", gotID, gotRole)
+			}
+		})
+	}
+}
+
+func TestAdminMiddleware_DemotedAdmin(t *testing.T) {
+	tokens, err := auth.NewTokens([]byte("0123456789abcdef0123456789abcdef"), time.Hour)
+	if err != nil {
+		t.Fatalf("NewTokens() error = %v", err)
+	}
+	az, err := authz.NewService(struct{ domain.OrgRepo }{})
+	if err != nil {
+		t.Fatalf("authz.NewService() error = %v", err)
+	}
+	user := &domain.User{ID: uuid.New(), Role: domain.UserRoleAdmin, IsActive: true}
+	repo := fakes.NewUserRepo(user)
+
+	// Токен выпущен, пока пользователь был администратором
+	token, err := tokens.Issue(user)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	handler := AuthMiddleware(tokens, repo)(AdminMiddleware(repo, az)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})))
+
+	for _, tt := range []struct {
+		role domain.UserRole
+		want int
+	}{
+		{role: domain.UserRoleAdmin, want: http.StatusOK},
+		{role: domain.UserRoleUser, want: http.StatusForbidden},
+	} {
+		user.Role = tt.role
+		req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Fatalf("role %q: status = %d, want %d", tt.role, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestPasswordChangeMiddleware(t *testing.T) {
+	tokens, err := auth.NewTokens([]byte("0123456789abcdef0123456789abcdef"), time.Hour)
+	if err != nil {
+		t.Fatalf("NewTokens() error = %v", err)
+	}
+	user := &domain.User{ID: uuid.New(), Role: domain.UserRoleUser, IsActive: true}
+	repo := fakes.NewUserRepo(user)
+	token, err := tokens.Issue(user)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	handler := AuthMiddleware(tokens, repo)(PasswordChangeMiddleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})))
+
+	for _, tt := range []struct {
+		mustChange bool
+		want       int
+	}{
+		{mustChange: true, want: http.StatusForbidden},
+		{mustChange: false, want: http.StatusOK},
+	} {
+		user.MustChangePassword = tt.mustChange
+		req := httptest.NewRequest(http.MethodGet, "/chats", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Fatalf("must change password = %v: status = %d, want %d", tt.mustChange, rec.Code, tt.want)
+		}
+	}
+}
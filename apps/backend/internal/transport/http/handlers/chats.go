@@ -3,23 +3,28 @@ package handlers
 import (
 	"backend/internal/domain"
 	"backend/internal/transport/http/dto"
+	"backend/internal/usecase/authz"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/google/uuid"
 )
 
 type ChatsHandler struct {
 	chatRepo domain.ChatRepo
+	authz    *authz.Service
 }
 
-func NewChatsHandler(chatRepo domain.ChatRepo) *ChatsHandler {
+func NewChatsHandler(chatRepo domain.ChatRepo, az *authz.Service) *ChatsHandler {
 	return &ChatsHandler{
 		chatRepo: chatRepo,
+		authz:    az,
 	}
 }
 
-// GetChats возвращает список чатов текущего пользователя
+// GetChats возвращает личные чаты текущего пользователя или, с параметром org_id, чаты организации
 func (h *ChatsHandler) GetChats(w http.ResponseWriter, r *http.Request) {
 	userID, err := getUserIDFromContext(r.Context())
 	if err != nil {
@@ -27,7 +32,21 @@ func (h *ChatsHandler) GetChats(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	chats, err := h.chatRepo.ListByUser(r.Context(), userID)
+	var chats []*domain.Chat
+	if orgIDStr := r.URL.Query().Get("org_id"); orgIDStr != "" {
+		orgID, err := uuid.Parse(orgIDStr)
+		if err != nil {
+			http.Error(w, "invalid org_id", http.StatusBadRequest)
+			return
+		}
+		if err := h.authz.Can(r.Context(), authz.User(userID), authz.OrgRead, authz.Org(orgID)); err != nil {
+			writeOrgError(w, err)
+			return
+		}
+		chats, err = h.chatRepo.ListByOrg(r.Context(), orgID)
+	} else {
+		chats, err = h.chatRepo.ListByUser(r.Context(), userID)
+	}
 	if err != nil {
 		http.Error(w, "failed to get chats", http.StatusInternalServerError)
 		return
@@ -41,6 +60,7 @@ func (h *ChatsHandler) GetChats(w http.ResponseWriter, r *http.Request) {
 		response.Chats[i] = dto.ChatResponse{
 			ID:        chat.ID.String(),
 			Title:     chat.Title,
+			OrgID:     optionalID(chat.OrgID),
 			CreatedAt: chat.CreatedAt,
 			UpdatedAt: chat.UpdatedAt,
 		}
@@ -64,9 +84,9 @@ func (h *ChatsHandler) CreateChat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Title == "" {
-		http.Error(w, "title is required", http.StatusBadRequest)
-		return
+	// Без названия чат получает название по умолчанию, которое позже заменит сгенерированное
+	if strings.TrimSpace(req.Title) == "" {
+		req.Title = domain.DefaultChatTitle
 	}
 
 	chat := &domain.Chat{
@@ -76,6 +96,21 @@ func (h *ChatsHandler) CreateChat(w http.ResponseWriter, r *http.Request) {
 		Status: domain.ChatActive,
 	}
 
+	if req.OrgID != nil {
+		orgID, err := uuid.Parse(*req.OrgID)
+		if err != nil {
+			http.Error(w, "invalid org_id", http.StatusBadRequest)
+			return
+		}
+		chat.OrgID = &orgID
+	}
+
+	// Личный чат может создать любой пользователь, чат в организации - участник с правом записи
+	if err := h.authz.Can(r.Context(), authz.User(userID), authz.ChatCreate, authz.Workspace(chat.OrgID)); err != nil {
+		writeOrgError(w, err)
+		return
+	}
+
 	if err := h.chatRepo.Create(r.Context(), chat); err != nil {
 		http.Error(w, "failed to create chat", http.StatusInternalServerError)
 		return
@@ -84,9 +119,39 @@ func (h *ChatsHandler) CreateChat(w http.ResponseWriter, r *http.Request) {
 	response := dto.CreateChatResponse{
 		ID:    chat.ID.String(),
 		Title: chat.Title,
+		OrgID: optionalID(chat.OrgID),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(response)
 }
+
+// writeOrgError отвечает на ошибку проверки прав в организации
+func writeOrgError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrOrgNotFound):
+		http.Error(w, "organization not found", http.StatusNotFound)
+	case errors.Is(err, domain.ErrAccessDenied):
+		http.Error(w, "access denied", http.StatusForbidden)
+	default:
+		http.Error(w, "failed to authorize", http.StatusInternalServerError)
+	}
+}
+
+// writeAccessError отвечает на ошибку проверки прав на чат или документ
+func writeAccessError(w http.ResponseWriter, err error) {
+	if errors.Is(err, domain.ErrAccessDenied) {
+		http.Error(w, "access denied", http.StatusForbidden)
+		return
+	}
+	http.Error(w, "failed to authorize", http.StatusInternalServerError)
+}
+
+func optionalID(id *uuid.UUID) *string {
+	if id == nil {
+		return nil
+	}
+	s := id.String()
+	return &s
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"backend/internal/domain"
+	"backend/internal/logging"
+	"backend/internal/usecase/export"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type ExportHandler struct {
+	exporter *export.Exporter
+}
+
+func NewExportHandler(exporter *export.Exporter) *ExportHandler {
+	return &ExportHandler{
+		exporter: exporter,
+	}
+}
+
+// ExportChat выгружает активную ветку чата файлом в формате md, html или json
+func (h *ExportHandler) ExportChat(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	chatID, err := uuid.Parse(chi.URLParam(r, "chat_id"))
+	if err != nil {
+		http.Error(w, "invalid chat_id", http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = export.FormatMarkdown
+	}
+	if !export.ValidFormat(format) {
+		http.Error(w, "format must be md, html or json", http.StatusBadRequest)
+		return
+	}
+
+	chat, err := h.exporter.GetChat(r.Context(), userID, chatID)
+	if errors.Is(err, domain.ErrAccessDenied) {
+		http.Error(w, "access denied", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, "chat not found", http.StatusNotFound)
+		return
+	}
+
+	// Длинный чат может выгружаться дольше WriteTimeout сервера
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", export.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="chat-%s.%s"`, chat.ID, format))
+
+	// Статус уже отправлен, поэтому ошибку посреди выгрузки можно только залогировать
+	if err := h.exporter.Write(r.Context(), w, chat, format); err != nil {
+		logging.FromContext(r.Context()).ErrorContext(r.Context(), "failed to export chat",
+			slog.String("chat_id", chat.ID.String()),
+			slog.Any("error", err),
+		)
+	}
+}
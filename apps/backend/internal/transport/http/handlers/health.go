@@ -1,22 +1,113 @@
 package handlers
 
 import (
+	"backend/internal/logging"
 	"backend/internal/transport/http/dto"
+	"context"
 	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
+	"sync"
+	"time"
 )
 
-type HealthHandler struct{}
+const (
+	healthStatusOK      = "ok"
+	healthStatusFail    = "fail"
+	healthStatusUnknown = "unknown"
 
-func NewHealthHandler() *HealthHandler {
-	return &HealthHandler{}
+	healthCheckTimeout = 3 * time.Second
+)
+
+// ErrHealthUnknown - состояние зависимости не удалось определить, но это не повод снимать сервис с трафика
+var ErrHealthUnknown = errors.New("health unknown")
+
+// HealthCheck - проверка одной зависимости для /health/ready.
+// Check возвращает короткое описание состояния (например версию схемы) или ошибку
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) (detail string, err error)
+}
+
+type HealthHandler struct {
+	checks []HealthCheck
+}
+
+func NewHealthHandler(checks ...HealthCheck) *HealthHandler {
+	return &HealthHandler{
+		checks: checks,
+	}
 }
 
+// Health - liveness: процесс жив и обслуживает HTTP. Зависимости не проверяются
 func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 	response := dto.HealthResponse{
-		Status: "ok",
+		Status: healthStatusOK,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Ready - readiness: параллельно проверяет все зависимости и возвращает 503, если хотя бы одна недоступна
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+	defer cancel()
+
+	response := dto.ReadinessResponse{
+		Status: healthStatusOK,
+		Checks: make(map[string]dto.DependencyStatus, len(h.checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			status := runHealthCheck(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			response.Checks[check.Name] = status
+			if status.Status == healthStatusFail {
+				response.Status = healthStatusFail
+			}
+		}()
+	}
+	wg.Wait()
+
+	code := http.StatusOK
+	if response.Status != healthStatusOK {
+		code = http.StatusServiceUnavailable
+		logging.FromContext(r.Context()).WarnContext(r.Context(), "readiness check failed", slog.Any("checks", response.Checks))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
 	json.NewEncoder(w).Encode(response)
 }
+
+func runHealthCheck(ctx context.Context, check HealthCheck) dto.DependencyStatus {
+	start := time.Now()
+	detail, err := check.Check(ctx)
+
+	status := dto.DependencyStatus{
+		Status:    healthStatusOK,
+		LatencyMs: time.Since(start).Milliseconds(),
+		Detail:    detail,
+	}
+
+	switch {
+	case errors.Is(err, ErrHealthUnknown):
+		status.Status = healthStatusUnknown
+		status.Error = err.Error()
+	case err != nil:
+		status.Status = healthStatusFail
+		status.Error = err.Error()
+	}
+
+	return status
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"backend/internal/transport/http/dto"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthHandler_Ready(t *testing.T) {
+	okCheck := HealthCheck{Name: "postgres", Check: func(context.Context) (string, error) { return "", nil }}
+	failCheck := HealthCheck{Name: "ollama", Check: func(context.Context) (string, error) {
+		return "model mistral", errors.New(`model "mistral" is not pulled`)
+	}}
+	unknownCheck := HealthCheck{Name: "migrations", Check: func(context.Context) (string, error) {
+		return "expected version 4", ErrHealthUnknown
+	}}
+
+	tests := []struct {
+		name       string
+		checks     []HealthCheck
+		wantCode   int
+		wantStatus string
+		wantChecks map[string]string
+	}{
+		{
+			name:       "no checks",
+			wantCode:   http.StatusOK,
+			wantStatus: "ok",
+			wantChecks: map[string]string{},
+		},
+		{
+			name:       "all ok",
+			checks:     []HealthCheck{okCheck},
+			wantCode:   http.StatusOK,
+			wantStatus: "ok",
+			wantChecks: map[string]string{"postgres": "ok"},
+		},
+		{
+			name:       "unknown does not fail readiness",
+			checks:     []HealthCheck{okCheck, unknownCheck},
+			wantCode:   http.StatusOK,
+			wantStatus: "ok",
+			wantChecks: map[string]string{"postgres": "ok", "migrations": "unknown"},
+		},
+		{
+			name:       "failed dependency",
+			checks:     []HealthCheck{okCheck, failCheck, unknownCheck},
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: "fail",
+			wantChecks: map[string]string{"postgres": "ok", "ollama": "fail", "migrations": "unknown"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHealthHandler(tt.checks...)
+			rec := httptest.NewRecorder()
+
+			h.Ready(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d", rec.Code, tt.wantCode)
+			}
+
+			var resp dto.ReadinessResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Status != tt.wantStatus {
+				t.Fatalf("status = %q, want %q", resp.Status, tt.wantStatus)
+			}
+			if len(resp.Checks) != len(tt.wantChecks) {
+				t.Fatalf("checks = %v, want %v", resp.Checks, tt.wantChecks)
+			}
+			for name, want := range tt.wantChecks {
+				if got := resp.Checks[name].Status; got != want {
+					t.Fatalf("check %q status = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
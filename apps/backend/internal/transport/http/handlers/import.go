@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"backend/internal/logging"
+	"backend/internal/transport/http/dto"
+	"backend/internal/usecase/importer"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+type ImportHandler struct {
+	importer *importer.Importer
+}
+
+func NewImportHandler(importer *importer.Importer) *ImportHandler {
+	return &ImportHandler{
+		importer: importer,
+	}
+}
+
+// ImportChats переносит чаты из JSON-выгрузки этого сервиса или conversations.json ChatGPT.
+// Файл передаётся телом запроса или полем file в multipart/form-data
+func (h *ImportHandler) ImportChats(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		// Запас сверх лимита файла - на заголовки частей формы
+		r.Body = http.MaxBytesReader(w, r.Body, h.importer.Limits().MaxBytes+1<<20)
+		file, _, err := r.FormFile("file")
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "import is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			http.Error(w, "file is required", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	results, err := h.importer.Import(r.Context(), userID, body)
+	switch {
+	case errors.Is(err, importer.ErrImportTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	case errors.Is(err, importer.ErrInvalidImport):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	case err != nil:
+		logging.FromContext(r.Context()).ErrorContext(r.Context(), "failed to import chats", slog.Any("error", err))
+		http.Error(w, "failed to import chats", http.StatusInternalServerError)
+		return
+	}
+
+	response := dto.ImportChatsResponse{
+		Chats: make([]dto.ImportedChatResponse, len(results)),
+	}
+	for i, result := range results {
+		response.Chats[i] = dto.ImportedChatResponse{
+			ID:       result.Chat.ID.String(),
+			Title:    result.Chat.Title,
+			Messages: result.Messages,
+			Skipped:  result.Skipped,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"backend/internal/domain"
+	"backend/internal/logging"
+	"backend/internal/transport/http/dto"
+	"backend/internal/usecase/jobs"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// jobEventsInterval - как часто SSE-поток проверяет состояние задачи
+const jobEventsInterval = 500 * time.Millisecond
+
+type JobsHandler struct {
+	queue *jobs.Queue
+}
+
+func NewJobsHandler(queue *jobs.Queue) *JobsHandler {
+	return &JobsHandler{
+		queue: queue,
+	}
+}
+
+// GetJob возвращает состояние фоновой задачи пользователя
+func (h *JobsHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.getJob(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toJobResponse(job))
+}
+
+// JobEvents - SSE-поток состояния задачи: событие при каждом изменении статуса,
+// поток закрывается, когда задача завершена
+func (h *JobsHandler) JobEvents(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.getJob(w, r)
+	if !ok {
+		return
+	}
+
+	// Поток живёт дольше WriteTimeout сервера, поэтому снимаем дедлайн записи
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	ticker := time.NewTicker(jobEventsInterval)
+	defer ticker.Stop()
+
+	var last domain.JobStatus
+	for {
+		if job.Status != last {
+			last = job.Status
+			data, _ := json.Marshal(toJobResponse(job))
+			if _, err := fmt.Fprintf(w, "event: status\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+		if job.Finished() {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+
+		next, err := h.queue.Get(r.Context(), job.UserID, job.ID)
+		if err != nil {
+			logging.FromContext(r.Context()).WarnContext(r.Context(), "failed to poll job", slog.Any("error", err))
+			return
+		}
+		job = next
+	}
+}
+
+// getJob читает job_id из пути и возвращает задачу текущего пользователя, иначе пишет ошибку
+func (h *JobsHandler) getJob(w http.ResponseWriter, r *http.Request) (*domain.Job, bool) {
+	userID, err := getUserIDFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	jobID, err := uuid.Parse(chi.URLParam(r, "job_id"))
+	if err != nil {
+		http.Error(w, "invalid job_id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	job, err := h.queue.Get(r.Context(), userID, jobID)
+	switch {
+	case errors.Is(err, domain.ErrJobNotFound):
+		http.Error(w, "job not found", http.StatusNotFound)
+		return nil, false
+	case errors.Is(err, domain.ErrAccessDenied):
+		http.Error(w, "access denied", http.StatusForbidden)
+		return nil, false
+	case err != nil:
+		http.Error(w, "failed to get job", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return job, true
+}
+
+func toJobResponse(job *domain.Job) dto.JobResponse {
+	return dto.JobResponse{
+		ID:        job.ID.String(),
+		Kind:      job.Kind,
+		Status:    string(job.Status),
+		Attempts:  job.Attempts,
+		Result:    job.Result,
+		Error:     job.Error,
+		CreatedAt: job.CreatedAt,
+		UpdatedAt: job.UpdatedAt,
+	}
+}
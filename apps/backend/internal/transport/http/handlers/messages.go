@@ -2,9 +2,14 @@ package handlers
 
 import (
 	"backend/internal/domain"
+	"backend/internal/logging"
 	"backend/internal/transport/http/dto"
+	"backend/internal/usecase/authz"
+	"backend/internal/usecase/jobs"
 	"backend/internal/usecase/llm"
 	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strings"
 
@@ -12,11 +17,22 @@ import (
 	"github.com/google/uuid"
 )
 
+const (
+	// maxClientMessageIDLen - ограничение длины ключа идемпотентности
+	maxClientMessageIDLen = 128
+
+	defaultMessagesLimit = 100
+	maxMessagesLimit     = 200
+)
+
 type MessagesHandler struct {
 	msgRepo       domain.MessageRepo
 	chatRepo      domain.ChatRepo
 	llmService    *llm.Service
 	docTextGetter llm.DocumentTextGetter
+	authz         *authz.Service
+	// jobs - очередь фоновых задач; nil - асинхронная отправка недоступна и запросы выполняются синхронно
+	jobs *jobs.Queue
 }
 
 func NewMessagesHandler(
@@ -24,16 +40,20 @@ func NewMessagesHandler(
 	chatRepo domain.ChatRepo,
 	llmService *llm.Service,
 	docTextGetter llm.DocumentTextGetter,
+	az *authz.Service,
+	jobQueue *jobs.Queue,
 ) *MessagesHandler {
 	return &MessagesHandler{
 		msgRepo:       msgRepo,
 		chatRepo:      chatRepo,
 		llmService:    llmService,
 		docTextGetter: docTextGetter,
+		authz:         az,
+		jobs:          jobQueue,
 	}
 }
 
-// GetMessages возвращает историю сообщений чата
+// GetMessages возвращает последние сообщения активной ветки чата (?limit=), более старые - по ?before=
 func (h *MessagesHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
 	userID, err := getUserIDFromContext(r.Context())
 	if err != nil {
@@ -48,6 +68,21 @@ func (h *MessagesHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	limit, ok := queryInt(r, "limit", defaultMessagesLimit)
+	if !ok || limit < 1 || limit > maxMessagesLimit {
+		http.Error(w, "invalid limit", http.StatusBadRequest)
+		return
+	}
+	var before *uuid.UUID
+	if v := r.URL.Query().Get("before"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "invalid before", http.StatusBadRequest)
+			return
+		}
+		before = &id
+	}
+
 	// Проверяем права доступа
 	chat, err := h.chatRepo.GetByID(r.Context(), chatID)
 	if err != nil {
@@ -55,28 +90,28 @@ func (h *MessagesHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if chat.UserID != userID {
-		http.Error(w, "access denied", http.StatusForbidden)
+	if err := h.authz.Can(r.Context(), authz.User(userID), authz.ChatRead, authz.Chat(chat)); err != nil {
+		writeAccessError(w, err)
 		return
 	}
 
-	messages, err := h.msgRepo.ListByChat(r.Context(), chatID, 100, 0)
+	// Лишнее (самое старое) сообщение показывает, есть ли более старая страница
+	messages, err := h.msgRepo.ListByChat(r.Context(), chatID, before, limit+1)
 	if err != nil {
 		http.Error(w, "failed to get messages", http.StatusInternalServerError)
 		return
 	}
 
-	response := dto.MessagesListResponse{
-		Messages: make([]dto.MessageResponse, len(messages)),
+	var response dto.MessagesListResponse
+	if len(messages) > limit {
+		messages = messages[1:]
+		next := messages[0].ID.String()
+		response.NextBefore = &next
 	}
+	response.Messages = make([]dto.MessageResponse, len(messages))
 
 	for i, msg := range messages {
-		response.Messages[i] = dto.MessageResponse{
-			ID:        msg.ID.String(),
-			Role:      msg.Role,
-			Content:   msg.Content,
-			CreatedAt: msg.CreatedAt,
-		}
+		response.Messages[i] = toMessageResponse(msg)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -109,19 +144,38 @@ func (h *MessagesHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Ключ идемпотентности: заголовок приоритетнее поля тела
+	clientMessageID := strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+	if clientMessageID == "" {
+		clientMessageID = strings.TrimSpace(req.ClientMessageID)
+	}
+	if len(clientMessageID) > maxClientMessageIDLen {
+		http.Error(w, "idempotency key is too long", http.StatusBadRequest)
+		return
+	}
+
 	// Парсим document IDs
 	var documentIDs []uuid.UUID
 	for _, docIDStr := range req.DocumentIDs {
 		docID, err := uuid.Parse(docIDStr)
 		if err != nil {
+			logging.FromContext(r.Context()).WarnContext(r.Context(), "skipping invalid document id",
+				slog.String("document_id", docIDStr),
+			)
 			continue // пропускаем невалидные ID
 		}
 		documentIDs = append(documentIDs, docID)
 	}
 
+	if h.jobs != nil && wantsAsync(r) {
+		h.enqueueReply(w, r, userID, chatID, clientMessageID, req, documentIDs)
+		return
+	}
+
 	// Вызываем LLM сервис
-	assistantMsg, err := h.llmService.Reply(
+	assistantMsg, err := h.llmService.ReplyIdempotent(
 		r.Context(),
+		clientMessageID,
 		chatID,
 		userID,
 		req.Content,
@@ -130,19 +184,206 @@ func (h *MessagesHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		h.docTextGetter,
 	)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeReplyError(w, r, err)
+		return
+	}
+
+	if h.jobs != nil {
+		h.jobs.EnqueueFollowUps(r.Context(), userID, chatID)
+	}
+
+	writeReply(w, assistantMsg)
+}
+
+// wantsAsync - клиент просит не ждать ответа: ?async=true или Prefer: respond-async
+func wantsAsync(r *http.Request) bool {
+	if r.URL.Query().Get("async") == "true" {
+		return true
+	}
+	for _, pref := range strings.Split(r.Header.Get("Prefer"), ",") {
+		if strings.EqualFold(strings.TrimSpace(pref), "respond-async") {
+			return true
+		}
+	}
+	return false
+}
+
+// enqueueReply ставит генерацию ответа в очередь и возвращает 202 с ID задачи
+func (h *MessagesHandler) enqueueReply(
+	w http.ResponseWriter,
+	r *http.Request,
+	userID, chatID uuid.UUID,
+	clientMessageID string,
+	req dto.SendMessageRequest,
+	documentIDs []uuid.UUID,
+) {
+	// Права проверяем сразу, чтобы не принимать задачу, которая заведомо упадёт
+	chat, err := h.chatRepo.GetByID(r.Context(), chatID)
+	if err != nil {
+		http.Error(w, "chat not found", http.StatusNotFound)
+		return
+	}
+	if err := h.authz.Can(r.Context(), authz.User(userID), authz.ChatWrite, authz.Chat(chat)); err != nil {
+		writeAccessError(w, err)
+		return
+	}
+
+	// Повтор запроса с тем же ключом, пока задача ждёт, возвращает её же
+	var dedupeKey string
+	if clientMessageID != "" {
+		dedupeKey = jobs.KindReply + ":" + chatID.String() + ":" + clientMessageID
+	}
+
+	job, err := h.jobs.Enqueue(r.Context(), jobs.KindReply, userID, jobs.ReplyPayload{
+		ChatID:          chatID,
+		Content:         req.Content,
+		DocumentIDs:     documentIDs,
+		ScenarioCode:    req.ScenarioCode,
+		ClientMessageID: clientMessageID,
+	}, dedupeKey)
+	if err != nil {
+		logging.FromContext(r.Context()).ErrorContext(r.Context(), "failed to enqueue reply", slog.Any("error", err))
+		http.Error(w, "failed to enqueue reply", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/jobs/"+job.ID.String())
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(dto.JobAcceptedResponse{
+		JobID:  job.ID.String(),
+		Status: string(job.Status),
+	})
+}
+
+// RegenerateMessage генерирует альтернативный ответ на вопрос, к которому относится сообщение
+func (h *MessagesHandler) RegenerateMessage(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	messageID, err := uuid.Parse(chi.URLParam(r, "message_id"))
+	if err != nil {
+		http.Error(w, "invalid message_id", http.StatusBadRequest)
+		return
+	}
+
+	assistantMsg, err := h.llmService.Regenerate(r.Context(), userID, messageID, h.docTextGetter)
+	if err != nil {
+		writeReplyError(w, r, err)
+		return
+	}
+
+	writeReply(w, assistantMsg)
+}
+
+// EditMessage сохраняет исправленный вопрос новой веткой и возвращает ответ на него
+func (h *MessagesHandler) EditMessage(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	messageID, err := uuid.Parse(chi.URLParam(r, "message_id"))
+	if err != nil {
+		http.Error(w, "invalid message_id", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.EditMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if strings.TrimSpace(req.Content) == "" {
+		http.Error(w, "content is required", http.StatusBadRequest)
+		return
+	}
+
+	assistantMsg, err := h.llmService.EditMessage(r.Context(), userID, messageID, req.Content, h.docTextGetter)
+	if err != nil {
+		writeReplyError(w, r, err)
+		return
+	}
+
+	writeReply(w, assistantMsg)
+}
+
+// ActivateBranch переключает чат на ветку, проходящую через сообщение
+func (h *MessagesHandler) ActivateBranch(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	messageID, err := uuid.Parse(chi.URLParam(r, "message_id"))
+	if err != nil {
+		http.Error(w, "invalid message_id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.llmService.SwitchBranch(r.Context(), userID, messageID); err != nil {
+		writeReplyError(w, r, err)
 		return
 	}
 
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeReplyError переводит ошибку генерации ответа в HTTP-статус
+func writeReplyError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, domain.ErrQuotaExceeded):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	case errors.Is(err, domain.ErrSensitiveData):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, domain.ErrMessageNotFound):
+		http.Error(w, "message not found", http.StatusNotFound)
+	case errors.Is(err, domain.ErrAccessDenied):
+		http.Error(w, "access denied", http.StatusForbidden)
+	case errors.Is(err, domain.ErrIdempotencyKeyReused):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, domain.ErrReplyInProgress):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, domain.ErrWrongMessageRole):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		logging.FromContext(r.Context()).ErrorContext(r.Context(), "failed to reply",
+			slog.String("path", r.URL.Path),
+			slog.Any("error", err),
+		)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeReply(w http.ResponseWriter, msg *domain.Message) {
 	response := dto.SendMessageResponse{
-		Message: dto.MessageResponse{
-			ID:        assistantMsg.ID.String(),
-			Role:      assistantMsg.Role,
-			Content:   assistantMsg.Content,
-			CreatedAt: assistantMsg.CreatedAt,
-		},
+		Message: toMessageResponse(msg),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
+
+func toMessageResponse(msg *domain.Message) dto.MessageResponse {
+	resp := dto.MessageResponse{
+		ID:        msg.ID.String(),
+		Role:      msg.Role,
+		Content:   msg.Content,
+		CreatedAt: msg.CreatedAt,
+		Status:    string(msg.Status),
+	}
+	if msg.ParentID != nil {
+		parentID := msg.ParentID.String()
+		resp.ParentID = &parentID
+	}
+	for _, id := range msg.SiblingIDs {
+		resp.SiblingIDs = append(resp.SiblingIDs, id.String())
+	}
+	return resp
+}
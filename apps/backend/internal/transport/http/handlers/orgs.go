@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"backend/internal/domain"
+	"backend/internal/logging"
+	"backend/internal/transport/http/dto"
+	"backend/internal/usecase/org"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type OrgsHandler struct {
+	orgs *org.Service
+}
+
+func NewOrgsHandler(orgs *org.Service) *OrgsHandler {
+	return &OrgsHandler{
+		orgs: orgs,
+	}
+}
+
+// GetOrgs возвращает организации текущего пользователя с его ролью
+func (h *OrgsHandler) GetOrgs(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	orgs, err := h.orgs.List(r.Context(), userID)
+	if err != nil {
+		writeOrgServiceError(w, r, err)
+		return
+	}
+
+	response := dto.OrgsListResponse{
+		Orgs: make([]dto.OrgResponse, len(orgs)),
+	}
+	for i, o := range orgs {
+		response.Orgs[i] = toOrgResponse(o)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateOrg создаёт организацию; текущий пользователь становится её владельцем
+func (h *OrgsHandler) CreateOrg(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.CreateOrgRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.orgs.Create(r.Context(), userID, req.Name)
+	if err != nil {
+		writeOrgServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(toOrgResponse(created))
+}
+
+// GetMembers возвращает участников организации
+func (h *OrgsHandler) GetMembers(w http.ResponseWriter, r *http.Request) {
+	userID, orgID, ok := orgRequest(w, r)
+	if !ok {
+		return
+	}
+
+	members, err := h.orgs.Members(r.Context(), userID, orgID)
+	if err != nil {
+		writeOrgServiceError(w, r, err)
+		return
+	}
+
+	response := dto.MembersListResponse{
+		Members: make([]dto.MemberResponse, len(members)),
+	}
+	for i, m := range members {
+		response.Members[i] = toMemberResponse(m)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateMember меняет роль участника
+func (h *OrgsHandler) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	userID, orgID, ok := orgRequest(w, r)
+	if !ok {
+		return
+	}
+
+	memberID, err := uuid.Parse(chi.URLParam(r, "user_id"))
+	if err != nil {
+		http.Error(w, "invalid user_id", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.UpdateMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.orgs.UpdateRole(r.Context(), userID, orgID, memberID, domain.OrgRole(req.Role)); err != nil {
+		writeOrgServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveMember исключает участника; участник может исключить и себя
+func (h *OrgsHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	userID, orgID, ok := orgRequest(w, r)
+	if !ok {
+		return
+	}
+
+	memberID, err := uuid.Parse(chi.URLParam(r, "user_id"))
+	if err != nil {
+		http.Error(w, "invalid user_id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.orgs.RemoveMember(r.Context(), userID, orgID, memberID); err != nil {
+		writeOrgServiceError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetInvites возвращает приглашения организации
+func (h *OrgsHandler) GetInvites(w http.ResponseWriter, r *http.Request) {
+	userID, orgID, ok := orgRequest(w, r)
+	if !ok {
+		return
+	}
+
+	invites, err := h.orgs.Invites(r.Context(), userID, orgID)
+	if err != nil {
+		writeOrgServiceError(w, r, err)
+		return
+	}
+
+	response := dto.InvitesListResponse{
+		Invites: make([]dto.InviteResponse, len(invites)),
+	}
+	for i, invite := range invites {
+		response.Invites[i] = toInviteResponse(invite)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateInvite приглашает пользователя по email. Токен приглашения уходит только в письмо
+func (h *OrgsHandler) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	userID, orgID, ok := orgRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.CreateInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Role == "" {
+		req.Role = string(domain.OrgRoleMember)
+	}
+
+	invite, err := h.orgs.Invite(r.Context(), userID, orgID, req.Email, domain.OrgRole(req.Role))
+	if err != nil {
+		writeOrgServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(toInviteResponse(invite))
+}
+
+// AcceptInvite добавляет текущего пользователя в организацию по токену из письма
+func (h *OrgsHandler) AcceptInvite(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.AcceptInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	membership, err := h.orgs.AcceptInvite(r.Context(), userID, req.Token)
+	if err != nil {
+		writeOrgServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toMemberResponse(membership))
+}
+
+// orgRequest достаёт пользователя и org_id из запроса; при ошибке ответ уже записан
+func orgRequest(w http.ResponseWriter, r *http.Request) (userID, orgID uuid.UUID, ok bool) {
+	userID, err := getUserIDFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	orgID, err = uuid.Parse(chi.URLParam(r, "org_id"))
+	if err != nil {
+		http.Error(w, "invalid org_id", http.StatusBadRequest)
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, orgID, true
+}
+
+func writeOrgServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, org.ErrInvalidName), errors.Is(err, org.ErrInvalidEmail), errors.Is(err, org.ErrInvalidRole):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, domain.ErrOrgNotFound):
+		http.Error(w, "organization not found", http.StatusNotFound)
+	case errors.Is(err, domain.ErrInviteNotFound):
+		http.Error(w, "invite not found", http.StatusNotFound)
+	case errors.Is(err, domain.ErrAccessDenied):
+		http.Error(w, "access denied", http.StatusForbidden)
+	case errors.Is(err, domain.ErrAlreadyMember):
+		http.Error(w, "already a member", http.StatusConflict)
+	case errors.Is(err, domain.ErrLastOwner):
+		http.Error(w, "organization must keep an owner", http.StatusConflict)
+	default:
+		logging.FromContext(r.Context()).ErrorContext(r.Context(), "organization request failed", slog.Any("error", err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
+
+func toOrgResponse(o *domain.Org) dto.OrgResponse {
+	return dto.OrgResponse{
+		ID:        o.ID.String(),
+		Name:      o.Name,
+		Role:      string(o.Role),
+		CreatedAt: o.CreatedAt,
+	}
+}
+
+func toMemberResponse(m *domain.Membership) dto.MemberResponse {
+	return dto.MemberResponse{
+		UserID:   m.UserID.String(),
+		Email:    m.Email,
+		Role:     string(m.Role),
+		JoinedAt: m.CreatedAt,
+	}
+}
+
+func toInviteResponse(invite *domain.OrgInvite) dto.InviteResponse {
+	return dto.InviteResponse{
+		ID:         invite.ID.String(),
+		Email:      invite.Email,
+		Role:       string(invite.Role),
+		ExpiresAt:  invite.ExpiresAt,
+		AcceptedAt: invite.AcceptedAt,
+		CreatedAt:  invite.CreatedAt,
+	}
+}
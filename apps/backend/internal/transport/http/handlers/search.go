@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"backend/internal/domain"
+	"backend/internal/logging"
+	"backend/internal/transport/http/dto"
+	"backend/internal/usecase/authz"
+	"encoding/json"
+	"html"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+	maxSearchQueryLen  = 256
+)
+
+type SearchHandler struct {
+	repo  domain.ChatSearchRepo
+	authz *authz.Service
+}
+
+func NewSearchHandler(repo domain.ChatSearchRepo, az *authz.Service) *SearchHandler {
+	return &SearchHandler{
+		repo:  repo,
+		authz: az,
+	}
+}
+
+// Search ищет по названиям и сообщениям чатов, которые текущий пользователь может читать
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
+	if utf8.RuneCountInString(query) > maxSearchQueryLen {
+		http.Error(w, "q is too long", http.StatusBadRequest)
+		return
+	}
+
+	limit, ok := queryInt(r, "limit", defaultSearchLimit)
+	if !ok || limit < 1 || limit > maxSearchLimit {
+		http.Error(w, "invalid limit", http.StatusBadRequest)
+		return
+	}
+	offset, ok := queryInt(r, "offset", 0)
+	if !ok || offset < 0 {
+		http.Error(w, "invalid offset", http.StatusBadRequest)
+		return
+	}
+
+	// Какие чаты можно читать, решает authz, а не запрос поиска
+	scope, err := h.authz.ChatScope(r.Context(), authz.User(userID), authz.ChatRead)
+	if err != nil {
+		logging.FromContext(r.Context()).ErrorContext(r.Context(), "failed to get search scope", slog.Any("error", err))
+		http.Error(w, "failed to search", http.StatusInternalServerError)
+		return
+	}
+
+	// Лишний результат показывает, есть ли следующая страница
+	hits, err := h.repo.Search(r.Context(), scope, query, limit+1, offset)
+	if err != nil {
+		logging.FromContext(r.Context()).ErrorContext(r.Context(), "failed to search chats", slog.Any("error", err))
+		http.Error(w, "failed to search", http.StatusInternalServerError)
+		return
+	}
+
+	response := dto.SearchResponse{
+		Results: make([]dto.SearchHitResponse, 0, min(len(hits), limit)),
+	}
+	if len(hits) > limit {
+		hits = hits[:limit]
+		next := offset + limit
+		response.NextOffset = &next
+	}
+
+	for _, hit := range hits {
+		resp := dto.SearchHitResponse{
+			ChatID:    hit.ChatID.String(),
+			ChatTitle: hit.ChatTitle,
+			Role:      hit.Role,
+			Snippet:   highlightHTML(hit.Snippet),
+			Rank:      hit.Rank,
+			CreatedAt: hit.CreatedAt,
+		}
+		if hit.MessageID != nil {
+			messageID := hit.MessageID.String()
+			resp.MessageID = &messageID
+		}
+		response.Results = append(response.Results, resp)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// highlightHTML экранирует сниппет и заменяет границы совпадений тегами <mark>
+func highlightHTML(snippet string) string {
+	return strings.NewReplacer(
+		domain.HighlightStart, "<mark>",
+		domain.HighlightEnd, "</mark>",
+	).Replace(html.EscapeString(snippet))
+}
+
+// queryInt читает целый параметр запроса; если его нет - def
+func queryInt(r *http.Request, key string, def int) (int, bool) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(v)
+	return n, err == nil
+}
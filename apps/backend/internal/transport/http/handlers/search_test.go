@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"backend/internal/domain"
+	"backend/internal/fakes"
+	"backend/internal/transport/http/dto"
+	"backend/internal/usecase/authz"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+type fakeSearchRepo struct {
+	hits                []*domain.ChatSearchHit
+	gotLimit, gotOffset int
+	gotScope            domain.ChatScope
+}
+
+func (f *fakeSearchRepo) Search(_ context.Context, scope domain.ChatScope, _ string, limit, offset int) ([]*domain.ChatSearchHit, error) {
+	f.gotScope, f.gotLimit, f.gotOffset = scope, limit, offset
+	if len(f.hits) > limit {
+		return f.hits[:limit], nil
+	}
+	return f.hits, nil
+}
+
+func TestSearchHandler_Search(t *testing.T) {
+	messageID := uuid.New()
+	repo := &fakeSearchRepo{hits: []*domain.ChatSearchHit{
+		{ChatID: uuid.New(), ChatTitle: "Аренда", MessageID: &messageID, Role: "user",
+			Snippet: "договор " + domain.HighlightStart + "аренды" + domain.HighlightEnd + " <b>офиса</b>"},
+		{ChatID: uuid.New(), ChatTitle: "Аренда склада", Snippet: domain.HighlightStart + "Аренда" + domain.HighlightEnd + " склада"},
+	}}
+	// Пользователь - зритель в организации orgID
+	userID, orgID := uuid.New(), uuid.New()
+	orgRepo := fakes.NewOrgRepo()
+	orgRepo.Orgs[orgID] = &domain.Org{ID: orgID, Name: "Кофейня"}
+	orgRepo.Members = append(orgRepo.Members, &domain.Membership{OrgID: orgID, UserID: userID, Role: domain.OrgRoleViewer})
+	az, err := authz.NewService(orgRepo)
+	if err != nil {
+		t.Fatalf("authz.NewService() error = %v", err)
+	}
+	handler := NewSearchHandler(repo, az)
+
+	tests := []struct {
+		name        string
+		url         string
+		wantCode    int
+		wantResults int
+		wantNext    int // 0 - следующей страницы нет
+	}{
+		{name: "missing query", url: "/search", wantCode: http.StatusBadRequest},
+		{name: "invalid limit", url: "/search?q=аренда&limit=500", wantCode: http.StatusBadRequest},
+		{name: "all results", url: "/search?q=аренда", wantCode: http.StatusOK, wantResults: 2},
+		{name: "first page", url: "/search?q=аренда&limit=1", wantCode: http.StatusOK, wantResults: 1, wantNext: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			req = req.WithContext(context.WithValue(req.Context(), UserIDKey, userID))
+			rec := httptest.NewRecorder()
+
+			handler.Search(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			// Поиск ограничен чатами, которые authz разрешает читать
+			if repo.gotScope.UserID != userID || !slices.Equal(repo.gotScope.OrgIDs, []uuid.UUID{orgID}) {
+				t.Fatalf("scope = %+v", repo.gotScope)
+			}
+
+			var resp dto.SearchResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if len(resp.Results) != tt.wantResults {
+				t.Fatalf("results = %d, want %d", len(resp.Results), tt.wantResults)
+			}
+			next := 0
+			if resp.NextOffset != nil {
+				next = *resp.NextOffset
+			}
+			if next != tt.wantNext {
+				t.Fatalf("next_offset = %d, want %d", next, tt.wantNext)
+			}
+
+			// Текст сообщения экранирован, совпадение выделено
+			if got, want := resp.Results[0].Snippet, "договор <mark>аренды</mark> &lt;b&gt;офиса&lt;/b&gt;"; got != want {
+				t.Fatalf("snippet = %q, want %q", got, want)
+			}
+		})
+	}
+}
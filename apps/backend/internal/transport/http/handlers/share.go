@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"backend/internal/domain"
+	"backend/internal/logging"
+	"backend/internal/transport/http/dto"
+	"backend/internal/usecase/share"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type ShareHandler struct {
+	shares *share.Service
+}
+
+func NewShareHandler(shares *share.Service) *ShareHandler {
+	return &ShareHandler{
+		shares: shares,
+	}
+}
+
+// CreateShare создаёт ссылку только для чтения на текущее состояние чата
+func (h *ShareHandler) CreateShare(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	chatID, err := uuid.Parse(chi.URLParam(r, "chat_id"))
+	if err != nil {
+		http.Error(w, "invalid chat_id", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.CreateShareRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ttl := time.Duration(req.ExpiresInHours) * time.Hour
+	created, token, err := h.shares.Create(r.Context(), userID, chatID, ttl, req.MaskPII)
+	switch {
+	case errors.Is(err, share.ErrInvalidTTL):
+		http.Error(w, "expires_in_hours must be between 0 and 8760", http.StatusBadRequest)
+		return
+	case errors.Is(err, domain.ErrAccessDenied):
+		http.Error(w, "access denied", http.StatusForbidden)
+		return
+	case err != nil:
+		http.Error(w, "chat not found", http.StatusNotFound)
+		return
+	}
+
+	response := dto.CreateShareResponse{
+		ShareResponse: toShareResponse(created),
+		Token:         token,
+		URL:           "/shared/" + token,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetShares возвращает ссылки чата, включая отозванные и истёкшие
+func (h *ShareHandler) GetShares(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	chatID, err := uuid.Parse(chi.URLParam(r, "chat_id"))
+	if err != nil {
+		http.Error(w, "invalid chat_id", http.StatusBadRequest)
+		return
+	}
+
+	shares, err := h.shares.List(r.Context(), userID, chatID)
+	if errors.Is(err, domain.ErrAccessDenied) {
+		http.Error(w, "access denied", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, "chat not found", http.StatusNotFound)
+		return
+	}
+
+	response := dto.SharesListResponse{
+		Shares: make([]dto.ShareResponse, len(shares)),
+	}
+	for i, s := range shares {
+		response.Shares[i] = toShareResponse(s)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// RevokeShare отзывает ссылку; повторный отзыв не меняет время отзыва
+func (h *ShareHandler) RevokeShare(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	shareID, err := uuid.Parse(chi.URLParam(r, "share_id"))
+	if err != nil {
+		http.Error(w, "invalid share_id", http.StatusBadRequest)
+		return
+	}
+
+	err = h.shares.Revoke(r.Context(), userID, shareID)
+	switch {
+	case errors.Is(err, domain.ErrShareNotFound):
+		http.Error(w, "share not found", http.StatusNotFound)
+		return
+	case errors.Is(err, domain.ErrAccessDenied):
+		http.Error(w, "access denied", http.StatusForbidden)
+		return
+	case err != nil:
+		logging.FromContext(r.Context()).ErrorContext(r.Context(), "failed to revoke share", slog.Any("error", err))
+		http.Error(w, "failed to revoke share", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetShared открывает чат по ссылке. Маршрут публичный: доступ даёт только токен
+func (h *ShareHandler) GetShared(w http.ResponseWriter, r *http.Request) {
+	// Снимок может содержать личные данные: не кешировать и не индексировать
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Robots-Tag", "noindex")
+
+	snapshot, err := h.shares.Open(r.Context(), chi.URLParam(r, "token"))
+	if errors.Is(err, domain.ErrShareNotFound) {
+		http.Error(w, "share not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).ErrorContext(r.Context(), "failed to open share", slog.Any("error", err))
+		http.Error(w, "failed to open share", http.StatusInternalServerError)
+		return
+	}
+
+	response := dto.SharedChatResponse{
+		Title:    snapshot.Title,
+		SharedAt: snapshot.SharedAt,
+		Messages: make([]dto.SharedMessageResponse, len(snapshot.Messages)),
+	}
+	for i, msg := range snapshot.Messages {
+		response.Messages[i] = dto.SharedMessageResponse{
+			Role:      msg.Role,
+			Content:   msg.Content,
+			CreatedAt: msg.CreatedAt,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func toShareResponse(s *domain.ChatShare) dto.ShareResponse {
+	response := dto.ShareResponse{
+		ID:        s.ID.String(),
+		MaskPII:   s.MaskPII,
+		ExpiresAt: s.ExpiresAt,
+		RevokedAt: s.RevokedAt,
+		CreatedAt: s.CreatedAt,
+	}
+	if s.MessageID != nil {
+		messageID := s.MessageID.String()
+		response.MessageID = &messageID
+	}
+	return response
+}
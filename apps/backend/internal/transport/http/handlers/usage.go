@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"backend/internal/domain"
+	"backend/internal/transport/http/dto"
+	"backend/internal/usecase/usage"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type UsageHandler struct {
+	usageService *usage.Service
+}
+
+func NewUsageHandler(usageService *usage.Service) *UsageHandler {
+	return &UsageHandler{
+		usageService: usageService,
+	}
+}
+
+// GetMyUsage возвращает потребление текущего пользователя относительно его квот
+func (h *UsageHandler) GetMyUsage(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	report, err := h.usageService.Report(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "failed to get usage", http.StatusInternalServerError)
+		return
+	}
+
+	response := toUsageResponse(report)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateQuota задаёт персональную квоту пользователя (только для администраторов)
+func (h *UsageHandler) UpdateQuota(w http.ResponseWriter, r *http.Request) {
+	userIDStr := chi.URLParam(r, "user_id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		http.Error(w, "invalid user_id", http.StatusBadRequest)
+		return
+	}
+
+	var req dto.QuotaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	quota := &domain.Quota{
+		UserID:               userID,
+		DailyTokens:          req.DailyTokens,
+		MonthlyTokens:        req.MonthlyTokens,
+		DailyRequests:        req.DailyRequests,
+		MonthlyRequests:      req.MonthlyRequests,
+		DailyDocumentBytes:   req.DailyDocumentBytes,
+		MonthlyDocumentBytes: req.MonthlyDocumentBytes,
+	}
+
+	if err := h.usageService.SetQuota(r.Context(), quota); err != nil {
+		if errors.Is(err, usage.ErrInvalidQuota) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "failed to update quota", http.StatusInternalServerError)
+		return
+	}
+
+	response := dto.QuotaResponse{
+		UserID:               quota.UserID.String(),
+		DailyTokens:          quota.DailyTokens,
+		MonthlyTokens:        quota.MonthlyTokens,
+		DailyRequests:        quota.DailyRequests,
+		MonthlyRequests:      quota.MonthlyRequests,
+		DailyDocumentBytes:   quota.DailyDocumentBytes,
+		MonthlyDocumentBytes: quota.MonthlyDocumentBytes,
+		UpdatedAt:            quota.UpdatedAt,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func usageCounters(u domain.Usage) dto.UsageCounters {
+	return dto.UsageCounters{
+		Requests:         u.Requests,
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.Tokens(),
+		DocumentBytes:    u.DocumentBytes,
+	}
+}
+
+// toUsageResponse - потребление за сутки и месяц вместе с лимитами квоты
+func toUsageResponse(report *usage.Report) dto.UsageResponse {
+	q := report.Quota
+	return dto.UsageResponse{
+		Daily: dto.UsagePeriod{
+			From: report.DayStart,
+			Used: usageCounters(report.Daily),
+			Limits: dto.UsageLimits{
+				Tokens:        q.DailyTokens,
+				Requests:      q.DailyRequests,
+				DocumentBytes: q.DailyDocumentBytes,
+			},
+		},
+		Monthly: dto.UsagePeriod{
+			From: report.MonthStart,
+			Used: usageCounters(report.Monthly),
+			Limits: dto.UsageLimits{
+				Tokens:        q.MonthlyTokens,
+				Requests:      q.MonthlyRequests,
+				DocumentBytes: q.MonthlyDocumentBytes,
+			},
+		},
+	}
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"backend/internal/domain"
+	"backend/internal/logging"
+	"backend/internal/transport/http/dto"
+	"backend/internal/usecase/users"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultUsersLimit = 50
+	maxUsersLimit     = 200
+)
+
+type UsersHandler struct {
+	users *users.Service
+}
+
+func NewUsersHandler(usersService *users.Service) *UsersHandler {
+	return &UsersHandler{
+		users: usersService,
+	}
+}
+
+// ListUsers ищет пользователей по части email (?q=) с числом их чатов (только для администраторов)
+func (h *UsersHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	limit, ok := queryInt(r, "limit", defaultUsersLimit)
+	if !ok || limit < 1 || limit > maxUsersLimit {
+		http.Error(w, "invalid limit", http.StatusBadRequest)
+		return
+	}
+	offset, ok := queryInt(r, "offset", 0)
+	if !ok || offset < 0 {
+		http.Error(w, "invalid offset", http.StatusBadRequest)
+		return
+	}
+
+	// Лишняя запись показывает, есть ли следующая страница
+	list, err := h.users.List(r.Context(), r.URL.Query().Get("q"), limit+1, offset)
+	if err != nil {
+		writeUsersError(w, r, err)
+		return
+	}
+
+	response := dto.AdminUsersListResponse{
+		Users: make([]dto.AdminUserResponse, 0, min(len(list), limit)),
+	}
+	if len(list) > limit {
+		list = list[:limit]
+		next := offset + limit
+		response.NextOffset = &next
+	}
+	for _, u := range list {
+		response.Users = append(response.Users, toAdminUserResponse(u))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetUser возвращает пользователя с числом чатов и потреблением за день и месяц (только для администраторов)
+func (h *UsersHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	overview, err := h.users.Get(r.Context(), userID)
+	if err != nil {
+		writeUsersError(w, r, err)
+		return
+	}
+
+	response := dto.AdminUserDetailResponse{
+		AdminUserResponse: toAdminUserResponse(overview.UserOverview),
+		Usage:             toUsageResponse(overview.Usage),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateUser создаёт пользователя и возвращает его временный пароль (только для администраторов)
+func (h *UsersHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, password, err := h.users.Create(r.Context(), req.Email, domain.UserRole(req.Role))
+	if err != nil {
+		writeUsersError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(dto.CreateUserResponse{
+		User:              toAdminUserResponse(&domain.UserOverview{User: *user}),
+		TemporaryPassword: password,
+	})
+}
+
+// UpdateUserStatus включает или отключает пользователя (только для администраторов)
+func (h *UsersHandler) UpdateUserStatus(w http.ResponseWriter, r *http.Request) {
+	adminID, err := getUserIDFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	userID, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.UpdateUserStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.IsActive == nil {
+		http.Error(w, "is_active is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.users.SetActive(r.Context(), adminID, userID, *req.IsActive); err != nil {
+		writeUsersError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeSessions завершает все сеансы пользователя (только для администраторов)
+func (h *UsersHandler) RevokeSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.users.RevokeSessions(r.Context(), userID); err != nil {
+		writeUsersError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResetPassword выдаёт пользователю новый временный пароль и завершает его сеансы (только для администраторов)
+func (h *UsersHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	password, err := h.users.ResetPassword(r.Context(), userID)
+	if err != nil {
+		writeUsersError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(dto.PasswordResetResponse{TemporaryPassword: password})
+}
+
+// ChangeMyPassword меняет пароль текущего пользователя, в том числе временный, и завершает
+// все его сеансы: клиент должен войти заново с новым паролем
+func (h *UsersHandler) ChangeMyPassword(w http.ResponseWriter, r *http.Request) {
+	userID, err := getUserIDFromContext(r.Context())
+	if err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dto.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.users.ChangePassword(r.Context(), userID, req.CurrentPassword, req.NewPassword); err != nil {
+		writeUsersError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// userIDParam достаёт user_id из пути; при ошибке ответ уже записан
+func userIDParam(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(chi.URLParam(r, "user_id"))
+	if err != nil {
+		http.Error(w, "invalid user_id", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func toAdminUserResponse(u *domain.UserOverview) dto.AdminUserResponse {
+	return dto.AdminUserResponse{
+		ID:                 u.ID.String(),
+		Email:              u.Email,
+		Role:               string(u.Role),
+		IsActive:           u.IsActive,
+		MustChangePassword: u.MustChangePassword,
+		ChatCount:          u.Chats,
+		CreatedAt:          u.CreatedAt,
+		LastLoginAt:        u.LastLoginAt,
+	}
+}
+
+func writeUsersError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, users.ErrInvalidEmail), errors.Is(err, users.ErrInvalidRole), errors.Is(err, users.ErrInvalidPassword):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, users.ErrWrongPassword):
+		http.Error(w, "wrong current password", http.StatusForbidden)
+	case errors.Is(err, users.ErrSelfLockout):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, domain.ErrUserNotFound):
+		http.Error(w, "user not found", http.StatusNotFound)
+	case errors.Is(err, domain.ErrUserExists):
+		http.Error(w, "user already exists", http.StatusConflict)
+	default:
+		logging.FromContext(r.Context()).ErrorContext(r.Context(), "user request failed", slog.Any("error", err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
@@ -2,8 +2,21 @@ package http
 
 import (
 	"backend/internal/domain"
+	"backend/internal/logging"
+	"backend/internal/metrics"
+	"backend/internal/tracing"
 	"backend/internal/transport/http/handlers"
+	"backend/internal/usecase/auth"
+	"backend/internal/usecase/authz"
+	"backend/internal/usecase/export"
+	"backend/internal/usecase/importer"
+	"backend/internal/usecase/jobs"
 	"backend/internal/usecase/llm"
+	"backend/internal/usecase/org"
+	"backend/internal/usecase/share"
+	"backend/internal/usecase/usage"
+	"backend/internal/usecase/users"
+	"log/slog"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -13,26 +26,59 @@ type Router struct {
 	chatRepo      domain.ChatRepo
 	msgRepo       domain.MessageRepo
 	userRepo      domain.UserRepo
+	searchRepo    domain.ChatSearchRepo
 	llmService    *llm.Service
+	exporter      *export.Exporter
+	importer      *importer.Importer
+	shareService  *share.Service
+	orgService    *org.Service
+	authz         *authz.Service
+	usageService  *usage.Service
+	userService   *users.Service
+	tokens        *auth.Tokens
 	docTextGetter llm.DocumentTextGetter
+	jobQueue      *jobs.Queue
 	limits        domain.Limits
+	healthChecks  []handlers.HealthCheck
 }
 
 func NewRouter(
 	chatRepo domain.ChatRepo,
 	msgRepo domain.MessageRepo,
 	userRepo domain.UserRepo,
+	searchRepo domain.ChatSearchRepo,
 	llmService *llm.Service,
+	exporter *export.Exporter,
+	importer *importer.Importer,
+	shareService *share.Service,
+	orgService *org.Service,
+	authorizer *authz.Service,
+	usageService *usage.Service,
+	userService *users.Service,
+	tokens *auth.Tokens,
 	docTextGetter llm.DocumentTextGetter,
+	jobQueue *jobs.Queue,
 	limits domain.Limits,
+	healthChecks []handlers.HealthCheck,
 ) *Router {
 	return &Router{
 		chatRepo:      chatRepo,
 		msgRepo:       msgRepo,
 		userRepo:      userRepo,
+		searchRepo:    searchRepo,
 		llmService:    llmService,
+		exporter:      exporter,
+		importer:      importer,
+		shareService:  shareService,
+		orgService:    orgService,
+		authz:         authorizer,
+		usageService:  usageService,
+		userService:   userService,
+		tokens:        tokens,
 		docTextGetter: docTextGetter,
+		jobQueue:      jobQueue,
 		limits:        limits,
+		healthChecks:  healthChecks,
 	}
 }
 
@@ -40,39 +86,112 @@ func (r *Router) SetupRoutes() *chi.Mux {
 	router := chi.NewRouter()
 
 	// Middleware
-	router.Use(middleware.Logger)
-	router.Use(middleware.Recoverer)
 	router.Use(middleware.RequestID)
+	router.Use(metrics.HTTPMiddleware)
+	router.Use(tracing.HTTPMiddleware)
+	router.Use(logging.Middleware(slog.Default()))
+	router.Use(middleware.Recoverer)
 
 	// Handlers
-	healthHandler := handlers.NewHealthHandler()
-	authHandler := handlers.NewAuthHandler(r.userRepo)
-	chatsHandler := handlers.NewChatsHandler(r.chatRepo)
-	messagesHandler := handlers.NewMessagesHandler(r.msgRepo, r.chatRepo, r.llmService, r.docTextGetter)
+	healthHandler := handlers.NewHealthHandler(r.healthChecks...)
+	authHandler := handlers.NewAuthHandler(r.userRepo, r.tokens)
+	chatsHandler := handlers.NewChatsHandler(r.chatRepo, r.authz)
+	messagesHandler := handlers.NewMessagesHandler(r.msgRepo, r.chatRepo, r.llmService, r.docTextGetter, r.authz, r.jobQueue)
 	scenariosHandler := handlers.NewScenariosHandler()
 	limitsHandler := handlers.NewLimitsHandler(r.limits)
+	usageHandler := handlers.NewUsageHandler(r.usageService)
+	searchHandler := handlers.NewSearchHandler(r.searchRepo, r.authz)
+	exportHandler := handlers.NewExportHandler(r.exporter)
+	importHandler := handlers.NewImportHandler(r.importer)
+	shareHandler := handlers.NewShareHandler(r.shareService)
+	orgsHandler := handlers.NewOrgsHandler(r.orgService)
+	usersHandler := handlers.NewUsersHandler(r.userService)
+
+	authMiddleware := handlers.AuthMiddleware(r.tokens, r.userRepo)
+
+	var jobsHandler *handlers.JobsHandler
+	if r.jobQueue != nil {
+		jobsHandler = handlers.NewJobsHandler(r.jobQueue)
+	}
 
 	// Public routes (без аутентификации)
 	router.Get("/health", healthHandler.Health)
+	router.Get("/health/live", healthHandler.Health)
+	router.Get("/health/ready", healthHandler.Ready)
 	router.Post("/login", authHandler.Login)
+	router.Get("/shared/{token}", shareHandler.GetShared)
 
 	// Protected routes (с аутентификацией)
 	router.Group(func(r chi.Router) {
-		r.Use(handlers.AuthMiddleware)
+		r.Use(authMiddleware)
+
+		// Account: смена пароля доступна и со временным паролем
+		r.Put("/me/password", usersHandler.ChangeMyPassword)
+
+		r.Group(func(r chi.Router) {
+			r.Use(handlers.PasswordChangeMiddleware)
+
+			// Chats
+			r.Get("/chats", chatsHandler.GetChats)
+			r.Post("/chats", chatsHandler.CreateChat)
+			r.Post("/chats/import", importHandler.ImportChats)
+			r.Get("/chats/{chat_id}/export", exportHandler.ExportChat)
 
-		// Chats
-		r.Get("/chats", chatsHandler.GetChats)
-		r.Post("/chats", chatsHandler.CreateChat)
+			// Shares
+			r.Post("/chats/{chat_id}/share", shareHandler.CreateShare)
+			r.Get("/chats/{chat_id}/shares", shareHandler.GetShares)
+			r.Delete("/shares/{share_id}", shareHandler.RevokeShare)
 
-		// Messages
-		r.Get("/chats/{chat_id}/messages", messagesHandler.GetMessages)
-		r.Post("/chats/{chat_id}/messages", messagesHandler.SendMessage)
+			// Organizations
+			r.Get("/orgs", orgsHandler.GetOrgs)
+			r.Post("/orgs", orgsHandler.CreateOrg)
+			r.Get("/orgs/{org_id}/members", orgsHandler.GetMembers)
+			r.Put("/orgs/{org_id}/members/{user_id}", orgsHandler.UpdateMember)
+			r.Delete("/orgs/{org_id}/members/{user_id}", orgsHandler.RemoveMember)
+			r.Get("/orgs/{org_id}/invites", orgsHandler.GetInvites)
+			r.Post("/orgs/{org_id}/invites", orgsHandler.CreateInvite)
+			r.Post("/invites/accept", orgsHandler.AcceptInvite)
+
+			// Search
+			r.Get("/search", searchHandler.Search)
+
+			// Messages
+			r.Get("/chats/{chat_id}/messages", messagesHandler.GetMessages)
+			r.Post("/chats/{chat_id}/messages", messagesHandler.SendMessage)
+			r.Put("/messages/{message_id}", messagesHandler.EditMessage)
+			r.Post("/messages/{message_id}/regenerate", messagesHandler.RegenerateMessage)
+			r.Post("/messages/{message_id}/activate", messagesHandler.ActivateBranch)
+
+			// Jobs (если очередь задач включена)
+			if jobsHandler != nil {
+				r.Get("/jobs/{job_id}", jobsHandler.GetJob)
+				r.Get("/jobs/{job_id}/events", jobsHandler.JobEvents)
+			}
+
+			// Scenarios
+			r.Get("/scenarios", scenariosHandler.GetScenarios)
+
+			// Config
+			r.Get("/config/limits", limitsHandler.GetLimits)
+
+			// Usage
+			r.Get("/me/usage", usageHandler.GetMyUsage)
+		})
+	})
 
-		// Scenarios
-		r.Get("/scenarios", scenariosHandler.GetScenarios)
+	// Admin routes (аутентификация, постоянный пароль и роль администратора)
+	router.Route("/admin", func(admin chi.Router) {
+		admin.Use(authMiddleware)
+		admin.Use(handlers.PasswordChangeMiddleware)
+		admin.Use(handlers.AdminMiddleware(r.userRepo, r.authz))
 
-		// Config
-		r.Get("/config/limits", limitsHandler.GetLimits)
+		admin.Get("/users", usersHandler.ListUsers)
+		admin.Post("/users", usersHandler.CreateUser)
+		admin.Get("/users/{user_id}", usersHandler.GetUser)
+		admin.Put("/users/{user_id}/status", usersHandler.UpdateUserStatus)
+		admin.Post("/users/{user_id}/logout", usersHandler.RevokeSessions)
+		admin.Post("/users/{user_id}/password", usersHandler.ResetPassword)
+		admin.Put("/users/{user_id}/quota", usageHandler.UpdateQuota)
 	})
 
 	return router
@@ -0,0 +1,250 @@
+package http
+
+import (
+	"backend/internal/domain"
+	"backend/internal/fakes"
+	"backend/internal/usecase/authz"
+	"backend/internal/usecase/export"
+	"backend/internal/usecase/importer"
+	"backend/internal/usecase/jobs"
+	"backend/internal/usecase/llm"
+	"backend/internal/usecase/org"
+	"backend/internal/usecase/share"
+	"backend/internal/usecase/usage"
+	"backend/internal/usecase/users"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+var (
+	// me - пользователь, которого подставляет заглушка AuthMiddleware
+	me = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	// foreignOrg - организация, в которой me не состоит
+	foreignOrg = uuid.MustParse("7d1f3c2a-5b6e-4f80-9a1b-2c3d4e5f6a7b")
+)
+
+type fakeSearchRepo struct{}
+
+func (fakeSearchRepo) Search(context.Context, domain.ChatScope, string, int, int) ([]*domain.ChatSearchHit, error) {
+	return nil, nil
+}
+
+// fixture - данные другого пользователя, к которым у me не должно быть доступа
+type fixture struct {
+	users       *fakes.UserRepo
+	ownChat     *domain.Chat
+	foreignChat *domain.Chat
+	foreignMsg  *domain.Message
+	shareID     uuid.UUID
+	jobID       uuid.UUID
+	other       uuid.UUID
+}
+
+func newTestRouter(t *testing.T) (*chi.Mux, *fixture) {
+	t.Helper()
+
+	other := uuid.New()
+	f := &fixture{
+		users: fakes.NewUserRepo(
+			&domain.User{ID: me, Email: "me@example.com", IsActive: true, Role: domain.UserRoleUser},
+			&domain.User{ID: other, Email: "other@example.com", IsActive: true, Role: domain.UserRoleUser},
+		),
+		ownChat:     &domain.Chat{ID: uuid.New(), UserID: me, Title: "Мой"},
+		foreignChat: &domain.Chat{ID: uuid.New(), UserID: other, Title: "Чужой"},
+		shareID:     uuid.New(),
+		jobID:       uuid.New(),
+		other:       other,
+	}
+	f.foreignMsg = &domain.Message{ID: uuid.New(), ChatID: f.foreignChat.ID, Role: string(domain.RoleUser), Content: "вопрос"}
+
+	chatRepo := fakes.NewChatRepo(f.ownChat, f.foreignChat)
+	msgRepo := fakes.NewMessageRepo(chatRepo)
+	msgRepo.AppendBranch(f.foreignChat, f.foreignMsg)
+	shareRepo := &fakes.ShareRepo{Shares: []*domain.ChatShare{{ID: f.shareID, ChatID: f.foreignChat.ID, UserID: other}}}
+	jobRepo := &fakes.JobRepo{Jobs: []*domain.Job{{ID: f.jobID, UserID: other, Kind: jobs.KindReply}}}
+	// me не состоит ни в одной организации
+	orgRepo := fakes.NewOrgRepo()
+	tx := fakes.NewTx()
+
+	az, err := authz.NewService(orgRepo)
+	must(t, err)
+	limits := &domain.Limits{}
+	llmService, err := llm.NewChatService(chatRepo, msgRepo, struct{ domain.LLM }{}, limits, llm.WithAuthorizer(az))
+	must(t, err)
+	exporter, err := export.NewExporter(chatRepo, msgRepo, export.WithAuthorizer(az))
+	must(t, err)
+	imp, err := importer.NewImporter(chatRepo, msgRepo, tx, importer.Limits{MaxBytes: 1 << 20, MaxChats: 10, MaxMessages: 100})
+	must(t, err)
+	shareService, err := share.NewService(shareRepo, chatRepo, msgRepo, az)
+	must(t, err)
+	orgService, err := org.NewService(orgRepo, &fakes.InviteRepo{}, f.users, &fakes.Mailer{}, tx, az)
+	must(t, err)
+	usageService, err := usage.NewService(fakes.NewUsageRepo(), &domain.Quota{})
+	must(t, err)
+	userService, err := users.NewService(f.users, usageService, tx)
+	must(t, err)
+	queue, err := jobs.NewQueue(jobRepo)
+	must(t, err)
+
+	router := NewRouter(chatRepo, msgRepo, f.users, fakeSearchRepo{}, llmService, exporter, imp, shareService,
+		orgService, az, usageService, userService, nil, nil, queue, *limits, nil)
+	return router.SetupRoutes(), f
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRouter_Authorization проходит по каждому маршруту SetupRoutes: публичные отвечают без прав,
+// защищённые пускают к своим данным и не пускают к чужим чатам, сообщениям, ссылкам,
+// организациям и задачам, административные - только к администраторам
+func TestRouter_Authorization(t *testing.T) {
+	type routeCase struct {
+		name    string
+		method  string
+		pattern string // маршрут как он зарегистрирован в chi
+		path    func(f *fixture) string
+		body    string
+		// role - роль me в приложении на время запроса; пустая - обычный пользователь
+		role     domain.UserRole
+		inactive bool
+		want     int
+	}
+
+	path := func(format string, args ...func(f *fixture) string) func(f *fixture) string {
+		return func(f *fixture) string {
+			values := make([]any, len(args))
+			for i, arg := range args {
+				values[i] = arg(f)
+			}
+			return fmt.Sprintf(format, values...)
+		}
+	}
+	ownChat := func(f *fixture) string { return f.ownChat.ID.String() }
+	foreignChat := func(f *fixture) string { return f.foreignChat.ID.String() }
+	foreignMsg := func(f *fixture) string { return f.foreignMsg.ID.String() }
+	foreignOrgID := func(*fixture) string { return foreignOrg.String() }
+	foreignShare := func(f *fixture) string { return f.shareID.String() }
+	foreignJob := func(f *fixture) string { return f.jobID.String() }
+	other := func(f *fixture) string { return f.other.String() }
+
+	tests := []routeCase{
+		// Публичные
+		{name: "health", method: http.MethodGet, pattern: "/health", path: path("/health"), want: http.StatusOK},
+		{name: "liveness", method: http.MethodGet, pattern: "/health/live", path: path("/health/live"), want: http.StatusOK},
+		{name: "readiness", method: http.MethodGet, pattern: "/health/ready", path: path("/health/ready"), want: http.StatusOK},
+		{name: "login without body", method: http.MethodPost, pattern: "/login", path: path("/login"), want: http.StatusBadRequest},
+		{name: "unknown share link", method: http.MethodGet, pattern: "/shared/{token}", path: path("/shared/abc"), want: http.StatusNotFound},
+
+		// Чаты
+		{name: "own chats", method: http.MethodGet, pattern: "/chats", path: path("/chats"), want: http.StatusOK},
+		{name: "chats of foreign org", method: http.MethodGet, pattern: "/chats", path: path("/chats?org_id=%s", foreignOrgID), want: http.StatusNotFound},
+		{name: "create personal chat", method: http.MethodPost, pattern: "/chats", path: path("/chats"), body: `{"title":"Новый"}`, want: http.StatusCreated},
+		{name: "create chat in foreign org", method: http.MethodPost, pattern: "/chats", path: path("/chats"), body: fmt.Sprintf(`{"org_id":%q}`, foreignOrg), want: http.StatusNotFound},
+		{name: "import into own space", method: http.MethodPost, pattern: "/chats/import", path: path("/chats/import"), body: `[]`, want: http.StatusUnprocessableEntity},
+		{name: "export foreign chat", method: http.MethodGet, pattern: "/chats/{chat_id}/export", path: path("/chats/%s/export", foreignChat), want: http.StatusForbidden},
+
+		// Ссылки
+		{name: "share foreign chat", method: http.MethodPost, pattern: "/chats/{chat_id}/share", path: path("/chats/%s/share", foreignChat), body: `{}`, want: http.StatusForbidden},
+		{name: "list shares of foreign chat", method: http.MethodGet, pattern: "/chats/{chat_id}/shares", path: path("/chats/%s/shares", foreignChat), want: http.StatusForbidden},
+		{name: "revoke foreign share", method: http.MethodDelete, pattern: "/shares/{share_id}", path: path("/shares/%s", foreignShare), want: http.StatusForbidden},
+
+		// Организации
+		{name: "own orgs", method: http.MethodGet, pattern: "/orgs", path: path("/orgs"), want: http.StatusOK},
+		{name: "create org", method: http.MethodPost, pattern: "/orgs", path: path("/orgs"), body: `{"name":"Кофейня"}`, want: http.StatusCreated},
+		{name: "members of foreign org", method: http.MethodGet, pattern: "/orgs/{org_id}/members", path: path("/orgs/%s/members", foreignOrgID), want: http.StatusNotFound},
+		{name: "update member of foreign org", method: http.MethodPut, pattern: "/orgs/{org_id}/members/{user_id}", path: path("/orgs/%s/members/%s", foreignOrgID, other), body: `{"role":"owner"}`, want: http.StatusNotFound},
+		{name: "remove member of foreign org", method: http.MethodDelete, pattern: "/orgs/{org_id}/members/{user_id}", path: path("/orgs/%s/members/%s", foreignOrgID, other), want: http.StatusNotFound},
+		{name: "invites of foreign org", method: http.MethodGet, pattern: "/orgs/{org_id}/invites", path: path("/orgs/%s/invites", foreignOrgID), want: http.StatusNotFound},
+		{name: "invite to foreign org", method: http.MethodPost, pattern: "/orgs/{org_id}/invites", path: path("/orgs/%s/invites", foreignOrgID), body: `{"email":"a@example.com"}`, want: http.StatusNotFound},
+		{name: "accept unknown invite", method: http.MethodPost, pattern: "/invites/accept", path: path("/invites/accept"), body: `{"token":"abc"}`, want: http.StatusNotFound},
+
+		// Поиск ищет только по доступным чатам на уровне запроса к БД
+		{name: "search", method: http.MethodGet, pattern: "/search", path: path("/search?q=договор"), want: http.StatusOK},
+
+		// Сообщения
+		{name: "messages of own chat", method: http.MethodGet, pattern: "/chats/{chat_id}/messages", path: path("/chats/%s/messages", ownChat), want: http.StatusOK},
+		{name: "messages of foreign chat", method: http.MethodGet, pattern: "/chats/{chat_id}/messages", path: path("/chats/%s/messages", foreignChat), want: http.StatusForbidden},
+		{name: "send to foreign chat", method: http.MethodPost, pattern: "/chats/{chat_id}/messages", path: path("/chats/%s/messages", foreignChat), body: `{"content":"привет"}`, want: http.StatusForbidden},
+		{name: "send to foreign chat async", method: http.MethodPost, pattern: "/chats/{chat_id}/messages", path: path("/chats/%s/messages?async=true", foreignChat), body: `{"content":"привет"}`, want: http.StatusForbidden},
+		{name: "edit foreign message", method: http.MethodPut, pattern: "/messages/{message_id}", path: path("/messages/%s", foreignMsg), body: `{"content":"исправлено"}`, want: http.StatusForbidden},
+		{name: "regenerate foreign message", method: http.MethodPost, pattern: "/messages/{message_id}/regenerate", path: path("/messages/%s/regenerate", foreignMsg), want: http.StatusForbidden},
+		{name: "activate foreign branch", method: http.MethodPost, pattern: "/messages/{message_id}/activate", path: path("/messages/%s/activate", foreignMsg), want: http.StatusForbidden},
+
+		// Задачи
+		{name: "foreign job", method: http.MethodGet, pattern: "/jobs/{job_id}", path: path("/jobs/%s", foreignJob), want: http.StatusForbidden},
+		{name: "foreign job events", method: http.MethodGet, pattern: "/jobs/{job_id}/events", path: path("/jobs/%s/events", foreignJob), want: http.StatusForbidden},
+
+		// Справочники и своё потребление
+		{name: "scenarios", method: http.MethodGet, pattern: "/scenarios", path: path("/scenarios"), want: http.StatusOK},
+		{name: "limits", method: http.MethodGet, pattern: "/config/limits", path: path("/config/limits"), want: http.StatusOK},
+		{name: "own usage", method: http.MethodGet, pattern: "/me/usage", path: path("/me/usage"), want: http.StatusOK},
+		{name: "change own password to short one", method: http.MethodPut, pattern: "/me/password", path: path("/me/password"), body: `{"current_password":"x","new_password":"short"}`, want: http.StatusBadRequest},
+
+		// Администрирование
+		{name: "list users by user", method: http.MethodGet, pattern: "/admin/users", path: path("/admin/users"), want: http.StatusForbidden},
+		{name: "list users by admin", method: http.MethodGet, pattern: "/admin/users", path: path("/admin/users?q=example"), role: domain.UserRoleAdmin, want: http.StatusOK},
+		{name: "create user by user", method: http.MethodPost, pattern: "/admin/users", path: path("/admin/users"), body: `{"email":"new@example.com"}`, want: http.StatusForbidden},
+		{name: "create user by admin", method: http.MethodPost, pattern: "/admin/users", path: path("/admin/users"), body: `{"email":"new@example.com"}`, role: domain.UserRoleAdmin, want: http.StatusCreated},
+		{name: "get user by user", method: http.MethodGet, pattern: "/admin/users/{user_id}", path: path("/admin/users/%s", other), want: http.StatusForbidden},
+		{name: "get user by admin", method: http.MethodGet, pattern: "/admin/users/{user_id}", path: path("/admin/users/%s", other), role: domain.UserRoleAdmin, want: http.StatusOK},
+		{name: "deactivate user by user", method: http.MethodPut, pattern: "/admin/users/{user_id}/status", path: path("/admin/users/%s/status", other), body: `{"is_active":false}`, want: http.StatusForbidden},
+		{name: "deactivate user by admin", method: http.MethodPut, pattern: "/admin/users/{user_id}/status", path: path("/admin/users/%s/status", other), body: `{"is_active":false}`, role: domain.UserRoleAdmin, want: http.StatusNoContent},
+		{name: "logout user by user", method: http.MethodPost, pattern: "/admin/users/{user_id}/logout", path: path("/admin/users/%s/logout", other), want: http.StatusForbidden},
+		{name: "logout user by admin", method: http.MethodPost, pattern: "/admin/users/{user_id}/logout", path: path("/admin/users/%s/logout", other), role: domain.UserRoleAdmin, want: http.StatusNoContent},
+		{name: "reset password by user", method: http.MethodPost, pattern: "/admin/users/{user_id}/password", path: path("/admin/users/%s/password", other), want: http.StatusForbidden},
+		{name: "reset password by admin", method: http.MethodPost, pattern: "/admin/users/{user_id}/password", path: path("/admin/users/%s/password", other), role: domain.UserRoleAdmin, want: http.StatusOK},
+		{name: "quota by user", method: http.MethodPut, pattern: "/admin/users/{user_id}/quota", path: path("/admin/users/%s/quota", other), body: `{}`, want: http.StatusForbidden},
+		{name: "quota by inactive admin", method: http.MethodPut, pattern: "/admin/users/{user_id}/quota", path: path("/admin/users/%s/quota", other), body: `{}`, role: domain.UserRoleAdmin, inactive: true, want: http.StatusForbidden},
+		{name: "quota by admin", method: http.MethodPut, pattern: "/admin/users/{user_id}/quota", path: path("/admin/users/%s/quota", other), body: `{}`, role: domain.UserRoleAdmin, want: http.StatusOK},
+	}
+
+	router, f := newTestRouter(t)
+
+	// Каждый маршрут роутера должен быть покрыт хотя бы одним случаем
+	covered := make(map[string]bool)
+	for _, tt := range tests {
+		covered[tt.method+" "+tt.pattern] = true
+	}
+	err := chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if !covered[method+" "+route] && !covered["* "+route] {
+			t.Errorf("route %s %s has no authorization case", method, route)
+		}
+		return nil
+	})
+	must(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := f.users.Users[me]
+			user.Role, user.IsActive = domain.UserRoleUser, !tt.inactive
+			if tt.role != "" {
+				user.Role = tt.role
+			}
+
+			method := tt.method
+			if method == "*" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, tt.path(f), strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("%s %s: status = %d, want %d: %s", method, tt.path(f), rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
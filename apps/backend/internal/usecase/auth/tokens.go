@@ -0,0 +1,131 @@
+// Package auth выпускает и проверяет токены доступа: JWT с подписью HS256
+package auth
+
+import (
+	"backend/internal/domain"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultTokenTTL - срок действия токена
+	DefaultTokenTTL = 24 * time.Hour
+	// MinSecretLen - самый короткий ключ подписи: 256 бит, как у HS256
+	MinSecretLen = 32
+)
+
+// ErrInvalidToken - токен не разобран, подпись не сошлась или срок истёк
+var ErrInvalidToken = errors.New("invalid token")
+
+// header - заголовок всех выпускаемых токенов; другие алгоритмы не принимаются
+var header = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// Claims - утверждения токена
+type Claims struct {
+	UserID uuid.UUID
+	// Role - роль в приложении на момент выпуска токена; только для клиента,
+	// права проверяются по текущей роли пользователя
+	Role domain.UserRole
+	// Version - domain.User.TokenVersion на момент выпуска; при расхождении токен отозван
+	Version   int
+	ExpiresAt time.Time
+}
+
+type payload struct {
+	Sub  string `json:"sub"`
+	Role string `json:"role"`
+	Ver  int    `json:"ver"`
+	Iat  int64  `json:"iat"`
+	Exp  int64  `json:"exp"`
+}
+
+type Tokens struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+func NewTokens(secret []byte, ttl time.Duration) (*Tokens, error) {
+	if len(secret) < MinSecretLen {
+		return nil, fmt.Errorf("token secret should be at least %d bytes", MinSecretLen)
+	}
+	if ttl <= 0 {
+		ttl = DefaultTokenTTL
+	}
+
+	return &Tokens{
+		secret: secret,
+		ttl:    ttl,
+		now:    time.Now,
+	}, nil
+}
+
+// Issue выпускает токен пользователя с его ролью и версией токенов
+func (t *Tokens) Issue(user *domain.User) (string, error) {
+	now := t.now()
+	body, err := json.Marshal(payload{
+		Sub:  user.ID.String(),
+		Role: string(user.Role),
+		Ver:  user.TokenVersion,
+		Iat:  now.Unix(),
+		Exp:  now.Add(t.ttl).Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode token: %w", err)
+	}
+
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(body)
+	return unsigned + "." + t.sign(unsigned), nil
+}
+
+// Parse проверяет подпись и срок действия токена и возвращает его утверждения.
+// Отзыв токена (Claims.Version) проверяет вызывающий код по данным пользователя
+func (t *Tokens) Parse(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != header {
+		return nil, ErrInvalidToken
+	}
+	unsigned := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(t.sign(unsigned))) {
+		return nil, ErrInvalidToken
+	}
+
+	body, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var p payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, ErrInvalidToken
+	}
+	userID, err := uuid.Parse(p.Sub)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	expiresAt := time.Unix(p.Exp, 0)
+	if !t.now().Before(expiresAt) {
+		return nil, fmt.Errorf("%w: expired", ErrInvalidToken)
+	}
+
+	return &Claims{
+		UserID:    userID,
+		Role:      domain.UserRole(p.Role),
+		Version:   p.Ver,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+func (t *Tokens) sign(unsigned string) string {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
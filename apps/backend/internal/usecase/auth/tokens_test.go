@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"backend/internal/domain"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func TestTokens_IssueParse(t *testing.T) {
+	tokens, err := NewTokens(testSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("NewTokens() error = %v", err)
+	}
+	user := &domain.User{ID: uuid.New(), Role: domain.UserRoleAdmin, TokenVersion: 3}
+
+	token, err := tokens.Issue(user)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	claims, err := tokens.Parse(token)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if claims.UserID != user.ID || claims.Role != domain.UserRoleAdmin || claims.Version != 3 {
+		t.Fatalf("claims = %+v", claims)
+	}
+}
+
+func TestTokens_Invalid(t *testing.T) {
+	tokens, err := NewTokens(testSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("NewTokens() error = %v", err)
+	}
+	other, err := NewTokens([]byte(strings.Repeat("x", MinSecretLen)), time.Hour)
+	if err != nil {
+		t.Fatalf("NewTokens() error = %v", err)
+	}
+	user := &domain.User{ID: uuid.New(), Role: domain.UserRoleUser}
+
+	valid, err := tokens.Issue(user)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	foreign, err := other.Issue(user)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+
+	// Подменённая роль в теле токена ломает подпись
+	parts := strings.Split(valid, ".")
+	forgedBody := strings.Replace(string(mustDecode(t, parts[1])), `"role":"user"`, `"role":"admin"`, 1)
+	forged := parts[0] + "." + encode(forgedBody) + "." + parts[2]
+
+	unsigned := encode(`{"alg":"none","typ":"JWT"}`) + "." + parts[1] + "."
+
+	tests := map[string]string{
+		"empty":          "",
+		"garbage":        "abc",
+		"other secret":   foreign,
+		"forged role":    forged,
+		"alg none":       unsigned,
+		"truncated sign": valid[:len(valid)-2],
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := tokens.Parse(token); !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("Parse() error = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+
+	tokens.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, err := tokens.Parse(valid); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("Parse() of expired token error = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestNewTokens_ShortSecret(t *testing.T) {
+	if _, err := NewTokens([]byte("short"), time.Hour); err == nil {
+		t.Fatal("NewTokens() with short secret error = nil")
+	}
+}
+
+func encode(s string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func mustDecode(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatalf("decode %q: %v", s, err)
+	}
+	return b
+}
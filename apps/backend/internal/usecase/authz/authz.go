@@ -0,0 +1,371 @@
+// Package authz - единый слой авторизации: может ли субъект выполнить действие над ресурсом.
+// Правила собраны в таблице политик по действиям; обработчики и сценарии не сравнивают
+// владельцев сами, а спрашивают Can. Личным ресурсом распоряжается только его автор,
+// ресурсом организации - участники по своим ролям, административными действиями - администраторы
+package authz
+
+import (
+	"backend/internal/domain"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Action - действие над ресурсом
+type Action string
+
+const (
+	// ChatRead - читать чат и его историю, выгружать
+	ChatRead Action = "chat.read"
+	// ChatWrite - отправлять сообщения в чат
+	ChatWrite Action = "chat.write"
+	// ChatManage - делиться чатом и отзывать ссылки
+	ChatManage Action = "chat.manage"
+	// ChatCreate - создавать чат в пространстве (Workspace)
+	ChatCreate Action = "chat.create"
+
+	// MessageRead - читать сообщение
+	MessageRead Action = "message.read"
+	// MessageWrite - редактировать, перегенерировать сообщение и переключать на него ветку
+	MessageWrite Action = "message.write"
+
+	// DocumentRead - читать документ и использовать его в ответах
+	DocumentRead Action = "document.read"
+	// DocumentWrite - загружать документ в пространство
+	DocumentWrite Action = "document.write"
+	// DocumentManage - удалять документ
+	DocumentManage Action = "document.manage"
+
+	// OrgRead - видеть организацию и её участников, выходить из неё
+	OrgRead Action = "org.read"
+	// OrgManage - управлять участниками и приглашениями
+	OrgManage Action = "org.manage"
+
+	// AdminAccess - административные действия над приложением (квоты, пользователи)
+	AdminAccess Action = "admin.access"
+)
+
+// Subject - тот, кто выполняет действие
+type Subject struct {
+	UserID uuid.UUID
+	// Role - роль в приложении; нужна только административным политикам,
+	// поэтому для обычных запросов может быть пустой
+	Role domain.UserRole
+}
+
+// User - субъект-пользователь без роли в приложении
+func User(userID uuid.UUID) Subject {
+	return Subject{UserID: userID}
+}
+
+// Resource - объект проверки; создаётся функциями Chat, Message, Document, Org, Workspace и System
+type Resource interface {
+	kind() string
+}
+
+type chatResource struct{ chat *domain.Chat }
+
+func (chatResource) kind() string { return "chat" }
+
+// Chat - чат как ресурс
+func Chat(chat *domain.Chat) Resource {
+	return chatResource{chat: chat}
+}
+
+type messageResource struct {
+	msg  *domain.Message
+	chat *domain.Chat
+}
+
+func (messageResource) kind() string { return "message" }
+
+// Message - сообщение как ресурс; права на него определяются чатом, которому оно принадлежит
+func Message(msg *domain.Message, chat *domain.Chat) Resource {
+	return messageResource{msg: msg, chat: chat}
+}
+
+type documentResource struct{ doc *domain.Document }
+
+func (documentResource) kind() string { return "document" }
+
+// Document - документ как ресурс
+func Document(doc *domain.Document) Resource {
+	return documentResource{doc: doc}
+}
+
+type orgResource struct{ orgID uuid.UUID }
+
+func (orgResource) kind() string { return "org" }
+
+// Org - организация как ресурс
+func Org(orgID uuid.UUID) Resource {
+	return orgResource{orgID: orgID}
+}
+
+type workspaceResource struct{ orgID *uuid.UUID }
+
+func (workspaceResource) kind() string { return "workspace" }
+
+// Workspace - пространство, в котором создаётся ресурс: личное (orgID = nil) или организации
+func Workspace(orgID *uuid.UUID) Resource {
+	return workspaceResource{orgID: orgID}
+}
+
+type systemResource struct{}
+
+func (systemResource) kind() string { return "system" }
+
+// System - приложение целиком, ресурс административных действий
+var System Resource = systemResource{}
+
+// level - уровень доступа к ресурсу организации
+type level int
+
+const (
+	read level = iota
+	write
+	manage
+)
+
+func (l level) String() string {
+	switch l {
+	case read:
+		return "read"
+	case write:
+		return "write"
+	case manage:
+		return "manage"
+	}
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// roleLevel - наибольший уровень доступа роли к ресурсам организации
+var roleLevel = map[domain.OrgRole]level{
+	domain.OrgRoleViewer: read,
+	domain.OrgRoleMember: write,
+	domain.OrgRoleOwner:  manage,
+}
+
+// policy решает, может ли субъект выполнить действие над ресурсом
+type policy func(ctx context.Context, s *Service, sub Subject, res Resource) error
+
+// policies - политика каждого действия; действие без политики запрещено
+var policies = map[Action]policy{
+	ChatRead:   chatPolicy(read),
+	ChatWrite:  chatPolicy(write),
+	ChatManage: chatPolicy(manage),
+	ChatCreate: workspacePolicy(write),
+
+	MessageRead:  messagePolicy(read),
+	MessageWrite: messagePolicy(write),
+
+	DocumentRead:   documentPolicy(read),
+	DocumentWrite:  workspacePolicy(write),
+	DocumentManage: documentPolicy(manage),
+
+	OrgRead:   orgPolicy(read),
+	OrgManage: orgPolicy(manage),
+
+	AdminAccess: adminPolicy,
+}
+
+type Service struct {
+	// orgs - nil, если организации не подключены: тогда доступны только личные ресурсы
+	orgs domain.OrgRepo
+}
+
+func NewService(orgs domain.OrgRepo) (*Service, error) {
+	if orgs == nil {
+		return nil, errors.New("org repo should be provided")
+	}
+	return &Service{orgs: orgs}, nil
+}
+
+// OwnerOnly - проверка без организаций: доступ к ресурсу есть только у его автора,
+// ресурсы организаций недоступны никому
+func OwnerOnly() *Service {
+	return &Service{}
+}
+
+// Can возвращает nil, если субъект может выполнить действие над ресурсом, иначе ошибку
+// с domain.ErrAccessDenied. Для действий над организацией не участнику возвращается
+// domain.ErrOrgNotFound: существование чужой организации не раскрывается
+func (s *Service) Can(ctx context.Context, sub Subject, action Action, res Resource) error {
+	p, ok := policies[action]
+	if !ok {
+		return fmt.Errorf("%w: unknown action %q", domain.ErrAccessDenied, action)
+	}
+	if sub.UserID == uuid.Nil {
+		return fmt.Errorf("%w: anonymous subject", domain.ErrAccessDenied)
+	}
+	if res == nil {
+		return fmt.Errorf("%w: no resource for %s", domain.ErrAccessDenied, action)
+	}
+	return p(ctx, s, sub, res)
+}
+
+// ChatScope перечисляет пространства, в которых субъект может выполнить действие над любым чатом, -
+// для выборок по многим чатам сразу (поиск). Каждое пространство проверяется через Can на чате
+// другого автора, поэтому правила те же, что у проверки одного чата. Права автора на свои чаты
+// в организации сверх роли сюда не входят
+func (s *Service) ChatScope(ctx context.Context, sub Subject, action Action) (domain.ChatScope, error) {
+	var scope domain.ChatScope
+	if err := s.Can(ctx, sub, action, Chat(&domain.Chat{UserID: sub.UserID})); err == nil {
+		scope.UserID = sub.UserID
+	} else if !errors.Is(err, domain.ErrAccessDenied) {
+		return domain.ChatScope{}, err
+	}
+	if s.orgs == nil {
+		return scope, nil
+	}
+
+	orgs, err := s.orgs.ListByUser(ctx, sub.UserID)
+	if err != nil {
+		return domain.ChatScope{}, fmt.Errorf("failed to list user orgs: %w", err)
+	}
+	for _, org := range orgs {
+		err := s.Can(ctx, sub, action, Chat(&domain.Chat{OrgID: &org.ID}))
+		if errors.Is(err, domain.ErrAccessDenied) {
+			continue
+		}
+		if err != nil {
+			return domain.ChatScope{}, err
+		}
+		scope.OrgIDs = append(scope.OrgIDs, org.ID)
+	}
+	return scope, nil
+}
+
+// chatPolicy - личный чат доступен только автору, чат организации - по роли.
+// Автор чата организации управляет им, пока состоит в ней с правом записи
+func chatPolicy(need level) policy {
+	return func(ctx context.Context, s *Service, sub Subject, res Resource) error {
+		r, ok := res.(chatResource)
+		if !ok || r.chat == nil {
+			return resourceMismatch("chat", res)
+		}
+		return s.owned(ctx, sub, "chat", r.chat.UserID, r.chat.OrgID, need)
+	}
+}
+
+// messagePolicy - права на сообщение равны правам на его чат
+func messagePolicy(need level) policy {
+	return func(ctx context.Context, s *Service, sub Subject, res Resource) error {
+		r, ok := res.(messageResource)
+		if !ok || r.msg == nil || r.chat == nil {
+			return resourceMismatch("message", res)
+		}
+		if r.msg.ChatID != r.chat.ID {
+			return fmt.Errorf("%w: message belongs to different chat", domain.ErrAccessDenied)
+		}
+		return s.owned(ctx, sub, "chat", r.chat.UserID, r.chat.OrgID, need)
+	}
+}
+
+// documentPolicy - документ доступен по тем же правилам, что и чат
+func documentPolicy(need level) policy {
+	return func(ctx context.Context, s *Service, sub Subject, res Resource) error {
+		r, ok := res.(documentResource)
+		if !ok || r.doc == nil {
+			return resourceMismatch("document", res)
+		}
+
+		authorID, err := uuid.Parse(r.doc.UserID)
+		if err != nil {
+			return fmt.Errorf("%w: invalid document user id", domain.ErrAccessDenied)
+		}
+		var orgID *uuid.UUID
+		if r.doc.OrgID != nil {
+			id, err := uuid.Parse(*r.doc.OrgID)
+			if err != nil {
+				return fmt.Errorf("%w: invalid document org id", domain.ErrAccessDenied)
+			}
+			orgID = &id
+		}
+		return s.owned(ctx, sub, "document", authorID, orgID, need)
+	}
+}
+
+// workspacePolicy - в личном пространстве создаёт любой пользователь, в организации - участник с уровнем need
+func workspacePolicy(need level) policy {
+	return func(ctx context.Context, s *Service, sub Subject, res Resource) error {
+		r, ok := res.(workspaceResource)
+		if !ok {
+			return resourceMismatch("workspace", res)
+		}
+		if r.orgID == nil {
+			return nil
+		}
+		return s.member(ctx, sub, *r.orgID, need)
+	}
+}
+
+// orgPolicy - действие доступно участнику организации с уровнем need
+func orgPolicy(need level) policy {
+	return func(ctx context.Context, s *Service, sub Subject, res Resource) error {
+		r, ok := res.(orgResource)
+		if !ok {
+			return resourceMismatch("org", res)
+		}
+		return s.member(ctx, sub, r.orgID, need)
+	}
+}
+
+// adminPolicy - административные действия доступны только администраторам.
+// Роль администратора не даёт доступа к чужим чатам и документам
+func adminPolicy(_ context.Context, _ *Service, sub Subject, res Resource) error {
+	if _, ok := res.(systemResource); !ok {
+		return resourceMismatch("system", res)
+	}
+	if sub.Role != domain.UserRoleAdmin {
+		return fmt.Errorf("%w: admin role required", domain.ErrAccessDenied)
+	}
+	return nil
+}
+
+// owned проверяет право на ресурс с автором authorID, лежащий в личном пространстве или в организации orgID
+func (s *Service) owned(ctx context.Context, sub Subject, resource string, authorID uuid.UUID, orgID *uuid.UUID, need level) error {
+	if orgID == nil {
+		if authorID != sub.UserID {
+			return fmt.Errorf("%w: %s belongs to different user", domain.ErrAccessDenied, resource)
+		}
+		return nil
+	}
+
+	if need == manage && authorID == sub.UserID {
+		need = write
+	}
+	err := s.member(ctx, sub, *orgID, need)
+	if errors.Is(err, domain.ErrOrgNotFound) {
+		// Не участник получает отказ в доступе, как и к чужому личному ресурсу
+		return fmt.Errorf("%w: %s belongs to organization user is not member of", domain.ErrAccessDenied, resource)
+	}
+	return err
+}
+
+// member проверяет, что роль субъекта в организации даёт уровень need
+func (s *Service) member(ctx context.Context, sub Subject, orgID uuid.UUID, need level) error {
+	if s.orgs == nil {
+		return domain.ErrOrgNotFound
+	}
+
+	m, err := s.orgs.GetMembership(ctx, orgID, sub.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get membership: %w", err)
+	}
+	if m == nil {
+		return domain.ErrOrgNotFound
+	}
+	if granted, ok := roleLevel[m.Role]; !ok || granted < need {
+		return fmt.Errorf("%w: role %s has no %s access", domain.ErrAccessDenied, m.Role, need)
+	}
+	return nil
+}
+
+// resourceMismatch - политика действия получила ресурс другого вида; это ошибка вызывающего кода,
+// но и она должна закрывать доступ
+func resourceMismatch(want string, res Resource) error {
+	return fmt.Errorf("%w: %s action applied to %s", domain.ErrAccessDenied, want, res.kind())
+}
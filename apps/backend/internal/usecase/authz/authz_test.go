@@ -0,0 +1,178 @@
+package authz
+
+import (
+	"backend/internal/domain"
+	"backend/internal/fakes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// newOrgRepo - организация orgID с участниками members
+func newOrgRepo(orgID uuid.UUID, members map[uuid.UUID]domain.OrgRole) *fakes.OrgRepo {
+	repo := fakes.NewOrgRepo()
+	repo.Orgs[orgID] = &domain.Org{ID: orgID}
+	for userID, role := range members {
+		repo.Members = append(repo.Members, &domain.Membership{OrgID: orgID, UserID: userID, Role: role})
+	}
+	return repo
+}
+
+func TestService_Can(t *testing.T) {
+	orgID := uuid.New()
+	orgIDStr := orgID.String()
+	author, owner, member, viewer, stranger := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	az, err := NewService(newOrgRepo(orgID, map[uuid.UUID]domain.OrgRole{
+		author: domain.OrgRoleMember,
+		owner:  domain.OrgRoleOwner,
+		member: domain.OrgRoleMember,
+		viewer: domain.OrgRoleViewer,
+	}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	personal := &domain.Chat{ID: uuid.New(), UserID: author}
+	shared := &domain.Chat{ID: uuid.New(), UserID: author, OrgID: &orgID}
+	sharedMsg := &domain.Message{ID: uuid.New(), ChatID: shared.ID}
+	orgDoc := &domain.Document{ID: uuid.NewString(), UserID: author.String(), OrgID: &orgIDStr}
+	personalDoc := &domain.Document{ID: uuid.NewString(), UserID: author.String()}
+
+	tests := []struct {
+		name     string
+		subject  Subject
+		action   Action
+		resource Resource
+		wantErr  error
+	}{
+		{name: "personal chat: author manages", subject: User(author), action: ChatManage, resource: Chat(personal)},
+		{name: "personal chat: org owner can't read", subject: User(owner), action: ChatRead, resource: Chat(personal), wantErr: domain.ErrAccessDenied},
+		{name: "personal chat: admin can't read", subject: Subject{UserID: owner, Role: domain.UserRoleAdmin}, action: ChatRead, resource: Chat(personal), wantErr: domain.ErrAccessDenied},
+		{name: "org chat: author manages", subject: User(author), action: ChatManage, resource: Chat(shared)},
+		{name: "org chat: owner manages", subject: User(owner), action: ChatManage, resource: Chat(shared)},
+		{name: "org chat: member writes", subject: User(member), action: ChatWrite, resource: Chat(shared)},
+		{name: "org chat: member can't manage others' chat", subject: User(member), action: ChatManage, resource: Chat(shared), wantErr: domain.ErrAccessDenied},
+		{name: "org chat: viewer reads", subject: User(viewer), action: ChatRead, resource: Chat(shared)},
+		{name: "org chat: viewer can't write", subject: User(viewer), action: ChatWrite, resource: Chat(shared), wantErr: domain.ErrAccessDenied},
+		{name: "org chat: stranger can't read", subject: User(stranger), action: ChatRead, resource: Chat(shared), wantErr: domain.ErrAccessDenied},
+
+		{name: "create personal chat", subject: User(stranger), action: ChatCreate, resource: Workspace(nil)},
+		{name: "member creates org chat", subject: User(member), action: ChatCreate, resource: Workspace(&orgID)},
+		{name: "viewer can't create org chat", subject: User(viewer), action: ChatCreate, resource: Workspace(&orgID), wantErr: domain.ErrAccessDenied},
+		{name: "stranger can't create org chat", subject: User(stranger), action: ChatCreate, resource: Workspace(&orgID), wantErr: domain.ErrOrgNotFound},
+
+		{name: "message: member edits", subject: User(member), action: MessageWrite, resource: Message(sharedMsg, shared)},
+		{name: "message: viewer reads", subject: User(viewer), action: MessageRead, resource: Message(sharedMsg, shared)},
+		{name: "message: viewer can't edit", subject: User(viewer), action: MessageWrite, resource: Message(sharedMsg, shared), wantErr: domain.ErrAccessDenied},
+		{name: "message: checked against foreign chat", subject: User(author), action: MessageWrite, resource: Message(sharedMsg, personal), wantErr: domain.ErrAccessDenied},
+
+		{name: "org document: member reads", subject: User(member), action: DocumentRead, resource: Document(orgDoc)},
+		{name: "org document: member can't delete others'", subject: User(member), action: DocumentManage, resource: Document(orgDoc), wantErr: domain.ErrAccessDenied},
+		{name: "org document: author deletes", subject: User(author), action: DocumentManage, resource: Document(orgDoc)},
+		{name: "personal document: member can't read", subject: User(member), action: DocumentRead, resource: Document(personalDoc), wantErr: domain.ErrAccessDenied},
+		{name: "viewer can't upload to org", subject: User(viewer), action: DocumentWrite, resource: Workspace(&orgID), wantErr: domain.ErrAccessDenied},
+
+		{name: "org: viewer reads", subject: User(viewer), action: OrgRead, resource: Org(orgID)},
+		{name: "org: member can't manage", subject: User(member), action: OrgManage, resource: Org(orgID), wantErr: domain.ErrAccessDenied},
+		{name: "org: stranger doesn't see it", subject: User(stranger), action: OrgRead, resource: Org(orgID), wantErr: domain.ErrOrgNotFound},
+
+		{name: "admin", subject: Subject{UserID: stranger, Role: domain.UserRoleAdmin}, action: AdminAccess, resource: System},
+		{name: "not admin", subject: User(owner), action: AdminAccess, resource: System, wantErr: domain.ErrAccessDenied},
+
+		{name: "anonymous", subject: Subject{}, action: ChatCreate, resource: Workspace(nil), wantErr: domain.ErrAccessDenied},
+		{name: "unknown action", subject: User(author), action: "chat.delete", resource: Chat(personal), wantErr: domain.ErrAccessDenied},
+		{name: "resource of wrong kind", subject: User(author), action: ChatRead, resource: Org(orgID), wantErr: domain.ErrAccessDenied},
+		{name: "nil chat", subject: User(author), action: ChatRead, resource: Chat(nil), wantErr: domain.ErrAccessDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := az.Can(context.Background(), tt.subject, tt.action, tt.resource)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("Can() error = %v, want access", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Can() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOwnerOnly(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	userID := uuid.New()
+	az := OwnerOnly()
+
+	if err := az.Can(ctx, User(userID), ChatWrite, Chat(&domain.Chat{UserID: userID})); err != nil {
+		t.Fatalf("Can() for own chat error = %v", err)
+	}
+	if err := az.Can(ctx, User(userID), ChatRead, Chat(&domain.Chat{UserID: userID, OrgID: &orgID})); !errors.Is(err, domain.ErrAccessDenied) {
+		t.Fatalf("Can() for org chat error = %v, want ErrAccessDenied", err)
+	}
+	if err := az.Can(ctx, User(userID), ChatCreate, Workspace(&orgID)); !errors.Is(err, domain.ErrOrgNotFound) {
+		t.Fatalf("Can() create in org error = %v, want ErrOrgNotFound", err)
+	}
+}
+
+// Каждое действие должно иметь политику, иначе оно молча запрещено
+func TestPolicies_CoverActions(t *testing.T) {
+	actions := []Action{
+		ChatRead, ChatWrite, ChatManage, ChatCreate,
+		MessageRead, MessageWrite,
+		DocumentRead, DocumentWrite, DocumentManage,
+		OrgRead, OrgManage,
+		AdminAccess,
+	}
+	for _, action := range actions {
+		if _, ok := policies[action]; !ok {
+			t.Errorf("action %s has no policy", action)
+		}
+	}
+	if len(policies) != len(actions) {
+		t.Errorf("policies has %d actions, test lists %d", len(policies), len(actions))
+	}
+}
+
+func TestService_ChatScope(t *testing.T) {
+	member, viewer := uuid.New(), uuid.New()
+	az, err := NewService(newOrgRepo(uuid.New(), map[uuid.UUID]domain.OrgRole{
+		member: domain.OrgRoleMember,
+		viewer: domain.OrgRoleViewer,
+	}))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		userID   uuid.UUID
+		action   Action
+		wantOrgs int
+	}{
+		{name: "viewer reads org chats", userID: viewer, action: ChatRead, wantOrgs: 1},
+		{name: "viewer can't write org chats", userID: viewer, action: ChatWrite, wantOrgs: 0},
+		{name: "member writes org chats", userID: member, action: ChatWrite, wantOrgs: 1},
+		{name: "member can't manage others' chats", userID: member, action: ChatManage, wantOrgs: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scope, err := az.ChatScope(context.Background(), User(tt.userID), tt.action)
+			if err != nil {
+				t.Fatalf("ChatScope() error = %v", err)
+			}
+			if scope.UserID != tt.userID || len(scope.OrgIDs) != tt.wantOrgs {
+				t.Fatalf("scope = %+v, want own chats and %d orgs", scope, tt.wantOrgs)
+			}
+		})
+	}
+
+	// Без организаций доступны только личные чаты
+	scope, err := OwnerOnly().ChatScope(context.Background(), User(member), ChatRead)
+	if err != nil || scope.UserID != member || len(scope.OrgIDs) != 0 {
+		t.Fatalf("OwnerOnly scope = %+v, %v", scope, err)
+	}
+}
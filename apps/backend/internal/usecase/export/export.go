@@ -0,0 +1,202 @@
+package export
+
+import (
+	"backend/internal/domain"
+	"backend/internal/usecase/authz"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Форматы выгрузки
+const (
+	FormatMarkdown = "md"
+	FormatHTML     = "html"
+	FormatJSON     = "json"
+)
+
+// ErrUnknownFormat - формат выгрузки не поддерживается
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// defaultPageSize - сколько сообщений читается из базы за раз; в памяти держатся только ID ветки, а не чат целиком
+const defaultPageSize = 100
+
+// DocumentNamer возвращает имя файла документа для подписи вложений
+type DocumentNamer interface {
+	DocumentName(ctx context.Context, docID uuid.UUID) (string, error)
+}
+
+// renderer пишет выгрузку по частям: заголовок, сообщения по одному и окончание
+type renderer interface {
+	header(chat *domain.Chat, exportedAt time.Time) error
+	message(msg *Message) error
+	footer() error
+}
+
+type Exporter struct {
+	chatRepo domain.ChatRepo
+	msgRepo  domain.MessageRepo
+	docs     DocumentNamer
+	authz    *authz.Service
+	pageSize int
+	now      func() time.Time
+}
+
+type Option func(*Exporter)
+
+// WithDocumentNames - подписывать вложения именами файлов. Без него вложения подписываются ID
+func WithDocumentNames(docs DocumentNamer) Option {
+	return func(e *Exporter) {
+		e.docs = docs
+	}
+}
+
+// WithAuthorizer - проверять права с учётом ролей в организациях. Без него выгрузить можно только личный чат
+func WithAuthorizer(az *authz.Service) Option {
+	return func(e *Exporter) {
+		e.authz = az
+	}
+}
+
+// WithPageSize - размер страницы при чтении истории
+func WithPageSize(n int) Option {
+	return func(e *Exporter) {
+		if n > 0 {
+			e.pageSize = n
+		}
+	}
+}
+
+func NewExporter(chatRepo domain.ChatRepo, msgRepo domain.MessageRepo, opts ...Option) (*Exporter, error) {
+	if chatRepo == nil {
+		return nil, errors.New("chat repo should be provided")
+	}
+	if msgRepo == nil {
+		return nil, errors.New("message repo should be provided")
+	}
+
+	e := &Exporter{
+		chatRepo: chatRepo,
+		msgRepo:  msgRepo,
+		authz:    authz.OwnerOnly(),
+		pageSize: defaultPageSize,
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e, nil
+}
+
+// ValidFormat - поддерживается ли формат выгрузки
+func ValidFormat(format string) bool {
+	switch format {
+	case FormatMarkdown, FormatHTML, FormatJSON:
+		return true
+	}
+	return false
+}
+
+// ContentType - MIME-тип выгрузки в формате format
+func ContentType(format string) string {
+	switch format {
+	case FormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	case FormatHTML:
+		return "text/html; charset=utf-8"
+	default:
+		return "application/json"
+	}
+}
+
+// GetChat возвращает чат, доступный пользователю на чтение, для выгрузки. Проверка отделена от Write,
+// чтобы ошибку доступа можно было вернуть до начала потоковой записи
+func (e *Exporter) GetChat(ctx context.Context, userID, chatID uuid.UUID) (*domain.Chat, error) {
+	chat, err := e.chatRepo.GetByID(ctx, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat: %w", err)
+	}
+	if err := e.authz.Can(ctx, authz.User(userID), authz.ChatRead, authz.Chat(chat)); err != nil {
+		return nil, err
+	}
+	return chat, nil
+}
+
+// Write выгружает активную ветку чата в w. Ветка фиксируется один раз списком ID, а сообщения читаются
+// страницами по нему: переключение ветки или новый ответ во время выгрузки не сдвигают страницы
+func (e *Exporter) Write(ctx context.Context, w io.Writer, chat *domain.Chat, format string) error {
+	var r renderer
+	switch format {
+	case FormatMarkdown:
+		r = newMarkdownRenderer(w)
+	case FormatHTML:
+		r = newHTMLRenderer(w)
+	case FormatJSON:
+		r = newJSONRenderer(w)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+
+	if err := r.header(chat, e.now()); err != nil {
+		return err
+	}
+
+	var ids []uuid.UUID
+	if chat.ActiveMessageID != nil {
+		var err error
+		ids, err = e.msgRepo.GetBranchIDs(ctx, *chat.ActiveMessageID)
+		if err != nil {
+			return fmt.Errorf("failed to get chat branch: %w", err)
+		}
+	}
+
+	names := make(map[uuid.UUID]string)
+	for start := 0; start < len(ids); start += e.pageSize {
+		page, err := e.msgRepo.GetByIDs(ctx, ids[start:min(start+e.pageSize, len(ids))])
+		if err != nil {
+			return fmt.Errorf("failed to get messages: %w", err)
+		}
+
+		for _, msg := range page {
+			if err := r.message(e.toMessage(ctx, msg, names)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return r.footer()
+}
+
+// toMessage дополняет сообщение именами документов; names - кэш имён на время выгрузки
+func (e *Exporter) toMessage(ctx context.Context, msg *domain.Message, names map[uuid.UUID]string) *Message {
+	out := &Message{
+		ID:        msg.ID,
+		ParentID:  msg.ParentID,
+		Role:      msg.Role,
+		Content:   msg.Content,
+		CreatedAt: msg.CreatedAt,
+		Metadata:  msg.Metadata,
+	}
+
+	for _, docID := range msg.Metadata.DocumentIDs {
+		name, ok := names[docID]
+		if !ok {
+			name = docID.String()
+			if e.docs != nil {
+				// Документ мог быть удалён - тогда остаётся ID
+				if n, err := e.docs.DocumentName(ctx, docID); err == nil && n != "" {
+					name = n
+				}
+			}
+			names[docID] = name
+		}
+		out.Documents = append(out.Documents, Document{ID: docID, Name: name})
+	}
+
+	return out
+}
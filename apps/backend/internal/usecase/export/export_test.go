@@ -0,0 +1,165 @@
+package export
+
+import (
+	"backend/internal/domain"
+	"backend/internal/fakes"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// pagedMessageRepo считает страницы истории, прочитанные выгрузкой
+type pagedMessageRepo struct {
+	*fakes.MessageRepo
+	pages int
+	// onPage вызывается после чтения каждой страницы
+	onPage func()
+}
+
+func (f *pagedMessageRepo) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*domain.Message, error) {
+	f.pages++
+	out, err := f.MessageRepo.GetByIDs(ctx, ids)
+	if f.onPage != nil {
+		f.onPage()
+	}
+	return out, err
+}
+
+type fakeNamer map[uuid.UUID]string
+
+func (f fakeNamer) DocumentName(_ context.Context, docID uuid.UUID) (string, error) {
+	if name, ok := f[docID]; ok {
+		return name, nil
+	}
+	return "", errors.New("not found")
+}
+
+func newTestExporter(t *testing.T, messages []*domain.Message, opts ...Option) (*Exporter, *domain.Chat, *pagedMessageRepo) {
+	t.Helper()
+
+	chat := &domain.Chat{ID: uuid.New(), UserID: uuid.New(), Title: "Договор аренды", CreatedAt: time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)}
+	chatRepo := fakes.NewChatRepo(chat)
+	msgRepo := &pagedMessageRepo{MessageRepo: fakes.NewMessageRepo(chatRepo)}
+	msgRepo.AppendBranch(chat, messages...)
+	e, err := NewExporter(chatRepo, msgRepo, opts...)
+	if err != nil {
+		t.Fatalf("NewExporter() error = %v", err)
+	}
+	e.now = func() time.Time { return time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC) }
+	return e, chat, msgRepo
+}
+
+// dialog - вопрос с документом, вызов инструмента и ответ с источниками
+func dialog() ([]*domain.Message, uuid.UUID) {
+	docID := uuid.New()
+	at := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	question := &domain.Message{ID: uuid.New(), Role: "user", Content: "Проверь <b>пункт 5</b>", CreatedAt: at,
+		Metadata: domain.MessageMetadata{DocumentIDs: []uuid.UUID{docID}}}
+	call := &domain.Message{ID: uuid.New(), ParentID: &question.ID, Role: "assistant", CreatedAt: at,
+		Metadata: domain.MessageMetadata{ToolCalls: []domain.ToolCall{{Name: "calculator"}}}}
+	result := &domain.Message{ID: uuid.New(), ParentID: &call.ID, Role: "tool", Content: "1200", CreatedAt: at}
+	answer := &domain.Message{ID: uuid.New(), ParentID: &result.ID, Role: "assistant", Content: "Пункт 5 в порядке.", CreatedAt: at.Add(time.Minute),
+		Metadata: domain.MessageMetadata{Searches: []domain.SearchRecord{
+			{Query: "ГК РФ аренда", URLs: []string{"https://example.com/gk", "https://example.com/gk"}},
+		}}}
+	return []*domain.Message{question, call, result, answer}, docID
+}
+
+func TestExporter_Markdown(t *testing.T) {
+	messages, docID := dialog()
+	e, chat, _ := newTestExporter(t, messages, WithDocumentNames(fakeNamer{docID: "lease.pdf"}))
+
+	var buf bytes.Buffer
+	if err := e.Write(context.Background(), &buf, chat, FormatMarkdown); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"# Договор аренды",
+		"### Пользователь · 01.03.2026 09:30 UTC",
+		"> Документы: lease.pdf",
+		"### Ассистент · 01.03.2026 09:31 UTC\n\nПункт 5 в порядке.",
+		"1. <https://example.com/gk>\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("markdown has no %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "1200") || strings.Count(out, "https://example.com/gk") != 1 {
+		t.Errorf("tool steps or duplicate sources leak into markdown:\n%s", out)
+	}
+}
+
+func TestExporter_HTMLEscapesContent(t *testing.T) {
+	messages, docID := dialog()
+	e, chat, _ := newTestExporter(t, messages)
+
+	var buf bytes.Buffer
+	if err := e.Write(context.Background(), &buf, chat, FormatHTML); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	out := buf.String()
+
+	if strings.Contains(out, "<b>пункт 5</b>") || !strings.Contains(out, "&lt;b&gt;пункт 5&lt;/b&gt;") {
+		t.Errorf("message content is not escaped:\n%s", out)
+	}
+	// Без DocumentNamer вложение подписано ID
+	if !strings.Contains(out, "Документы: "+docID.String()) {
+		t.Errorf("document is not listed:\n%s", out)
+	}
+	if !strings.HasSuffix(out, "</html>\n") {
+		t.Errorf("html is not closed")
+	}
+}
+
+func TestExporter_JSONPagesThroughHistory(t *testing.T) {
+	var messages []*domain.Message
+	for i := range 250 {
+		messages = append(messages, &domain.Message{ID: uuid.New(), Role: "user", Content: fmt.Sprintf("сообщение %d", i)})
+	}
+	e, chat, msgRepo := newTestExporter(t, messages)
+	// Во время выгрузки в ветку добавляется сообщение: страницы не должны сдвинуться
+	msgRepo.onPage = func() {
+		if len(msgRepo.Messages) == 250 {
+			msgRepo.AppendBranch(chat, &domain.Message{ID: uuid.New(), Role: "user", Content: "новое"})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := e.Write(context.Background(), &buf, chat, FormatJSON); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	var file File
+	if err := json.Unmarshal(buf.Bytes(), &file); err != nil {
+		t.Fatalf("export is not valid JSON: %v", err)
+	}
+	if file.Format != FileFormat || file.Version != FileVersion || file.Chat.ID != chat.ID {
+		t.Fatalf("unexpected header: %+v", file)
+	}
+	if len(file.Messages) != 250 || file.Messages[100].Content != "сообщение 100" || file.Messages[249].Content != "сообщение 249" {
+		t.Fatalf("exported %d messages, want all 250", len(file.Messages))
+	}
+	if msgRepo.pages != 3 {
+		t.Fatalf("history read in %d pages, want 3", msgRepo.pages)
+	}
+}
+
+func TestExporter_GetChatChecksOwner(t *testing.T) {
+	e, chat, _ := newTestExporter(t, nil)
+
+	if _, err := e.GetChat(context.Background(), uuid.New(), chat.ID); !errors.Is(err, domain.ErrAccessDenied) {
+		t.Fatalf("error = %v, want ErrAccessDenied", err)
+	}
+	if err := e.Write(context.Background(), &bytes.Buffer{}, chat, "pdf"); !errors.Is(err, ErrUnknownFormat) {
+		t.Fatalf("error = %v, want ErrUnknownFormat", err)
+	}
+}
@@ -0,0 +1,270 @@
+package export
+
+import (
+	"backend/internal/domain"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Формат JSON-выгрузки. Его же принимает импорт чатов
+const (
+	FileFormat  = "alfa-copilot-chat"
+	FileVersion = 1
+)
+
+// File - JSON-выгрузка чата
+type File struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Chat       Chat      `json:"chat"`
+	Messages   []Message `json:"messages"`
+}
+
+type Chat struct {
+	ID        uuid.UUID `json:"id"`
+	Title     string    `json:"title"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Message struct {
+	ID        uuid.UUID              `json:"id"`
+	ParentID  *uuid.UUID             `json:"parent_id,omitempty"`
+	Role      string                 `json:"role"`
+	Content   string                 `json:"content"`
+	CreatedAt time.Time              `json:"created_at"`
+	Documents []Document             `json:"documents,omitempty"`
+	Metadata  domain.MessageMetadata `json:"metadata"`
+}
+
+// Document - документ, приложенный к вопросу
+type Document struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+// Sources - адреса веб-страниц, на которые опирался ответ, без повторов
+func (m *Message) Sources() []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, search := range m.Metadata.Searches {
+		for _, url := range search.URLs {
+			if !seen[url] {
+				seen[url] = true
+				out = append(out, url)
+			}
+		}
+	}
+	return out
+}
+
+// visible - сообщение показывается в документе для чтения. Вызовы инструментов
+// и их результаты - служебные шаги ответа, они остаются только в JSON
+func (m *Message) visible() bool {
+	if m.Role != string(domain.RoleUser) && m.Role != string(domain.RoleAssistant) {
+		return false
+	}
+	return strings.TrimSpace(m.Content) != ""
+}
+
+func roleTitle(role string) string {
+	switch domain.Role(role) {
+	case domain.RoleUser:
+		return "Пользователь"
+	case domain.RoleAssistant:
+		return "Ассистент"
+	case domain.RoleSystem:
+		return "Система"
+	case domain.RoleTool:
+		return "Инструмент"
+	}
+	return role
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("02.01.2006 15:04 UTC")
+}
+
+type markdownRenderer struct {
+	w *bufio.Writer
+}
+
+func newMarkdownRenderer(w io.Writer) *markdownRenderer {
+	return &markdownRenderer{w: bufio.NewWriter(w)}
+}
+
+func (r *markdownRenderer) header(chat *domain.Chat, exportedAt time.Time) error {
+	_, err := fmt.Fprintf(r.w, "# %s\n\n_Экспортировано %s_\n", chat.Title, formatTime(exportedAt))
+	return err
+}
+
+func (r *markdownRenderer) message(msg *Message) error {
+	if !msg.visible() {
+		return nil
+	}
+
+	fmt.Fprintf(r.w, "\n---\n\n### %s · %s\n\n%s\n", roleTitle(msg.Role), formatTime(msg.CreatedAt), strings.TrimSpace(msg.Content))
+
+	if len(msg.Documents) > 0 {
+		names := make([]string, len(msg.Documents))
+		for i, doc := range msg.Documents {
+			names[i] = doc.Name
+		}
+		fmt.Fprintf(r.w, "\n> Документы: %s\n", strings.Join(names, ", "))
+	}
+
+	if sources := msg.Sources(); len(sources) > 0 {
+		fmt.Fprint(r.w, "\n**Источники:**\n\n")
+		for i, url := range sources {
+			fmt.Fprintf(r.w, "%d. <%s>\n", i+1, url)
+		}
+	}
+
+	// Буфер сбрасываем по сообщению, чтобы длинный чат уходил клиенту по мере чтения
+	return r.w.Flush()
+}
+
+func (r *markdownRenderer) footer() error {
+	return r.w.Flush()
+}
+
+// htmlTemplates - документ для печати в PDF из браузера
+var htmlTemplates = template.Must(template.New("header").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+@page { size: A4; margin: 20mm; }
+body { font-family: -apple-system, "Segoe UI", Roboto, Arial, sans-serif; font-size: 11pt; line-height: 1.5; color: #1a1a1a; max-width: 800px; margin: 0 auto; }
+h1 { font-size: 18pt; margin-bottom: 0; }
+.exported { color: #666; font-size: 9pt; }
+.message { border-top: 1px solid #ddd; padding: 8pt 0; break-inside: avoid; }
+.meta { color: #666; font-size: 9pt; margin-bottom: 4pt; }
+.role { font-weight: bold; color: #1a1a1a; }
+.user .role { color: #c0392b; }
+.content { white-space: pre-wrap; }
+.documents, .sources { font-size: 9pt; color: #444; }
+.sources a { color: #444; word-break: break-all; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="exported">Экспортировано {{.ExportedAt}}</p>
+`))
+
+func init() {
+	template.Must(htmlTemplates.New("message").Parse(`<section class="message {{.Role}}">
+<div class="meta"><span class="role">{{.RoleTitle}}</span> · {{.CreatedAt}}</div>
+<div class="content">{{.Content}}</div>
+{{- if .Documents}}
+<div class="documents">Документы: {{range $i, $d := .Documents}}{{if $i}}, {{end}}{{$d.Name}}{{end}}</div>
+{{- end}}
+{{- if .Sources}}
+<div class="sources">Источники:<ol>{{range .Sources}}<li><a href="{{.}}">{{.}}</a></li>{{end}}</ol></div>
+{{- end}}
+</section>
+`))
+	template.Must(htmlTemplates.New("footer").Parse(`</body>
+</html>
+`))
+}
+
+type htmlRenderer struct {
+	w *bufio.Writer
+}
+
+func newHTMLRenderer(w io.Writer) *htmlRenderer {
+	return &htmlRenderer{w: bufio.NewWriter(w)}
+}
+
+func (r *htmlRenderer) header(chat *domain.Chat, exportedAt time.Time) error {
+	return htmlTemplates.ExecuteTemplate(r.w, "header", map[string]string{
+		"Title":      chat.Title,
+		"ExportedAt": formatTime(exportedAt),
+	})
+}
+
+func (r *htmlRenderer) message(msg *Message) error {
+	if !msg.visible() {
+		return nil
+	}
+
+	err := htmlTemplates.ExecuteTemplate(r.w, "message", map[string]any{
+		"Role":      msg.Role,
+		"RoleTitle": roleTitle(msg.Role),
+		"CreatedAt": formatTime(msg.CreatedAt),
+		"Content":   strings.TrimSpace(msg.Content),
+		"Documents": msg.Documents,
+		"Sources":   msg.Sources(),
+	})
+	if err != nil {
+		return err
+	}
+	return r.w.Flush()
+}
+
+func (r *htmlRenderer) footer() error {
+	if err := htmlTemplates.ExecuteTemplate(r.w, "footer", nil); err != nil {
+		return err
+	}
+	return r.w.Flush()
+}
+
+// jsonRenderer пишет File по частям: массив сообщений не собирается в памяти
+type jsonRenderer struct {
+	w     *bufio.Writer
+	count int
+}
+
+func newJSONRenderer(w io.Writer) *jsonRenderer {
+	return &jsonRenderer{w: bufio.NewWriter(w)}
+}
+
+func (r *jsonRenderer) header(chat *domain.Chat, exportedAt time.Time) error {
+	head, err := json.Marshal(struct {
+		Format     string    `json:"format"`
+		Version    int       `json:"version"`
+		ExportedAt time.Time `json:"exported_at"`
+		Chat       Chat      `json:"chat"`
+	}{
+		Format:     FileFormat,
+		Version:    FileVersion,
+		ExportedAt: exportedAt.UTC(),
+		Chat:       Chat{ID: chat.ID, Title: chat.Title, CreatedAt: chat.CreatedAt},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Дописываем массив сообщений в открытый объект вместо закрывающей скобки
+	r.w.Write(head[:len(head)-1])
+	_, err = r.w.WriteString(`,"messages":[`)
+	return err
+}
+
+func (r *jsonRenderer) message(msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	if r.count > 0 {
+		r.w.WriteByte(',')
+	}
+	r.count++
+	r.w.Write(data)
+	return r.w.Flush()
+}
+
+func (r *jsonRenderer) footer() error {
+	r.w.WriteString("]}\n")
+	return r.w.Flush()
+}
@@ -0,0 +1,244 @@
+package importer
+
+import (
+	"backend/internal/domain"
+	"backend/internal/usecase/export"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// parse определяет формат файла и приводит его к общему виду
+func parse(data []byte) ([]*chat, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: empty file", ErrInvalidImport)
+	}
+
+	// conversations.json из ChatGPT - массив диалогов
+	if data[0] == '[' {
+		var conversations []gptConversation
+		if err := json.Unmarshal(data, &conversations); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+		return parseChatGPT(conversations), nil
+	}
+
+	var probe struct {
+		Format  string          `json:"format"`
+		Mapping json.RawMessage `json:"mapping"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	switch {
+	case probe.Format == export.FileFormat:
+		var file export.File
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+		return parseExport(&file)
+	case probe.Mapping != nil:
+		// Один диалог ChatGPT
+		var conversation gptConversation
+		if err := json.Unmarshal(data, &conversation); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+		return parseChatGPT([]gptConversation{conversation}), nil
+	}
+
+	return nil, fmt.Errorf("%w: unknown file format", ErrInvalidImport)
+}
+
+// importRole - роль сообщения в чате. Системные сообщения не переносятся: они попали бы
+// в историю промпта как инструкции модели
+func importRole(role string) (domain.Role, bool) {
+	switch r := domain.Role(role); r {
+	case domain.RoleUser, domain.RoleAssistant, domain.RoleTool:
+		return r, true
+	}
+	return "", false
+}
+
+func parseExport(file *export.File) ([]*chat, error) {
+	if file.Version != export.FileVersion {
+		return nil, fmt.Errorf("%w: unsupported export version %d", ErrInvalidImport, file.Version)
+	}
+
+	c := &chat{title: file.Chat.Title, createdAt: file.Chat.CreatedAt}
+	for _, m := range file.Messages {
+		role, ok := importRole(m.Role)
+		// Вызовы инструментов не переносятся, поэтому ответ ассистента только с ними остаётся пустым
+		if !ok || strings.TrimSpace(m.Content) == "" {
+			c.skipped++
+			continue
+		}
+
+		c.messages = append(c.messages, message{
+			role:      role,
+			content:   m.Content,
+			createdAt: m.CreatedAt,
+			metadata:  importMetadata(m.Metadata),
+		})
+	}
+
+	if len(c.messages) == 0 {
+		return nil, nil
+	}
+	return []*chat{c}, nil
+}
+
+// importMetadata оставляет из метаданных файла только источники веб-поиска для показа.
+// Остальное файлу доверять нельзя: документы и сценарий подставляются в промпт при перегенерации,
+// вызовы инструментов меняют обход истории, а отметки PII, guardrails и prompt-injection -
+// журнал проверок, который подделывать нельзя
+func importMetadata(m domain.MessageMetadata) domain.MessageMetadata {
+	return domain.MessageMetadata{Searches: m.Searches}
+}
+
+// gptConversation - диалог из conversations.json ChatGPT. Сообщения хранятся деревом в mapping,
+// current_node - последнее сообщение выбранной ветки
+type gptConversation struct {
+	Title       string             `json:"title"`
+	CreateTime  *float64           `json:"create_time"`
+	Mapping     map[string]gptNode `json:"mapping"`
+	CurrentNode string             `json:"current_node"`
+}
+
+type gptNode struct {
+	Message  *gptMessage `json:"message"`
+	Parent   *string     `json:"parent"`
+	Children []string    `json:"children"`
+}
+
+type gptMessage struct {
+	Author struct {
+		Role string `json:"role"`
+		Name string `json:"name"`
+	} `json:"author"`
+	Content struct {
+		ContentType string            `json:"content_type"`
+		Parts       []json.RawMessage `json:"parts"`
+		Text        string            `json:"text"`
+	} `json:"content"`
+	CreateTime *float64 `json:"create_time"`
+	Metadata   struct {
+		Hidden bool `json:"is_visually_hidden_in_conversation"`
+	} `json:"metadata"`
+}
+
+// text - текстовые части сообщения; картинки и вложения пропускаются
+func (m *gptMessage) text() string {
+	var parts []string
+	for _, raw := range m.Content.Parts {
+		var s string
+		if json.Unmarshal(raw, &s) == nil && strings.TrimSpace(s) != "" {
+			parts = append(parts, s)
+		}
+	}
+	if len(parts) == 0 && m.Content.Text != "" {
+		return m.Content.Text
+	}
+	return strings.Join(parts, "\n")
+}
+
+func parseChatGPT(conversations []gptConversation) []*chat {
+	var chats []*chat
+	for _, conv := range conversations {
+		c := &chat{title: conv.Title, createdAt: unixTime(conv.CreateTime)}
+		at := c.createdAt
+
+		for _, node := range conv.branch() {
+			msg := node.Message
+			if msg == nil {
+				continue
+			}
+
+			role, ok := importRole(msg.Author.Role)
+			content := msg.text()
+			if !ok || msg.Metadata.Hidden || strings.TrimSpace(content) == "" {
+				c.skipped++
+				continue
+			}
+
+			// Без времени сообщение получает время предыдущего, чтобы не нарушить порядок
+			if t := unixTime(msg.CreateTime); !t.IsZero() {
+				at = t
+			}
+
+			var metadata domain.MessageMetadata
+			if role == domain.RoleTool {
+				metadata.ToolName = msg.Author.Name
+			}
+			c.messages = append(c.messages, message{role: role, content: content, createdAt: at, metadata: metadata})
+		}
+
+		if len(c.messages) > 0 {
+			chats = append(chats, c)
+		}
+	}
+	return chats
+}
+
+// branch - сообщения выбранной ветки от корня до current_node. Без current_node
+// ветка продолжается по последним ответам, как в интерфейсе ChatGPT
+func (c *gptConversation) branch() []gptNode {
+	var path []gptNode
+	seen := make(map[string]bool)
+
+	id := c.CurrentNode
+	if _, ok := c.Mapping[id]; !ok {
+		id = c.lastLeaf()
+	}
+
+	for id != "" && !seen[id] {
+		node, ok := c.Mapping[id]
+		if !ok {
+			break
+		}
+		seen[id] = true
+		path = append(path, node)
+		if node.Parent == nil {
+			break
+		}
+		id = *node.Parent
+	}
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+func (c *gptConversation) lastLeaf() string {
+	var id string
+	for nodeID, node := range c.Mapping {
+		if node.Parent == nil {
+			id = nodeID
+			break
+		}
+	}
+
+	seen := make(map[string]bool)
+	for id != "" && !seen[id] {
+		seen[id] = true
+		children := c.Mapping[id].Children
+		if len(children) == 0 {
+			break
+		}
+		id = children[len(children)-1]
+	}
+	return id
+}
+
+func unixTime(ts *float64) time.Time {
+	if ts == nil || *ts <= 0 {
+		return time.Time{}
+	}
+	sec, frac := math.Modf(*ts)
+	return time.Unix(int64(sec), int64(frac*1e9)).UTC()
+}
@@ -0,0 +1,199 @@
+package importer
+
+import (
+	"backend/internal/domain"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrInvalidImport - файл не разобран или не проходит проверки
+	ErrInvalidImport = errors.New("invalid import")
+	// ErrImportTooLarge - файл больше Limits.MaxBytes
+	ErrImportTooLarge = errors.New("import is too large")
+)
+
+const maxTitleRunes = 200
+
+// Limits - ограничения на импортируемый файл
+type Limits struct {
+	MaxBytes        int64
+	MaxChats        int
+	MaxMessages     int // в одном чате
+	MaxMessageChars int
+}
+
+var DefaultLimits = Limits{
+	MaxBytes:        20 << 20,
+	MaxChats:        100,
+	MaxMessages:     5000,
+	MaxMessageChars: 100_000,
+}
+
+// Result - импортированный чат
+type Result struct {
+	Chat     *domain.Chat
+	Messages int
+	// Skipped - сообщения, которые не переносятся: системные, пустые, неизвестных ролей
+	Skipped int
+}
+
+// chat и message - разобранный файл до сохранения, общий вид для всех форматов
+type chat struct {
+	title     string
+	createdAt time.Time
+	messages  []message
+	skipped   int
+}
+
+type message struct {
+	role      domain.Role
+	content   string
+	createdAt time.Time
+	metadata  domain.MessageMetadata
+}
+
+type Importer struct {
+	chatRepo domain.ChatRepo
+	msgRepo  domain.MessageRepo
+	tx       domain.TxManager
+	limits   Limits
+}
+
+func NewImporter(chatRepo domain.ChatRepo, msgRepo domain.MessageRepo, tx domain.TxManager, limits Limits) (*Importer, error) {
+	if chatRepo == nil {
+		return nil, errors.New("chat repo should be provided")
+	}
+	if msgRepo == nil {
+		return nil, errors.New("message repo should be provided")
+	}
+	if tx == nil {
+		return nil, errors.New("tx manager should be provided")
+	}
+
+	return &Importer{
+		chatRepo: chatRepo,
+		msgRepo:  msgRepo,
+		tx:       tx,
+		limits:   limits,
+	}, nil
+}
+
+// Limits возвращает действующие ограничения импорта
+func (i *Importer) Limits() Limits {
+	return i.limits
+}
+
+// Import читает файл выгрузки (свой JSON-формат или conversations.json из ChatGPT) и сохраняет
+// чаты пользователю userID одной транзакцией: либо переносятся все чаты, либо ни один.
+// Каждый чат сохраняется одной веткой с исходным временем сообщений
+func (i *Importer) Import(ctx context.Context, userID uuid.UUID, r io.Reader) ([]*Result, error) {
+	data, err := io.ReadAll(io.LimitReader(r, i.limits.MaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import: %w", err)
+	}
+	if int64(len(data)) > i.limits.MaxBytes {
+		return nil, fmt.Errorf("%w: limit is %d bytes", ErrImportTooLarge, i.limits.MaxBytes)
+	}
+
+	chats, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := i.validate(chats); err != nil {
+		return nil, err
+	}
+
+	var results []*Result
+	err = i.tx.WithinTx(ctx, func(ctx context.Context) error {
+		for _, c := range chats {
+			result, err := i.save(ctx, userID, c)
+			if err != nil {
+				return err
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (i *Importer) validate(chats []*chat) error {
+	if len(chats) == 0 {
+		return fmt.Errorf("%w: no conversations with messages", ErrInvalidImport)
+	}
+	if len(chats) > i.limits.MaxChats {
+		return fmt.Errorf("%w: %d chats, limit is %d", ErrInvalidImport, len(chats), i.limits.MaxChats)
+	}
+
+	for n, c := range chats {
+		if len(c.messages) > i.limits.MaxMessages {
+			return fmt.Errorf("%w: chat %d has %d messages, limit is %d", ErrInvalidImport, n+1, len(c.messages), i.limits.MaxMessages)
+		}
+		for _, msg := range c.messages {
+			if utf8.RuneCountInString(msg.content) > i.limits.MaxMessageChars {
+				return fmt.Errorf("%w: chat %d has a message longer than %d chars", ErrInvalidImport, n+1, i.limits.MaxMessageChars)
+			}
+		}
+	}
+	return nil
+}
+
+// save сохраняет чат и его сообщения цепочкой: каждое следующее - ответ на предыдущее
+func (i *Importer) save(ctx context.Context, userID uuid.UUID, c *chat) (*Result, error) {
+	title := strings.TrimSpace(c.title)
+	if title == "" {
+		title = domain.DefaultChatTitle
+	}
+	if runes := []rune(title); len(runes) > maxTitleRunes {
+		title = string(runes[:maxTitleRunes])
+	}
+
+	saved := &domain.Chat{
+		ID:        uuid.New(),
+		Title:     title,
+		UserID:    userID,
+		Status:    domain.ChatActive,
+		CreatedAt: c.createdAt,
+	}
+	if err := i.chatRepo.Create(ctx, saved); err != nil {
+		return nil, fmt.Errorf("failed to create chat: %w", err)
+	}
+
+	var parentID *uuid.UUID
+	var lastAt time.Time
+	for _, m := range c.messages {
+		msg := &domain.Message{
+			ID:        uuid.New(),
+			ChatID:    saved.ID,
+			ParentID:  parentID,
+			Role:      string(m.role),
+			Content:   m.content,
+			Status:    domain.MessageStatusComplete,
+			CreatedAt: m.createdAt,
+			Metadata:  m.metadata,
+		}
+		if err := i.msgRepo.Append(ctx, msg); err != nil {
+			return nil, fmt.Errorf("failed to save message: %w", err)
+		}
+		parentID = &msg.ID
+		lastAt = msg.CreatedAt
+	}
+
+	if err := i.chatRepo.Touch(ctx, saved.ID, lastAt); err != nil {
+		return nil, fmt.Errorf("failed to touch chat: %w", err)
+	}
+
+	return &Result{Chat: saved, Messages: len(c.messages), Skipped: c.skipped}, nil
+}
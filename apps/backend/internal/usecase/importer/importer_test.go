@@ -0,0 +1,169 @@
+package importer
+
+import (
+	"backend/internal/domain"
+	"backend/internal/fakes"
+	"backend/internal/usecase/export"
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newTestImporter(t *testing.T, limits Limits) (*Importer, *fakes.ChatRepo, *fakes.MessageRepo) {
+	t.Helper()
+
+	chatRepo := fakes.NewChatRepo()
+	msgRepo := fakes.NewMessageRepo(chatRepo)
+	i, err := NewImporter(chatRepo, msgRepo, fakes.NewTx(), limits)
+	if err != nil {
+		t.Fatalf("NewImporter() error = %v", err)
+	}
+	return i, chatRepo, msgRepo
+}
+
+// conversationsJSON - выгрузка ChatGPT: скрытое системное сообщение, вопрос и два варианта ответа,
+// выбран второй (current_node)
+const conversationsJSON = `[{
+  "title": "Аренда офиса",
+  "create_time": 1700000000.5,
+  "current_node": "a2",
+  "mapping": {
+    "root": {"id": "root", "message": null, "parent": null, "children": ["sys"]},
+    "sys": {"id": "sys", "parent": "root", "children": ["q"], "message": {
+      "author": {"role": "system"}, "content": {"content_type": "text", "parts": [""]},
+      "metadata": {"is_visually_hidden_in_conversation": true}}},
+    "q": {"id": "q", "parent": "sys", "children": ["a1", "a2"], "message": {
+      "author": {"role": "user"}, "create_time": 1700000100, "content": {"content_type": "text", "parts": ["Можно ли расторгнуть договор?"]}}},
+    "a1": {"id": "a1", "parent": "q", "children": [], "message": {
+      "author": {"role": "assistant"}, "create_time": 1700000110, "content": {"content_type": "text", "parts": ["Нет."]}}},
+    "a2": {"id": "a2", "parent": "q", "children": [], "message": {
+      "author": {"role": "assistant"}, "create_time": 1700000120, "content": {"content_type": "text", "parts": ["Да, по статье 619 ГК РФ."]}}}
+  }
+}]`
+
+func TestImport_ChatGPT(t *testing.T) {
+	i, chatRepo, msgRepo := newTestImporter(t, DefaultLimits)
+
+	results, err := i.Import(context.Background(), uuid.New(), strings.NewReader(conversationsJSON))
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	if len(results) != 1 || results[0].Messages != 2 || results[0].Skipped != 1 {
+		t.Fatalf("unexpected results: %+v", results[0])
+	}
+	chat := results[0].Chat
+	if chat.Title != "Аренда офиса" || !chat.CreatedAt.Equal(time.Unix(1700000000, 5e8)) {
+		t.Fatalf("chat = %+v", chat)
+	}
+
+	question, answer := msgRepo.Messages[0], msgRepo.Messages[1]
+	if question.Role != string(domain.RoleUser) || answer.Content != "Да, по статье 619 ГК РФ." {
+		t.Fatalf("selected branch is not imported: %q, %q", question.Content, answer.Content)
+	}
+	if answer.ParentID == nil || *answer.ParentID != question.ID {
+		t.Fatalf("answer is not linked to the question")
+	}
+	if !answer.CreatedAt.Equal(time.Unix(1700000120, 0)) || !chatRepo.Touched[chat.ID].Equal(answer.CreatedAt) {
+		t.Fatalf("original timestamps are lost: %v", answer.CreatedAt)
+	}
+}
+
+func TestImport_ExportRoundTrip(t *testing.T) {
+	original := &domain.Chat{ID: uuid.New(), UserID: uuid.New(), Title: "Маркетинг"}
+	chatRepo := fakes.NewChatRepo(original)
+	msgRepo := fakes.NewMessageRepo(chatRepo)
+
+	at := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	question := &domain.Message{ID: uuid.New(), Role: "user", Content: "Придумай слоган", CreatedAt: at,
+		Metadata: domain.MessageMetadata{Scenario: "marketing", DocumentIDs: []uuid.UUID{uuid.New()}}}
+	toolCall := &domain.Message{ID: uuid.New(), Role: "assistant", CreatedAt: at,
+		Metadata: domain.MessageMetadata{ToolCalls: []domain.ToolCall{{Name: "calculator"}}}}
+	answer := &domain.Message{ID: uuid.New(), Role: "assistant", Content: "Альфа - быстрее.",
+		CreatedAt: at.Add(time.Minute), Metadata: domain.MessageMetadata{
+			Searches:   []domain.SearchRecord{{Query: "слоганы банков"}},
+			PII:        &domain.PIIReport{Action: "mask", Counts: map[string]int{"phone": 1}},
+			Guardrails: []domain.GuardrailFlag{{Check: "language"}},
+			Injections: []domain.InjectionFlag{{Source: "web_search", Signals: []string{"role_marker"}}},
+		}}
+	msgRepo.AppendBranch(original, question, toolCall, answer)
+
+	exporter, err := export.NewExporter(chatRepo, msgRepo)
+	if err != nil {
+		t.Fatalf("NewExporter() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := exporter.Write(context.Background(), &buf, original, export.FormatJSON); err != nil {
+		t.Fatalf("export error = %v", err)
+	}
+
+	i, err := NewImporter(chatRepo, msgRepo, fakes.NewTx(), DefaultLimits)
+	if err != nil {
+		t.Fatalf("NewImporter() error = %v", err)
+	}
+	userID := uuid.New()
+	results, err := i.Import(context.Background(), userID, &buf)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	imported := results[0].Chat
+	if imported.ID == original.ID || imported.UserID != userID || imported.Title != "Маркетинг" {
+		t.Fatalf("imported chat = %+v", imported)
+	}
+	copied := msgRepo.Messages[3:]
+	if len(copied) != 2 || !copied[1].CreatedAt.Equal(at.Add(time.Minute)) {
+		t.Fatalf("messages are not copied with time: %+v", copied)
+	}
+	// Из метаданных файла переносятся только источники веб-поиска
+	want := domain.MessageMetadata{Searches: answer.Metadata.Searches}
+	if !reflect.DeepEqual(copied[0].Metadata, domain.MessageMetadata{}) || !reflect.DeepEqual(copied[1].Metadata, want) {
+		t.Fatalf("untrusted metadata is imported: %+v, %+v", copied[0].Metadata, copied[1].Metadata)
+	}
+}
+
+func TestImport_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		limits  Limits
+		wantErr error
+	}{
+		{name: "not json", data: "hello", limits: DefaultLimits, wantErr: ErrInvalidImport},
+		{name: "unknown object", data: `{"chats": []}`, limits: DefaultLimits, wantErr: ErrInvalidImport},
+		{name: "no messages", data: `[{"title": "x", "mapping": {}}]`, limits: DefaultLimits, wantErr: ErrInvalidImport},
+		{name: "unsupported version", data: `{"format": "alfa-copilot-chat", "version": 2, "messages": []}`, limits: DefaultLimits, wantErr: ErrInvalidImport},
+		{name: "too large", data: conversationsJSON, limits: Limits{MaxBytes: 100, MaxChats: 1, MaxMessages: 10, MaxMessageChars: 100}, wantErr: ErrImportTooLarge},
+		{name: "too many messages", data: conversationsJSON, limits: Limits{MaxBytes: 1 << 20, MaxChats: 1, MaxMessages: 1, MaxMessageChars: 100}, wantErr: ErrInvalidImport},
+		{name: "message too long", data: conversationsJSON, limits: Limits{MaxBytes: 1 << 20, MaxChats: 1, MaxMessages: 10, MaxMessageChars: 10}, wantErr: ErrInvalidImport},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i, chatRepo, _ := newTestImporter(t, tt.limits)
+
+			_, err := i.Import(context.Background(), uuid.New(), strings.NewReader(tt.data))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if len(chatRepo.Chats) != 0 {
+				t.Fatalf("chat created despite error")
+			}
+		})
+	}
+}
+
+func TestImport_SaveErrorAbortsImport(t *testing.T) {
+	i, _, msgRepo := newTestImporter(t, DefaultLimits)
+	msgRepo.AppendErr = errors.New("connection reset")
+
+	if _, err := i.Import(context.Background(), uuid.New(), strings.NewReader(conversationsJSON)); err == nil {
+		t.Fatalf("expected save error")
+	}
+}
@@ -0,0 +1,245 @@
+package jobs
+
+import (
+	"backend/internal/domain"
+	"backend/internal/logging"
+	"backend/internal/usecase/authz"
+	"backend/internal/usecase/llm"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Виды задач
+const (
+	KindReply       = "reply"        // ответ на сообщение пользователя
+	KindChatTitle   = "chat_title"   // название чата по первому обмену сообщениями
+	KindChatSummary = "chat_summary" // краткое содержание чата
+)
+
+const (
+	maxTitleRunes = 80
+	// summaryMinMessages - короткие чаты не пересказываем
+	summaryMinMessages = 6
+	summaryMaxMessages = 50
+	summaryMaxChars    = 8000
+)
+
+// ReplyPayload - сообщение пользователя, на которое нужно ответить
+type ReplyPayload struct {
+	ChatID       uuid.UUID   `json:"chat_id"`
+	Content      string      `json:"content"`
+	DocumentIDs  []uuid.UUID `json:"document_ids,omitempty"`
+	ScenarioCode *string     `json:"scenario_code,omitempty"`
+	// ClientMessageID - ключ идемпотентности клиента; без него ключом служит ID задачи
+	ClientMessageID string `json:"client_message_id,omitempty"`
+}
+
+// ReplyResult - сохранённый ответ ассистента
+type ReplyResult struct {
+	MessageID uuid.UUID `json:"message_id"`
+	Content   string    `json:"content"`
+}
+
+// ChatPayload - задача над чатом целиком (название, краткое содержание)
+type ChatPayload struct {
+	ChatID uuid.UUID `json:"chat_id"`
+}
+
+// EnqueueFollowUps ставит фоновые задачи после ответа в чате: название и краткое содержание,
+// если для них зарегистрированы обработчики. Повторные вызовы, пока задача ждёт, не плодят дубли
+func (q *Queue) EnqueueFollowUps(ctx context.Context, userID, chatID uuid.UUID) {
+	for _, kind := range []string{KindChatTitle, KindChatSummary} {
+		if !q.Handles(kind) {
+			continue
+		}
+		if _, err := q.Enqueue(ctx, kind, userID, ChatPayload{ChatID: chatID}, kind+":"+chatID.String()); err != nil {
+			logging.FromContext(ctx).WarnContext(ctx, "failed to enqueue follow-up job",
+				slog.String("kind", kind),
+				slog.String("chat_id", chatID.String()),
+				slog.Any("error", err),
+			)
+		}
+	}
+}
+
+// ReplyHandler генерирует ответ через llm.Service. Повтор задачи не дублирует вопрос:
+// Reply вызывается с ключом идемпотентности
+func ReplyHandler(svc *llm.Service, docTextGetter llm.DocumentTextGetter, q *Queue) Handler {
+	return func(ctx context.Context, job *domain.Job) (any, error) {
+		var p ReplyPayload
+		if err := json.Unmarshal(job.Payload, &p); err != nil {
+			return nil, Permanent(fmt.Errorf("invalid reply payload: %w", err))
+		}
+
+		key := p.ClientMessageID
+		if key == "" {
+			key = "job:" + job.ID.String()
+		}
+
+		msg, err := svc.ReplyIdempotent(ctx, key, p.ChatID, job.UserID, p.Content, p.DocumentIDs, p.ScenarioCode, docTextGetter)
+		if err != nil {
+			// Эти ошибки не исправятся повтором
+			for _, target := range []error{
+				domain.ErrQuotaExceeded,
+				domain.ErrSensitiveData,
+				domain.ErrAccessDenied,
+				domain.ErrIdempotencyKeyReused,
+			} {
+				if errors.Is(err, target) {
+					return nil, Permanent(err)
+				}
+			}
+			return nil, err
+		}
+
+		q.EnqueueFollowUps(ctx, job.UserID, p.ChatID)
+
+		return ReplyResult{MessageID: msg.ID, Content: msg.Content}, nil
+	}
+}
+
+const titlePrompt = `Придумай короткое название (до 6 слов) для чата по его началу.
+Ответь только названием, без кавычек и точки в конце.
+
+%s`
+
+// TitleHandler заменяет название чата по умолчанию сгенерированным. Название,
+// заданное пользователем, не трогает
+func TitleHandler(chatRepo domain.ChatRepo, msgRepo domain.MessageRepo, model domain.LLM, az *authz.Service) Handler {
+	return func(ctx context.Context, job *domain.Job) (any, error) {
+		chat, err := getJobChat(ctx, chatRepo, az, job)
+		if err != nil {
+			return nil, err
+		}
+		if chat.Title != domain.DefaultChatTitle {
+			return nil, nil
+		}
+
+		messages, err := msgRepo.GetLastN(ctx, chat.ID, 2)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get messages: %w", err)
+		}
+		if len(messages) == 0 {
+			return nil, nil
+		}
+
+		gen, err := model.Generate(ctx, []byte(fmt.Sprintf(titlePrompt, transcript(messages, summaryMaxChars))))
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate title: %w", err)
+		}
+
+		title := cleanTitle(gen.Content)
+		if title == "" {
+			return nil, nil
+		}
+
+		chat.Title = title
+		if err := chatRepo.UpdateTitle(ctx, chat); err != nil {
+			return nil, fmt.Errorf("failed to update title: %w", err)
+		}
+		return ChatPayload{ChatID: chat.ID}, nil
+	}
+}
+
+const summaryPrompt = `Кратко (3-5 предложений) перескажи содержание диалога: о чём спрашивал пользователь
+и к каким выводам пришли. Ответь только пересказом.
+
+%s`
+
+// SummaryHandler сохраняет краткое содержание активной ветки чата
+func SummaryHandler(chatRepo domain.ChatRepo, msgRepo domain.MessageRepo, model domain.LLM, az *authz.Service) Handler {
+	return func(ctx context.Context, job *domain.Job) (any, error) {
+		chat, err := getJobChat(ctx, chatRepo, az, job)
+		if err != nil {
+			return nil, err
+		}
+
+		messages, err := msgRepo.GetLastN(ctx, chat.ID, summaryMaxMessages)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get messages: %w", err)
+		}
+		if len(messages) < summaryMinMessages {
+			return nil, nil
+		}
+
+		gen, err := model.Generate(ctx, []byte(fmt.Sprintf(summaryPrompt, transcript(messages, summaryMaxChars))))
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate summary: %w", err)
+		}
+
+		summary := strings.TrimSpace(gen.Content)
+		if summary == "" {
+			return nil, nil
+		}
+		if err := chatRepo.UpdateSummary(ctx, chat.ID, summary); err != nil {
+			return nil, fmt.Errorf("failed to update summary: %w", err)
+		}
+		return ChatPayload{ChatID: chat.ID}, nil
+	}
+}
+
+// getJobChat возвращает чат из ChatPayload, проверив, что владелец задачи может писать в него
+func getJobChat(ctx context.Context, chatRepo domain.ChatRepo, az *authz.Service, job *domain.Job) (*domain.Chat, error) {
+	var p ChatPayload
+	if err := json.Unmarshal(job.Payload, &p); err != nil {
+		return nil, Permanent(fmt.Errorf("invalid chat payload: %w", err))
+	}
+
+	chat, err := chatRepo.GetByID(ctx, p.ChatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat: %w", err)
+	}
+	if err := az.Can(ctx, authz.User(job.UserID), authz.ChatWrite, authz.Chat(chat)); err != nil {
+		if errors.Is(err, domain.ErrAccessDenied) {
+			return nil, Permanent(err)
+		}
+		return nil, err
+	}
+	return chat, nil
+}
+
+// transcript - реплики пользователя и ассистента для пересказа; при превышении maxChars
+// отбрасываются самые старые
+func transcript(messages []*domain.Message, maxChars int) string {
+	var lines []string
+	total := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		if msg.Content == "" || (msg.Role != string(domain.RoleUser) && msg.Role != string(domain.RoleAssistant)) {
+			continue
+		}
+
+		line := msg.Role + ": " + msg.Content
+		total += utf8.RuneCountInString(line)
+		if total > maxChars && len(lines) > 0 {
+			break
+		}
+		lines = append(lines, line)
+	}
+
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// cleanTitle - первая строка ответа модели без кавычек и точки, не длиннее maxTitleRunes
+func cleanTitle(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.Trim(s, " \t\"'«».")
+
+	if runes := []rune(s); len(runes) > maxTitleRunes {
+		s = strings.TrimSpace(string(runes[:maxTitleRunes]))
+	}
+	return s
+}
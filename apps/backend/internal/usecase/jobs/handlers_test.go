@@ -0,0 +1,93 @@
+package jobs
+
+import (
+	"backend/internal/domain"
+	"backend/internal/fakes"
+	"backend/internal/usecase/authz"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func chatJob(chat *domain.Chat) *domain.Job {
+	payload, _ := json.Marshal(ChatPayload{ChatID: chat.ID})
+	return &domain.Job{ID: uuid.New(), UserID: chat.UserID, Payload: payload}
+}
+
+func dialog(n int) []*domain.Message {
+	messages := make([]*domain.Message, n)
+	for i := range messages {
+		role := domain.RoleUser
+		if i%2 == 1 {
+			role = domain.RoleAssistant
+		}
+		messages[i] = &domain.Message{ID: uuid.New(), Role: string(role), Content: "реплика"}
+	}
+	return messages
+}
+
+func TestTitleHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		title     string
+		response  string
+		wantTitle string
+	}{
+		{name: "default title is replaced", title: domain.DefaultChatTitle, response: "«Сроки поставки по договору».\nПояснение", wantTitle: "Сроки поставки по договору"},
+		{name: "user title is kept", title: "Мой чат", response: "Сроки поставки", wantTitle: "Мой чат"},
+		{name: "empty answer keeps default", title: domain.DefaultChatTitle, response: "  ", wantTitle: domain.DefaultChatTitle},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chat := &domain.Chat{ID: uuid.New(), UserID: uuid.New(), Title: tt.title}
+			chatRepo := fakes.NewChatRepo(chat)
+			msgRepo := fakes.NewMessageRepo(chatRepo)
+			msgRepo.AppendBranch(chat, dialog(2)...)
+			model := &fakes.LLM{Response: tt.response}
+
+			handler := TitleHandler(chatRepo, msgRepo, model, authz.OwnerOnly())
+			if _, err := handler(context.Background(), chatJob(chat)); err != nil {
+				t.Fatalf("handler error = %v", err)
+			}
+
+			if chat.Title != tt.wantTitle {
+				t.Fatalf("title = %q, want %q", chat.Title, tt.wantTitle)
+			}
+		})
+	}
+}
+
+func TestSummaryHandler(t *testing.T) {
+	chat := &domain.Chat{ID: uuid.New(), UserID: uuid.New()}
+	chatRepo := fakes.NewChatRepo(chat)
+	msgRepo := fakes.NewMessageRepo(chatRepo)
+	msgRepo.AppendBranch(chat, dialog(summaryMinMessages-1)...)
+	model := &fakes.LLM{Response: "Обсудили сроки поставки."}
+	handler := SummaryHandler(chatRepo, msgRepo, model, authz.OwnerOnly())
+
+	// Короткий чат не пересказываем
+	if _, err := handler(context.Background(), chatJob(chat)); err != nil {
+		t.Fatalf("handler error = %v", err)
+	}
+	if len(model.Prompts) != 0 || chat.Summary != nil {
+		t.Fatalf("short chat was summarized")
+	}
+
+	msgRepo.AppendBranch(chat, dialog(1)...)
+	if _, err := handler(context.Background(), chatJob(chat)); err != nil {
+		t.Fatalf("handler error = %v", err)
+	}
+	if chat.Summary == nil || *chat.Summary != "Обсудили сроки поставки." {
+		t.Fatalf("summary = %v", chat.Summary)
+	}
+
+	// Задача по чужому чату не выполняется
+	foreign := chatJob(chat)
+	foreign.UserID = uuid.New()
+	if _, err := handler(context.Background(), foreign); err == nil {
+		t.Fatalf("expected access error")
+	}
+}
@@ -0,0 +1,316 @@
+package jobs
+
+import (
+	"backend/internal/domain"
+	"backend/internal/logging"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Handler выполняет задачу. Результат сохраняется в задаче как JSON
+type Handler func(ctx context.Context, job *domain.Job) (any, error)
+
+// permanentError - ошибка, после которой повтор задачи бессмыслен
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent помечает ошибку обработчика как окончательную: задача завершается без повторов
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+const (
+	maxRetryDelay  = 5 * time.Minute
+	baseRetryDelay = 5 * time.Second
+)
+
+// retryDelay - экспоненциальная пауза перед повтором после attempts неудачных попыток
+func retryDelay(attempts int) time.Duration {
+	delay := baseRetryDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
+
+// Queue - очередь фоновых задач поверх JobRepo. Задачи переживают перезапуск сервиса,
+// а несколько экземпляров сервиса разбирают одну очередь, не мешая друг другу
+type Queue struct {
+	repo         domain.JobRepo
+	handlers     map[string]Handler
+	workers      int
+	pollInterval time.Duration
+	lease        time.Duration
+	maxAttempts  int
+	now          func() time.Time
+}
+
+type Option func(*Queue)
+
+// WithWorkers - число задач, выполняемых одновременно
+func WithWorkers(n int) Option {
+	return func(q *Queue) {
+		if n > 0 {
+			q.workers = n
+		}
+	}
+}
+
+// WithPollInterval - как часто свободный воркер проверяет очередь
+func WithPollInterval(d time.Duration) Option {
+	return func(q *Queue) {
+		if d > 0 {
+			q.pollInterval = d
+		}
+	}
+}
+
+// WithLease - сколько задача может выполняться. Задачу, не завершённую за это время
+// (например, воркер упал), заберёт другой воркер
+func WithLease(d time.Duration) Option {
+	return func(q *Queue) {
+		if d > 0 {
+			q.lease = d
+		}
+	}
+}
+
+// WithMaxAttempts - сколько раз задача запускается, прежде чем завершиться ошибкой
+func WithMaxAttempts(n int) Option {
+	return func(q *Queue) {
+		if n > 0 {
+			q.maxAttempts = n
+		}
+	}
+}
+
+func NewQueue(repo domain.JobRepo, opts ...Option) (*Queue, error) {
+	if repo == nil {
+		return nil, errors.New("job repo should be provided")
+	}
+
+	q := &Queue{
+		repo:         repo,
+		handlers:     make(map[string]Handler),
+		workers:      2,
+		pollInterval: time.Second,
+		lease:        10 * time.Minute,
+		maxAttempts:  3,
+		now:          time.Now,
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+
+	return q, nil
+}
+
+// Register задаёт обработчик задач вида kind. Вызывается до Run
+func (q *Queue) Register(kind string, h Handler) {
+	q.handlers[kind] = h
+}
+
+// Handles - есть ли обработчик задач вида kind
+func (q *Queue) Handles(kind string) bool {
+	_, ok := q.handlers[kind]
+	return ok
+}
+
+// Enqueue ставит задачу в очередь. Пока задача с тем же непустым dedupeKey ждёт выполнения,
+// возвращается она, а новая не создаётся
+func (q *Queue) Enqueue(ctx context.Context, kind string, userID uuid.UUID, payload any, dedupeKey string) (*domain.Job, error) {
+	if !q.Handles(kind) {
+		return nil, fmt.Errorf("no handler for job kind %q", kind)
+	}
+
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal job payload: %w", err)
+	}
+
+	job := &domain.Job{
+		ID:          uuid.New(),
+		Kind:        kind,
+		UserID:      userID,
+		Payload:     raw,
+		MaxAttempts: q.maxAttempts,
+		DedupeKey:   dedupeKey,
+	}
+	if err := q.repo.Enqueue(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to enqueue job: %w", err)
+	}
+
+	logging.FromContext(ctx).InfoContext(ctx, "job enqueued",
+		slog.String("job_id", job.ID.String()),
+		slog.String("kind", kind),
+	)
+	return job, nil
+}
+
+// Get возвращает задачу пользователя
+func (q *Queue) Get(ctx context.Context, userID, jobID uuid.UUID) (*domain.Job, error) {
+	job, err := q.repo.GetByID(ctx, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	if job == nil {
+		return nil, domain.ErrJobNotFound
+	}
+	if job.UserID != userID {
+		return nil, fmt.Errorf("%w: job belongs to different user", domain.ErrAccessDenied)
+	}
+	return job, nil
+}
+
+// Run запускает воркеры и блокируется до отмены ctx. Начатые задачи при этом дорабатывают
+// (не дольше аренды), и Run возвращается после их завершения
+func (q *Queue) Run(ctx context.Context) {
+	kinds := make([]string, 0, len(q.handlers))
+	for kind := range q.handlers {
+		kinds = append(kinds, kind)
+	}
+	if len(kinds) == 0 {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for range q.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx, kinds)
+		}()
+	}
+	wg.Wait()
+}
+
+func (q *Queue) work(ctx context.Context, kinds []string) {
+	for {
+		// Пока задачи есть, берём следующую сразу, иначе ждём pollInterval
+		processed, err := q.RunOnce(ctx, kinds)
+		if err != nil {
+			logging.FromContext(ctx).ErrorContext(ctx, "failed to process job queue", slog.Any("error", err))
+		}
+		if processed && err == nil {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(q.pollInterval):
+		}
+	}
+}
+
+// RunOnce забирает и выполняет одну задачу. processed = false - готовых задач нет
+func (q *Queue) RunOnce(ctx context.Context, kinds []string) (processed bool, err error) {
+	job, err := q.repo.Claim(ctx, kinds, q.lease)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim job: %w", err)
+	}
+	if job == nil {
+		return false, nil
+	}
+
+	// Отмена ctx останавливает приём задач, но не прерывает уже начатую
+	jobCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), q.lease)
+	defer cancel()
+	jobCtx = logging.With(jobCtx,
+		slog.String("job_id", job.ID.String()),
+		slog.String("kind", job.Kind),
+		slog.Int("attempt", job.Attempts),
+	)
+
+	return true, q.process(jobCtx, job)
+}
+
+func (q *Queue) process(ctx context.Context, job *domain.Job) error {
+	logger := logging.FromContext(ctx)
+
+	// Аренда истекла столько раз, сколько разрешено попыток: воркер падает на этой задаче
+	if job.MaxAttempts > 0 && job.Attempts > job.MaxAttempts {
+		logger.WarnContext(ctx, "job attempts exhausted")
+		return q.saved(ctx, q.repo.Fail(ctx, job.ID, job.Attempts, "attempts exhausted", nil))
+	}
+
+	handler, ok := q.handlers[job.Kind]
+	if !ok {
+		return q.saved(ctx, q.repo.Fail(ctx, job.ID, job.Attempts, fmt.Sprintf("no handler for job kind %q", job.Kind), nil))
+	}
+
+	started := q.now()
+	result, err := q.call(ctx, handler, job)
+	if err != nil {
+		var retryAt *time.Time
+		var permanent *permanentError
+		if !errors.As(err, &permanent) && job.Attempts < job.MaxAttempts {
+			at := q.now().Add(retryDelay(job.Attempts))
+			retryAt = &at
+		}
+
+		logger.WarnContext(ctx, "job failed",
+			slog.Any("error", err),
+			slog.Bool("retry", retryAt != nil),
+		)
+		if ferr := q.saved(ctx, q.repo.Fail(ctx, job.ID, job.Attempts, err.Error(), retryAt)); ferr != nil {
+			return fmt.Errorf("failed to save job error: %w", ferr)
+		}
+		return nil
+	}
+
+	var raw json.RawMessage
+	if result != nil {
+		raw, err = json.Marshal(result)
+		if err != nil {
+			return q.saved(ctx, q.repo.Fail(ctx, job.ID, job.Attempts, fmt.Sprintf("failed to marshal result: %v", err), nil))
+		}
+	}
+
+	if err := q.repo.Complete(ctx, job.ID, job.Attempts, raw); err != nil {
+		if errors.Is(err, domain.ErrJobLeaseLost) {
+			return q.saved(ctx, err)
+		}
+		return fmt.Errorf("failed to complete job: %w", err)
+	}
+
+	logger.InfoContext(ctx, "job done", slog.Duration("duration", q.now().Sub(started)))
+	return nil
+}
+
+// saved разбирает ошибку сохранения итога задачи. Потерянная аренда - не ошибка воркера:
+// задача уже у другого воркера, и итог этой попытки отбрасывается
+func (q *Queue) saved(ctx context.Context, err error) error {
+	if errors.Is(err, domain.ErrJobLeaseLost) {
+		logging.FromContext(ctx).WarnContext(ctx, "job lease lost, result discarded")
+		return nil
+	}
+	return err
+}
+
+// call выполняет обработчик; паника обработчика завершает задачу ошибкой, а не весь процесс
+func (q *Queue) call(ctx context.Context, handler Handler, job *domain.Job) (result any, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = Permanent(fmt.Errorf("job handler panic: %v", p))
+		}
+	}()
+	return handler(ctx, job)
+}
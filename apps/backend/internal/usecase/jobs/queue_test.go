@@ -0,0 +1,183 @@
+package jobs
+
+import (
+	"backend/internal/domain"
+	"backend/internal/fakes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// runAll выполняет задачи, пока очередь не опустеет
+func runAll(t *testing.T, q *Queue) {
+	t.Helper()
+	for {
+		processed, err := q.RunOnce(context.Background(), []string{"test"})
+		if err != nil {
+			t.Fatalf("RunOnce() error = %v", err)
+		}
+		if !processed {
+			return
+		}
+	}
+}
+
+func TestQueue_Run(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	tests := []struct {
+		name         string
+		failures     int // сколько раз обработчик падает перед успехом
+		err          error
+		wantStatus   domain.JobStatus
+		wantAttempts int
+		wantResult   string
+	}{
+		{name: "success", wantStatus: domain.JobDone, wantAttempts: 1, wantResult: `"ok"`},
+		{name: "retry after error", failures: 2, err: errBoom, wantStatus: domain.JobDone, wantAttempts: 3, wantResult: `"ok"`},
+		{name: "attempts exhausted", failures: 5, err: errBoom, wantStatus: domain.JobFailed, wantAttempts: 3},
+		{name: "permanent error", failures: 5, err: Permanent(errBoom), wantStatus: domain.JobFailed, wantAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakes.JobRepo{}
+			q, err := NewQueue(repo, WithMaxAttempts(3))
+			if err != nil {
+				t.Fatalf("NewQueue() error = %v", err)
+			}
+
+			calls := 0
+			q.Register("test", func(_ context.Context, _ *domain.Job) (any, error) {
+				calls++
+				if calls <= tt.failures {
+					return nil, tt.err
+				}
+				return "ok", nil
+			})
+
+			userID := uuid.New()
+			job, err := q.Enqueue(context.Background(), "test", userID, map[string]string{"a": "b"}, "")
+			if err != nil {
+				t.Fatalf("Enqueue() error = %v", err)
+			}
+
+			runAll(t, q)
+
+			got, err := q.Get(context.Background(), userID, job.ID)
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if got.Status != tt.wantStatus || got.Attempts != tt.wantAttempts {
+				t.Fatalf("status = %s, attempts = %d; want %s, %d", got.Status, got.Attempts, tt.wantStatus, tt.wantAttempts)
+			}
+			if string(got.Result) != tt.wantResult {
+				t.Fatalf("result = %s, want %s", got.Result, tt.wantResult)
+			}
+			if tt.wantStatus == domain.JobFailed && got.Error != "boom" {
+				t.Fatalf("error = %q, want boom", got.Error)
+			}
+		})
+	}
+}
+
+func TestQueue_HandlerPanicFailsJob(t *testing.T) {
+	repo := &fakes.JobRepo{}
+	q, _ := NewQueue(repo)
+	q.Register("test", func(_ context.Context, _ *domain.Job) (any, error) {
+		panic("nil map")
+	})
+
+	userID := uuid.New()
+	job, err := q.Enqueue(context.Background(), "test", userID, nil, "")
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	runAll(t, q)
+
+	got, _ := q.Get(context.Background(), userID, job.ID)
+	if got.Status != domain.JobFailed || got.Attempts != 1 {
+		t.Fatalf("status = %s, attempts = %d; want failed after one attempt", got.Status, got.Attempts)
+	}
+}
+
+func TestQueue_LostLeaseKeepsNewOwnerResult(t *testing.T) {
+	repo := &fakes.JobRepo{}
+	q, _ := NewQueue(repo)
+	// Пока обработчик работал, аренда истекла и задачу забрал и завершил другой воркер
+	q.Register("test", func(_ context.Context, job *domain.Job) (any, error) {
+		repo.Update(job.ID, func(job *domain.Job) { job.Attempts++ })
+		if err := repo.Complete(context.Background(), job.ID, job.Attempts+1, json.RawMessage(`"second"`)); err != nil {
+			return nil, err
+		}
+		return "first", nil
+	})
+
+	userID := uuid.New()
+	job, err := q.Enqueue(context.Background(), "test", userID, nil, "")
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	processed, err := q.RunOnce(context.Background(), []string{"test"})
+	if !processed || err != nil {
+		t.Fatalf("RunOnce() = %v, %v; want processed without error", processed, err)
+	}
+
+	got, _ := q.Get(context.Background(), userID, job.ID)
+	if got.Status != domain.JobDone || string(got.Result) != `"second"` {
+		t.Fatalf("status = %s, result = %s; want result of the worker holding the lease", got.Status, got.Result)
+	}
+}
+
+func TestQueue_EnqueueAndGet(t *testing.T) {
+	repo := &fakes.JobRepo{}
+	q, _ := NewQueue(repo)
+	q.Register("test", func(_ context.Context, _ *domain.Job) (any, error) { return nil, nil })
+	ctx := context.Background()
+	userID := uuid.New()
+
+	first, err := q.Enqueue(ctx, "test", userID, nil, "chat:1")
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	second, err := q.Enqueue(ctx, "test", userID, nil, "chat:1")
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if first.ID != second.ID || len(repo.Jobs) != 1 {
+		t.Fatalf("pending job with the same dedupe key was duplicated")
+	}
+
+	if _, err := q.Enqueue(ctx, "unknown", userID, nil, ""); err == nil {
+		t.Fatalf("expected error for unregistered kind")
+	}
+
+	if _, err := q.Get(ctx, uuid.New(), first.ID); !errors.Is(err, domain.ErrAccessDenied) {
+		t.Fatalf("Get() by other user error = %v, want ErrAccessDenied", err)
+	}
+	if _, err := q.Get(ctx, userID, uuid.New()); !errors.Is(err, domain.ErrJobNotFound) {
+		t.Fatalf("Get() unknown job error = %v, want ErrJobNotFound", err)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{4, 40 * time.Second},
+		{10, 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempts); got != tt.want {
+			t.Errorf("retryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package llm
+
+import (
+	"backend/internal/domain"
+	"backend/internal/usecase/authz"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Regenerate генерирует альтернативный ответ на тот же вопрос. messageID - ответ ассистента
+// (или сам вопрос); новый ответ становится соседней веткой, старый остаётся в дереве
+func (s *Service) Regenerate(
+	ctx context.Context,
+	userID uuid.UUID,
+	messageID uuid.UUID,
+	docTextGetter DocumentTextGetter,
+) (_ *domain.Message, err error) {
+	ctx, span := tracer.Start(ctx, "llm.Regenerate", trace.WithAttributes(
+		attribute.String("message.id", messageID.String()),
+	))
+	defer func() {
+		endSpan(span, err)
+	}()
+
+	msg, chat, err := s.getChatMessage(ctx, userID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Поднимаемся по ветке до вопроса: между ним и ответом могут быть вызовы инструментов
+	for msg.Role != string(domain.RoleUser) {
+		if msg.ParentID == nil {
+			return nil, fmt.Errorf("%w: no user message before %s", domain.ErrWrongMessageRole, messageID)
+		}
+		msg, err = s.msgRepo.GetByID(ctx, *msg.ParentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get parent message: %w", err)
+		}
+		if msg == nil {
+			return nil, domain.ErrMessageNotFound
+		}
+	}
+
+	return s.answer(ctx, userID, chat, msg, false, docTextGetter)
+}
+
+// EditMessage сохраняет исправленный вопрос рядом с исходным и отвечает на него заново.
+// Исходный вопрос и ответы на него остаются в соседней ветке
+func (s *Service) EditMessage(
+	ctx context.Context,
+	userID uuid.UUID,
+	messageID uuid.UUID,
+	userText string,
+	docTextGetter DocumentTextGetter,
+) (_ *domain.Message, err error) {
+	ctx, span := tracer.Start(ctx, "llm.EditMessage", trace.WithAttributes(
+		attribute.String("message.id", messageID.String()),
+	))
+	defer func() {
+		endSpan(span, err)
+	}()
+
+	if userText == "" {
+		return nil, errors.New("user text cannot be empty")
+	}
+
+	original, chat, err := s.getChatMessage(ctx, userID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if original.Role != string(domain.RoleUser) {
+		return nil, fmt.Errorf("%w: only user messages can be edited", domain.ErrWrongMessageRole)
+	}
+
+	edited := &domain.Message{
+		ID:       uuid.New(),
+		ChatID:   original.ChatID,
+		ParentID: original.ParentID,
+		Role:     string(domain.RoleUser),
+		Content:  userText,
+		Metadata: domain.MessageMetadata{
+			DocumentIDs: original.Metadata.DocumentIDs,
+			Scenario:    original.Metadata.Scenario,
+		},
+	}
+
+	return s.answer(ctx, userID, chat, edited, true, docTextGetter)
+}
+
+// SwitchBranch делает активной ветку, проходящую через messageID: её видно в истории чата,
+// и следующий вопрос продолжит её
+func (s *Service) SwitchBranch(ctx context.Context, userID uuid.UUID, messageID uuid.UUID) error {
+	if _, _, err := s.getChatMessage(ctx, userID, messageID); err != nil {
+		return err
+	}
+
+	if err := s.msgRepo.ActivateBranch(ctx, messageID); err != nil {
+		return fmt.Errorf("failed to activate branch: %w", err)
+	}
+	return nil
+}
+
+// getChatMessage возвращает сообщение и его чат, проверив, что пользователь может изменять сообщение
+func (s *Service) getChatMessage(ctx context.Context, userID uuid.UUID, messageID uuid.UUID) (*domain.Message, *domain.Chat, error) {
+	msg, err := s.msgRepo.GetByID(ctx, messageID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get message: %w", err)
+	}
+	if msg == nil {
+		return nil, nil, domain.ErrMessageNotFound
+	}
+
+	chat, err := s.chatRepo.GetByID(ctx, msg.ChatID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get chat: %w", err)
+	}
+	if err := s.authz.Can(ctx, authz.User(userID), authz.MessageWrite, authz.Message(msg, chat)); err != nil {
+		return nil, nil, err
+	}
+
+	return msg, chat, nil
+}
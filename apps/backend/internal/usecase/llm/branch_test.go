@@ -0,0 +1,214 @@
+package llm
+
+import (
+	"backend/internal/domain"
+	"backend/internal/fakes"
+	"backend/internal/usecase/authz"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// newBranchService - сервис над чатом с веткой "вопрос о сроке -> ответ -> вопрос о неустойке -> ответ"
+func newBranchService(t *testing.T, model *fakes.LLM) (*Service, *domain.Chat, *fakes.MessageRepo) {
+	t.Helper()
+
+	chat := &domain.Chat{ID: uuid.New(), UserID: uuid.New()}
+	msgRepo := newBranchRepo(chat,
+		&domain.Message{ID: uuid.New(), Role: string(domain.RoleUser), Content: "какой срок поставки?"},
+		&domain.Message{ID: uuid.New(), Role: string(domain.RoleAssistant), Content: "30 дней"},
+		&domain.Message{ID: uuid.New(), Role: string(domain.RoleUser), Content: "какая неустойка?",
+			Metadata: domain.MessageMetadata{Scenario: "contract_helper"}},
+		&domain.Message{ID: uuid.New(), Role: string(domain.RoleAssistant), Content: "0,1% в день"},
+	)
+
+	svc, err := NewChatService(msgRepo.Chats, msgRepo, model, &domain.Limits{
+		MaxPromptChars:  10000,
+		MaxHistoryChars: 5000,
+		MaxRequestChars: 1000,
+	})
+	if err != nil {
+		t.Fatalf("NewChatService() error = %v", err)
+	}
+	return svc, chat, msgRepo
+}
+
+func TestReply_HistoryFollowsActiveBranch(t *testing.T) {
+	model := &fakes.LLM{Response: "Да."}
+	svc, chat, _ := newBranchService(t, model)
+
+	if _, err := svc.Reply(context.Background(), chat.ID, chat.UserID, "можно ли расторгнуть?", nil, nil, nil); err != nil {
+		t.Fatalf("Reply() error = %v", err)
+	}
+
+	prompt := model.Prompts[0]
+	first, second := strings.Index(prompt, "30 дней"), strings.Index(prompt, "0,1% в день")
+	if first < 0 || second < 0 || first > second {
+		t.Fatalf("history is not in chronological order:\n%s", prompt)
+	}
+}
+
+func TestRegenerate(t *testing.T) {
+	model := &fakes.LLM{Response: "0,2% в день"}
+	svc, chat, msgRepo := newBranchService(t, model)
+	question, oldAnswer := msgRepo.Messages[2], msgRepo.Messages[3]
+
+	msg, err := svc.Regenerate(context.Background(), chat.UserID, oldAnswer.ID, nil)
+	if err != nil {
+		t.Fatalf("Regenerate() error = %v", err)
+	}
+
+	if msg.ParentID == nil || *msg.ParentID != question.ID {
+		t.Fatalf("new answer parent = %v, want question %s", msg.ParentID, question.ID)
+	}
+	if *chat.ActiveMessageID != msg.ID {
+		t.Fatalf("new answer is not the active branch")
+	}
+	// Вопрос не дублируется, старый ответ остаётся в дереве
+	if len(msgRepo.Messages) != 5 {
+		t.Fatalf("saved messages = %d, want 5", len(msgRepo.Messages))
+	}
+
+	prompt := model.Prompts[0]
+	if strings.Contains(prompt, "0,1% в день") {
+		t.Fatalf("replaced answer leaks into history:\n%s", prompt)
+	}
+	if !strings.Contains(prompt, "помощник по анализу договоров") {
+		t.Fatalf("scenario of the question is not reused:\n%s", prompt)
+	}
+}
+
+func TestEditMessage(t *testing.T) {
+	model := &fakes.LLM{Response: "Неустойки нет."}
+	svc, chat, msgRepo := newBranchService(t, model)
+	firstAnswer, original := msgRepo.Messages[1], msgRepo.Messages[2]
+
+	msg, err := svc.EditMessage(context.Background(), chat.UserID, original.ID, "есть ли штраф?", nil)
+	if err != nil {
+		t.Fatalf("EditMessage() error = %v", err)
+	}
+
+	edited := msgRepo.Messages[4]
+	if edited.Role != string(domain.RoleUser) || edited.Content != "есть ли штраф?" {
+		t.Fatalf("edited question = %+v", edited)
+	}
+	if edited.ParentID == nil || *edited.ParentID != firstAnswer.ID {
+		t.Fatalf("edited question must be a sibling of the original")
+	}
+	if edited.Metadata.Scenario != "contract_helper" {
+		t.Fatalf("edited question scenario = %q", edited.Metadata.Scenario)
+	}
+	if original.Content != "какая неустойка?" {
+		t.Fatalf("original question changed: %q", original.Content)
+	}
+	if msg.ParentID == nil || *msg.ParentID != edited.ID {
+		t.Fatalf("answer parent = %v, want edited question", msg.ParentID)
+	}
+
+	prompt := model.Prompts[0]
+	if strings.Contains(prompt, "какая неустойка?") || strings.Contains(prompt, "0,1% в день") {
+		t.Fatalf("original branch leaks into history:\n%s", prompt)
+	}
+	if !strings.Contains(prompt, "30 дней") {
+		t.Fatalf("shared history is missing:\n%s", prompt)
+	}
+}
+
+func TestBranchErrors(t *testing.T) {
+	svc, chat, msgRepo := newBranchService(t, &fakes.LLM{Response: "ok"})
+	answer := msgRepo.Messages[3]
+
+	tests := []struct {
+		name string
+		call func() error
+		want error
+	}{
+		{
+			name: "edit assistant message",
+			call: func() error {
+				_, err := svc.EditMessage(context.Background(), chat.UserID, answer.ID, "текст", nil)
+				return err
+			},
+			want: domain.ErrWrongMessageRole,
+		},
+		{
+			name: "regenerate in foreign chat",
+			call: func() error {
+				_, err := svc.Regenerate(context.Background(), uuid.New(), answer.ID, nil)
+				return err
+			},
+			want: domain.ErrAccessDenied,
+		},
+		{
+			name: "regenerate unknown message",
+			call: func() error {
+				_, err := svc.Regenerate(context.Background(), chat.UserID, uuid.New(), nil)
+				return err
+			},
+			want: domain.ErrMessageNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, tt.want) {
+				t.Fatalf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// fakeUsage запоминает, кому засчитано потребление
+type fakeUsage struct {
+	checked, recorded []uuid.UUID
+}
+
+func (f *fakeUsage) Check(_ context.Context, userID uuid.UUID) error {
+	f.checked = append(f.checked, userID)
+	return nil
+}
+
+func (f *fakeUsage) Record(_ context.Context, userID uuid.UUID, _ domain.Usage) error {
+	f.recorded = append(f.recorded, userID)
+	return nil
+}
+
+func TestReply_OrgMemberIsCharged(t *testing.T) {
+	orgID, member := uuid.New(), uuid.New()
+	chat := &domain.Chat{ID: uuid.New(), UserID: uuid.New(), OrgID: &orgID}
+	msgRepo := newBranchRepo(chat)
+	orgRepo := fakes.NewOrgRepo()
+	orgRepo.Members = append(orgRepo.Members, &domain.Membership{OrgID: orgID, UserID: member, Role: domain.OrgRoleMember})
+	az, err := authz.NewService(orgRepo)
+	if err != nil {
+		t.Fatalf("authz.NewService() error = %v", err)
+	}
+	usage := &fakeUsage{}
+
+	svc, err := NewChatService(msgRepo.Chats, msgRepo, &fakes.LLM{Response: "Да."}, &domain.Limits{
+		MaxPromptChars:  10000,
+		MaxHistoryChars: 5000,
+		MaxRequestChars: 1000,
+	}, WithAuthorizer(az), WithUsageTracker(usage))
+	if err != nil {
+		t.Fatalf("NewChatService() error = %v", err)
+	}
+
+	// Участник пишет в чат, созданный владельцем, и перегенерирует ответ
+	answer, err := svc.Reply(context.Background(), chat.ID, member, "можно ли расторгнуть?", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Reply() error = %v", err)
+	}
+	if _, err := svc.Regenerate(context.Background(), member, answer.ID, nil); err != nil {
+		t.Fatalf("Regenerate() error = %v", err)
+	}
+
+	for _, ids := range [][]uuid.UUID{usage.checked, usage.recorded} {
+		if len(ids) != 2 || ids[0] != member || ids[1] != member {
+			t.Fatalf("usage charged to %v, want member %s twice", ids, member)
+		}
+	}
+}
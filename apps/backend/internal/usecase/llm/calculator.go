@@ -0,0 +1,294 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const defaultVATRate = 20.0
+
+var errDivisionByZero = errors.New("division by zero")
+
+// calculatorArgs - аргументы инструмента calculator, набор зависит от операции
+type calculatorArgs struct {
+	Operation  string   `json:"operation"`
+	Expression string   `json:"expression"`
+	Amount     *float64 `json:"amount"`
+	Rate       *float64 `json:"rate"`
+	Price      *float64 `json:"price"`
+	Cost       *float64 `json:"cost"`
+	Principal  *float64 `json:"principal"`
+	AnnualRate *float64 `json:"annual_rate"`
+	Months     *int     `json:"months"`
+}
+
+// CalculatorTool - калькулятор для бизнес-расчётов: выражения, НДС, маржа и наценка, аннуитетный платёж
+func CalculatorTool() Tool {
+	return Tool{
+		Name: "calculator",
+		Description: "Точные расчёты. Операции: expression - арифметическое выражение; " +
+			"vat_add - начислить НДС сверху на сумму без НДС; vat_extract - выделить НДС из суммы с НДС; " +
+			"margin - маржа и наценка по цене и себестоимости; loan_payment - ежемесячный аннуитетный платёж по кредиту.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"operation": {"type": "string", "enum": ["expression", "vat_add", "vat_extract", "margin", "loan_payment"]},
+				"expression": {"type": "string", "description": "Для expression: например (1500 - 200) * 1.2"},
+				"amount": {"type": "number", "description": "Для vat_add и vat_extract: сумма"},
+				"rate": {"type": "number", "description": "Для vat_add и vat_extract: ставка НДС в процентах, по умолчанию 20"},
+				"price": {"type": "number", "description": "Для margin: цена продажи"},
+				"cost": {"type": "number", "description": "Для margin: себестоимость"},
+				"principal": {"type": "number", "description": "Для loan_payment: сумма кредита"},
+				"annual_rate": {"type": "number", "description": "Для loan_payment: годовая ставка в процентах"},
+				"months": {"type": "integer", "description": "Для loan_payment: срок в месяцах"}
+			},
+			"required": ["operation"]
+		}`),
+		Handler: func(_ context.Context, _ *ToolEnv, args json.RawMessage) (string, error) {
+			var in calculatorArgs
+			if err := decodeToolArgs(args, &in); err != nil {
+				return "", err
+			}
+			return calculate(in)
+		},
+	}
+}
+
+func calculate(in calculatorArgs) (string, error) {
+	switch in.Operation {
+	case "expression":
+		value, err := evalExpression(in.Expression)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s = %s", strings.TrimSpace(in.Expression), formatNumber(value)), nil
+
+	case "vat_add", "vat_extract":
+		if in.Amount == nil {
+			return "", fmt.Errorf("%w: amount is required", ErrInvalidToolArgs)
+		}
+		rate := defaultVATRate
+		if in.Rate != nil {
+			rate = *in.Rate
+		}
+		if rate < 0 {
+			return "", fmt.Errorf("%w: rate must not be negative", ErrInvalidToolArgs)
+		}
+
+		if in.Operation == "vat_add" {
+			vat := *in.Amount * rate / 100
+			return fmt.Sprintf("Сумма без НДС: %s\nНДС %s%%: %s\nСумма с НДС: %s",
+				formatMoney(*in.Amount), formatNumber(rate), formatMoney(vat), formatMoney(*in.Amount+vat)), nil
+		}
+
+		vat := *in.Amount * rate / (100 + rate)
+		return fmt.Sprintf("Сумма с НДС: %s\nНДС %s%%: %s\nСумма без НДС: %s",
+			formatMoney(*in.Amount), formatNumber(rate), formatMoney(vat), formatMoney(*in.Amount-vat)), nil
+
+	case "margin":
+		if in.Price == nil || in.Cost == nil {
+			return "", fmt.Errorf("%w: price and cost are required", ErrInvalidToolArgs)
+		}
+		if *in.Price == 0 || *in.Cost == 0 {
+			return "", fmt.Errorf("%w: price and cost must not be zero", ErrInvalidToolArgs)
+		}
+
+		profit := *in.Price - *in.Cost
+		return fmt.Sprintf("Прибыль с единицы: %s\nМаржа: %s%%\nНаценка: %s%%",
+			formatMoney(profit), formatNumber(round2(profit / *in.Price * 100)), formatNumber(round2(profit / *in.Cost * 100))), nil
+
+	case "loan_payment":
+		if in.Principal == nil || in.AnnualRate == nil || in.Months == nil {
+			return "", fmt.Errorf("%w: principal, annual_rate and months are required", ErrInvalidToolArgs)
+		}
+		if *in.Months <= 0 || *in.Principal <= 0 || *in.AnnualRate < 0 {
+			return "", fmt.Errorf("%w: principal and months must be positive, annual_rate not negative", ErrInvalidToolArgs)
+		}
+
+		payment := annuityPayment(*in.Principal, *in.AnnualRate, *in.Months)
+		total := payment * float64(*in.Months)
+		return fmt.Sprintf("Ежемесячный платёж: %s\nВсего выплат: %s\nПереплата: %s",
+			formatMoney(payment), formatMoney(total), formatMoney(total-*in.Principal)), nil
+
+	default:
+		return "", fmt.Errorf("%w: unknown operation %q", ErrInvalidToolArgs, in.Operation)
+	}
+}
+
+// annuityPayment - ежемесячный платёж по аннуитетной схеме
+func annuityPayment(principal, annualRate float64, months int) float64 {
+	r := annualRate / 12 / 100
+	if r == 0 {
+		return principal / float64(months)
+	}
+	return principal * r / (1 - math.Pow(1+r, -float64(months)))
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// formatMoney печатает сумму с двумя знаками после запятой
+func formatMoney(v float64) string {
+	return strconv.FormatFloat(round2(v), 'f', 2, 64)
+}
+
+// formatNumber печатает число без лишних нулей
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// evalExpression вычисляет арифметическое выражение: + - * /, скобки, унарный минус.
+// Десятичный разделитель - точка или запятая, пробелы между разрядами допускаются
+func evalExpression(expr string) (float64, error) {
+	p := &exprParser{input: []rune(strings.TrimSpace(expr))}
+	if len(p.input) == 0 {
+		return 0, fmt.Errorf("%w: expression is empty", ErrInvalidToolArgs)
+	}
+
+	value, err := p.parseSum()
+	if err != nil {
+		return 0, err
+	}
+
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return 0, fmt.Errorf("%w: unexpected %q at position %d", ErrInvalidToolArgs, p.input[p.pos], p.pos+1)
+	}
+
+	return value, nil
+}
+
+type exprParser struct {
+	input []rune
+	pos   int
+}
+
+func (p *exprParser) skipSpaces() {
+	for p.pos < len(p.input) && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *exprParser) peek() rune {
+	p.skipSpaces()
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *exprParser) parseSum() (float64, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			return left, nil
+		}
+		p.pos++
+
+		right, err := p.parseProduct()
+		if err != nil {
+			return 0, err
+		}
+		if op == '+' {
+			left += right
+		} else {
+			left -= right
+		}
+	}
+}
+
+func (p *exprParser) parseProduct() (float64, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' && op != '×' && op != ':' {
+			return left, nil
+		}
+		p.pos++
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return 0, err
+		}
+		if op == '/' || op == ':' {
+			if right == 0 {
+				return 0, errDivisionByZero
+			}
+			left /= right
+		} else {
+			left *= right
+		}
+	}
+}
+
+func (p *exprParser) parseUnary() (float64, error) {
+	if p.peek() == '-' {
+		p.pos++
+		v, err := p.parseUnary()
+		return -v, err
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (float64, error) {
+	if p.peek() == '(' {
+		p.pos++
+		v, err := p.parseSum()
+		if err != nil {
+			return 0, err
+		}
+		if p.peek() != ')' {
+			return 0, fmt.Errorf("%w: missing closing bracket", ErrInvalidToolArgs)
+		}
+		p.pos++
+		return v, nil
+	}
+
+	p.skipSpaces()
+	start := p.pos
+	var digits strings.Builder
+scan:
+	for p.pos < len(p.input) {
+		r := p.input[p.pos]
+		switch {
+		case unicode.IsDigit(r):
+			digits.WriteRune(r)
+		case r == '.' || r == ',':
+			digits.WriteRune('.')
+		case r == ' ' && p.pos+1 < len(p.input) && unicode.IsDigit(p.input[p.pos+1]) && digits.Len() > 0:
+			// "1 500 000" - пробел как разделитель разрядов
+		default:
+			break scan
+		}
+		p.pos++
+	}
+
+	if digits.Len() == 0 {
+		if start >= len(p.input) {
+			return 0, fmt.Errorf("%w: unexpected end of expression", ErrInvalidToolArgs)
+		}
+		return 0, fmt.Errorf("%w: unexpected %q at position %d", ErrInvalidToolArgs, p.input[start], start+1)
+	}
+
+	v, err := strconv.ParseFloat(digits.String(), 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: bad number %q", ErrInvalidToolArgs, digits.String())
+	}
+	return v, nil
+}
@@ -0,0 +1,124 @@
+package llm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestEvalExpression(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		want    float64
+		wantErr error
+	}{
+		{name: "precedence", expr: "2 + 3 * 4", want: 14},
+		{name: "brackets", expr: "(1500 - 200) * 1.2", want: 1560},
+		{name: "comma decimal", expr: "10,5 * 2", want: 21},
+		{name: "thousands separator", expr: "1 500 000 / 12", want: 125000},
+		{name: "unary minus", expr: "-(2 - 5)", want: 3},
+		{name: "colon division", expr: "100 : 4", want: 25},
+		{name: "division by zero", expr: "1 / (2 - 2)", wantErr: errDivisionByZero},
+		{name: "unknown symbol", expr: "2 ^ 3", wantErr: ErrInvalidToolArgs},
+		{name: "unclosed bracket", expr: "(2 + 3", wantErr: ErrInvalidToolArgs},
+		{name: "empty", expr: "  ", wantErr: ErrInvalidToolArgs},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := evalExpression(tt.expr)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("evalExpression(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculate(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     calculatorArgs
+		contains []string
+		wantErr  bool
+	}{
+		{
+			name:     "vat add default rate",
+			args:     calculatorArgs{Operation: "vat_add", Amount: ptr(1000.0)},
+			contains: []string{"НДС 20%: 200.00", "Сумма с НДС: 1200.00"},
+		},
+		{
+			name:     "vat extract custom rate",
+			args:     calculatorArgs{Operation: "vat_extract", Amount: ptr(1100.0), Rate: ptr(10.0)},
+			contains: []string{"НДС 10%: 100.00", "Сумма без НДС: 1000.00"},
+		},
+		{
+			name:     "margin and markup",
+			args:     calculatorArgs{Operation: "margin", Price: ptr(150.0), Cost: ptr(100.0)},
+			contains: []string{"Прибыль с единицы: 50.00", "Маржа: 33.33%", "Наценка: 50%"},
+		},
+		{
+			name:     "annuity loan",
+			args:     calculatorArgs{Operation: "loan_payment", Principal: ptr(1000000.0), AnnualRate: ptr(12.0), Months: ptr(12)},
+			contains: []string{"Ежемесячный платёж: 88848.79", "Переплата: 66185.46"},
+		},
+		{
+			name:     "zero rate loan",
+			args:     calculatorArgs{Operation: "loan_payment", Principal: ptr(1200.0), AnnualRate: ptr(0.0), Months: ptr(12)},
+			contains: []string{"Ежемесячный платёж: 100.00", "Переплата: 0.00"},
+		},
+		{
+			name:     "expression",
+			args:     calculatorArgs{Operation: "expression", Expression: "2+2"},
+			contains: []string{"2+2 = 4"},
+		},
+		{
+			name:    "missing amount",
+			args:    calculatorArgs{Operation: "vat_add"},
+			wantErr: true,
+		},
+		{
+			name:    "loan without months",
+			args:    calculatorArgs{Operation: "loan_payment", Principal: ptr(1000.0), AnnualRate: ptr(10.0)},
+			wantErr: true,
+		},
+		{
+			name:    "unknown operation",
+			args:    calculatorArgs{Operation: "sqrt"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := calculate(tt.args)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidToolArgs) {
+					t.Fatalf("error = %v, want ErrInvalidToolArgs", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(got, want) {
+					t.Fatalf("result does not contain %q:\n%s", want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package llm
+
+import (
+	"backend/internal/domain"
+	"backend/internal/logging"
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	// maxGuardrailRetries - сколько раз ответ генерируется заново, если он пустой, нечитаемый или не на русском
+	maxGuardrailRetries = 2
+
+	// fallbackAnswer показывается, если модель так и не дала читаемого ответа
+	fallbackAnswer = "Не получилось сформулировать ответ. Попробуйте переформулировать вопрос."
+	// moderatedAnswer заменяет ответ, отклонённый модерацией
+	moderatedAnswer = "Не могу помочь с этим запросом."
+
+	// minLanguageLetters - ответы короче этого числа букв (числа, "OK", код) по языку не проверяются
+	minLanguageLetters = 20
+
+	// moderationUnavailable - причина в метаданных сообщения при сбое классификатора.
+	// Текст ошибки туда не попадает: сообщение видно пользователю и уходит в экспорт, ошибка пишется в лог
+	moderationUnavailable = "classifier unavailable"
+)
+
+const (
+	GuardrailEmpty      = "empty"
+	GuardrailGarbage    = "garbage"
+	GuardrailLanguage   = "language"
+	GuardrailEcho       = "echo"
+	GuardrailModeration = "moderation"
+
+	GuardrailActionRetried  = "retried"
+	GuardrailActionFixed    = "fixed"
+	GuardrailActionReplaced = "replaced"
+	GuardrailActionKept     = "kept"
+)
+
+var (
+	// echoLineRe - строки, в которых модель повторяет разметку промпта: "WEB_SEARCH:", "[WEB_SEARCH_RESULTS]", границы документов
+	echoLineRe = regexp.MustCompile(`(?im)^[\t ]*(\[/?(WEB_SEARCH(_RESULTS)?|SEARCH_RESULTS|DOCUMENTS|HISTORY|SYSTEM|USER)\]|(WEB_SEARCH|DOCUMENTS|HISTORY|SYSTEM|USER):|<<<(END )?UNTRUSTED[^\n]*>>>)[\t ]*$\n?`)
+	// echoInlineRe - те же метки посреди текста
+	echoInlineRe = regexp.MustCompile(`\[/?WEB_SEARCH(_RESULTS)?\]|<<<(END )?UNTRUSTED [0-9a-f]+[^>\n]*>>>`)
+	// assistantPrefixRe - модель продолжает формат истории "assistant: ..."
+	assistantPrefixRe = regexp.MustCompile(`(?i)^\s*(assistant|ассистент)\s*:\s*`)
+	extraNewlinesRe   = regexp.MustCompile(`\n{3,}`)
+
+	// codeRe и urlRe вырезаются перед проверкой языка: код и ссылки всегда латиницей
+	codeRe = regexp.MustCompile("(?s)```.*?```|`[^`\n]*`")
+	urlRe  = regexp.MustCompile(`https?://\S+`)
+
+	// otherLanguageMarkers - пользователь сам просит ответ не на русском
+	otherLanguageMarkers = []string{"английск", "на англ", "english", "translat", "перевед", "перевод", "по-немецки", "немецк", "китайск"}
+)
+
+// answerProblem - почему ответ нужно сгенерировать заново
+type answerProblem struct {
+	check  string
+	reason string
+	// hint дописывается в конец промпта при повторной генерации
+	hint string
+}
+
+// regenerateFunc генерирует ответ заново с подсказкой в конце промпта
+type regenerateFunc func(ctx context.Context, hint string) (*domain.Generation, error)
+
+// applyGuardrails проверяет ответ модели перед сохранением: убирает повторённую разметку промпта,
+// при пустом, нечитаемом или не русском ответе генерирует заново (если regenerate != nil),
+// затем прогоняет через модерацию. Токены повторных генераций добавляются к generation
+func (s *Service) applyGuardrails(ctx context.Context, userText string, generation *domain.Generation, regenerate regenerateFunc) (*domain.Generation, []domain.GuardrailFlag) {
+	var flags []domain.GuardrailFlag
+
+	for attempt := 0; ; attempt++ {
+		content, echoed := stripPromptEchoes(generation.Content)
+		if echoed {
+			flags = append(flags, domain.GuardrailFlag{Check: GuardrailEcho, Reason: "prompt markup in answer", Action: GuardrailActionFixed})
+		}
+		generation.Content = content
+
+		problem := checkAnswer(userText, content)
+		if problem == nil {
+			break
+		}
+
+		if regenerate != nil && attempt < maxGuardrailRetries {
+			next, err := regenerate(ctx, problem.hint)
+			if err == nil {
+				flags = append(flags, domain.GuardrailFlag{Check: problem.check, Reason: problem.reason, Action: GuardrailActionRetried})
+				next.PromptTokens += generation.PromptTokens
+				next.CompletionTokens += generation.CompletionTokens
+				generation = next
+				continue
+			}
+			logging.FromContext(ctx).WarnContext(ctx, "guardrail regeneration failed", slog.Any("error", err))
+		}
+
+		// Повторы исчерпаны: нечитаемый ответ заменяем заглушкой, ответ на другом языке оставляем
+		if problem.check == GuardrailLanguage {
+			flags = append(flags, domain.GuardrailFlag{Check: problem.check, Reason: problem.reason, Action: GuardrailActionKept})
+		} else {
+			generation.Content = fallbackAnswer
+			flags = append(flags, domain.GuardrailFlag{Check: problem.check, Reason: problem.reason, Action: GuardrailActionReplaced})
+		}
+		break
+	}
+
+	if s.moderator != nil && generation.Content != fallbackAnswer {
+		if flag := s.moderate(ctx, generation); flag != nil {
+			flags = append(flags, *flag)
+		}
+	}
+
+	for _, flag := range flags {
+		logging.FromContext(ctx).WarnContext(ctx, "answer guardrail triggered",
+			slog.String("check", flag.Check),
+			slog.String("reason", flag.Reason),
+			slog.String("action", flag.Action),
+		)
+	}
+
+	return generation, flags
+}
+
+// moderate проверяет ответ классификатором. Сбой классификатора не блокирует ответ, но отмечается
+func (s *Service) moderate(ctx context.Context, generation *domain.Generation) *domain.GuardrailFlag {
+	ctx, span := tracer.Start(ctx, "reply.moderation")
+	result, err := s.moderator.Moderate(ctx, generation.Content)
+	endSpan(span, err)
+
+	if err != nil {
+		logging.FromContext(ctx).WarnContext(ctx, "moderation classifier failed", slog.Any("error", err))
+		return &domain.GuardrailFlag{Check: GuardrailModeration, Reason: moderationUnavailable, Action: GuardrailActionKept}
+	}
+	if !result.Flagged {
+		return nil
+	}
+
+	generation.Content = moderatedAnswer
+	reason := "flagged"
+	if len(result.Categories) > 0 {
+		reason = strings.Join(result.Categories, ", ")
+	}
+	return &domain.GuardrailFlag{Check: GuardrailModeration, Reason: reason, Action: GuardrailActionReplaced}
+}
+
+// stripPromptEchoes убирает из ответа разметку промпта, которую модель иногда повторяет
+func stripPromptEchoes(content string) (string, bool) {
+	cleaned := assistantPrefixRe.ReplaceAllString(content, "")
+	cleaned = echoLineRe.ReplaceAllString(cleaned, "")
+	cleaned = echoInlineRe.ReplaceAllString(cleaned, "")
+	cleaned = strings.ReplaceAll(cleaned, strings.TrimSpace(webSearchNote), "")
+	cleaned = extraNewlinesRe.ReplaceAllString(cleaned, "\n\n")
+	cleaned = strings.TrimSpace(cleaned)
+
+	return cleaned, cleaned != strings.TrimSpace(content)
+}
+
+// checkAnswer возвращает первую найденную проблему ответа или nil
+func checkAnswer(userText, content string) *answerProblem {
+	if content == "" {
+		return &answerProblem{
+			check:  GuardrailEmpty,
+			reason: "empty answer",
+			hint:   "\n\nПредыдущий ответ был пустым. Дай содержательный ответ обычным текстом.",
+		}
+	}
+
+	if reason := garbageReason(content); reason != "" {
+		return &answerProblem{
+			check:  GuardrailGarbage,
+			reason: reason,
+			hint:   "\n\nПредыдущий ответ был нечитаемым. Дай связный ответ обычным текстом, без повторов.",
+		}
+	}
+
+	if !asksForOtherLanguage(userText) {
+		if share, letters := cyrillicShare(content); letters >= minLanguageLetters && share < 0.5 {
+			return &answerProblem{
+				check:  GuardrailLanguage,
+				reason: fmt.Sprintf("cyrillic letters %.0f%%", share*100),
+				hint:   "\n\nОтвечай только на русском языке.",
+			}
+		}
+	}
+
+	return nil
+}
+
+// garbageReason распознаёт вырожденные ответы: почти без букв, битая кодировка, зацикливание
+func garbageReason(content string) string {
+	var total, meaningful, broken int
+	for _, r := range content {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		total++
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			meaningful++
+		}
+		if r == unicode.ReplacementChar {
+			broken++
+		}
+	}
+
+	if broken > 3 {
+		return "broken encoding"
+	}
+	if total >= 10 && meaningful*3 < total {
+		return "mostly symbols"
+	}
+
+	lines := make(map[string]int)
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if len([]rune(line)) < 5 {
+			continue
+		}
+		lines[line]++
+		if lines[line] >= 5 {
+			return "repeated lines"
+		}
+	}
+
+	words := strings.Fields(strings.ToLower(content))
+	if len(words) >= 30 {
+		counts := make(map[string]int)
+		for _, w := range words {
+			counts[w]++
+			if counts[w]*5 > len(words)*2 {
+				return "repeated words"
+			}
+		}
+	}
+
+	return ""
+}
+
+// cyrillicShare - доля кириллицы среди букв ответа без кода и ссылок
+func cyrillicShare(content string) (float64, int) {
+	content = codeRe.ReplaceAllString(content, "")
+	content = urlRe.ReplaceAllString(content, "")
+
+	var letters, cyrillic int
+	for _, r := range content {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.Is(unicode.Cyrillic, r) {
+			cyrillic++
+		}
+	}
+	if letters == 0 {
+		return 1, 0
+	}
+	return float64(cyrillic) / float64(letters), letters
+}
+
+func asksForOtherLanguage(userText string) bool {
+	lower := strings.ToLower(userText)
+	for _, m := range otherLanguageMarkers {
+		if strings.Contains(lower, m) {
+			return true
+		}
+	}
+	return false
+}
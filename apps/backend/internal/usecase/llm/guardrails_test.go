@@ -0,0 +1,257 @@
+package llm
+
+import (
+	"backend/internal/domain"
+	"backend/internal/fakes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestStripPromptEchoes(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		want     string
+		wantEcho bool
+	}{
+		{
+			name:    "clean answer is unchanged",
+			content: "Ключевая ставка - 16% [1].",
+			want:    "Ключевая ставка - 16% [1].",
+		},
+		{
+			name:     "echoed web search markers are removed",
+			content:  "[WEB_SEARCH_RESULTS]\nКлючевая ставка - 16% [1].\n[/WEB_SEARCH_RESULTS]",
+			want:     "Ключевая ставка - 16% [1].",
+			wantEcho: true,
+		},
+		{
+			name:     "section headers and fences are removed",
+			content:  "assistant: WEB_SEARCH:\n\n\n<<<UNTRUSTED 0123456789abcdef source=web_search>>>\nОтвет по данным [1].",
+			want:     "Ответ по данным [1].",
+			wantEcho: true,
+		},
+		{
+			name:     "inline marker is removed",
+			content:  "Судя по [WEB_SEARCH_RESULTS] курс вырос.",
+			want:     "Судя по  курс вырос.",
+			wantEcho: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, echoed := stripPromptEchoes(tt.content)
+			if got != tt.want || echoed != tt.wantEcho {
+				t.Fatalf("stripPromptEchoes() = %q, %v; want %q, %v", got, echoed, tt.want, tt.wantEcho)
+			}
+		})
+	}
+}
+
+func TestCheckAnswer(t *testing.T) {
+	tests := []struct {
+		name     string
+		userText string
+		content  string
+		want     string
+	}{
+		{name: "normal russian answer", userText: "как дела?", content: "Всё хорошо, чем помочь?"},
+		{name: "short latin answer", userText: "код ответа?", content: "HTTP 200 OK"},
+		{name: "empty", userText: "вопрос", content: "", want: GuardrailEmpty},
+		{name: "mostly symbols", userText: "вопрос", content: "### *** ----- !!! ??? ||| ###", want: GuardrailGarbage},
+		{name: "broken encoding", userText: "вопрос", content: "Ответ �����", want: GuardrailGarbage},
+		{name: "repeated lines", userText: "вопрос", content: strings.Repeat("Сумма НДС равна\n", 6), want: GuardrailGarbage},
+		{name: "repeated words", userText: "вопрос", content: strings.Repeat("налог ", 40), want: GuardrailGarbage},
+		{
+			name:     "english answer",
+			userText: "как посчитать маржу?",
+			content:  "Margin is the difference between the price and the cost divided by the price.",
+			want:     GuardrailLanguage,
+		},
+		{
+			name:     "english answer on request",
+			userText: "переведи на английский: спасибо за заказ",
+			content:  "Thank you for your order, we appreciate your business.",
+		},
+		{
+			name:     "code and links do not count as foreign language",
+			userText: "как посчитать НДС в Excel?",
+			content:  "Используйте формулу:\n```\n=ROUND(A1*20/120; 2) // extract VAT from gross amount\n```\nПодробнее: https://support.microsoft.com/en-us/office/round-function",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problem := checkAnswer(tt.userText, tt.content)
+			got := ""
+			if problem != nil {
+				got = problem.check
+			}
+			if got != tt.want {
+				t.Fatalf("checkAnswer() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// seqLLM отвечает по очереди заранее заданными ответами, последний повторяется
+type seqLLM struct {
+	responses []string
+	prompts   []string
+}
+
+func (f *seqLLM) Generate(_ context.Context, prompt []byte) (*domain.Generation, error) {
+	f.prompts = append(f.prompts, string(prompt))
+	i := len(f.prompts) - 1
+	if i >= len(f.responses) {
+		i = len(f.responses) - 1
+	}
+	return &domain.Generation{Content: f.responses[i], Model: "fake", PromptTokens: 10, CompletionTokens: 5}, nil
+}
+
+// fakeModerator помечает ответы, содержащие flagWord
+type fakeModerator struct {
+	flagWord string
+	err      error
+}
+
+func (f *fakeModerator) Moderate(_ context.Context, text string) (*domain.Moderation, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if strings.Contains(text, f.flagWord) {
+		return &domain.Moderation{Flagged: true, Categories: []string{"illegal"}}, nil
+	}
+	return &domain.Moderation{}, nil
+}
+
+func TestReply_Guardrails(t *testing.T) {
+	english := "Sure, here is a detailed answer about taxes for your small business."
+
+	tests := []struct {
+		name        string
+		responses   []string
+		moderator   domain.Moderator
+		wantContent string
+		wantCalls   int
+		wantFlags   []domain.GuardrailFlag
+	}{
+		{
+			name:        "passing answer is saved as is",
+			responses:   []string{"Налог по УСН - 6% от доходов."},
+			wantContent: "Налог по УСН - 6% от доходов.",
+			wantCalls:   1,
+		},
+		{
+			name:        "empty and english answers are regenerated",
+			responses:   []string{"  ", english, "Налог по УСН - 6% от доходов."},
+			wantContent: "Налог по УСН - 6% от доходов.",
+			wantCalls:   3,
+			wantFlags: []domain.GuardrailFlag{
+				{Check: GuardrailEmpty, Reason: "empty answer", Action: GuardrailActionRetried},
+				{Check: GuardrailLanguage, Reason: "cyrillic letters 0%", Action: GuardrailActionRetried},
+			},
+		},
+		{
+			name:        "answer stays empty after retries",
+			responses:   []string{""},
+			wantContent: fallbackAnswer,
+			wantCalls:   3,
+			wantFlags: []domain.GuardrailFlag{
+				{Check: GuardrailEmpty, Reason: "empty answer", Action: GuardrailActionRetried},
+				{Check: GuardrailEmpty, Reason: "empty answer", Action: GuardrailActionRetried},
+				{Check: GuardrailEmpty, Reason: "empty answer", Action: GuardrailActionReplaced},
+			},
+		},
+		{
+			name:        "english answer is kept after retries",
+			responses:   []string{english},
+			wantContent: english,
+			wantCalls:   3,
+			wantFlags: []domain.GuardrailFlag{
+				{Check: GuardrailLanguage, Reason: "cyrillic letters 0%", Action: GuardrailActionRetried},
+				{Check: GuardrailLanguage, Reason: "cyrillic letters 0%", Action: GuardrailActionRetried},
+				{Check: GuardrailLanguage, Reason: "cyrillic letters 0%", Action: GuardrailActionKept},
+			},
+		},
+		{
+			name:        "moderation replaces flagged answer",
+			responses:   []string{"Схема обнала через фирму-однодневку такова..."},
+			moderator:   &fakeModerator{flagWord: "обнал"},
+			wantContent: moderatedAnswer,
+			wantCalls:   1,
+			wantFlags: []domain.GuardrailFlag{
+				{Check: GuardrailModeration, Reason: "illegal", Action: GuardrailActionReplaced},
+			},
+		},
+		{
+			name:        "moderation failure keeps answer",
+			responses:   []string{"Налог по УСН - 6% от доходов."},
+			moderator:   &fakeModerator{err: errors.New("dial tcp 10.0.0.5:11434: connection refused")},
+			wantContent: "Налог по УСН - 6% от доходов.",
+			wantCalls:   1,
+			wantFlags: []domain.GuardrailFlag{
+				{Check: GuardrailModeration, Reason: moderationUnavailable, Action: GuardrailActionKept},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New()
+			chat := &domain.Chat{ID: uuid.New(), UserID: userID}
+			model := &seqLLM{responses: tt.responses}
+
+			opts := []Option{}
+			if tt.moderator != nil {
+				opts = append(opts, WithModerator(tt.moderator))
+			}
+			svc, err := NewChatService(fakes.NewChatRepo(chat), &fakes.MessageRepo{}, model, &domain.Limits{
+				MaxPromptChars:  10000,
+				MaxHistoryChars: 5000,
+				MaxRequestChars: 1000,
+			}, opts...)
+			if err != nil {
+				t.Fatalf("NewChatService() error = %v", err)
+			}
+
+			msg, err := svc.Reply(context.Background(), chat.ID, userID, "какой налог на УСН?", nil, nil, nil)
+			if err != nil {
+				t.Fatalf("Reply() error = %v", err)
+			}
+
+			if msg.Content != tt.wantContent {
+				t.Fatalf("content = %q, want %q", msg.Content, tt.wantContent)
+			}
+			if len(model.prompts) != tt.wantCalls {
+				t.Fatalf("llm calls = %d, want %d", len(model.prompts), tt.wantCalls)
+			}
+			if !reflect.DeepEqual(msg.Metadata.Guardrails, tt.wantFlags) {
+				t.Fatalf("guardrails = %+v, want %+v", msg.Metadata.Guardrails, tt.wantFlags)
+			}
+			if tt.wantCalls > 1 && !strings.HasPrefix(model.prompts[1], model.prompts[0]) {
+				t.Fatalf("retry prompt must extend the original prompt:\n%s", model.prompts[1])
+			}
+		})
+	}
+}
+
+func TestParseModeration(t *testing.T) {
+	got, err := parseModeration("```json\n{\"flagged\": true, \"categories\": [\"violence\"]}\n```")
+	if err != nil {
+		t.Fatalf("parseModeration() error = %v", err)
+	}
+	if !got.Flagged || !reflect.DeepEqual(got.Categories, []string{"violence"}) {
+		t.Fatalf("parseModeration() = %+v", got)
+	}
+
+	if _, err := parseModeration("не знаю"); err == nil {
+		t.Fatalf("expected error for response without JSON")
+	}
+}
@@ -0,0 +1,104 @@
+package llm
+
+import (
+	"backend/internal/domain"
+	"backend/internal/logging"
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// pendingReplyTimeout - сколько вопрос может ждать ответа в статусе pending. Дольше - генерация
+// оборвалась вместе с процессом, и повтор запроса генерирует ответ заново
+const pendingReplyTimeout = 5 * time.Minute
+
+// replyCall - генерация ответа по ключу идемпотентности, которую ждут повторные запросы
+type replyCall struct {
+	done chan struct{}
+	msg  *domain.Message
+	err  error
+}
+
+// wait ждёт завершения генерации. Отмена ожидающего запроса не прерывает саму генерацию
+func (c *replyCall) wait(ctx context.Context) (*domain.Message, error) {
+	select {
+	case <-c.done:
+		return c.msg, c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// inflightReplies - генерации с ключом идемпотентности, идущие в этом процессе.
+// Между экземплярами сервиса повторы разводит уникальный индекс по client_message_id
+type inflightReplies struct {
+	mu    sync.Mutex
+	calls map[string]*replyCall
+}
+
+func inflightKey(chatID uuid.UUID, clientMessageID string) string {
+	return chatID.String() + "/" + clientMessageID
+}
+
+// join возвращает генерацию по ключу. leader = true - генерации не было, и вызывающий должен её выполнить
+func (r *inflightReplies) join(chatID uuid.UUID, clientMessageID string) (call *replyCall, leader bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := inflightKey(chatID, clientMessageID)
+	if call, ok := r.calls[key]; ok {
+		return call, false
+	}
+
+	if r.calls == nil {
+		r.calls = make(map[string]*replyCall)
+	}
+	call = &replyCall{done: make(chan struct{})}
+	r.calls[key] = call
+	return call, true
+}
+
+// finish сохраняет результат генерации для ожидающих и убирает её из списка идущих
+func (r *inflightReplies) finish(chatID uuid.UUID, clientMessageID string, call *replyCall, msg *domain.Message, err error) {
+	r.mu.Lock()
+	delete(r.calls, inflightKey(chatID, clientMessageID))
+	r.mu.Unlock()
+
+	call.msg, call.err = msg, err
+	close(call.done)
+}
+
+// replay отвечает на повтор уже сохранённого сообщения: возвращает готовый ответ, сообщает,
+// что ответ ещё генерируется, а если прошлая генерация оборвалась - генерирует его без повторного сохранения вопроса
+func (s *Service) replay(ctx context.Context, userID uuid.UUID, chat *domain.Chat, userMsg *domain.Message, userText string, docTextGetter DocumentTextGetter) (*domain.Message, error) {
+	if userMsg.Content != userText {
+		return nil, domain.ErrIdempotencyKeyReused
+	}
+
+	answer, err := s.msgRepo.GetAnswer(ctx, userMsg.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get answer: %w", err)
+	}
+	if answer != nil {
+		logging.FromContext(ctx).InfoContext(ctx, "idempotent replay",
+			slog.String("chat_id", chat.ID.String()),
+			slog.String("message_id", answer.ID.String()),
+		)
+		return answer, nil
+	}
+
+	if userMsg.Status == domain.MessageStatusPending && time.Since(userMsg.CreatedAt) < pendingReplyTimeout {
+		// Ответ генерирует другой экземпляр сервиса
+		return nil, domain.ErrReplyInProgress
+	}
+
+	logging.FromContext(ctx).WarnContext(ctx, "resuming reply without answer",
+		slog.String("chat_id", chat.ID.String()),
+		slog.String("question_id", userMsg.ID.String()),
+	)
+	return s.answer(ctx, userID, chat, userMsg, false, docTextGetter)
+}
@@ -0,0 +1,126 @@
+package llm
+
+import (
+	"backend/internal/domain"
+	"backend/internal/fakes"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// blockingLLM не отвечает, пока тест не закроет release
+type blockingLLM struct {
+	started chan struct{}
+	release chan struct{}
+	calls   atomic.Int32
+}
+
+func (b *blockingLLM) Generate(_ context.Context, _ []byte) (*domain.Generation, error) {
+	if b.calls.Add(1) == 1 {
+		close(b.started)
+	}
+	<-b.release
+	return &domain.Generation{Content: "Срок поставки - 30 дней.", Model: "fake"}, nil
+}
+
+func newIdempotencyService(t *testing.T, model domain.LLM) (*Service, *domain.Chat, *fakes.MessageRepo) {
+	t.Helper()
+
+	chat := &domain.Chat{ID: uuid.New(), UserID: uuid.New()}
+	msgRepo := newBranchRepo(chat)
+	svc, err := NewChatService(msgRepo.Chats, msgRepo, model, &domain.Limits{
+		MaxPromptChars:  10000,
+		MaxHistoryChars: 5000,
+		MaxRequestChars: 1000,
+	})
+	if err != nil {
+		t.Fatalf("NewChatService() error = %v", err)
+	}
+	return svc, chat, msgRepo
+}
+
+func TestReplyIdempotent_RetryReturnsOriginalAnswer(t *testing.T) {
+	model := &fakes.LLM{Response: "Срок поставки - 30 дней."}
+	svc, chat, msgRepo := newIdempotencyService(t, model)
+	ctx := context.Background()
+
+	first, err := svc.ReplyIdempotent(ctx, "key-1", chat.ID, chat.UserID, "какой срок?", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("ReplyIdempotent() error = %v", err)
+	}
+	retry, err := svc.ReplyIdempotent(ctx, "key-1", chat.ID, chat.UserID, "какой срок?", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("retry error = %v", err)
+	}
+
+	if retry.ID != first.ID {
+		t.Fatalf("retry returned %s, want original answer %s", retry.ID, first.ID)
+	}
+	if len(model.Prompts) != 1 || len(msgRepo.Messages) != 2 {
+		t.Fatalf("llm calls = %d, saved messages = %d; want 1 and 2", len(model.Prompts), len(msgRepo.Messages))
+	}
+
+	// Тот же ключ с другим текстом - ошибка клиента
+	_, err = svc.ReplyIdempotent(ctx, "key-1", chat.ID, chat.UserID, "другой вопрос", nil, nil, nil)
+	if !errors.Is(err, domain.ErrIdempotencyKeyReused) {
+		t.Fatalf("error = %v, want ErrIdempotencyKeyReused", err)
+	}
+}
+
+func TestReplyIdempotent_ConcurrentRetryWaitsForGeneration(t *testing.T) {
+	model := &blockingLLM{started: make(chan struct{}), release: make(chan struct{})}
+	svc, chat, msgRepo := newIdempotencyService(t, model)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	results := make([]*domain.Message, 2)
+	errs := make([]error, 2)
+	reply := func(i int) {
+		defer wg.Done()
+		results[i], errs[i] = svc.ReplyIdempotent(ctx, "key-1", chat.ID, chat.UserID, "какой срок?", nil, nil, nil)
+	}
+
+	wg.Add(2)
+	go reply(0)
+	<-model.started
+	go reply(1)
+	close(model.release)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("request %d error = %v", i, err)
+		}
+	}
+	if results[0].ID != results[1].ID {
+		t.Fatalf("requests got different answers: %s and %s", results[0].ID, results[1].ID)
+	}
+	if calls := model.calls.Load(); calls != 1 || len(msgRepo.Messages) != 2 {
+		t.Fatalf("llm calls = %d, saved messages = %d; want 1 and 2", calls, len(msgRepo.Messages))
+	}
+}
+
+func TestReplyIdempotent_ResumesFailedGeneration(t *testing.T) {
+	model := &fakes.LLM{Err: errors.New("timeout")}
+	svc, chat, msgRepo := newIdempotencyService(t, model)
+	ctx := context.Background()
+
+	if _, err := svc.ReplyIdempotent(ctx, "key-1", chat.ID, chat.UserID, "какой срок?", nil, nil, nil); err == nil {
+		t.Fatalf("expected generation error")
+	}
+
+	model.Err, model.Response = nil, "30 дней."
+	msg, err := svc.ReplyIdempotent(ctx, "key-1", chat.ID, chat.UserID, "какой срок?", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("retry error = %v", err)
+	}
+
+	// Вопрос сохранён один раз, ответ прикреплён к нему
+	if len(msgRepo.Messages) != 2 || msg.ParentID == nil || *msg.ParentID != msgRepo.Messages[0].ID {
+		t.Fatalf("unexpected messages after retry: %d saved, answer parent %v", len(msgRepo.Messages), msg.ParentID)
+	}
+}
@@ -0,0 +1,64 @@
+package llm
+
+import (
+	"backend/internal/domain"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// maxModerationChars - сколько символов ответа отправляется классификатору
+const maxModerationChars = 4000
+
+const moderationPrompt = "Ты проверяешь ответ ассистента для владельцев малого бизнеса перед показом пользователю." +
+	"\nОтметь ответ, только если он содержит: инструкции по насилию или оружию (violence), поощрение самоповреждения (self_harm), " +
+	"откровенный сексуальный контент (sexual), оскорбления и разжигание ненависти (hate), " +
+	"схемы мошенничества, уклонения от налогов, отмывания денег или другие незаконные действия (illegal)." +
+	"\nОбычные деловые, юридические и налоговые советы, в том числе о рисках и штрафах, допустимы." +
+	"\nОтветь только JSON без пояснений: {\"flagged\": true или false, \"categories\": [\"категории из списка\"]}" +
+	"\n\nОтвет ассистента:\n%s"
+
+// LLMModerator - модерация ответа отдельным вызовом модели (лучше лёгкой и с JSON-выводом)
+type LLMModerator struct {
+	classifier domain.LLM
+}
+
+func NewLLMModerator(classifier domain.LLM) *LLMModerator {
+	return &LLMModerator{classifier: classifier}
+}
+
+func (m *LLMModerator) Moderate(ctx context.Context, text string) (*domain.Moderation, error) {
+	if runes := []rune(text); len(runes) > maxModerationChars {
+		text = string(runes[:maxModerationChars])
+	}
+
+	gen, err := m.classifier.Generate(ctx, []byte(fmt.Sprintf(moderationPrompt, text)))
+	if err != nil {
+		return nil, err
+	}
+
+	return parseModeration(gen.Content)
+}
+
+// parseModeration достаёт JSON из ответа классификатора
+func parseModeration(raw string) (*domain.Moderation, error) {
+	start := strings.Index(raw, "{")
+	end := strings.LastIndex(raw, "}")
+	if start == -1 || end < start {
+		return nil, errors.New("moderation response has no JSON object")
+	}
+
+	var out struct {
+		Flagged    bool     `json:"flagged"`
+		Categories []string `json:"categories"`
+	}
+	if err := json.Unmarshal([]byte(raw[start:end+1]), &out); err != nil {
+		return nil, fmt.Errorf("failed to decode moderation response: %w", err)
+	}
+
+	return &domain.Moderation{Flagged: out.Flagged, Categories: out.Categories}, nil
+}
+
+var _ domain.Moderator = (*LLMModerator)(nil)
@@ -0,0 +1,45 @@
+package llm
+
+import (
+	"backend/internal/logging"
+	"backend/internal/usecase/pii"
+	"context"
+	"log/slog"
+
+	"github.com/google/uuid"
+)
+
+// piiTokenNote - правило для модели, когда персональные данные заменены обратимыми метками
+const piiTokenNote = "\nПерсональные данные и реквизиты в запросе заменены метками вида [ИНН_1], [ТЕЛЕФОН_2]. " +
+	"Если значение нужно в ответе, пиши метку без изменений - она будет заменена на настоящее значение."
+
+// newRedactor создаёт скрывающий персональные данные Redactor для запроса. Без политики данные не меняются
+func (s *Service) newRedactor(scenarioCode *string) *pii.Redactor {
+	if s.piiPolicy == nil {
+		return pii.NewRedactor(pii.ActionAllow)
+	}
+	return pii.NewRedactor(s.piiPolicy.For(scenarioCode))
+}
+
+// logPIIBlocked пишет в лог, какие виды данных заблокировали запрос, без самих значений
+func (s *Service) logPIIBlocked(ctx context.Context, chatID uuid.UUID, redactor *pii.Redactor) {
+	attrs := []any{slog.String("chat_id", chatID.String())}
+	if report := redactor.Report(); report != nil {
+		attrs = append(attrs, slog.Any("counts", report.Counts))
+	}
+	logging.FromContext(ctx).WarnContext(ctx, "request blocked by pii policy", attrs...)
+}
+
+// redactingDocs отдаёт текст документов уже без персональных данных - и в промпт, и инструменту document_lookup
+type redactingDocs struct {
+	getter   DocumentTextGetter
+	redactor *pii.Redactor
+}
+
+func (d redactingDocs) GetDocumentText(ctx context.Context, docID uuid.UUID) (string, error) {
+	text, err := d.getter.GetDocumentText(ctx, docID)
+	if err != nil {
+		return "", err
+	}
+	return d.redactor.Protect(text)
+}
@@ -0,0 +1,110 @@
+package llm
+
+import (
+	"backend/internal/domain"
+	"backend/internal/fakes"
+	"backend/internal/usecase/pii"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestReply_TokenizesPersonalData(t *testing.T) {
+	userID := uuid.New()
+	chat := &domain.Chat{ID: uuid.New(), UserID: userID}
+	docID := uuid.New()
+	scenario := "contract_helper"
+
+	msgRepo := newBranchRepo(chat,
+		&domain.Message{ID: uuid.New(), Role: string(domain.RoleUser), Content: "моя карта 4111 1111 1111 1111"},
+	)
+	mainLLM := &fakes.LLM{Response: "Покупатель с ИНН [ИНН_1], связь по [ТЕЛЕФОН_1]."}
+
+	svc, err := NewChatService(msgRepo.Chats, msgRepo, mainLLM, &domain.Limits{
+		MaxPromptChars:  10000,
+		MaxHistoryChars: 5000,
+		MaxRequestChars: 1000,
+		MaxSearchChars:  2000,
+	}, WithPIIPolicy(pii.DefaultPolicy))
+	if err != nil {
+		t.Fatalf("NewChatService() error = %v", err)
+	}
+
+	docs := fakeDocs{docID: "Покупатель: ООО Ромашка, ИНН 7707083893"}
+	msg, err := svc.Reply(context.Background(), chat.ID, userID, "кто покупатель? мой телефон +7 916 123-45-67",
+		[]uuid.UUID{docID}, &scenario, docs)
+	if err != nil {
+		t.Fatalf("Reply() error = %v", err)
+	}
+
+	prompt := mainLLM.Prompts[0]
+	for _, raw := range []string{"7707083893", "916 123-45-67", "4111 1111 1111 1111"} {
+		if strings.Contains(prompt, raw) {
+			t.Fatalf("prompt leaks %q:\n%s", raw, prompt)
+		}
+	}
+	for _, token := range []string{"[ИНН_1]", "[ТЕЛЕФОН_1]", "[КАРТА_1]"} {
+		if !strings.Contains(prompt, token) {
+			t.Fatalf("prompt has no %q:\n%s", token, prompt)
+		}
+	}
+
+	if msg.Content != "Покупатель с ИНН 7707083893, связь по +7 916 123-45-67." {
+		t.Fatalf("answer is not restored: %q", msg.Content)
+	}
+
+	want := &domain.PIIReport{Action: "tokenize", Counts: map[string]int{"inn": 1, "phone": 1, "card": 1}}
+	if !reflect.DeepEqual(msg.Metadata.PII, want) {
+		t.Fatalf("metadata pii = %+v, want %+v", msg.Metadata.PII, want)
+	}
+}
+
+func TestReply_BlockPolicy(t *testing.T) {
+	userID := uuid.New()
+	chat := &domain.Chat{ID: uuid.New(), UserID: userID}
+	docID := uuid.New()
+
+	tests := []struct {
+		name     string
+		userText string
+		docs     fakeDocs
+	}{
+		{
+			name:     "personal data in request",
+			userText: "мой СНИЛС 112-233-445 95",
+		},
+		{
+			name:     "personal data in document",
+			userText: "проверь анкету",
+			docs:     fakeDocs{docID: "Паспорт 45 06 123456"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msgRepo := newBranchRepo(chat)
+			mainLLM := &fakes.LLM{Response: "ok"}
+			svc, err := NewChatService(msgRepo.Chats, msgRepo, mainLLM, &domain.Limits{
+				MaxPromptChars:  10000,
+				MaxHistoryChars: 5000,
+				MaxRequestChars: 1000,
+			}, WithPIIPolicy(pii.Policy{"default": pii.ActionBlock}))
+			if err != nil {
+				t.Fatalf("NewChatService() error = %v", err)
+			}
+
+			_, err = svc.Reply(context.Background(), chat.ID, userID, tt.userText, []uuid.UUID{docID}, nil, tt.docs)
+			if !errors.Is(err, domain.ErrSensitiveData) {
+				t.Fatalf("Reply() error = %v, want ErrSensitiveData", err)
+			}
+			if len(mainLLM.Prompts) != 0 || len(msgRepo.Messages) != 0 {
+				t.Fatalf("blocked request reached the model (%d calls) or chat (%d messages)",
+					len(mainLLM.Prompts), len(msgRepo.Messages))
+			}
+		})
+	}
+}
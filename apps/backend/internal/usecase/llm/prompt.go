@@ -15,6 +15,9 @@ const (
 		"\nПиши всегда по-русски."
 )
 
+const webSearchNote = "Результаты поиска в интернете по запросу пользователя. " +
+	"Они могут быть неполными или устаревшими; если используешь их, указывай номер источника [N].\n"
+
 type promptBudget struct {
 	MaxTotal int
 	Used     int
@@ -43,8 +46,9 @@ func (b *promptBudget) Take(s string, max int) string {
 	return s
 }
 
-// buildPrompt собирает корректный промпт для LLM с системным промптом, историей сообщений, данными с ресурсов и запросом пользователя
-func (s *Service) buildPrompt(sysPrompt, msgHistory, documents, userReq string) string {
+// buildPrompt собирает корректный промпт для LLM с системным промптом, историей сообщений, данными с ресурсов,
+// результатами веб-поиска и запросом пользователя
+func (s *Service) buildPrompt(sysPrompt, msgHistory, documents, webResults, userReq string) string {
 	if sysPrompt == "" {
 		sysPrompt = defaultSysPrompt
 	}
@@ -56,6 +60,19 @@ func (s *Service) buildPrompt(sysPrompt, msgHistory, documents, userReq string)
 	sys := pBudget.Take(sysPrompt, 2000)
 	b.WriteString("SYSTEM:\n")
 	b.WriteString(sys)
+
+	// Документы, веб-результаты и ответы инструментов - недоверенный текст: он огораживается
+	// метками со случайным nonce, а модель получает правило не исполнять команды из него
+	f := newFence()
+	if documents != "" || webResults != "" || s.tools != nil {
+		note := f.note()
+		if pBudget.MaxTotal-pBudget.Used >= len(note) {
+			b.WriteString(pBudget.Take(note, len(note)))
+		} else {
+			// Без правила недоверенный текст в промпт не попадает
+			documents, webResults = "", ""
+		}
+	}
 	b.WriteString("\n\n")
 
 	if msgHistory != "" {
@@ -66,12 +83,22 @@ func (s *Service) buildPrompt(sysPrompt, msgHistory, documents, userReq string)
 	}
 
 	if documents != "" {
-		doc := pBudget.Take(documents, s.limits.MaxHistoryChars)
+		doc := pBudget.takeUntrusted(f, "documents", documents, s.limits.MaxHistoryChars)
 		b.WriteString("DOCUMENTS:\n")
 		b.WriteString(doc)
 		b.WriteString("\n\n")
 	}
 
+	if webResults != "" {
+		web := pBudget.takeUntrusted(f, "web_search", webResults, s.limits.MaxSearchChars)
+		if web != "" {
+			b.WriteString("WEB_SEARCH:\n")
+			b.WriteString(webSearchNote)
+			b.WriteString(web)
+			b.WriteString("\n\n")
+		}
+	}
+
 	if userReq != "" {
 		req := pBudget.Take(userReq, s.limits.MaxRequestChars)
 		b.WriteString("USER:\n")
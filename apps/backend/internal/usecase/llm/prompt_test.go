@@ -15,6 +15,7 @@ func newServiceNoTrunc() *Service {
 			MaxFileTextChars:  2000,
 			MaxHistoryChars:   2000,
 			MaxRequestChars:   1000,
+			MaxSearchChars:    1000,
 			MaxRequestsPerMin: 60,
 			MaxConcurrentLLM:  4,
 		},
@@ -30,6 +31,7 @@ func newServiceTightLimits() *Service {
 			MaxFileTextChars:  80,
 			MaxHistoryChars:   80,
 			MaxRequestChars:   60,
+			MaxSearchChars:    60,
 			MaxRequestsPerMin: 60,
 			MaxConcurrentLLM:  4,
 		},
@@ -132,6 +134,7 @@ func TestBuildPrompt_BasicSectionsAndDefaultSystem(t *testing.T) {
 		sysPrompt  string
 		history    string
 		documents  string
+		webResults string
 		userReq    string
 		wantChecks []string
 	}{
@@ -162,17 +165,23 @@ func TestBuildPrompt_BasicSectionsAndDefaultSystem(t *testing.T) {
 			},
 		},
 		{
-			name:      "all sections appear in correct order",
+			name:       "all sections appear in correct order",
+			sysPrompt:  "sys",
+			history:    "hist",
+			documents:  "doc",
+			webResults: "web",
+			userReq:    "user",
+		},
+		{
+			name:      "web search section is omitted without results",
 			sysPrompt: "sys",
-			history:   "hist",
-			documents: "doc",
 			userReq:   "user",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := svc.buildPrompt(tt.sysPrompt, tt.history, tt.documents, tt.userReq)
+			got := svc.buildPrompt(tt.sysPrompt, tt.history, tt.documents, tt.webResults, tt.userReq)
 
 			for _, substr := range tt.wantChecks {
 				if substr == "" {
@@ -184,7 +193,7 @@ func TestBuildPrompt_BasicSectionsAndDefaultSystem(t *testing.T) {
 			}
 
 			if tt.name == "all sections appear in correct order" {
-				order := []string{"SYSTEM:", "HISTORY:", "DOCUMENTS:", "USER:"}
+				order := []string{"SYSTEM:", "HISTORY:", "DOCUMENTS:", "WEB_SEARCH:", "USER:"}
 				lastIndex := -1
 				for _, marker := range order {
 					idx := strings.Index(got, marker)
@@ -197,6 +206,10 @@ func TestBuildPrompt_BasicSectionsAndDefaultSystem(t *testing.T) {
 					lastIndex = idx
 				}
 			}
+
+			if tt.webResults == "" && strings.Contains(got, "WEB_SEARCH:") {
+				t.Fatalf("unexpected WEB_SEARCH section without results\nprompt:\n%s", got)
+			}
 		})
 	}
 }
@@ -207,12 +220,14 @@ func TestBuildPrompt_RespectsBudgetAndLimits(t *testing.T) {
 	longHistory := strings.Repeat("H", 200)
 	longDocs := strings.Repeat("D", 200)
 	longUser := strings.Repeat("U", 200)
+	longWeb := strings.Repeat("W", 200)
 
 	tests := []struct {
-		name      string
-		history   string
-		documents string
-		userReq   string
+		name       string
+		history    string
+		documents  string
+		webResults string
+		userReq    string
 	}{
 		{
 			name:      "history truncated by history limit and global budget",
@@ -233,16 +248,22 @@ func TestBuildPrompt_RespectsBudgetAndLimits(t *testing.T) {
 			userReq:   longUser,
 		},
 		{
-			name:      "all together still within MaxPromptChars",
-			history:   longHistory,
-			documents: longDocs,
-			userReq:   longUser,
+			name:       "web results truncated by search limit and global budget",
+			webResults: longWeb,
+			userReq:    "ok",
+		},
+		{
+			name:       "all together still within MaxPromptChars",
+			history:    longHistory,
+			documents:  longDocs,
+			webResults: longWeb,
+			userReq:    longUser,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			prompt := svc.buildPrompt("", tt.history, tt.documents, tt.userReq)
+			prompt := svc.buildPrompt("", tt.history, tt.documents, tt.webResults, tt.userReq)
 
 			if len(prompt) > svc.limits.MaxPromptChars {
 				t.Fatalf("prompt length = %d, exceeds MaxPromptChars = %d",
@@ -255,6 +276,9 @@ func TestBuildPrompt_RespectsBudgetAndLimits(t *testing.T) {
 			if tt.documents != "" && strings.Contains(prompt, tt.documents) {
 				t.Fatalf("documents text not truncated as expected")
 			}
+			if tt.webResults != "" && strings.Contains(prompt, tt.webResults) {
+				t.Fatalf("web results text not truncated as expected")
+			}
 			if tt.userReq != "" && strings.Contains(prompt, tt.userReq) {
 				t.Fatalf("user request text not truncated as expected")
 			}
@@ -2,15 +2,24 @@ package llm
 
 import (
 	"backend/internal/domain"
+	"backend/internal/logging"
+	"backend/internal/usecase/authz"
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("backend/internal/usecase/llm")
+
 // DocumentTextGetter - интерфейс для получения текста документа
 // Это может быть реализовано через отдельный сервис или метод в DocumentRepo
 type DocumentTextGetter interface {
@@ -27,6 +36,31 @@ func (s *Service) Reply(
 	scenarioCode *string,
 	docTextGetter DocumentTextGetter,
 ) (*domain.Message, error) {
+	return s.ReplyIdempotent(ctx, "", chatID, userID, userText, documentIDs, scenarioCode, docTextGetter)
+}
+
+// ReplyIdempotent - Reply с ключом идемпотентности клиента. Повтор запроса с тем же ключом
+// не создаёт новое сообщение: возвращается уже сохранённый ответ, ожидается идущая генерация
+// или, если прошлая генерация оборвалась, ответ генерируется заново. Пустой ключ - обычный Reply
+func (s *Service) ReplyIdempotent(
+	ctx context.Context,
+	clientMessageID string,
+	chatID uuid.UUID,
+	userID uuid.UUID,
+	userText string,
+	documentIDs []uuid.UUID,
+	scenarioCode *string,
+	docTextGetter DocumentTextGetter,
+) (assistantMsg *domain.Message, err error) {
+	ctx, span := tracer.Start(ctx, "llm.Reply", trace.WithAttributes(
+		attribute.String("chat.id", chatID.String()),
+		attribute.Int("documents.count", len(documentIDs)),
+		attribute.Bool("reply.idempotent", clientMessageID != ""),
+	))
+	defer func() {
+		endSpan(span, err)
+	}()
+
 	if userText == "" {
 		return nil, errors.New("user text cannot be empty")
 	}
@@ -37,92 +71,343 @@ func (s *Service) Reply(
 		return nil, fmt.Errorf("failed to get chat: %w", err)
 	}
 
-	if chat.UserID != userID {
-		return nil, errors.New("access denied: chat belongs to different user")
+	if err := s.authz.Can(ctx, authz.User(userID), authz.ChatWrite, authz.Chat(chat)); err != nil {
+		return nil, err
+	}
+
+	if clientMessageID != "" {
+		// Повтор того же запроса в этом процессе ждёт первую генерацию, а не запускает свою
+		call, leader := s.inflight.join(chatID, clientMessageID)
+		if !leader {
+			return call.wait(ctx)
+		}
+		defer func() {
+			s.inflight.finish(chatID, clientMessageID, call, assistantMsg, err)
+		}()
+
+		existing, err := s.msgRepo.GetByClientID(ctx, chatID, clientMessageID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get message by client id: %w", err)
+		}
+		if existing != nil {
+			return s.replay(ctx, userID, chat, existing, userText, docTextGetter)
+		}
 	}
 
-	// 2. Создаём сообщение пользователя
+	// Новый вопрос продолжает активную ветку. Документы и сценарий запоминаются,
+	// чтобы перегенерировать ответ в том же контексте
 	userMsg := &domain.Message{
-		ID:      uuid.New(),
-		ChatID:  chatID,
-		Role:    string(domain.RoleUser),
-		Content: userText,
+		ID:              uuid.New(),
+		ChatID:          chatID,
+		ParentID:        chat.ActiveMessageID,
+		Role:            string(domain.RoleUser),
+		Content:         userText,
+		ClientMessageID: clientMessageID,
+		Metadata:        domain.MessageMetadata{DocumentIDs: documentIDs},
+	}
+	if scenarioCode != nil {
+		userMsg.Metadata.Scenario = *scenarioCode
 	}
 
-	if err := s.msgRepo.Append(ctx, userMsg); err != nil {
-		return nil, fmt.Errorf("failed to save user message: %w", err)
+	assistantMsg, err = s.answer(ctx, userID, chat, userMsg, true, docTextGetter)
+	if errors.Is(err, domain.ErrDuplicateMessage) {
+		// Тот же ключ одновременно обрабатывает другой экземпляр сервиса
+		return nil, domain.ErrReplyInProgress
 	}
+	return assistantMsg, err
+}
 
-	// 3. Получаем историю сообщений
-	rawMsgHistory, err := s.msgRepo.GetLastN(ctx, chatID, 50) // берем больше, потом обрежем по лимитам
-	if err != nil {
-		return nil, fmt.Errorf("failed to get message history: %w", err)
+// answer генерирует ответ на сообщение пользователя userMsg и сохраняет его следующим в ветке.
+// userID - пользователь, который задал вопрос или запросил ответ: ему засчитывается потребление
+// и от его имени работают инструменты, даже если чат принадлежит другому участнику организации.
+// persistUser = false - сообщение уже сохранено (перегенерация ответа).
+// Пока ответ генерируется, вопрос в статусе pending; ответ, статус complete и время чата
+// сохраняются одной транзакцией, а при любой ошибке после сохранения вопроса он помечается failed
+func (s *Service) answer(
+	ctx context.Context,
+	userID uuid.UUID,
+	chat *domain.Chat,
+	userMsg *domain.Message,
+	persistUser bool,
+	docTextGetter DocumentTextGetter,
+) (_ *domain.Message, err error) {
+	chatID := chat.ID
+	userText, documentIDs := userMsg.Content, userMsg.Metadata.DocumentIDs
+	var scenarioCode *string
+	if userMsg.Metadata.Scenario != "" {
+		scenarioCode = &userMsg.Metadata.Scenario
 	}
 
-	// Реверсируем порядок (GetLastN возвращает от новых к старым, нужны от старых к новым)
-	var msgHistory strings.Builder
-	for i := len(rawMsgHistory) - 1; i >= 0; i-- {
-		msg := rawMsgHistory[i]
-		msgHistory.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, msg.Content))
+	// Проверяем квоты до сохранения сообщения, чтобы не оставлять запросы без ответа
+	if s.usage != nil {
+		if err := s.usage.Check(ctx, userID); err != nil {
+			return nil, err
+		}
 	}
 
-	// 4. Получаем текст документов
+	// Персональные данные скрываются до того, как текст попадёт в модель, веб-поиск или логи
+	redactor := s.newRedactor(scenarioCode)
+	promptText, err := redactor.Protect(userText)
+	if err != nil {
+		s.logPIIBlocked(ctx, chatID, redactor)
+		return nil, err
+	}
+	if docTextGetter != nil {
+		docTextGetter = redactingDocs{getter: docTextGetter, redactor: redactor}
+	}
+
+	// 2. Получаем текст документов. Читаем их до сохранения сообщения: политика персональных данных
+	// может запретить запрос, и тогда в чате не должно остаться сообщения без ответа
+	docsCtx, docsSpan := tracer.Start(ctx, "reply.documents")
 	var documentsText strings.Builder
+	var documentBytes int64
+	var injections []domain.InjectionFlag
 	if docTextGetter != nil && len(documentIDs) > 0 {
 		for _, docID := range documentIDs {
-			text, err := docTextGetter.GetDocumentText(ctx, docID)
+			text, err := docTextGetter.GetDocumentText(docsCtx, docID)
+			if errors.Is(err, domain.ErrSensitiveData) {
+				s.logPIIBlocked(ctx, chatID, redactor)
+				return nil, err
+			}
 			if err != nil {
-				// Логируем ошибку, но продолжаем
+				// Документ не обязателен для ответа - логируем и продолжаем без него
+				logging.FromContext(ctx).WarnContext(ctx, "failed to get document text",
+					slog.String("chat_id", chatID.String()),
+					slog.String("document_id", docID.String()),
+					slog.Any("error", err),
+				)
 				continue
 			}
 			if text != "" {
+				if signals := detectInjection(text); len(signals) > 0 {
+					injections = append(injections, domain.InjectionFlag{Source: "document:" + docID.String(), Signals: signals})
+				}
+				documentBytes += int64(len(text))
 				documentsText.WriteString(text)
 				documentsText.WriteString("\n\n")
 			}
 		}
 	}
 
+	docsSpan.SetAttributes(attribute.Int64("documents.bytes", documentBytes))
+	docsSpan.End()
+
+	// 3. Сохраняем сообщение пользователя в статусе pending
+	persistCtx, persistSpan := tracer.Start(ctx, "reply.persist_user_message")
+	userMsg.Status = domain.MessageStatusPending
+	if persistUser {
+		err = s.msgRepo.Append(persistCtx, userMsg)
+	} else {
+		err = s.msgRepo.UpdateStatus(persistCtx, userMsg.ID, domain.MessageStatusPending)
+	}
+	endSpan(persistSpan, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save user message: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			s.markFailed(ctx, userMsg)
+		}
+	}()
+
+	// 4. Получаем историю ветки, которая заканчивается вопросом пользователя
+	historyCtx, historySpan := tracer.Start(ctx, "reply.history")
+	rawMsgHistory, err := s.msgRepo.GetBranch(historyCtx, userMsg.ID, 50) // берем больше, потом обрежем по лимитам
+	historySpan.SetAttributes(attribute.Int("messages.count", len(rawMsgHistory)))
+	endSpan(historySpan, err)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message history: %w", err)
+	}
+
+	var msgHistory strings.Builder
+	for _, msg := range rawMsgHistory {
+		// Промежуточные вызовы инструментов и их результаты в историю не попадают - хватает итогового ответа
+		if msg.Role == string(domain.RoleTool) || len(msg.Metadata.ToolCalls) > 0 {
+			continue
+		}
+		msgHistory.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, redactor.Replace(msg.Content)))
+	}
+
+	// Веб-поиск по запросу пользователя (если включён и нужен)
+	searchCtx, searchSpan := tracer.Start(ctx, "reply.web_search")
+	webResults, searchRecord := s.searchWeb(searchCtx, promptText)
+	searchSpan.SetAttributes(attribute.Bool("web_search.performed", searchRecord != nil))
+	searchSpan.End()
+	if signals := detectInjection(webResults); len(signals) > 0 {
+		injections = append(injections, domain.InjectionFlag{Source: "web_search", Signals: signals})
+	}
+
 	// 5. Выбираем системный промпт (по сценарию или дефолтный)
+	_, promptSpan := tracer.Start(ctx, "reply.build_prompt")
 	sysPrompt := s.getSystemPrompt(scenarioCode)
+	if redactor.Tokenized() {
+		if sysPrompt == "" {
+			sysPrompt = defaultSysPrompt
+		}
+		sysPrompt += piiTokenNote
+	}
 
 	// 6. Собираем промпт
 	prompt := s.buildPrompt(
 		sysPrompt,
 		msgHistory.String(),
 		documentsText.String(),
-		userText,
+		webResults,
+		promptText,
 	)
+	promptSpan.SetAttributes(attribute.Int("prompt.chars", len(prompt)))
+	promptSpan.End()
 
 	// 7. Вызываем LLM
+	generateCtx, generateSpan := tracer.Start(ctx, "reply.generate")
 	startTime := time.Now()
-	llmResponse, err := s.llm.Generate(ctx, []byte(prompt))
+	toolEnv := &ToolEnv{
+		ChatID:      chatID,
+		UserID:      userID,
+		DocumentIDs: documentIDs,
+		Documents:   docTextGetter,
+		lastID:      userMsg.ID,
+	}
+	var generation *domain.Generation
+	if s.tools != nil {
+		generation, err = s.generateWithTools(generateCtx, prompt, toolEnv)
+	} else {
+		generation, err = s.llm.Generate(generateCtx, []byte(prompt))
+	}
+	if err == nil {
+		generateSpan.SetAttributes(
+			attribute.String("llm.model", generation.Model),
+			attribute.Int("llm.prompt_tokens", generation.PromptTokens),
+			attribute.Int("llm.completion_tokens", generation.CompletionTokens),
+		)
+	}
+	endSpan(generateSpan, err)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate LLM response: %w", err)
 	}
+
+	// Проверяем ответ до сохранения. Повторная генерация возможна только без инструментов:
+	// цикл инструментов уже сохранил свои сообщения в чат
+	guardCtx, guardSpan := tracer.Start(ctx, "reply.guardrails")
+	var regenerate regenerateFunc
+	if s.tools == nil {
+		regenerate = func(ctx context.Context, hint string) (*domain.Generation, error) {
+			return s.llm.Generate(ctx, []byte(prompt+hint))
+		}
+	}
+	generation, guardrails := s.applyGuardrails(guardCtx, promptText, generation, regenerate)
+	guardSpan.SetAttributes(attribute.Int("guardrails.flags", len(guardrails)))
+	guardSpan.End()
 	latencyMs := time.Since(startTime).Milliseconds()
 
+	persistCtx, persistSpan = tracer.Start(ctx, "reply.persist")
+	defer persistSpan.End()
+
+	if s.usage != nil {
+		err := s.usage.Record(persistCtx, userID, domain.Usage{
+			Requests:         1,
+			PromptTokens:     int64(generation.PromptTokens),
+			CompletionTokens: int64(generation.CompletionTokens),
+			DocumentBytes:    documentBytes,
+		})
+		if err != nil {
+			// Ошибка учёта не должна ломать ответ пользователю
+			logging.FromContext(ctx).ErrorContext(ctx, "failed to record usage",
+				slog.String("chat_id", chatID.String()),
+				slog.Any("error", err),
+			)
+		}
+	}
+
 	// 8. Создаём сообщение ассистента
+	// Родитель ответа - вопрос или последний результат инструмента
+	parentID := toolEnv.lastID
 	assistantMsg := &domain.Message{
 		ID:        uuid.New(),
 		ChatID:    chatID,
+		ParentID:  &parentID,
 		Role:      string(domain.RoleAssistant),
-		Content:   llmResponse,
+		Content:   redactor.Restore(generation.Content),
 		LatencyMs: &latencyMs,
 	}
-
-	if err := s.msgRepo.Append(ctx, assistantMsg); err != nil {
-		return nil, fmt.Errorf("failed to save assistant message: %w", err)
+	if searchRecord != nil {
+		assistantMsg.Metadata.Searches = append(assistantMsg.Metadata.Searches, *searchRecord)
+	}
+	assistantMsg.Metadata.Searches = append(assistantMsg.Metadata.Searches, toolEnv.searches...)
+	assistantMsg.Metadata.Injections = append(injections, toolEnv.injections...)
+	assistantMsg.Metadata.Guardrails = guardrails
+	assistantMsg.Metadata.PII = redactor.Report()
+	if report := assistantMsg.Metadata.PII; report != nil {
+		// Только количество по видам - сами значения в лог не попадают
+		logging.FromContext(ctx).InfoContext(ctx, "personal data hidden from model",
+			slog.String("chat_id", chatID.String()),
+			slog.String("action", report.Action),
+			slog.Any("counts", report.Counts),
+		)
+	}
+	for _, flag := range assistantMsg.Metadata.Injections {
+		// Ответ не блокируется: текст уже огорожен, а пометка нужна для разбора инцидентов
+		logging.FromContext(ctx).WarnContext(ctx, "possible prompt injection",
+			slog.String("chat_id", chatID.String()),
+			slog.String("source", flag.Source),
+			slog.Any("signals", flag.Signals),
+		)
 	}
 
-	// 9. Обновляем время последнего сообщения в чате
-	now := time.Now()
-	if err := s.chatRepo.Touch(ctx, chatID, now); err != nil {
-		// Логируем, но не возвращаем ошибку
+	// 9. Сохраняем ответ, завершаем вопрос и обновляем время последнего сообщения в чате атомарно
+	err = s.tx.WithinTx(persistCtx, func(ctx context.Context) error {
+		if err := s.msgRepo.Append(ctx, assistantMsg); err != nil {
+			return fmt.Errorf("failed to save assistant message: %w", err)
+		}
+		if err := s.msgRepo.UpdateStatus(ctx, userMsg.ID, domain.MessageStatusComplete); err != nil {
+			return fmt.Errorf("failed to complete user message: %w", err)
+		}
+		if err := s.chatRepo.Touch(ctx, chatID, time.Now()); err != nil {
+			return fmt.Errorf("failed to touch chat: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	userMsg.Status = domain.MessageStatusComplete
+
+	logging.FromContext(ctx).InfoContext(ctx, "reply generated",
+		slog.String("chat_id", chatID.String()),
+		slog.String("message_id", assistantMsg.ID.String()),
+		slog.Int64("latency_ms", latencyMs),
+		slog.Int("prompt_tokens", generation.PromptTokens),
+		slog.Int("completion_tokens", generation.CompletionTokens),
+		// Ответ логируется до подстановки скрытых значений
+		logging.Content("answer", generation.Content),
+	)
 
 	return assistantMsg, nil
 }
 
+// markFailed помечает вопрос, ответ на который не удалось получить или сохранить.
+// Выполняется и после отмены запроса: чаще всего генерация обрывается именно по таймауту
+func (s *Service) markFailed(ctx context.Context, userMsg *domain.Message) {
+	ctx = context.WithoutCancel(ctx)
+	if err := s.msgRepo.UpdateStatus(ctx, userMsg.ID, domain.MessageStatusFailed); err != nil {
+		logging.FromContext(ctx).ErrorContext(ctx, "failed to mark user message as failed",
+			slog.String("message_id", userMsg.ID.String()),
+			slog.Any("error", err),
+		)
+		return
+	}
+	userMsg.Status = domain.MessageStatusFailed
+}
+
+// endSpan завершает спан, помечая его ошибкой при err != nil
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
 // getSystemPrompt возвращает системный промпт по коду сценария
 func (s *Service) getSystemPrompt(scenarioCode *string) string {
 	if scenarioCode == nil {
@@ -0,0 +1,299 @@
+package llm
+
+import (
+	"backend/internal/domain"
+	"backend/internal/logging"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+const (
+	DecisionSourceLLM       = "llm"
+	DecisionSourceHeuristic = "heuristic"
+	DecisionSourceExplicit  = "explicit"
+	// DecisionSourceTool - поиск запросила сама модель через инструмент web_search
+	DecisionSourceTool = "tool"
+
+	maxSearchQueryChars = 200
+	maxSearchResults    = 5
+	// maxFetchedPages - у скольких первых результатов скачивается полный текст
+	maxFetchedPages = 2
+	// maxExcerptChars - размер выдержки из одной страницы
+	maxExcerptChars = 1200
+)
+
+const searchClassifierPrompt = "Ты решаешь, нужен ли поиск в интернете, чтобы ответить на сообщение пользователя." +
+	"\nПоиск нужен, только если ответ зависит от свежих или внешних данных: курсы, ставки, цены, новости, расписания, адреса, законы и их изменения." +
+	"\nПоиск не нужен для приветствий, вопросов по прикреплённым документам, составления текстов, расчётов и общих советов." +
+	"\nЕсли поиск нужен, сформулируй короткий поисковый запрос (до 10 слов) на языке пользователя." +
+	"\nОтветь только JSON без пояснений: {\"search\": true или false, \"query\": \"поисковый запрос или пустая строка\"}" +
+	"\n\nСообщение пользователя:\n%s"
+
+// SearchDecision - нужен ли веб-поиск и по какому запросу
+type SearchDecision struct {
+	Search bool   `json:"search"`
+	Query  string `json:"query"`
+	// Source - кто принял решение: llm, heuristic (запасной вариант) или explicit (метка [поиск])
+	Source string `json:"-"`
+}
+
+// SearchDecider решает, нужен ли веб-поиск для последней реплики пользователя.
+// Основной путь - дешёвый вызов LLM, при его ошибке используется список ключевых слов
+type SearchDecider struct {
+	classifier domain.LLM
+}
+
+// NewSearchDecider принимает модель-классификатор (лучше лёгкую и с JSON-выводом).
+// nil означает работу только на ключевых словах (офлайн-режим)
+func NewSearchDecider(classifier domain.LLM) *SearchDecider {
+	return &SearchDecider{classifier: classifier}
+}
+
+// Decide никогда не возвращает ошибку: при сбое классификатора срабатывает эвристика
+func (d *SearchDecider) Decide(ctx context.Context, userTurn string) SearchDecision {
+	query := cleanSearchQuery(userTurn)
+	if query == "" {
+		return SearchDecision{Source: DecisionSourceHeuristic}
+	}
+
+	if hasExplicitSearchTag(userTurn) {
+		return SearchDecision{Search: true, Query: query, Source: DecisionSourceExplicit}
+	}
+
+	if d.classifier != nil {
+		decision, err := d.classifyTurn(ctx, query)
+		if err == nil {
+			return decision
+		}
+
+		logging.FromContext(ctx).WarnContext(ctx, "search classifier failed, falling back to keywords", slog.Any("error", err))
+	}
+
+	return SearchDecision{
+		Search: needsWebSearch(query),
+		Query:  query,
+		Source: DecisionSourceHeuristic,
+	}
+}
+
+func (d *SearchDecider) classifyTurn(ctx context.Context, userTurn string) (SearchDecision, error) {
+	gen, err := d.classifier.Generate(ctx, []byte(fmt.Sprintf(searchClassifierPrompt, userTurn)))
+	if err != nil {
+		return SearchDecision{}, err
+	}
+
+	decision, err := parseSearchDecision(gen.Content)
+	if err != nil {
+		return SearchDecision{}, err
+	}
+
+	decision.Source = DecisionSourceLLM
+	decision.Query = strings.TrimSpace(decision.Query)
+	if decision.Search && decision.Query == "" {
+		decision.Query = userTurn
+	}
+	if len([]rune(decision.Query)) > maxSearchQueryChars {
+		decision.Query = string([]rune(decision.Query)[:maxSearchQueryChars])
+	}
+
+	return decision, nil
+}
+
+// parseSearchDecision достаёт JSON из ответа модели - модели любят оборачивать его в ```json
+func parseSearchDecision(raw string) (SearchDecision, error) {
+	start := strings.Index(raw, "{")
+	end := strings.LastIndex(raw, "}")
+	if start == -1 || end < start {
+		return SearchDecision{}, errors.New("classifier response has no JSON object")
+	}
+
+	var decision SearchDecision
+	if err := json.Unmarshal([]byte(raw[start:end+1]), &decision); err != nil {
+		return SearchDecision{}, fmt.Errorf("failed to decode classifier response: %w", err)
+	}
+
+	return decision, nil
+}
+
+func hasExplicitSearchTag(text string) bool {
+	lower := strings.ToLower(text)
+	return strings.Contains(lower, "[web_search]") || strings.Contains(lower, "[поиск]")
+}
+
+// cleanSearchQuery убирает метки веб-поиска из текста пользователя
+func cleanSearchQuery(text string) string {
+	replacer := strings.NewReplacer("[web_search]", "", "[WEB_SEARCH]", "", "[поиск]", "", "[ПОИСК]", "")
+	return strings.TrimSpace(replacer.Replace(text))
+}
+
+// needsWebSearch - офлайн-эвристика по ключевым словам, применяется только к реплике пользователя
+func needsWebSearch(userTurn string) bool {
+	lowerPrompt := strings.ToLower(userTurn)
+
+	searchKeywords := []string{
+		"текущий", "актуальный", "сегодня", "сейчас", "последний",
+		"новости", "события", "курс", "цена", "погода",
+		"когда", "где находится", "адрес", "расписание",
+		"что происходит", "что случилось", "когда будет",
+		"проверь в интернете", "найди в интернете", "поищи",
+	}
+
+	for _, keyword := range searchKeywords {
+		if strings.Contains(lowerPrompt, keyword) {
+			return true
+		}
+	}
+
+	return hasExplicitSearchTag(lowerPrompt)
+}
+
+// searchWeb решает, нужен ли поиск по запросу пользователя, выполняет его и
+// возвращает текст секции WEB_SEARCH вместе с записью о поиске (nil, если поиска не было)
+func (s *Service) searchWeb(ctx context.Context, userText string) (string, *domain.SearchRecord) {
+	if s.search == nil {
+		return "", nil
+	}
+
+	decision := s.searchDecider.Decide(ctx, userText)
+	if !decision.Search {
+		return "", nil
+	}
+
+	return runSearch(ctx, s.search, s.pages, decision.Query, decision.Source)
+}
+
+// runSearch выполняет поиск и возвращает отформатированные результаты и запись о поиске.
+// Если задан pages, к первым результатам добавляются выдержки из полного текста страниц.
+// Ошибка поиска не фатальна и сохраняется в записи
+func runSearch(ctx context.Context, provider domain.SearchProvider, pages domain.PageFetcher, query, source string) (string, *domain.SearchRecord) {
+	record := &domain.SearchRecord{
+		Query:  query,
+		Source: source,
+	}
+
+	results, err := provider.Search(ctx, query, maxSearchResults)
+	if err != nil {
+		// Без результатов поиска модель всё равно может ответить
+		logging.FromContext(ctx).WarnContext(ctx, "web search failed",
+			logging.Content("query", query),
+			slog.Any("error", err),
+		)
+		record.Error = err.Error()
+		return "", record
+	}
+
+	record.Results = len(results)
+	for _, r := range results {
+		if r.URL != "" {
+			record.URLs = append(record.URLs, r.URL)
+		}
+	}
+
+	if pages != nil {
+		record.FetchedURLs = fetchExcerpts(ctx, pages, query, results)
+	}
+
+	return formatSearchResults(results), record
+}
+
+// fetchExcerpts параллельно скачивает первые maxFetchedPages страниц и кладёт выдержки в results[i].Excerpt.
+// Возвращает адреса страниц, из которых удалось взять текст
+func fetchExcerpts(ctx context.Context, pages domain.PageFetcher, query string, results []domain.SearchResult) []string {
+	var wg sync.WaitGroup
+	fetched := 0
+	for i := range results {
+		if fetched >= maxFetchedPages {
+			break
+		}
+		if results[i].URL == "" {
+			continue
+		}
+		fetched++
+
+		wg.Add(1)
+		go func(r *domain.SearchResult) {
+			defer wg.Done()
+
+			page, err := pages.Fetch(ctx, r.URL)
+			if err != nil {
+				// Страница необязательна - остаётся сниппет из выдачи
+				logging.FromContext(ctx).InfoContext(ctx, "failed to fetch search result page",
+					slog.String("url", r.URL),
+					slog.Any("error", err),
+				)
+				return
+			}
+			r.Excerpt = pageExcerpt(page.Text, query)
+		}(&results[i])
+	}
+	wg.Wait()
+
+	var urls []string
+	for _, r := range results {
+		if r.Excerpt != "" {
+			urls = append(urls, r.URL)
+		}
+	}
+	return urls
+}
+
+// pageExcerpt выбирает из текста страницы абзацы, похожие на запрос, а если таких нет - начало текста
+func pageExcerpt(text, query string) string {
+	paragraphs := splitParagraphs(text)
+
+	excerpt := bestParagraphs(paragraphs, query, 3)
+	if len(excerpt) == 0 {
+		excerpt = paragraphs
+	}
+
+	var b strings.Builder
+	for _, p := range excerpt {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(p)
+		if len([]rune(b.String())) >= maxExcerptChars {
+			break
+		}
+	}
+
+	out := []rune(b.String())
+	if len(out) > maxExcerptChars {
+		out = append(out[:maxExcerptChars], '…')
+	}
+	return string(out)
+}
+
+// formatSearchResults форматирует результаты поиска для секции промпта
+func formatSearchResults(results []domain.SearchResult) string {
+	var b strings.Builder
+	for i, r := range results {
+		b.WriteString(fmt.Sprintf("[%d]", i+1))
+		if r.Title != "" {
+			b.WriteString(" ")
+			b.WriteString(r.Title)
+		}
+		b.WriteString("\n")
+		if r.URL != "" {
+			b.WriteString("URL: ")
+			b.WriteString(r.URL)
+			b.WriteString("\n")
+		}
+		if r.Snippet != "" {
+			b.WriteString(r.Snippet)
+			b.WriteString("\n")
+		}
+		if r.Excerpt != "" {
+			b.WriteString("Текст страницы: ")
+			b.WriteString(r.Excerpt)
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,368 @@
+package llm
+
+import (
+	"backend/internal/domain"
+	"backend/internal/fakes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSearchDecider_Decide(t *testing.T) {
+	tests := []struct {
+		name       string
+		userTurn   string
+		response   string
+		err        error
+		wantSearch bool
+		wantQuery  string
+		wantSource string
+		wantCalls  int
+	}{
+		{
+			name:       "llm says search and rewrites query",
+			userTurn:   "Подскажи пожалуйста, какая сейчас ключевая ставка ЦБ? Мне для кредита",
+			response:   `{"search": true, "query": "ключевая ставка ЦБ РФ"}`,
+			wantSearch: true,
+			wantQuery:  "ключевая ставка ЦБ РФ",
+			wantSource: DecisionSourceLLM,
+			wantCalls:  1,
+		},
+		{
+			name:       "llm says no search despite keywords",
+			userTurn:   "Когда лучше отправлять коммерческое предложение?",
+			response:   `{"search": false, "query": ""}`,
+			wantSearch: false,
+			wantQuery:  "",
+			wantSource: DecisionSourceLLM,
+			wantCalls:  1,
+		},
+		{
+			name:       "json wrapped in markdown fence",
+			userTurn:   "курс доллара",
+			response:   "```json\n{\"search\": true, \"query\": \"курс доллара ЦБ\"}\n```",
+			wantSearch: true,
+			wantQuery:  "курс доллара ЦБ",
+			wantSource: DecisionSourceLLM,
+			wantCalls:  1,
+		},
+		{
+			name:       "empty query from llm falls back to user turn",
+			userTurn:   "погода в Казани",
+			response:   `{"search": true}`,
+			wantSearch: true,
+			wantQuery:  "погода в Казани",
+			wantSource: DecisionSourceLLM,
+			wantCalls:  1,
+		},
+		{
+			name:       "llm error falls back to keywords",
+			userTurn:   "какая цена на аренду офиса",
+			err:        errors.New("connection refused"),
+			wantSearch: true,
+			wantQuery:  "какая цена на аренду офиса",
+			wantSource: DecisionSourceHeuristic,
+			wantCalls:  1,
+		},
+		{
+			name:       "garbage response falls back to keywords",
+			userTurn:   "напиши пост про скидки",
+			response:   "Конечно! Вот ответ.",
+			wantSearch: false,
+			wantQuery:  "напиши пост про скидки",
+			wantSource: DecisionSourceHeuristic,
+			wantCalls:  1,
+		},
+		{
+			name:       "explicit tag skips classifier",
+			userTurn:   "[поиск] реквизиты ФНС №5",
+			wantSearch: true,
+			wantQuery:  "реквизиты ФНС №5",
+			wantSource: DecisionSourceExplicit,
+			wantCalls:  0,
+		},
+		{
+			name:       "empty turn",
+			userTurn:   "   ",
+			wantSearch: false,
+			wantSource: DecisionSourceHeuristic,
+			wantCalls:  0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakes.LLM{Response: tt.response, Err: tt.err}
+			decider := NewSearchDecider(fake)
+
+			got := decider.Decide(context.Background(), tt.userTurn)
+
+			if got.Search != tt.wantSearch {
+				t.Fatalf("Search = %v, want %v", got.Search, tt.wantSearch)
+			}
+			if tt.wantSearch && got.Query != tt.wantQuery {
+				t.Fatalf("Query = %q, want %q", got.Query, tt.wantQuery)
+			}
+			if got.Source != tt.wantSource {
+				t.Fatalf("Source = %q, want %q", got.Source, tt.wantSource)
+			}
+			if len(fake.Prompts) != tt.wantCalls {
+				t.Fatalf("classifier calls = %d, want %d", len(fake.Prompts), tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestSearchDecider_OfflineHeuristic(t *testing.T) {
+	decider := NewSearchDecider(nil)
+
+	got := decider.Decide(context.Background(), "какой сейчас курс юаня")
+	if !got.Search {
+		t.Fatalf("expected search by keywords")
+	}
+	if got.Source != DecisionSourceHeuristic {
+		t.Fatalf("Source = %q, want heuristic", got.Source)
+	}
+}
+
+// fakeSearch - поддельный поисковик с фиксированной выдачей
+type fakeSearch struct {
+	results []domain.SearchResult
+	err     error
+	queries []string
+}
+
+func (f *fakeSearch) Search(_ context.Context, query string, _ int) ([]domain.SearchResult, error) {
+	f.queries = append(f.queries, query)
+	return f.results, f.err
+}
+
+// newBranchRepo - хранилище с одним чатом, сообщения которого сохранены одной веткой в порядке перечисления
+func newBranchRepo(chat *domain.Chat, messages ...*domain.Message) *fakes.MessageRepo {
+	repo := fakes.NewMessageRepo(fakes.NewChatRepo(chat))
+	repo.AppendBranch(chat, messages...)
+	return repo
+}
+
+func TestReply_WebSearch(t *testing.T) {
+	userID := uuid.New()
+	chat := &domain.Chat{ID: uuid.New(), UserID: userID}
+
+	msgRepo := newBranchRepo(chat,
+		&domain.Message{ID: uuid.New(), Role: string(domain.RoleUser), Content: "какая цена на нефть сегодня?"},
+		&domain.Message{ID: uuid.New(), Role: string(domain.RoleAssistant), Content: "около 80 долларов"},
+	)
+	mainLLM := &fakes.LLM{Response: "Ставка 16%."}
+	classifier := &fakes.LLM{Response: `{"search": true, "query": "ключевая ставка ЦБ"}`}
+	provider := &fakeSearch{results: []domain.SearchResult{
+		{Title: "Ключевая ставка", URL: "https://cbr.ru/hd_base/KeyRate/", Snippet: "Ключевая ставка Банка России"},
+	}}
+
+	svc, err := NewChatService(msgRepo.Chats, msgRepo, mainLLM, &domain.Limits{
+		MaxPromptChars:  10000,
+		MaxHistoryChars: 5000,
+		MaxRequestChars: 1000,
+		MaxSearchChars:  2000,
+	}, WithWebSearch(provider, classifier))
+	if err != nil {
+		t.Fatalf("NewChatService() error = %v", err)
+	}
+
+	msg, err := svc.Reply(context.Background(), chat.ID, userID, "какая сейчас ключевая ставка?", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Reply() error = %v", err)
+	}
+
+	// В классификатор уходит только текущая реплика - без истории и системного промпта
+	if len(classifier.Prompts) != 1 {
+		t.Fatalf("classifier calls = %d, want 1", len(classifier.Prompts))
+	}
+	for _, leaked := range []string{"SYSTEM:", "HISTORY:", "нефть"} {
+		if strings.Contains(classifier.Prompts[0], leaked) {
+			t.Fatalf("classifier prompt leaks %q:\n%s", leaked, classifier.Prompts[0])
+		}
+	}
+
+	if len(provider.queries) != 1 || provider.queries[0] != "ключевая ставка ЦБ" {
+		t.Fatalf("search queries = %v", provider.queries)
+	}
+
+	if len(mainLLM.Prompts) != 1 {
+		t.Fatalf("llm calls = %d, want 1", len(mainLLM.Prompts))
+	}
+	prompt := mainLLM.Prompts[0]
+	if !strings.Contains(prompt, "WEB_SEARCH:") || !strings.Contains(prompt, "https://cbr.ru/hd_base/KeyRate/") {
+		t.Fatalf("prompt has no web search section:\n%s", prompt)
+	}
+	if strings.Index(prompt, "WEB_SEARCH:") > strings.Index(prompt, "USER:") {
+		t.Fatalf("WEB_SEARCH must go before USER:\n%s", prompt)
+	}
+
+	if len(msg.Metadata.Searches) != 1 {
+		t.Fatalf("metadata searches = %d, want 1", len(msg.Metadata.Searches))
+	}
+	record := msg.Metadata.Searches[0]
+	if record.Query != "ключевая ставка ЦБ" || record.Source != DecisionSourceLLM || record.Results != 1 {
+		t.Fatalf("unexpected search record: %+v", record)
+	}
+	if len(record.URLs) != 1 || record.URLs[0] != "https://cbr.ru/hd_base/KeyRate/" {
+		t.Fatalf("record URLs = %v", record.URLs)
+	}
+}
+
+// Без классификатора эвристика смотрит только на текущую реплику: ключевые слова
+// из истории и системного промпта не должны включать поиск
+func TestReply_OfflineHeuristicIgnoresHistory(t *testing.T) {
+	userID := uuid.New()
+	chat := &domain.Chat{ID: uuid.New(), UserID: userID}
+
+	msgRepo := newBranchRepo(chat,
+		&domain.Message{ID: uuid.New(), Role: string(domain.RoleUser), Content: "какой сегодня курс доллара и цена на нефть?"},
+		&domain.Message{ID: uuid.New(), Role: string(domain.RoleAssistant), Content: "около 90 рублей и 80 долларов"},
+	)
+	mainLLM := &fakes.LLM{Response: "Здравствуйте, дорогие клиенты!"}
+	provider := &fakeSearch{}
+
+	svc, err := NewChatService(msgRepo.Chats, msgRepo, mainLLM, &domain.Limits{
+		MaxPromptChars:  10000,
+		MaxHistoryChars: 5000,
+		MaxRequestChars: 1000,
+		MaxSearchChars:  2000,
+	}, WithWebSearch(provider, nil))
+	if err != nil {
+		t.Fatalf("NewChatService() error = %v", err)
+	}
+
+	msg, err := svc.Reply(context.Background(), chat.ID, userID, "напиши приветствие для клиентов", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Reply() error = %v", err)
+	}
+
+	if len(provider.queries) != 0 {
+		t.Fatalf("search queries = %v, want none", provider.queries)
+	}
+	if len(msg.Metadata.Searches) != 0 {
+		t.Fatalf("metadata searches = %+v, want none", msg.Metadata.Searches)
+	}
+	if strings.Contains(mainLLM.Prompts[0], "WEB_SEARCH:") {
+		t.Fatalf("prompt must not have web search section:\n%s", mainLLM.Prompts[0])
+	}
+}
+
+func TestReply_WebSearchFailureDoesNotBreakReply(t *testing.T) {
+	userID := uuid.New()
+	chat := &domain.Chat{ID: uuid.New(), UserID: userID}
+
+	mainLLM := &fakes.LLM{Response: "Не удалось проверить, но обычно..."}
+	provider := &fakeSearch{err: errors.New("timeout")}
+
+	svc, err := NewChatService(fakes.NewChatRepo(chat), &fakes.MessageRepo{}, mainLLM, &domain.Limits{
+		MaxPromptChars:  10000,
+		MaxHistoryChars: 5000,
+		MaxRequestChars: 1000,
+		MaxSearchChars:  2000,
+	}, WithWebSearch(provider, nil))
+	if err != nil {
+		t.Fatalf("NewChatService() error = %v", err)
+	}
+
+	msg, err := svc.Reply(context.Background(), chat.ID, userID, "[поиск] курс доллара", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Reply() error = %v", err)
+	}
+
+	if strings.Contains(mainLLM.Prompts[0], "WEB_SEARCH:") {
+		t.Fatalf("prompt must not contain empty web search section")
+	}
+	if len(msg.Metadata.Searches) != 1 || msg.Metadata.Searches[0].Error != "timeout" {
+		t.Fatalf("expected failed search in metadata, got %+v", msg.Metadata.Searches)
+	}
+}
+
+// fakePages - поддельный загрузчик страниц: тексты по URL, остальные адреса - ошибка
+type fakePages struct {
+	mu    sync.Mutex
+	texts map[string]string
+	urls  []string
+}
+
+func (f *fakePages) Fetch(_ context.Context, url string) (*domain.Page, error) {
+	f.mu.Lock()
+	f.urls = append(f.urls, url)
+	f.mu.Unlock()
+
+	text, ok := f.texts[url]
+	if !ok {
+		return nil, errors.New("disallowed by robots.txt")
+	}
+	return &domain.Page{URL: url, Text: text}, nil
+}
+
+func TestRunSearch_FetchesTopPages(t *testing.T) {
+	provider := &fakeSearch{results: []domain.SearchResult{
+		{Title: "ЦБ", URL: "https://cbr.ru/keyrate", Snippet: "Ключевая ставка"},
+		{Title: "Закрыт", URL: "https://closed.example.com/", Snippet: "нет доступа"},
+		{Title: "Третий", URL: "https://third.example.com/", Snippet: "не скачивается"},
+	}}
+	pages := &fakePages{texts: map[string]string{
+		"https://cbr.ru/keyrate":     "Меню сайта\n\nСовет директоров сохранил ключевую ставку на уровне 16%.\n\nКонтакты",
+		"https://third.example.com/": "не должен скачиваться",
+	}}
+
+	text, record := runSearch(context.Background(), provider, pages, "ключевая ставка", DecisionSourceLLM)
+
+	if len(pages.urls) != maxFetchedPages {
+		t.Fatalf("fetched %d pages, want %d: %v", len(pages.urls), maxFetchedPages, pages.urls)
+	}
+	if !strings.Contains(text, "Текст страницы: Совет директоров сохранил ключевую ставку на уровне 16%.") {
+		t.Fatalf("formatted results have no excerpt:\n%s", text)
+	}
+	if strings.Contains(text, "Меню сайта") {
+		t.Fatalf("excerpt should keep only relevant paragraphs:\n%s", text)
+	}
+	if len(record.FetchedURLs) != 1 || record.FetchedURLs[0] != "https://cbr.ru/keyrate" {
+		t.Fatalf("FetchedURLs = %v", record.FetchedURLs)
+	}
+}
+
+func TestPageExcerpt(t *testing.T) {
+	long := strings.Repeat("слово ", 400)
+
+	tests := []struct {
+		name  string
+		text  string
+		query string
+		want  string
+	}{
+		{
+			name:  "relevant paragraphs only",
+			text:  "Шапка\n\nКурс доллара на сегодня 95 рублей.\n\nПодвал",
+			query: "курс доллара",
+			want:  "Курс доллара на сегодня 95 рублей.",
+		},
+		{
+			name:  "lead when nothing matches",
+			text:  "Первый абзац.\n\nВторой абзац.",
+			query: "ипотека",
+			want:  "Первый абзац.\nВторой абзац.",
+		},
+		{
+			name:  "long text is cut",
+			text:  long,
+			query: "ипотека",
+			want:  string([]rune(strings.TrimSpace(long))[:maxExcerptChars]) + "…",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pageExcerpt(tt.text, tt.query); got != tt.want {
+				t.Fatalf("pageExcerpt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
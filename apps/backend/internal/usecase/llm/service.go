@@ -2,17 +2,119 @@ package llm
 
 import (
 	"backend/internal/domain"
+	"backend/internal/usecase/authz"
+	"backend/internal/usecase/pii"
+	"context"
 	"errors"
+
+	"github.com/google/uuid"
 )
 
+// UsageTracker - учёт потребления и проверка квот пользователя
+type UsageTracker interface {
+	// Check возвращает domain.ErrQuotaExceeded, если квота исчерпана
+	Check(ctx context.Context, userID uuid.UUID) error
+	// Record добавляет потребление к счётчикам пользователя
+	Record(ctx context.Context, userID uuid.UUID, usage domain.Usage) error
+}
+
 type Service struct {
 	chatRepo domain.ChatRepo
 	msgRepo  domain.MessageRepo
 	llm      domain.LLM
 	limits   domain.Limits
+	usage    UsageTracker
+
+	search        domain.SearchProvider
+	searchDecider *SearchDecider
+	pages         domain.PageFetcher
+
+	toolLLM domain.ToolLLM
+	tools   *ToolRegistry
+
+	piiPolicy pii.Policy
+	moderator domain.Moderator
+
+	authz    *authz.Service
+	tx       domain.TxManager
+	inflight inflightReplies
+}
+
+// noTx выполняет fn без транзакции - для хранилищ, которые её не поддерживают
+type noTx struct{}
+
+func (noTx) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// Option - необязательная зависимость сервиса
+type Option func(*Service)
+
+// WithUsageTracker включает проверку квот перед вызовом LLM и учёт потребления
+func WithUsageTracker(tracker UsageTracker) Option {
+	return func(s *Service) {
+		s.usage = tracker
+	}
+}
+
+// WithWebSearch включает веб-поиск перед генерацией ответа.
+// classifier - модель, решающая нужен ли поиск; nil - решать по ключевым словам
+func WithWebSearch(provider domain.SearchProvider, classifier domain.LLM) Option {
+	return func(s *Service) {
+		s.search = provider
+		s.searchDecider = NewSearchDecider(classifier)
+	}
+}
+
+// WithPageFetcher включает скачивание страниц из выдачи веб-поиска: модель получает
+// выдержки из полного текста, а не только сниппеты
+func WithPageFetcher(pages domain.PageFetcher) Option {
+	return func(s *Service) {
+		s.pages = pages
+	}
 }
 
-func NewChatService(chatRepo domain.ChatRepo, msgRepo domain.MessageRepo, llm domain.LLM, limits *domain.Limits) (*Service, error) {
+// WithTools включает вызов инструментов: ответ генерируется через model.Chat
+// с описаниями инструментов из registry вместо llm.Generate
+func WithTools(model domain.ToolLLM, registry *ToolRegistry) Option {
+	return func(s *Service) {
+		s.toolLLM = model
+		s.tools = registry
+	}
+}
+
+// WithPIIPolicy включает поиск персональных данных в запросе, истории и документах
+// и их скрытие от модели по политике сценария
+func WithPIIPolicy(policy pii.Policy) Option {
+	return func(s *Service) {
+		s.piiPolicy = policy
+	}
+}
+
+// WithTxManager делает сохранение ответа атомарным: ответ, статус вопроса и время чата
+// записываются одной транзакцией. Без него шаги выполняются по отдельности
+func WithTxManager(tx domain.TxManager) Option {
+	return func(s *Service) {
+		s.tx = tx
+	}
+}
+
+// WithAuthorizer включает проверку прав с учётом ролей в организациях.
+// Без него пользователю доступны только его личные чаты
+func WithAuthorizer(az *authz.Service) Option {
+	return func(s *Service) {
+		s.authz = az
+	}
+}
+
+// WithModerator включает проверку каждого ответа классификатором недопустимого содержимого
+func WithModerator(moderator domain.Moderator) Option {
+	return func(s *Service) {
+		s.moderator = moderator
+	}
+}
+
+func NewChatService(chatRepo domain.ChatRepo, msgRepo domain.MessageRepo, llm domain.LLM, limits *domain.Limits, opts ...Option) (*Service, error) {
 	if chatRepo == nil {
 		return nil, errors.New("chat repo should be provided")
 	}
@@ -29,10 +131,18 @@ func NewChatService(chatRepo domain.ChatRepo, msgRepo domain.MessageRepo, llm do
 		return nil, errors.New("limits should be provided")
 	}
 
-	return &Service{
+	s := &Service{
 		chatRepo: chatRepo,
 		msgRepo:  msgRepo,
 		llm:      llm,
 		limits:   *limits,
-	}, nil
+		authz:    authz.OwnerOnly(),
+		tx:       noTx{},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
 }
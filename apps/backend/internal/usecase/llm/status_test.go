@@ -0,0 +1,74 @@
+package llm
+
+import (
+	"backend/internal/domain"
+	"backend/internal/fakes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestReply_MessageStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		llmErr     error
+		touchErr   error
+		wantStatus domain.MessageStatus
+		wantSaved  int
+	}{
+		{
+			name:       "answer saved",
+			wantStatus: domain.MessageStatusComplete,
+			wantSaved:  2,
+		},
+		{
+			name:       "generation failed",
+			llmErr:     errors.New("timeout"),
+			wantStatus: domain.MessageStatusFailed,
+			wantSaved:  1,
+		},
+		{
+			name:       "persistence failed",
+			touchErr:   errors.New("connection reset"),
+			wantStatus: domain.MessageStatusFailed,
+			wantSaved:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chat := &domain.Chat{ID: uuid.New(), UserID: uuid.New()}
+			msgRepo := newBranchRepo(chat)
+			msgRepo.Chats.TouchErr = tt.touchErr
+			tx := fakes.NewTx(msgRepo)
+
+			svc, err := NewChatService(msgRepo.Chats, msgRepo,
+				&fakes.LLM{Response: "Срок - 30 дней.", Err: tt.llmErr}, &domain.Limits{
+					MaxPromptChars:  10000,
+					MaxHistoryChars: 5000,
+					MaxRequestChars: 1000,
+				}, WithTxManager(tx))
+			if err != nil {
+				t.Fatalf("NewChatService() error = %v", err)
+			}
+
+			_, err = svc.Reply(context.Background(), chat.ID, chat.UserID, "какой срок?", nil, nil, nil)
+			if (err != nil) != (tt.wantStatus == domain.MessageStatusFailed) {
+				t.Fatalf("Reply() error = %v", err)
+			}
+
+			// Ответ без завершённого вопроса не остаётся: при сбое транзакция откатывает его целиком
+			if len(msgRepo.Messages) != tt.wantSaved {
+				t.Fatalf("saved messages = %d, want %d", len(msgRepo.Messages), tt.wantSaved)
+			}
+			if status := msgRepo.Messages[0].Status; status != tt.wantStatus {
+				t.Fatalf("user message status = %q, want %q", status, tt.wantStatus)
+			}
+			if tt.llmErr == nil && tx.Calls != 1 {
+				t.Fatalf("transactions = %d, want 1", tx.Calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,420 @@
+package llm
+
+import (
+	"backend/internal/domain"
+	"backend/internal/logging"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// maxToolRounds - сколько раз подряд модель может вызывать инструменты, после этого ответ без инструментов
+	maxToolRounds = 3
+	// maxToolResultChars - ограничение на размер результата одного инструмента в диалоге
+	maxToolResultChars = 4000
+)
+
+var (
+	ErrUnknownTool     = errors.New("unknown tool")
+	ErrInvalidToolArgs = errors.New("invalid tool arguments")
+)
+
+// ToolEnv - данные запроса, доступные инструментам
+type ToolEnv struct {
+	ChatID      uuid.UUID
+	UserID      uuid.UUID
+	DocumentIDs []uuid.UUID
+	Documents   DocumentTextGetter
+
+	// searches - веб-поиски, выполненные инструментами, попадают в metadata ответа
+	searches []domain.SearchRecord
+	// injections - признаки prompt-injection в результатах инструментов
+	injections []domain.InjectionFlag
+	// lastID - последнее сохранённое сообщение ветки: следующее сообщение цикла продолжает его
+	lastID uuid.UUID
+}
+
+// ToolHandler выполняет инструмент и возвращает текст результата для модели
+type ToolHandler func(ctx context.Context, env *ToolEnv, args json.RawMessage) (string, error)
+
+// Tool - инструмент, который модель может вызвать: описание в JSON Schema и Go-обработчик
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  json.RawMessage
+	Handler     ToolHandler
+}
+
+// ToolRegistry - набор инструментов, доступных ассистенту
+type ToolRegistry struct {
+	tools map[string]Tool
+	specs []domain.ToolSpec
+}
+
+func NewToolRegistry(tools ...Tool) (*ToolRegistry, error) {
+	r := &ToolRegistry{tools: make(map[string]Tool, len(tools))}
+
+	for _, t := range tools {
+		if t.Name == "" {
+			return nil, errors.New("tool name should be provided")
+		}
+		if t.Handler == nil {
+			return nil, fmt.Errorf("tool %q: handler should be provided", t.Name)
+		}
+		if !json.Valid(t.Parameters) {
+			return nil, fmt.Errorf("tool %q: parameters should be a valid JSON schema", t.Name)
+		}
+		if _, ok := r.tools[t.Name]; ok {
+			return nil, fmt.Errorf("tool %q registered twice", t.Name)
+		}
+
+		r.tools[t.Name] = t
+		r.specs = append(r.specs, domain.ToolSpec{
+			Name:        t.Name,
+			Description: t.Description,
+			Parameters:  t.Parameters,
+		})
+	}
+
+	return r, nil
+}
+
+// Specs возвращает описания инструментов для модели в порядке регистрации
+func (r *ToolRegistry) Specs() []domain.ToolSpec {
+	return r.specs
+}
+
+// Call выполняет вызов инструмента, запрошенный моделью
+func (r *ToolRegistry) Call(ctx context.Context, env *ToolEnv, call domain.ToolCall) (string, error) {
+	tool, ok := r.tools[call.Name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownTool, call.Name)
+	}
+
+	args, err := normalizeToolArgs(call.Arguments)
+	if err != nil {
+		return "", err
+	}
+
+	return tool.Handler(ctx, env, args)
+}
+
+// normalizeToolArgs приводит аргументы к JSON-объекту: некоторые модели присылают их строкой
+func normalizeToolArgs(raw json.RawMessage) (json.RawMessage, error) {
+	trimmed := strings.TrimSpace(string(raw))
+	if trimmed == "" || trimmed == "null" {
+		return json.RawMessage("{}"), nil
+	}
+
+	if strings.HasPrefix(trimmed, `"`) {
+		var inner string
+		if err := json.Unmarshal([]byte(trimmed), &inner); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidToolArgs, err)
+		}
+		trimmed = inner
+	}
+
+	if !json.Valid([]byte(trimmed)) {
+		return nil, fmt.Errorf("%w: not a JSON object", ErrInvalidToolArgs)
+	}
+
+	return json.RawMessage(trimmed), nil
+}
+
+// decodeToolArgs разбирает аргументы инструмента в структуру
+func decodeToolArgs(args json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(args, v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidToolArgs, err)
+	}
+	return nil
+}
+
+// generateWithTools ведёт диалог с моделью, пока она вызывает инструменты (не больше maxToolRounds раз).
+// Вызовы и результаты инструментов сохраняются в чат сообщениями ассистента и роли tool
+func (s *Service) generateWithTools(ctx context.Context, prompt string, env *ToolEnv) (*domain.Generation, error) {
+	conversation := []domain.ChatMessage{
+		{Role: domain.RoleUser, Content: prompt},
+	}
+
+	var promptTokens, completionTokens int
+	for round := 0; ; round++ {
+		specs := s.tools.Specs()
+		if round >= maxToolRounds {
+			// Лимит исчерпан - просим ответить по уже собранным данным
+			specs = nil
+		}
+
+		generation, err := s.toolLLM.Chat(ctx, conversation, specs)
+		if err != nil {
+			return nil, err
+		}
+
+		promptTokens += generation.PromptTokens
+		completionTokens += generation.CompletionTokens
+
+		if len(generation.ToolCalls) == 0 || specs == nil {
+			generation.PromptTokens = promptTokens
+			generation.CompletionTokens = completionTokens
+			generation.ToolCalls = nil
+			return generation, nil
+		}
+
+		parentID := env.lastID
+		callMsg := &domain.Message{
+			ID:       uuid.New(),
+			ChatID:   env.ChatID,
+			ParentID: &parentID,
+			Role:     string(domain.RoleAssistant),
+			Content:  generation.Content,
+			Metadata: domain.MessageMetadata{ToolCalls: generation.ToolCalls},
+		}
+		if err := s.msgRepo.Append(ctx, callMsg); err != nil {
+			return nil, fmt.Errorf("failed to save tool call message: %w", err)
+		}
+		env.lastID = callMsg.ID
+
+		conversation = append(conversation, domain.ChatMessage{
+			Role:      domain.RoleAssistant,
+			Content:   generation.Content,
+			ToolCalls: generation.ToolCalls,
+		})
+
+		for _, call := range generation.ToolCalls {
+			resultMsg := s.runTool(ctx, env, call)
+			parentID := env.lastID
+			resultMsg.ParentID = &parentID
+			if err := s.msgRepo.Append(ctx, resultMsg); err != nil {
+				return nil, fmt.Errorf("failed to save tool result message: %w", err)
+			}
+			env.lastID = resultMsg.ID
+
+			conversation = append(conversation, domain.ChatMessage{
+				Role:     domain.RoleTool,
+				Content:  resultMsg.Content,
+				ToolName: call.Name,
+			})
+		}
+	}
+}
+
+// runTool выполняет один вызов и оформляет результат сообщением роли tool.
+// Ошибка инструмента не прерывает ответ - модель получает её текст и может отреагировать
+func (s *Service) runTool(ctx context.Context, env *ToolEnv, call domain.ToolCall) *domain.Message {
+	ctx, span := tracer.Start(ctx, "reply.tool", trace.WithAttributes(
+		attribute.String("tool.name", call.Name),
+	))
+
+	msg := &domain.Message{
+		ID:       uuid.New(),
+		ChatID:   env.ChatID,
+		Role:     string(domain.RoleTool),
+		Metadata: domain.MessageMetadata{ToolName: call.Name},
+	}
+
+	result, err := s.tools.Call(ctx, env, call)
+	endSpan(span, err)
+	if err != nil {
+		logging.FromContext(ctx).WarnContext(ctx, "tool call failed",
+			slog.String("tool", call.Name),
+			slog.Any("error", err),
+		)
+		msg.Content = "Ошибка: " + err.Error()
+		msg.Metadata.ToolError = err.Error()
+		return msg
+	}
+
+	// Результат инструмента - такой же недоверенный текст, как документы и страницы
+	if signals := detectInjection(result); len(signals) > 0 {
+		flag := domain.InjectionFlag{Source: "tool:" + call.Name, Signals: signals}
+		msg.Metadata.Injections = []domain.InjectionFlag{flag}
+		env.injections = append(env.injections, flag)
+	}
+	result = sanitizeUntrusted(result)
+
+	if len(result) > maxToolResultChars {
+		// Обрезаем по байтам, не оставляя половину UTF-8 символа
+		result = strings.ToValidUTF8(result[:maxToolResultChars], "")
+		msg.Truncated = true
+	}
+	msg.Content = result
+
+	return msg
+}
+
+// WebSearchTool - поиск в интернете по запросу, который сформулировала модель.
+// pages (может быть nil) добавляет к первым результатам выдержки из полного текста страниц
+func WebSearchTool(provider domain.SearchProvider, pages domain.PageFetcher) Tool {
+	return Tool{
+		Name:        "web_search",
+		Description: "Поиск в интернете. Используй для свежих и внешних данных: курсы, ставки, цены, новости, законы, адреса.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"query": {"type": "string", "description": "Короткий поисковый запрос на языке пользователя"}
+			},
+			"required": ["query"]
+		}`),
+		Handler: func(ctx context.Context, env *ToolEnv, args json.RawMessage) (string, error) {
+			var in struct {
+				Query string `json:"query"`
+			}
+			if err := decodeToolArgs(args, &in); err != nil {
+				return "", err
+			}
+
+			query := strings.TrimSpace(in.Query)
+			if query == "" {
+				return "", fmt.Errorf("%w: query is empty", ErrInvalidToolArgs)
+			}
+
+			text, record := runSearch(ctx, provider, pages, query, DecisionSourceTool)
+			env.searches = append(env.searches, *record)
+			if record.Error != "" {
+				return "", errors.New(record.Error)
+			}
+			if text == "" {
+				return "Ничего не найдено.", nil
+			}
+
+			return text, nil
+		},
+	}
+}
+
+// DocumentLookupTool - поиск фрагментов в документах, прикреплённых к сообщению
+func DocumentLookupTool() Tool {
+	return Tool{
+		Name:        "document_lookup",
+		Description: "Поиск фрагментов текста в документах, прикреплённых пользователем к сообщению. Возвращает самые подходящие абзацы.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"query": {"type": "string", "description": "Что искать: слова или фраза из вопроса"},
+				"document_id": {"type": "string", "description": "ID документа, если нужно искать только в одном"}
+			},
+			"required": ["query"]
+		}`),
+		Handler: func(ctx context.Context, env *ToolEnv, args json.RawMessage) (string, error) {
+			var in struct {
+				Query      string `json:"query"`
+				DocumentID string `json:"document_id"`
+			}
+			if err := decodeToolArgs(args, &in); err != nil {
+				return "", err
+			}
+
+			if env.Documents == nil || len(env.DocumentIDs) == 0 {
+				return "К сообщению не прикреплены документы.", nil
+			}
+
+			docIDs := env.DocumentIDs
+			if in.DocumentID != "" {
+				docID, err := uuid.Parse(in.DocumentID)
+				if err != nil {
+					return "", fmt.Errorf("%w: bad document_id", ErrInvalidToolArgs)
+				}
+				// Искать можно только в документах текущего сообщения
+				if !containsUUID(env.DocumentIDs, docID) {
+					return "", fmt.Errorf("document %s is not attached to the message", docID)
+				}
+				docIDs = []uuid.UUID{docID}
+			}
+
+			var paragraphs []string
+			for _, docID := range docIDs {
+				text, err := env.Documents.GetDocumentText(ctx, docID)
+				if err != nil {
+					return "", fmt.Errorf("failed to get document %s: %w", docID, err)
+				}
+				paragraphs = append(paragraphs, splitParagraphs(text)...)
+			}
+
+			found := bestParagraphs(paragraphs, in.Query, 3)
+			if len(found) == 0 {
+				return "В документах не найдено фрагментов по запросу.", nil
+			}
+
+			return strings.Join(found, "\n\n---\n\n"), nil
+		},
+	}
+}
+
+func containsUUID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// splitParagraphs делит текст документа на непустые абзацы
+func splitParagraphs(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	var out []string
+	for _, p := range strings.Split(text, "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// bestParagraphs возвращает до limit абзацев с наибольшим числом слов запроса.
+// Слова сравниваются по началу (первые 5 букв), чтобы не зависеть от русских окончаний
+func bestParagraphs(paragraphs []string, query string, limit int) []string {
+	var stems []string
+	for _, w := range strings.Fields(strings.ToLower(query)) {
+		w = strings.Trim(w, ".,;:!?\"'«»()")
+		r := []rune(w)
+		if len(r) < 3 {
+			continue
+		}
+		if len(r) > 5 {
+			r = r[:5]
+		}
+		stems = append(stems, string(r))
+	}
+	if len(stems) == 0 {
+		return nil
+	}
+
+	type scored struct {
+		text  string
+		score int
+	}
+	var candidates []scored
+	for _, p := range paragraphs {
+		lower := strings.ToLower(p)
+		score := 0
+		for _, stem := range stems {
+			if strings.Contains(lower, stem) {
+				score++
+			}
+		}
+		if score > 0 {
+			candidates = append(candidates, scored{text: p, score: score})
+		}
+	}
+
+	// Стабильная сортировка: при равном счёте сохраняем порядок в документе
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	out := make([]string, 0, limit)
+	for i := 0; i < len(candidates) && i < limit; i++ {
+		out = append(out, candidates[i].text)
+	}
+	return out
+}
@@ -0,0 +1,253 @@
+package llm
+
+import (
+	"backend/internal/domain"
+	"backend/internal/fakes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// fakeToolLLM отдаёт заранее заданные ответы по очереди и запоминает, с чем её вызывали
+type fakeToolLLM struct {
+	responses []*domain.Generation
+	calls     [][]domain.ChatMessage
+	tools     [][]domain.ToolSpec
+}
+
+func (f *fakeToolLLM) Chat(_ context.Context, messages []domain.ChatMessage, tools []domain.ToolSpec) (*domain.Generation, error) {
+	f.calls = append(f.calls, append([]domain.ChatMessage(nil), messages...))
+	f.tools = append(f.tools, tools)
+
+	i := len(f.calls) - 1
+	if i >= len(f.responses) {
+		i = len(f.responses) - 1
+	}
+	gen := *f.responses[i]
+	return &gen, nil
+}
+
+// fakeDocs - тексты документов по ID
+type fakeDocs map[uuid.UUID]string
+
+func (f fakeDocs) GetDocumentText(_ context.Context, docID uuid.UUID) (string, error) {
+	text, ok := f[docID]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return text, nil
+}
+
+func TestNewToolRegistry(t *testing.T) {
+	handler := func(context.Context, *ToolEnv, json.RawMessage) (string, error) { return "", nil }
+
+	tests := []struct {
+		name  string
+		tools []Tool
+		ok    bool
+	}{
+		{name: "builtin tools", tools: []Tool{CalculatorTool(), DocumentLookupTool(), WebSearchTool(&fakeSearch{}, nil)}, ok: true},
+		{name: "empty name", tools: []Tool{{Parameters: json.RawMessage(`{}`), Handler: handler}}},
+		{name: "no handler", tools: []Tool{{Name: "x", Parameters: json.RawMessage(`{}`)}}},
+		{name: "bad schema", tools: []Tool{{Name: "x", Parameters: json.RawMessage(`{`), Handler: handler}}},
+		{name: "duplicate", tools: []Tool{CalculatorTool(), CalculatorTool()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewToolRegistry(tt.tools...)
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(r.Specs()) != len(tt.tools) {
+					t.Fatalf("specs = %d, want %d", len(r.Specs()), len(tt.tools))
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
+
+func TestToolRegistry_Call(t *testing.T) {
+	r, err := NewToolRegistry(CalculatorTool())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		call    domain.ToolCall
+		want    string
+		wantErr error
+	}{
+		{
+			name: "object arguments",
+			call: domain.ToolCall{Name: "calculator", Arguments: json.RawMessage(`{"operation":"expression","expression":"6*7"}`)},
+			want: "6*7 = 42",
+		},
+		{
+			name: "arguments as json string",
+			call: domain.ToolCall{Name: "calculator", Arguments: json.RawMessage(`"{\"operation\":\"expression\",\"expression\":\"1+1\"}"`)},
+			want: "1+1 = 2",
+		},
+		{
+			name:    "unknown tool",
+			call:    domain.ToolCall{Name: "rm_rf"},
+			wantErr: ErrUnknownTool,
+		},
+		{
+			name:    "broken arguments",
+			call:    domain.ToolCall{Name: "calculator", Arguments: json.RawMessage(`"{oops"`)},
+			wantErr: ErrInvalidToolArgs,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Call(context.Background(), &ToolEnv{}, tt.call)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("Call() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocumentLookupTool(t *testing.T) {
+	attached := uuid.New()
+	foreign := uuid.New()
+	env := &ToolEnv{
+		DocumentIDs: []uuid.UUID{attached},
+		Documents: fakeDocs{
+			attached: "1. Предмет договора\nПоставка оборудования.\n\n2. Штрафы\nЗа просрочку поставки начисляется неустойка 0,1% в день.\n\n3. Срок действия\nДо 31 декабря.",
+			foreign:  "чужой документ про неустойку",
+		},
+	}
+	tool := DocumentLookupTool()
+
+	got, err := tool.Handler(context.Background(), env, json.RawMessage(`{"query":"неустойка за просрочку"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(got, "2. Штрафы") {
+		t.Fatalf("best paragraph should go first, got:\n%s", got)
+	}
+
+	_, err = tool.Handler(context.Background(), env, json.RawMessage(`{"query":"неустойка","document_id":"`+foreign.String()+`"}`))
+	if err == nil {
+		t.Fatalf("lookup in a document not attached to the message must fail")
+	}
+}
+
+func TestReply_ToolLoop(t *testing.T) {
+	userID := uuid.New()
+	chat := &domain.Chat{ID: uuid.New(), UserID: userID}
+	msgRepo := newBranchRepo(chat)
+
+	model := &fakeToolLLM{responses: []*domain.Generation{
+		{
+			PromptTokens:     100,
+			CompletionTokens: 10,
+			ToolCalls: []domain.ToolCall{
+				{Name: "calculator", Arguments: json.RawMessage(`{"operation":"vat_add","amount":1000}`)},
+			},
+		},
+		{Content: "С НДС получится 1200 ₽.", PromptTokens: 150, CompletionTokens: 20},
+	}}
+	registry, err := NewToolRegistry(CalculatorTool())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc, err := NewChatService(msgRepo.Chats, msgRepo, &fakes.LLM{}, &domain.Limits{
+		MaxPromptChars:  10000,
+		MaxHistoryChars: 5000,
+		MaxRequestChars: 1000,
+	}, WithTools(model, registry))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := svc.Reply(context.Background(), chat.ID, userID, "сколько будет 1000 с НДС?", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Reply() error = %v", err)
+	}
+
+	if msg.Content != "С НДС получится 1200 ₽." {
+		t.Fatalf("Content = %q", msg.Content)
+	}
+
+	// user -> assistant(tool_calls) -> tool -> assistant
+	wantRoles := []domain.Role{domain.RoleUser, domain.RoleAssistant, domain.RoleTool, domain.RoleAssistant}
+	if len(msgRepo.Messages) != len(wantRoles) {
+		t.Fatalf("saved messages = %d, want %d", len(msgRepo.Messages), len(wantRoles))
+	}
+	for i, role := range wantRoles {
+		if msgRepo.Messages[i].Role != string(role) {
+			t.Fatalf("message %d role = %s, want %s", i, msgRepo.Messages[i].Role, role)
+		}
+	}
+	if len(msgRepo.Messages[1].Metadata.ToolCalls) != 1 {
+		t.Fatalf("tool call is not recorded in metadata")
+	}
+	toolMsg := msgRepo.Messages[2]
+	if toolMsg.Metadata.ToolName != "calculator" || !strings.Contains(toolMsg.Content, "1200.00") {
+		t.Fatalf("unexpected tool message: %+v", toolMsg)
+	}
+
+	// Результат инструмента уходит модели во втором вызове
+	last := model.calls[1][len(model.calls[1])-1]
+	if last.Role != domain.RoleTool || last.ToolName != "calculator" {
+		t.Fatalf("second call should end with tool result, got %+v", last)
+	}
+}
+
+func TestReply_ToolLoopIsBounded(t *testing.T) {
+	userID := uuid.New()
+	chat := &domain.Chat{ID: uuid.New(), UserID: userID}
+
+	// Модель бесконечно просит инструмент
+	model := &fakeToolLLM{responses: []*domain.Generation{
+		{ToolCalls: []domain.ToolCall{{Name: "calculator", Arguments: json.RawMessage(`{"operation":"sqrt"}`)}}},
+	}}
+	registry, err := NewToolRegistry(CalculatorTool())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc, err := NewChatService(fakes.NewChatRepo(chat), &fakes.MessageRepo{}, &fakes.LLM{}, &domain.Limits{
+		MaxPromptChars:  10000,
+		MaxHistoryChars: 5000,
+		MaxRequestChars: 1000,
+	}, WithTools(model, registry))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := svc.Reply(context.Background(), chat.ID, userID, "посчитай", nil, nil, nil); err != nil {
+		t.Fatalf("Reply() error = %v", err)
+	}
+
+	if len(model.calls) != maxToolRounds+1 {
+		t.Fatalf("model calls = %d, want %d", len(model.calls), maxToolRounds+1)
+	}
+	if model.tools[maxToolRounds] != nil {
+		t.Fatalf("last call must be made without tools")
+	}
+}
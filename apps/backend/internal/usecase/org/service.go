@@ -0,0 +1,367 @@
+package org
+
+import (
+	"backend/internal/domain"
+	"backend/internal/usecase/authz"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxNameRunes = 100
+	// DefaultInviteTTL - срок действия приглашения
+	DefaultInviteTTL = 7 * 24 * time.Hour
+)
+
+var (
+	// ErrInvalidName - пустое или слишком длинное название организации
+	ErrInvalidName = errors.New("invalid organization name")
+	// ErrInvalidEmail - адрес приглашения не разобран
+	ErrInvalidEmail = errors.New("invalid email")
+	// ErrInvalidRole - неизвестная роль участника
+	ErrInvalidRole = errors.New("invalid role")
+)
+
+type Service struct {
+	orgs    domain.OrgRepo
+	invites domain.InviteRepo
+	users   domain.UserRepo
+	mailer  domain.Mailer
+	tx      domain.TxManager
+	authz   *authz.Service
+
+	inviteURL string
+	inviteTTL time.Duration
+	now       func() time.Time
+}
+
+type Option func(*Service)
+
+// WithInviteURL задаёт адрес страницы принятия приглашения; токен добавляется параметром token
+func WithInviteURL(url string) Option {
+	return func(s *Service) {
+		s.inviteURL = url
+	}
+}
+
+// WithInviteTTL задаёт срок действия приглашения
+func WithInviteTTL(ttl time.Duration) Option {
+	return func(s *Service) {
+		if ttl > 0 {
+			s.inviteTTL = ttl
+		}
+	}
+}
+
+func NewService(
+	orgs domain.OrgRepo,
+	invites domain.InviteRepo,
+	users domain.UserRepo,
+	mailer domain.Mailer,
+	tx domain.TxManager,
+	az *authz.Service,
+	opts ...Option,
+) (*Service, error) {
+	if orgs == nil {
+		return nil, errors.New("org repo should be provided")
+	}
+	if invites == nil {
+		return nil, errors.New("invite repo should be provided")
+	}
+	if users == nil {
+		return nil, errors.New("user repo should be provided")
+	}
+	if mailer == nil {
+		return nil, errors.New("mailer should be provided")
+	}
+	if tx == nil {
+		return nil, errors.New("tx manager should be provided")
+	}
+	if az == nil {
+		return nil, errors.New("authorizer should be provided")
+	}
+
+	s := &Service{
+		orgs:      orgs,
+		invites:   invites,
+		users:     users,
+		mailer:    mailer,
+		tx:        tx,
+		authz:     az,
+		inviteURL: "/invites/accept",
+		inviteTTL: DefaultInviteTTL,
+		now:       time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
+}
+
+// Create создаёт организацию, владельцем которой становится userID
+func (s *Service) Create(ctx context.Context, userID uuid.UUID, name string) (*domain.Org, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxNameRunes {
+		return nil, fmt.Errorf("%w: must be 1 to %d characters", ErrInvalidName, maxNameRunes)
+	}
+
+	org := &domain.Org{ID: uuid.New(), Name: name}
+	if err := s.orgs.Create(ctx, org, userID); err != nil {
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+	return org, nil
+}
+
+// List возвращает организации пользователя с его ролью в каждой
+func (s *Service) List(ctx context.Context, userID uuid.UUID) ([]*domain.Org, error) {
+	orgs, err := s.orgs.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+	return orgs, nil
+}
+
+// Members возвращает участников организации; доступно любому участнику
+func (s *Service) Members(ctx context.Context, userID, orgID uuid.UUID) ([]*domain.Membership, error) {
+	if err := s.authz.Can(ctx, authz.User(userID), authz.OrgRead, authz.Org(orgID)); err != nil {
+		return nil, err
+	}
+
+	members, err := s.orgs.ListMembers(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members: %w", err)
+	}
+	return members, nil
+}
+
+// Invites возвращает приглашения организации; доступно владельцам
+func (s *Service) Invites(ctx context.Context, userID, orgID uuid.UUID) ([]*domain.OrgInvite, error) {
+	if err := s.authz.Can(ctx, authz.User(userID), authz.OrgManage, authz.Org(orgID)); err != nil {
+		return nil, err
+	}
+
+	invites, err := s.invites.ListByOrg(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invites: %w", err)
+	}
+	return invites, nil
+}
+
+// Invite приглашает email в организацию с ролью role и отправляет письмо со ссылкой.
+// Письмо уходит после сохранения приглашения, вне транзакции: ссылка в нём всегда ведёт
+// на сохранённое приглашение. Если письмо не отправлено, приглашение удаляется
+func (s *Service) Invite(ctx context.Context, userID, orgID uuid.UUID, email string, role domain.OrgRole) (*domain.OrgInvite, error) {
+	addr, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEmail, err)
+	}
+	email = strings.ToLower(addr.Address)
+	if !role.Valid() {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidRole, role)
+	}
+
+	if err := s.authz.Can(ctx, authz.User(userID), authz.OrgManage, authz.Org(orgID)); err != nil {
+		return nil, err
+	}
+
+	org, err := s.orgs.GetByID(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+	if org == nil {
+		return nil, domain.ErrOrgNotFound
+	}
+
+	members, err := s.orgs.ListMembers(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members: %w", err)
+	}
+	for _, m := range members {
+		if strings.EqualFold(m.Email, email) {
+			return nil, domain.ErrAlreadyMember
+		}
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	invite := &domain.OrgInvite{
+		ID:        uuid.New(),
+		OrgID:     orgID,
+		Email:     email,
+		Role:      role,
+		TokenHash: hashToken(token),
+		InvitedBy: userID,
+		ExpiresAt: s.now().Add(s.inviteTTL),
+	}
+
+	if err := s.invites.Create(ctx, invite); err != nil {
+		return nil, fmt.Errorf("failed to create invite: %w", err)
+	}
+	if err := s.mailer.Send(ctx, s.inviteEmail(org, invite, token)); err != nil {
+		if delErr := s.invites.Delete(context.WithoutCancel(ctx), invite.ID); delErr != nil {
+			return nil, fmt.Errorf("failed to send invite: %w (and to delete it: %v)", err, delErr)
+		}
+		return nil, fmt.Errorf("failed to send invite: %w", err)
+	}
+
+	return invite, nil
+}
+
+// AcceptInvite добавляет пользователя в организацию по токену из письма.
+// Принять приглашение может только пользователь с тем email, на который оно выписано
+func (s *Service) AcceptInvite(ctx context.Context, userID uuid.UUID, token string) (*domain.Membership, error) {
+	if token == "" {
+		return nil, domain.ErrInviteNotFound
+	}
+
+	invite, err := s.invites.GetByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invite: %w", err)
+	}
+	if invite == nil || !invite.Pending(s.now()) {
+		return nil, domain.ErrInviteNotFound
+	}
+
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || !strings.EqualFold(user.Email, invite.Email) {
+		return nil, domain.ErrInviteNotFound
+	}
+
+	membership := &domain.Membership{OrgID: invite.OrgID, UserID: userID, Email: user.Email, Role: invite.Role}
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.orgs.AddMember(ctx, membership); err != nil {
+			return err
+		}
+		if err := s.invites.MarkAccepted(ctx, invite.ID, s.now()); err != nil {
+			return fmt.Errorf("failed to accept invite: %w", err)
+		}
+		return nil
+	})
+	if errors.Is(err, domain.ErrAlreadyMember) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to add member: %w", err)
+	}
+
+	return membership, nil
+}
+
+// UpdateRole меняет роль участника; доступно владельцам. Последний владелец не может стать рядовым участником
+func (s *Service) UpdateRole(ctx context.Context, userID, orgID, memberID uuid.UUID, role domain.OrgRole) error {
+	if !role.Valid() {
+		return fmt.Errorf("%w: %q", ErrInvalidRole, role)
+	}
+	if err := s.authz.Can(ctx, authz.User(userID), authz.OrgManage, authz.Org(orgID)); err != nil {
+		return err
+	}
+
+	return s.changeMember(ctx, orgID, memberID, role == domain.OrgRoleOwner, func(ctx context.Context) error {
+		if err := s.orgs.UpdateMemberRole(ctx, orgID, memberID, role); err != nil {
+			return fmt.Errorf("failed to update role: %w", err)
+		}
+		return nil
+	})
+}
+
+// RemoveMember исключает участника; доступно владельцам, а также самому участнику (выход из организации).
+// Чаты участника остаются в организации
+func (s *Service) RemoveMember(ctx context.Context, userID, orgID, memberID uuid.UUID) error {
+	action := authz.OrgManage
+	if userID == memberID {
+		action = authz.OrgRead
+	}
+	if err := s.authz.Can(ctx, authz.User(userID), action, authz.Org(orgID)); err != nil {
+		return err
+	}
+
+	return s.changeMember(ctx, orgID, memberID, false, func(ctx context.Context) error {
+		if err := s.orgs.RemoveMember(ctx, orgID, memberID); err != nil {
+			return fmt.Errorf("failed to remove member: %w", err)
+		}
+		return nil
+	})
+}
+
+// changeMember выполняет change над участником memberID в одной транзакции с проверкой,
+// что у организации останется владелец. stillOwner - участник останется владельцем после change.
+// Записи владельцев блокируются до конца транзакции, поэтому два владельца, одновременно
+// понижающие друг друга, не оставят организацию без владельца: второй дождётся первого и получит ErrLastOwner
+func (s *Service) changeMember(ctx context.Context, orgID, memberID uuid.UUID, stillOwner bool, change func(ctx context.Context) error) error {
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		owners, err := s.orgs.LockOwners(ctx, orgID)
+		if err != nil {
+			return fmt.Errorf("failed to lock owners: %w", err)
+		}
+		member, err := s.getMember(ctx, orgID, memberID)
+		if err != nil {
+			return err
+		}
+		if member.Role == domain.OrgRoleOwner && !stillOwner {
+			if !slices.ContainsFunc(owners, func(id uuid.UUID) bool { return id != memberID }) {
+				return domain.ErrLastOwner
+			}
+		}
+		return change(ctx)
+	})
+}
+
+func (s *Service) getMember(ctx context.Context, orgID, memberID uuid.UUID) (*domain.Membership, error) {
+	member, err := s.orgs.GetMembership(ctx, orgID, memberID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get membership: %w", err)
+	}
+	if member == nil {
+		return nil, domain.ErrOrgNotFound
+	}
+	return member, nil
+}
+
+func (s *Service) inviteEmail(org *domain.Org, invite *domain.OrgInvite, token string) *domain.Email {
+	sep := "?"
+	if strings.Contains(s.inviteURL, "?") {
+		sep = "&"
+	}
+	link := s.inviteURL + sep + "token=" + token
+
+	return &domain.Email{
+		To:      invite.Email,
+		Subject: fmt.Sprintf("Приглашение в «%s»", org.Name),
+		Text: fmt.Sprintf("Вас пригласили в организацию «%s» в Alfa Copilot.\n\n"+
+			"Чтобы присоединиться, войдите в аккаунт с адресом %s и откройте ссылку:\n%s\n\n"+
+			"Приглашение действует до %s.\n",
+			org.Name, invite.Email, link, invite.ExpiresAt.Format("02.01.2006 15:04 MST")),
+	}
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate invite token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
@@ -0,0 +1,182 @@
+package org
+
+import (
+	"backend/internal/domain"
+	"backend/internal/fakes"
+	"backend/internal/usecase/authz"
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+type testEnv struct {
+	svc     *Service
+	orgs    *fakes.OrgRepo
+	invites *fakes.InviteRepo
+	users   *fakes.UserRepo
+	mailer  *fakes.Mailer
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	env := &testEnv{
+		orgs:    fakes.NewOrgRepo(),
+		invites: &fakes.InviteRepo{},
+		users:   fakes.NewUserRepo(),
+		mailer:  &fakes.Mailer{},
+	}
+	az, err := authz.NewService(env.orgs)
+	if err != nil {
+		t.Fatalf("authz.NewService() error = %v", err)
+	}
+	env.svc, err = NewService(env.orgs, env.invites, env.users, env.mailer, fakes.NewTx(), az,
+		WithInviteURL("https://copilot.example.com/invite"))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	return env
+}
+
+func (e *testEnv) addUser(email string) uuid.UUID {
+	id := uuid.New()
+	e.users.Users[id] = &domain.User{ID: id, Email: email, IsActive: true}
+	return id
+}
+
+var linkRe = regexp.MustCompile(`https://\S+`)
+
+// inviteToken достаёт токен из ссылки в последнем письме
+func (e *testEnv) inviteToken(t *testing.T) string {
+	t.Helper()
+
+	email := e.mailer.Sent[len(e.mailer.Sent)-1]
+	link, err := url.Parse(linkRe.FindString(email.Text))
+	if err != nil {
+		t.Fatalf("invite link is not parsed: %v", err)
+	}
+	return link.Query().Get("token")
+}
+
+func TestService_InviteAndAccept(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+
+	owner := env.addUser("owner@example.com")
+	invitee := env.addUser("anna@example.com")
+	other := env.addUser("boris@example.com")
+
+	org, err := env.svc.Create(ctx, owner, "  Кофейня  ")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if org.Name != "Кофейня" {
+		t.Fatalf("name = %q", org.Name)
+	}
+
+	if _, err := env.svc.Invite(ctx, owner, org.ID, "Anna <ANNA@example.com>", domain.OrgRoleViewer); err != nil {
+		t.Fatalf("Invite() error = %v", err)
+	}
+	if len(env.mailer.Sent) != 1 || env.mailer.Sent[0].To != "anna@example.com" {
+		t.Fatalf("invite email is not sent: %+v", env.mailer.Sent)
+	}
+	token := env.inviteToken(t)
+
+	// Приглашение выписано на другой адрес
+	if _, err := env.svc.AcceptInvite(ctx, other, token); !errors.Is(err, domain.ErrInviteNotFound) {
+		t.Fatalf("AcceptInvite() by other user error = %v, want ErrInviteNotFound", err)
+	}
+
+	m, err := env.svc.AcceptInvite(ctx, invitee, token)
+	if err != nil {
+		t.Fatalf("AcceptInvite() error = %v", err)
+	}
+	if m.Role != domain.OrgRoleViewer {
+		t.Fatalf("role = %s, want viewer", m.Role)
+	}
+
+	// Повторно приглашение не принимается
+	if _, err := env.svc.AcceptInvite(ctx, invitee, token); !errors.Is(err, domain.ErrInviteNotFound) {
+		t.Fatalf("second AcceptInvite() error = %v, want ErrInviteNotFound", err)
+	}
+
+	// Зритель не приглашает
+	if _, err := env.svc.Invite(ctx, invitee, org.ID, "boris@example.com", domain.OrgRoleMember); !errors.Is(err, domain.ErrAccessDenied) {
+		t.Fatalf("Invite() by viewer error = %v, want ErrAccessDenied", err)
+	}
+}
+
+func TestService_InviteErrors(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	owner := env.addUser("owner@example.com")
+	org, err := env.svc.Create(ctx, owner, "Кофейня")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	env.orgs.Members[0].Email = "owner@example.com"
+
+	tests := []struct {
+		name    string
+		userID  uuid.UUID
+		email   string
+		role    domain.OrgRole
+		mailErr error
+		wantErr error
+	}{
+		{name: "invalid email", userID: owner, email: "not an email", role: domain.OrgRoleMember, wantErr: ErrInvalidEmail},
+		{name: "invalid role", userID: owner, email: "a@example.com", role: "admin", wantErr: ErrInvalidRole},
+		{name: "not a member", userID: uuid.New(), email: "a@example.com", role: domain.OrgRoleMember, wantErr: domain.ErrOrgNotFound},
+		{name: "already a member", userID: owner, email: "Owner@Example.com", role: domain.OrgRoleMember, wantErr: domain.ErrAlreadyMember},
+		{name: "mail failure", userID: owner, email: "a@example.com", role: domain.OrgRoleMember, mailErr: errors.New("smtp down")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env.mailer.Err = tt.mailErr
+			_, err := env.svc.Invite(ctx, tt.userID, org.ID, tt.email, tt.role)
+			if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Fatalf("Invite() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(env.invites.Invites) != 0 {
+				t.Fatalf("invite saved despite error")
+			}
+		})
+	}
+}
+
+func TestService_LastOwner(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := context.Background()
+	owner := env.addUser("owner@example.com")
+	member := env.addUser("member@example.com")
+
+	org, err := env.svc.Create(ctx, owner, "Кофейня")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := env.orgs.AddMember(ctx, &domain.Membership{OrgID: org.ID, UserID: member, Role: domain.OrgRoleMember}); err != nil {
+		t.Fatalf("AddMember() error = %v", err)
+	}
+
+	if err := env.svc.UpdateRole(ctx, owner, org.ID, owner, domain.OrgRoleMember); !errors.Is(err, domain.ErrLastOwner) {
+		t.Fatalf("demoting last owner error = %v, want ErrLastOwner", err)
+	}
+	if err := env.svc.RemoveMember(ctx, owner, org.ID, owner); !errors.Is(err, domain.ErrLastOwner) {
+		t.Fatalf("last owner leaving error = %v, want ErrLastOwner", err)
+	}
+	if err := env.svc.UpdateRole(ctx, member, org.ID, member, domain.OrgRoleOwner); !errors.Is(err, domain.ErrAccessDenied) {
+		t.Fatalf("member promoting self error = %v, want ErrAccessDenied", err)
+	}
+
+	if err := env.svc.UpdateRole(ctx, owner, org.ID, member, domain.OrgRoleOwner); err != nil {
+		t.Fatalf("UpdateRole() error = %v", err)
+	}
+	if err := env.svc.RemoveMember(ctx, owner, org.ID, owner); err != nil {
+		t.Fatalf("owner leaving with another owner error = %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package pii
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Action - что делать с найденными персональными данными
+type Action string
+
+const (
+	// ActionAllow - передавать в модель как есть
+	ActionAllow Action = "allow"
+	// ActionMask - заменять необратимо на метку вида "[ИНН]"
+	ActionMask Action = "mask"
+	// ActionTokenize - заменять на метку вида "[ИНН_1]" и подставлять исходное значение обратно в ответ
+	ActionTokenize Action = "tokenize"
+	// ActionBlock - не отправлять запрос в модель
+	ActionBlock Action = "block"
+)
+
+// defaultScenario - ключ политики для чатов без сценария и неизвестных сценариев
+const defaultScenario = "default"
+
+// Policy - действие с персональными данными для каждого сценария
+type Policy map[string]Action
+
+// DefaultPolicy - маскирование везде, кроме помощи с договорами: там реквизиты сторон нужны в ответе
+var DefaultPolicy = Policy{
+	defaultScenario:   ActionMask,
+	"contract_helper": ActionTokenize,
+}
+
+// For возвращает действие для сценария
+func (p Policy) For(scenarioCode *string) Action {
+	if scenarioCode != nil {
+		if action, ok := p[*scenarioCode]; ok {
+			return action
+		}
+	}
+	if action, ok := p[defaultScenario]; ok {
+		return action
+	}
+	return ActionMask
+}
+
+// ParsePolicy разбирает политику из строки вида "default=mask,contract_helper=tokenize,marketing=block"
+func ParsePolicy(s string) (Policy, error) {
+	p := make(Policy)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		scenario, action, ok := strings.Cut(item, "=")
+		scenario, action = strings.TrimSpace(scenario), strings.TrimSpace(action)
+		if !ok || scenario == "" {
+			return nil, fmt.Errorf("invalid pii policy item %q: expected scenario=action", item)
+		}
+
+		switch Action(action) {
+		case ActionAllow, ActionMask, ActionTokenize, ActionBlock:
+			p[scenario] = Action(action)
+		default:
+			return nil, fmt.Errorf("invalid pii policy item %q: unknown action %q", item, action)
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,157 @@
+package pii
+
+import (
+	"backend/internal/domain"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// labels - как вид данных называется в метке, которую видит модель
+var labels = map[Kind]string{
+	KindPassport: "ПАСПОРТ",
+	KindINN:      "ИНН",
+	KindSNILS:    "СНИЛС",
+	KindPhone:    "ТЕЛЕФОН",
+	KindCard:     "КАРТА",
+	KindAccount:  "СЧЁТ",
+	KindEmail:    "EMAIL",
+	KindIBAN:     "IBAN",
+}
+
+// Redactor скрывает персональные данные в текстах одного запроса. Одно и то же значение
+// получает одну и ту же метку во всех текстах, поэтому ответ можно восстановить через Restore.
+// Не потокобезопасен: создаётся на каждый запрос
+type Redactor struct {
+	action Action
+	tokens map[string]string // значение -> метка
+	values map[string]string // метка -> значение
+	seq    map[Kind]int
+	seen   map[string]bool
+	counts map[Kind]int
+}
+
+func NewRedactor(action Action) *Redactor {
+	return &Redactor{
+		action: action,
+		tokens: make(map[string]string),
+		values: make(map[string]string),
+		seq:    make(map[Kind]int),
+		seen:   make(map[string]bool),
+		counts: make(map[Kind]int),
+	}
+}
+
+// Action - действие, с которым создан Redactor
+func (r *Redactor) Action() Action {
+	return r.action
+}
+
+// Protect готовит текст для модели. Для ActionBlock возвращает domain.ErrSensitiveData,
+// если в тексте есть персональные данные
+func (r *Redactor) Protect(text string) (string, error) {
+	if r.action == ActionBlock {
+		if matches := Scan(text); len(matches) > 0 {
+			r.count(matches)
+			return "", fmt.Errorf("%w: %s", domain.ErrSensitiveData, describe(matches))
+		}
+		return text, nil
+	}
+	return r.Replace(text), nil
+}
+
+// Replace заменяет персональные данные метками и никогда не блокирует: при ActionBlock
+// данные маскируются. Нужен для текстов, которые уже сохранены в чате (история)
+func (r *Redactor) Replace(text string) string {
+	if r.action == ActionAllow {
+		return text
+	}
+
+	matches := Scan(text)
+	if len(matches) == 0 {
+		return text
+	}
+	r.count(matches)
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(text[last:m.Start])
+		b.WriteString(r.placeholder(m))
+		last = m.End
+	}
+	b.WriteString(text[last:])
+
+	return b.String()
+}
+
+// Restore подставляет исходные значения вместо меток в ответ модели (только для ActionTokenize)
+func (r *Redactor) Restore(text string) string {
+	if len(r.values) == 0 {
+		return text
+	}
+
+	pairs := make([]string, 0, len(r.values)*2)
+	for token, value := range r.values {
+		pairs = append(pairs, token, value)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// Tokenized - были ли в запросе обратимые метки
+func (r *Redactor) Tokenized() bool {
+	return len(r.values) > 0
+}
+
+// Report возвращает статистику найденного без самих значений; nil - ничего не найдено
+func (r *Redactor) Report() *domain.PIIReport {
+	if len(r.counts) == 0 {
+		return nil
+	}
+
+	report := &domain.PIIReport{Action: string(r.action), Counts: make(map[string]int, len(r.counts))}
+	for kind, n := range r.counts {
+		report.Counts[string(kind)] = n
+	}
+	return report
+}
+
+func (r *Redactor) placeholder(m Match) string {
+	label := labels[m.Kind]
+	if r.action != ActionTokenize {
+		return "[" + label + "]"
+	}
+
+	if token, ok := r.tokens[m.Value]; ok {
+		return token
+	}
+	r.seq[m.Kind]++
+	token := fmt.Sprintf("[%s_%d]", label, r.seq[m.Kind])
+	r.tokens[m.Value] = token
+	r.values[token] = m.Value
+	return token
+}
+
+// count учитывает разные значения: одно и то же значение в запросе и в истории считается один раз
+func (r *Redactor) count(matches []Match) {
+	for _, m := range matches {
+		if !r.seen[m.Value] {
+			r.seen[m.Value] = true
+			r.counts[m.Kind]++
+		}
+	}
+}
+
+// describe перечисляет виды найденных данных для сообщения об ошибке, без значений
+func describe(matches []Match) string {
+	seen := make(map[Kind]bool)
+	var kinds []string
+	for _, m := range matches {
+		if !seen[m.Kind] {
+			seen[m.Kind] = true
+			kinds = append(kinds, string(m.Kind))
+		}
+	}
+	sort.Strings(kinds)
+	return strings.Join(kinds, ", ")
+}
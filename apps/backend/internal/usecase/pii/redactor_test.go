@@ -0,0 +1,116 @@
+package pii
+
+import (
+	"backend/internal/domain"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRedactor_Protect(t *testing.T) {
+	text := "Поставщик: ИНН 7707083893, тел. +7 (916) 123-45-67. Покупатель: ИНН 7707083893"
+
+	tests := []struct {
+		name    string
+		action  Action
+		want    string
+		wantErr error
+	}{
+		{
+			name:   "allow keeps text",
+			action: ActionAllow,
+			want:   text,
+		},
+		{
+			name:   "mask replaces values with labels",
+			action: ActionMask,
+			want:   "Поставщик: ИНН [ИНН], тел. [ТЕЛЕФОН]. Покупатель: ИНН [ИНН]",
+		},
+		{
+			name:   "tokenize gives the same value the same token",
+			action: ActionTokenize,
+			want:   "Поставщик: ИНН [ИНН_1], тел. [ТЕЛЕФОН_1]. Покупатель: ИНН [ИНН_1]",
+		},
+		{
+			name:    "block rejects text with personal data",
+			action:  ActionBlock,
+			wantErr: domain.ErrSensitiveData,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewRedactor(tt.action).Protect(text)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Protect() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("Protect() = %q, want %q", got, tt.want)
+			}
+			if err != nil && strings.Contains(err.Error(), "7707083893") {
+				t.Fatalf("error leaks the value: %v", err)
+			}
+		})
+	}
+}
+
+func TestRedactor_RestoreAndReport(t *testing.T) {
+	r := NewRedactor(ActionTokenize)
+
+	first, _ := r.Protect("Карта 4111 1111 1111 1111")
+	second, _ := r.Protect("Переведите на 4111 1111 1111 1111, чек на ivan@example.ru")
+	if first != "Карта [КАРТА_1]" || second != "Переведите на [КАРТА_1], чек на [EMAIL_1]" {
+		t.Fatalf("unexpected tokens: %q, %q", first, second)
+	}
+
+	answer := r.Restore("Оплата на [КАРТА_1] прошла, чек отправлен на [EMAIL_1].")
+	if answer != "Оплата на 4111 1111 1111 1111 прошла, чек отправлен на ivan@example.ru." {
+		t.Fatalf("Restore() = %q", answer)
+	}
+
+	want := &domain.PIIReport{Action: "tokenize", Counts: map[string]int{"card": 1, "email": 1}}
+	if got := r.Report(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Report() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRedactor_ReplaceNeverBlocks(t *testing.T) {
+	r := NewRedactor(ActionBlock)
+
+	if got := r.Replace("СНИЛС 112-233-445 95"); got != "СНИЛС [СНИЛС]" {
+		t.Fatalf("Replace() = %q", got)
+	}
+	if r.Tokenized() {
+		t.Fatalf("masked values must not be restorable")
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	contract, marketing, unknown := "contract_helper", "marketing", "other"
+
+	p, err := ParsePolicy(" default=mask, contract_helper=tokenize ,marketing=block")
+	if err != nil {
+		t.Fatalf("ParsePolicy() error = %v", err)
+	}
+
+	for _, tt := range []struct {
+		scenario *string
+		want     Action
+	}{
+		{nil, ActionMask},
+		{&contract, ActionTokenize},
+		{&marketing, ActionBlock},
+		{&unknown, ActionMask},
+	} {
+		if got := p.For(tt.scenario); got != tt.want {
+			t.Fatalf("For(%v) = %q, want %q", tt.scenario, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"mask", "default=hide", "=mask"} {
+		if _, err := ParsePolicy(bad); err == nil {
+			t.Fatalf("ParsePolicy(%q) expected error", bad)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Package pii находит персональные данные в тексте (российские документы и реквизиты,
+// телефоны, карты, почта) и скрывает их от модели по политике сценария.
+package pii
+
+import (
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Kind - вид персональных данных
+type Kind string
+
+const (
+	KindPassport Kind = "passport"
+	KindINN      Kind = "inn"
+	KindSNILS    Kind = "snils"
+	KindPhone    Kind = "phone"
+	KindCard     Kind = "card"
+	KindAccount  Kind = "account"
+	KindEmail    Kind = "email"
+	KindIBAN     Kind = "iban"
+)
+
+// Match - найденное значение и его позиция в тексте (в байтах)
+type Match struct {
+	Kind  Kind
+	Start int
+	End   int
+	Value string
+}
+
+// detector - правило поиска: регулярное выражение кандидатов и проверка контрольной суммы.
+// Если в выражении есть группа, значением считается она (нужно для поиска по контексту: "паспорт 45 06 123456")
+type detector struct {
+	kind  Kind
+	re    *regexp.Regexp
+	valid func(number string) bool
+}
+
+// detectors упорядочены по приоритету: при пересечении остаётся совпадение правила, стоящего выше.
+// Например, 20 цифр - это счёт, а не номер карты, а "8 916 ..." - телефон, а не СНИЛС без дефисов
+var detectors = []detector{
+	{kind: KindEmail, re: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+	{kind: KindIBAN, re: regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,4})?\b`), valid: validIBAN},
+	{kind: KindAccount, re: regexp.MustCompile(`\b\d{5}[ .]?\d{3}[ .]?\d[ .]?\d{4}[ .]?\d{7}\b`)},
+	{kind: KindCard, re: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), valid: validLuhn},
+	{kind: KindSNILS, re: regexp.MustCompile(`\b\d{3}-\d{3}-\d{3}[ -]\d{2}\b`), valid: validSNILS},
+	{kind: KindPassport, re: regexp.MustCompile(`(?i)(?:паспорт\S*|серия)[^\d\n]{0,40}(\d{2} ?\d{2} ?(?:№ ?|номер )?\d{6})\b`)},
+	{kind: KindPassport, re: regexp.MustCompile(`\b\d{2} \d{2} \d{6}\b`)},
+	{kind: KindPhone, re: regexp.MustCompile(`(?:\+7|\b[78])[ (-]*\d{3}[ )-]*\d{3}[ -]*\d{2}[ -]*\d{2}\b`)},
+	{kind: KindPhone, re: regexp.MustCompile(`\+[1-9]\d{0,2}[ (-]*\d[\d ()-]{6,14}\d\b`)},
+	{kind: KindSNILS, re: regexp.MustCompile(`\b\d{11}\b`), valid: validSNILS},
+	{kind: KindINN, re: regexp.MustCompile(`\b\d{10}\b|\b\d{12}\b`), valid: validINN},
+}
+
+// Scan возвращает персональные данные в тексте, упорядоченные по позиции. Совпадения не пересекаются
+func Scan(text string) []Match {
+	var found []Match
+	for _, d := range detectors {
+		for _, loc := range d.re.FindAllStringSubmatchIndex(text, -1) {
+			start, end := loc[0], loc[1]
+			if len(loc) > 2 && loc[2] >= 0 {
+				start, end = loc[2], loc[3]
+			}
+			value := text[start:end]
+			if d.valid != nil && !d.valid(normalizeNumber(value)) {
+				continue
+			}
+			if overlaps(found, start, end) {
+				continue
+			}
+			found = append(found, Match{Kind: d.kind, Start: start, End: end, Value: value})
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].Start < found[j].Start })
+	return found
+}
+
+func overlaps(matches []Match, start, end int) bool {
+	for _, m := range matches {
+		if start < m.End && m.Start < end {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeNumber оставляет цифры и заглавные латинские буквы: разделители в номерах не важны для проверки
+func normalizeNumber(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsDigit(r) || unicode.IsUpper(r)) {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// validLuhn - контрольная сумма номера банковской карты
+func validLuhn(number string) bool {
+	if len(number) < 13 || len(number) > 19 {
+		return false
+	}
+
+	sum := 0
+	for i := 0; i < len(number); i++ {
+		d := int(number[len(number)-1-i] - '0')
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// validINN - контрольные цифры ИНН организации (10 цифр) или физического лица (12 цифр)
+func validINN(inn string) bool {
+	checkDigit := func(coeffs []int) int {
+		sum := 0
+		for i, c := range coeffs {
+			sum += c * int(inn[i]-'0')
+		}
+		return sum % 11 % 10
+	}
+
+	switch len(inn) {
+	case 10:
+		return checkDigit([]int{2, 4, 10, 3, 5, 9, 4, 6, 8}) == int(inn[9]-'0')
+	case 12:
+		return checkDigit([]int{7, 2, 4, 10, 3, 5, 9, 4, 6, 8}) == int(inn[10]-'0') &&
+			checkDigit([]int{3, 7, 2, 4, 10, 3, 5, 9, 4, 6, 8}) == int(inn[11]-'0')
+	default:
+		return false
+	}
+}
+
+// validSNILS - контрольное число СНИЛС (последние две цифры)
+func validSNILS(snils string) bool {
+	if len(snils) != 11 {
+		return false
+	}
+
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(snils[i]-'0') * (9 - i)
+	}
+	check := sum % 101
+	if check == 100 {
+		check = 0
+	}
+
+	return check == int(snils[9]-'0')*10+int(snils[10]-'0')
+}
+
+// validIBAN - проверка IBAN по модулю 97
+func validIBAN(iban string) bool {
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+
+	var numeric strings.Builder
+	for _, r := range iban[4:] + iban[:4] {
+		if r >= 'A' && r <= 'Z' {
+			numeric.WriteString(strconv.Itoa(int(r-'A') + 10))
+		} else {
+			numeric.WriteRune(r)
+		}
+	}
+
+	n, ok := new(big.Int).SetString(numeric.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
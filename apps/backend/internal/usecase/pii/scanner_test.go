@@ -0,0 +1,88 @@
+package pii
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScan(t *testing.T) {
+	type found struct {
+		Kind  Kind
+		Value string
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want []found
+	}{
+		{
+			name: "inn of organization and person",
+			text: "ИНН 7707083893, ИНН ИП 500100732259",
+			want: []found{{KindINN, "7707083893"}, {KindINN, "500100732259"}},
+		},
+		{
+			name: "inn with wrong checksum is ignored",
+			text: "Номер заказа 7707083894",
+		},
+		{
+			name: "snils formatted and bare",
+			text: "СНИЛС 112-233-445 95, второй 11223344595",
+			want: []found{{KindSNILS, "112-233-445 95"}, {KindSNILS, "11223344595"}},
+		},
+		{
+			name: "passport by context",
+			text: "Паспорт: серия 45 06 № 123456 выдан ОВД района Арбат",
+			want: []found{{KindPassport, "45 06 № 123456"}},
+		},
+		{
+			name: "passport without context as ten digits",
+			text: "паспорт 4506123456",
+			want: []found{{KindPassport, "4506123456"}},
+		},
+		{
+			name: "russian phone numbers",
+			text: "Звоните +7 (916) 123-45-67 или 8 800 555-35-35",
+			want: []found{{KindPhone, "+7 (916) 123-45-67"}, {KindPhone, "8 800 555-35-35"}},
+		},
+		{
+			name: "international phone",
+			text: "Office: +44 20 7946 0958",
+			want: []found{{KindPhone, "+44 20 7946 0958"}},
+		},
+		{
+			name: "card number with luhn check",
+			text: "Карта 4111 1111 1111 1111, не карта 4111 1111 1111 1112",
+			want: []found{{KindCard, "4111 1111 1111 1111"}},
+		},
+		{
+			name: "bank account is not a card",
+			text: "р/с 40702810900000000001 в ПАО Сбербанк",
+			want: []found{{KindAccount, "40702810900000000001"}},
+		},
+		{
+			name: "email and iban",
+			text: "Пишите ivan.petrov@example.ru, IBAN DE89 3704 0044 0532 0130 00",
+			want: []found{{KindEmail, "ivan.petrov@example.ru"}, {KindIBAN, "DE89 3704 0044 0532 0130 00"}},
+		},
+		{
+			name: "business numbers are not personal data",
+			text: "Выручка 1500000 рублей за 2024 год, ставка 16%, договор № 15 от 01.02.2025",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []found
+			for _, m := range Scan(tt.text) {
+				if tt.text[m.Start:m.End] != m.Value {
+					t.Fatalf("match position %d:%d does not point at %q", m.Start, m.End, m.Value)
+				}
+				got = append(got, found{m.Kind, m.Value})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Scan() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
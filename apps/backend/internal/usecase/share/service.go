@@ -0,0 +1,225 @@
+package share
+
+import (
+	"backend/internal/domain"
+	"backend/internal/usecase/authz"
+	"backend/internal/usecase/pii"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// tokenBytes - случайная часть токена: 256 бит не подобрать перебором
+	tokenBytes = 32
+	// maxSharedMessages - сколько последних сообщений снимка показывается по ссылке
+	maxSharedMessages = 1000
+	// MaxTTL - самый долгий срок действия ссылки
+	MaxTTL = 365 * 24 * time.Hour
+)
+
+// ErrInvalidTTL - срок действия ссылки вне (0, MaxTTL]
+var ErrInvalidTTL = errors.New("invalid share ttl")
+
+// Snapshot - чат, открытый по ссылке
+type Snapshot struct {
+	Title    string
+	SharedAt time.Time
+	// Messages - вопросы и ответы ветки до момента создания ссылки, от старых к новым
+	Messages []*domain.Message
+}
+
+type Service struct {
+	shares   domain.ShareRepo
+	chatRepo domain.ChatRepo
+	msgRepo  domain.MessageRepo
+	authz    *authz.Service
+	now      func() time.Time
+}
+
+func NewService(shares domain.ShareRepo, chatRepo domain.ChatRepo, msgRepo domain.MessageRepo, az *authz.Service) (*Service, error) {
+	if shares == nil {
+		return nil, errors.New("share repo should be provided")
+	}
+	if chatRepo == nil {
+		return nil, errors.New("chat repo should be provided")
+	}
+	if msgRepo == nil {
+		return nil, errors.New("message repo should be provided")
+	}
+	if az == nil {
+		return nil, errors.New("authorizer should be provided")
+	}
+
+	return &Service{
+		shares:   shares,
+		chatRepo: chatRepo,
+		msgRepo:  msgRepo,
+		authz:    az,
+		now:      time.Now,
+	}, nil
+}
+
+// Create создаёт ссылку на текущее состояние активной ветки чата. ttl = 0 - бессрочная ссылка.
+// Возвращает ссылку и токен; токен больше нигде не хранится в открытом виде
+func (s *Service) Create(ctx context.Context, userID, chatID uuid.UUID, ttl time.Duration, maskPII bool) (*domain.ChatShare, string, error) {
+	if ttl < 0 || ttl > MaxTTL {
+		return nil, "", fmt.Errorf("%w: must be up to %s", ErrInvalidTTL, MaxTTL)
+	}
+
+	chat, err := s.getChat(ctx, userID, chatID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	share := &domain.ChatShare{
+		ID:        uuid.New(),
+		ChatID:    chat.ID,
+		UserID:    userID,
+		TokenHash: hashToken(token),
+		MessageID: chat.ActiveMessageID,
+		MaskPII:   maskPII,
+	}
+	if ttl > 0 {
+		expiresAt := s.now().Add(ttl)
+		share.ExpiresAt = &expiresAt
+	}
+
+	if err := s.shares.Create(ctx, share); err != nil {
+		return nil, "", fmt.Errorf("failed to create share: %w", err)
+	}
+	return share, token, nil
+}
+
+// List возвращает ссылки чата пользователю, который может ими управлять
+func (s *Service) List(ctx context.Context, userID, chatID uuid.UUID) ([]*domain.ChatShare, error) {
+	if _, err := s.getChat(ctx, userID, chatID); err != nil {
+		return nil, err
+	}
+
+	shares, err := s.shares.ListByChat(ctx, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shares: %w", err)
+	}
+	return shares, nil
+}
+
+// Revoke отзывает ссылку: открыть её больше нельзя. Отозвать может автор ссылки
+// или тот, кто управляет чатом (например, владелец организации)
+func (s *Service) Revoke(ctx context.Context, userID, shareID uuid.UUID) error {
+	share, err := s.shares.GetByID(ctx, shareID)
+	if err != nil {
+		return fmt.Errorf("failed to get share: %w", err)
+	}
+	if share == nil {
+		return domain.ErrShareNotFound
+	}
+	if share.UserID != userID {
+		if _, err := s.getChat(ctx, userID, share.ChatID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.shares.Revoke(ctx, shareID, s.now()); err != nil {
+		return fmt.Errorf("failed to revoke share: %w", err)
+	}
+	return nil
+}
+
+// Open возвращает снимок чата по токену из ссылки. Неизвестная, отозванная и истёкшая ссылки
+// неразличимы для открывающего: всегда domain.ErrShareNotFound
+func (s *Service) Open(ctx context.Context, token string) (*Snapshot, error) {
+	if token == "" {
+		return nil, domain.ErrShareNotFound
+	}
+
+	share, err := s.shares.GetByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get share: %w", err)
+	}
+	if share == nil || !share.Active(s.now()) {
+		return nil, domain.ErrShareNotFound
+	}
+
+	chat, err := s.chatRepo.GetByID(ctx, share.ChatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat: %w", err)
+	}
+
+	snapshot := &Snapshot{Title: chat.Title, SharedAt: share.CreatedAt}
+	if share.MessageID == nil {
+		return snapshot, nil
+	}
+
+	branch, err := s.msgRepo.GetBranch(ctx, *share.MessageID, maxSharedMessages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages: %w", err)
+	}
+
+	var redactor *pii.Redactor
+	if share.MaskPII {
+		redactor = pii.NewRedactor(pii.ActionMask)
+		snapshot.Title = redactor.Replace(snapshot.Title)
+	}
+
+	for _, msg := range branch {
+		// Вызовы инструментов и их результаты - служебные шаги, читателю ссылки они не нужны
+		if msg.Role != string(domain.RoleUser) && msg.Role != string(domain.RoleAssistant) {
+			continue
+		}
+		if strings.TrimSpace(msg.Content) == "" {
+			continue
+		}
+
+		shared := &domain.Message{
+			ID:        msg.ID,
+			ChatID:    msg.ChatID,
+			Role:      msg.Role,
+			Content:   msg.Content,
+			CreatedAt: msg.CreatedAt,
+		}
+		if redactor != nil {
+			shared.Content = redactor.Replace(shared.Content)
+		}
+		snapshot.Messages = append(snapshot.Messages, shared)
+	}
+
+	return snapshot, nil
+}
+
+// getChat возвращает чат, если пользователь может им управлять: делиться и отзывать ссылки
+func (s *Service) getChat(ctx context.Context, userID, chatID uuid.UUID) (*domain.Chat, error) {
+	chat, err := s.chatRepo.GetByID(ctx, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat: %w", err)
+	}
+	if err := s.authz.Can(ctx, authz.User(userID), authz.ChatManage, authz.Chat(chat)); err != nil {
+		return nil, err
+	}
+	return chat, nil
+}
+
+func newToken() (string, error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate share token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
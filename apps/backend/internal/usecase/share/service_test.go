@@ -0,0 +1,115 @@
+package share
+
+import (
+	"backend/internal/domain"
+	"backend/internal/fakes"
+	"backend/internal/usecase/authz"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// add продолжает активную ветку чата новым сообщением
+func add(repo *fakes.MessageRepo, chat *domain.Chat, role, content string) *domain.Message {
+	msg := &domain.Message{ID: uuid.New(), Role: role, Content: content}
+	repo.AppendBranch(chat, msg)
+	return msg
+}
+
+func newTestService(t *testing.T) (*Service, *domain.Chat, *fakes.MessageRepo) {
+	t.Helper()
+
+	chat := &domain.Chat{ID: uuid.New(), UserID: uuid.New(), Title: "Договор"}
+	chatRepo := fakes.NewChatRepo(chat)
+	msgRepo := fakes.NewMessageRepo(chatRepo)
+	svc, err := NewService(&fakes.ShareRepo{}, chatRepo, msgRepo, authz.OwnerOnly())
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	return svc, chat, msgRepo
+}
+
+func TestShare_SnapshotAndMasking(t *testing.T) {
+	svc, chat, msgRepo := newTestService(t)
+	ctx := context.Background()
+
+	add(msgRepo, chat, "user", "Мой телефон +7 912 345-67-89, перезвоните")
+	add(msgRepo, chat, "assistant", "")
+	add(msgRepo, chat, "tool", "результат инструмента")
+	add(msgRepo, chat, "assistant", "Хорошо.")
+
+	_, token, err := svc.Create(ctx, chat.UserID, chat.ID, 0, true)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// Сообщения после создания ссылки в снимок не попадают
+	add(msgRepo, chat, "user", "ещё вопрос")
+
+	snapshot, err := svc.Open(ctx, token)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if len(snapshot.Messages) != 2 {
+		t.Fatalf("snapshot has %d messages, want question and answer", len(snapshot.Messages))
+	}
+	if strings.Contains(snapshot.Messages[0].Content, "345-67-89") {
+		t.Fatalf("phone is not masked: %q", snapshot.Messages[0].Content)
+	}
+	if snapshot.Messages[1].Content != "Хорошо." {
+		t.Fatalf("answer = %q", snapshot.Messages[1].Content)
+	}
+}
+
+func TestShare_OpenInactive(t *testing.T) {
+	svc, chat, _ := newTestService(t)
+	ctx := context.Background()
+
+	revoked, revokedToken, err := svc.Create(ctx, chat.UserID, chat.ID, 0, false)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := svc.Revoke(ctx, chat.UserID, revoked.ID); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+
+	_, expiredToken, err := svc.Create(ctx, chat.UserID, chat.ID, time.Hour, false)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	svc.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+
+	for name, token := range map[string]string{"revoked": revokedToken, "expired": expiredToken, "unknown": "abc"} {
+		if _, err := svc.Open(ctx, token); !errors.Is(err, domain.ErrShareNotFound) {
+			t.Errorf("%s link: error = %v, want ErrShareNotFound", name, err)
+		}
+	}
+}
+
+func TestShare_OwnerChecks(t *testing.T) {
+	svc, chat, _ := newTestService(t)
+	ctx := context.Background()
+	stranger := uuid.New()
+
+	if _, _, err := svc.Create(ctx, stranger, chat.ID, 0, false); !errors.Is(err, domain.ErrAccessDenied) {
+		t.Fatalf("Create() by stranger error = %v, want ErrAccessDenied", err)
+	}
+	if _, _, err := svc.Create(ctx, chat.UserID, chat.ID, MaxTTL+time.Hour, false); !errors.Is(err, ErrInvalidTTL) {
+		t.Fatalf("Create() with long ttl error = %v, want ErrInvalidTTL", err)
+	}
+
+	share, _, err := svc.Create(ctx, chat.UserID, chat.ID, 0, false)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := svc.Revoke(ctx, stranger, share.ID); !errors.Is(err, domain.ErrAccessDenied) {
+		t.Fatalf("Revoke() by stranger error = %v, want ErrAccessDenied", err)
+	}
+	if _, err := svc.List(ctx, stranger, chat.ID); !errors.Is(err, domain.ErrAccessDenied) {
+		t.Fatalf("List() by stranger error = %v, want ErrAccessDenied", err)
+	}
+}
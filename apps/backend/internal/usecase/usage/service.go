@@ -0,0 +1,151 @@
+package usage
+
+import (
+	"backend/internal/domain"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidQuota - квота с некорректными значениями
+var ErrInvalidQuota = errors.New("invalid quota")
+
+// Report - потребление пользователя за текущие сутки и месяц вместе с действующей квотой
+type Report struct {
+	Daily      domain.Usage
+	Monthly    domain.Usage
+	DayStart   time.Time
+	MonthStart time.Time
+	Quota      domain.Quota
+}
+
+type Service struct {
+	repo     domain.UsageRepo
+	defaults domain.Quota
+	now      func() time.Time
+}
+
+// NewService создаёт сервис учёта потребления.
+// defaults - квота, которая действует для пользователей без персональной квоты
+func NewService(repo domain.UsageRepo, defaults *domain.Quota) (*Service, error) {
+	if repo == nil {
+		return nil, errors.New("usage repo should be provided")
+	}
+
+	if defaults == nil {
+		return nil, errors.New("default quota should be provided")
+	}
+
+	return &Service{
+		repo:     repo,
+		defaults: *defaults,
+		now:      time.Now,
+	}, nil
+}
+
+// Check возвращает domain.ErrQuotaExceeded, если пользователь исчерпал хотя бы один из лимитов
+func (s *Service) Check(ctx context.Context, userID uuid.UUID) error {
+	report, err := s.Report(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	q := report.Quota
+	checks := []struct {
+		name  string
+		used  int64
+		limit int64
+	}{
+		{"daily tokens", report.Daily.Tokens(), q.DailyTokens},
+		{"monthly tokens", report.Monthly.Tokens(), q.MonthlyTokens},
+		{"daily requests", report.Daily.Requests, q.DailyRequests},
+		{"monthly requests", report.Monthly.Requests, q.MonthlyRequests},
+		{"daily document bytes", report.Daily.DocumentBytes, q.DailyDocumentBytes},
+		{"monthly document bytes", report.Monthly.DocumentBytes, q.MonthlyDocumentBytes},
+	}
+
+	for _, c := range checks {
+		if c.limit > 0 && c.used >= c.limit {
+			return fmt.Errorf("%w: %s limit %d reached", domain.ErrQuotaExceeded, c.name, c.limit)
+		}
+	}
+
+	return nil
+}
+
+// Record добавляет потребление в дневную запись пользователя
+func (s *Service) Record(ctx context.Context, userID uuid.UUID, u domain.Usage) error {
+	u.UserID = userID
+	u.Day = dayStart(s.now())
+
+	return s.repo.Add(ctx, &u)
+}
+
+// Report возвращает текущее потребление пользователя и действующую квоту
+func (s *Service) Report(ctx context.Context, userID uuid.UUID) (*Report, error) {
+	today := dayStart(s.now())
+	month := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	quota, err := s.GetQuota(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	daily, err := s.repo.Sum(ctx, userID, today, today)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily usage: %w", err)
+	}
+
+	monthly, err := s.repo.Sum(ctx, userID, month, today)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get monthly usage: %w", err)
+	}
+
+	return &Report{
+		Daily:      *daily,
+		Monthly:    *monthly,
+		DayStart:   today,
+		MonthStart: month,
+		Quota:      *quota,
+	}, nil
+}
+
+// GetQuota возвращает персональную квоту пользователя или квоту по умолчанию
+func (s *Service) GetQuota(ctx context.Context, userID uuid.UUID) (*domain.Quota, error) {
+	quota, err := s.repo.GetQuota(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quota: %w", err)
+	}
+
+	if quota == nil {
+		q := s.defaults
+		q.UserID = userID
+		return &q, nil
+	}
+
+	return quota, nil
+}
+
+// SetQuota сохраняет персональную квоту пользователя
+func (s *Service) SetQuota(ctx context.Context, quota *domain.Quota) error {
+	if quota.UserID == uuid.Nil {
+		return fmt.Errorf("%w: user id is required", ErrInvalidQuota)
+	}
+
+	if quota.DailyTokens < 0 || quota.MonthlyTokens < 0 ||
+		quota.DailyRequests < 0 || quota.MonthlyRequests < 0 ||
+		quota.DailyDocumentBytes < 0 || quota.MonthlyDocumentBytes < 0 {
+		return fmt.Errorf("%w: values must not be negative", ErrInvalidQuota)
+	}
+
+	return s.repo.SetQuota(ctx, quota)
+}
+
+// dayStart возвращает начало суток в UTC - граница записей в app.usage
+func dayStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
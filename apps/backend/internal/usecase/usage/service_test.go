@@ -0,0 +1,174 @@
+package usage
+
+import (
+	"backend/internal/domain"
+	"backend/internal/fakes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestService_Check(t *testing.T) {
+	now := time.Date(2025, 3, 15, 12, 0, 0, 0, time.UTC)
+	today := dayStart(now)
+	earlierThisMonth := today.AddDate(0, 0, -5)
+	lastMonth := today.AddDate(0, -1, 0)
+
+	tests := []struct {
+		name     string
+		defaults domain.Quota
+		personal *domain.Quota
+		history  map[time.Time]domain.Usage
+		wantErr  bool
+	}{
+		{
+			name:     "no limits configured",
+			defaults: domain.Quota{},
+			history:  map[time.Time]domain.Usage{today: {Requests: 1000, PromptTokens: 1_000_000}},
+			wantErr:  false,
+		},
+		{
+			name:     "daily tokens below limit",
+			defaults: domain.Quota{DailyTokens: 100},
+			history:  map[time.Time]domain.Usage{today: {PromptTokens: 40, CompletionTokens: 59}},
+			wantErr:  false,
+		},
+		{
+			name:     "daily tokens reached",
+			defaults: domain.Quota{DailyTokens: 100},
+			history:  map[time.Time]domain.Usage{today: {PromptTokens: 40, CompletionTokens: 60}},
+			wantErr:  true,
+		},
+		{
+			name:     "monthly requests counted across days",
+			defaults: domain.Quota{MonthlyRequests: 10},
+			history: map[time.Time]domain.Usage{
+				today:            {Requests: 4},
+				earlierThisMonth: {Requests: 6},
+			},
+			wantErr: true,
+		},
+		{
+			name:     "previous month is ignored",
+			defaults: domain.Quota{MonthlyRequests: 10},
+			history: map[time.Time]domain.Usage{
+				today:     {Requests: 4},
+				lastMonth: {Requests: 100},
+			},
+			wantErr: false,
+		},
+		{
+			name:     "personal quota overrides defaults",
+			defaults: domain.Quota{DailyRequests: 1},
+			personal: &domain.Quota{DailyRequests: 50},
+			history:  map[time.Time]domain.Usage{today: {Requests: 5}},
+			wantErr:  false,
+		},
+		{
+			name:     "document bytes limit",
+			defaults: domain.Quota{DailyDocumentBytes: 1024},
+			history:  map[time.Time]domain.Usage{today: {DocumentBytes: 2048}},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New()
+			repo := fakes.NewUsageRepo()
+			for day, u := range tt.history {
+				u.UserID, u.Day = userID, day
+				repo.Days = append(repo.Days, &u)
+			}
+			if tt.personal != nil {
+				tt.personal.UserID = userID
+				repo.Quotas[userID] = tt.personal
+			}
+
+			svc, err := NewService(repo, &tt.defaults)
+			if err != nil {
+				t.Fatalf("NewService() error = %v", err)
+			}
+			svc.now = func() time.Time { return now }
+
+			err = svc.Check(context.Background(), userID)
+			if tt.wantErr {
+				if !errors.Is(err, domain.ErrQuotaExceeded) {
+					t.Fatalf("Check() error = %v, want ErrQuotaExceeded", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Check() unexpected error = %v", err)
+			}
+		})
+	}
+}
+
+func TestService_RecordAndReport(t *testing.T) {
+	now := time.Date(2025, 3, 15, 23, 30, 0, 0, time.FixedZone("MSK", 3*60*60))
+	userID := uuid.New()
+	repo := fakes.NewUsageRepo()
+
+	svc, err := NewService(repo, &domain.Quota{DailyTokens: 500, MonthlyTokens: 5000})
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	svc.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		err := svc.Record(ctx, userID, domain.Usage{Requests: 1, PromptTokens: 100, CompletionTokens: 20, DocumentBytes: 10})
+		if err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	report, err := svc.Report(ctx, userID)
+	if err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+
+	wantDay := time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)
+	if !report.DayStart.Equal(wantDay) {
+		t.Fatalf("DayStart = %v, want %v", report.DayStart, wantDay)
+	}
+	if report.Daily.Requests != 2 || report.Daily.Tokens() != 240 || report.Daily.DocumentBytes != 20 {
+		t.Fatalf("Daily = %+v, want 2 requests, 240 tokens, 20 bytes", report.Daily)
+	}
+	if report.Monthly.Tokens() != 240 {
+		t.Fatalf("Monthly tokens = %d, want 240", report.Monthly.Tokens())
+	}
+	if report.Quota.DailyTokens != 500 || report.Quota.UserID != userID {
+		t.Fatalf("Quota = %+v, want defaults for user", report.Quota)
+	}
+}
+
+func TestService_SetQuota_Validation(t *testing.T) {
+	svc, err := NewService(fakes.NewUsageRepo(), &domain.Quota{})
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		quota   *domain.Quota
+		wantErr bool
+	}{
+		{name: "missing user", quota: &domain.Quota{DailyTokens: 1}, wantErr: true},
+		{name: "negative value", quota: &domain.Quota{UserID: uuid.New(), MonthlyTokens: -1}, wantErr: true},
+		{name: "valid", quota: &domain.Quota{UserID: uuid.New(), DailyTokens: 10}, wantErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := svc.SetQuota(context.Background(), tt.quota)
+			if tt.wantErr != errors.Is(err, ErrInvalidQuota) {
+				t.Fatalf("SetQuota() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+// Package users - управление пользователями администратором и смена собственного пароля
+package users
+
+import (
+	"backend/internal/domain"
+	"backend/internal/usecase/usage"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// MinPasswordLen - самый короткий пароль, который пользователь может задать сам
+	MinPasswordLen = 10
+	// maxPasswordBytes - bcrypt учитывает только первые 72 байта
+	maxPasswordBytes = 72
+	// tempPasswordLen - длина временного пароля: ~92 бита из алфавита в 55 символов
+	tempPasswordLen = 16
+	// tempPasswordAlphabet - без похожих символов (0/O, 1/l/I), чтобы пароль можно было продиктовать
+	tempPasswordAlphabet = "abcdefghjkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+var (
+	// ErrInvalidEmail - email не разобран
+	ErrInvalidEmail = errors.New("invalid email")
+	// ErrInvalidRole - неизвестная роль в приложении
+	ErrInvalidRole = errors.New("invalid role")
+	// ErrInvalidPassword - новый пароль короче MinPasswordLen или длиннее 72 байт
+	ErrInvalidPassword = errors.New("invalid password")
+	// ErrWrongPassword - текущий пароль не совпал
+	ErrWrongPassword = errors.New("wrong password")
+	// ErrSelfLockout - администратор не может отключить сам себя
+	ErrSelfLockout = errors.New("can't deactivate yourself")
+)
+
+// Overview - карточка пользователя для администратора
+type Overview struct {
+	*domain.UserOverview
+	// Usage - потребление за день и месяц относительно квот
+	Usage *usage.Report
+}
+
+type Service struct {
+	users domain.UserRepo
+	usage *usage.Service
+	tx    domain.TxManager
+	// hashCost - стоимость bcrypt; в тестах снижается
+	hashCost int
+}
+
+func NewService(users domain.UserRepo, usageService *usage.Service, tx domain.TxManager) (*Service, error) {
+	if users == nil {
+		return nil, errors.New("user repo should be provided")
+	}
+	if usageService == nil {
+		return nil, errors.New("usage service should be provided")
+	}
+	if tx == nil {
+		return nil, errors.New("tx manager should be provided")
+	}
+
+	return &Service{
+		users:    users,
+		usage:    usageService,
+		tx:       tx,
+		hashCost: bcrypt.DefaultCost,
+	}, nil
+}
+
+// List ищет пользователей по части email
+func (s *Service) List(ctx context.Context, query string, limit, offset int) ([]*domain.UserOverview, error) {
+	users, err := s.users.List(ctx, strings.TrimSpace(query), limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	return users, nil
+}
+
+// Get возвращает пользователя с числом чатов и потреблением
+func (s *Service) Get(ctx context.Context, userID uuid.UUID) (*Overview, error) {
+	user, err := s.users.GetOverview(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, domain.ErrUserNotFound
+	}
+
+	report, err := s.usage.Report(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage: %w", err)
+	}
+	return &Overview{UserOverview: user, Usage: report}, nil
+}
+
+// Create создаёт активного пользователя с временным паролем. Пароль возвращается один раз;
+// при первом входе пользователь должен его сменить
+func (s *Service) Create(ctx context.Context, email string, role domain.UserRole) (*domain.User, string, error) {
+	addr, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidEmail, err)
+	}
+	if role == "" {
+		role = domain.UserRoleUser
+	}
+	if !role.Valid() {
+		return nil, "", fmt.Errorf("%w: %q", ErrInvalidRole, role)
+	}
+
+	password, hash, err := s.tempPassword()
+	if err != nil {
+		return nil, "", err
+	}
+
+	user := &domain.User{
+		ID:                 uuid.New(),
+		Email:              strings.ToLower(addr.Address),
+		PasswordHash:       hash,
+		IsActive:           true,
+		Role:               role,
+		MustChangePassword: true,
+	}
+	if err := s.users.Create(ctx, user); err != nil {
+		if errors.Is(err, domain.ErrUserExists) {
+			return nil, "", err
+		}
+		return nil, "", fmt.Errorf("failed to create user: %w", err)
+	}
+	return user, password, nil
+}
+
+// SetActive включает или отключает пользователя. Отключённый не может войти,
+// а его выданные токены перестают приниматься
+func (s *Service) SetActive(ctx context.Context, adminID, userID uuid.UUID, active bool) error {
+	if !active && adminID == userID {
+		return ErrSelfLockout
+	}
+	return s.update(s.users.SetActive(ctx, userID, active))
+}
+
+// RevokeSessions завершает все сеансы пользователя: выданные токены больше не принимаются
+func (s *Service) RevokeSessions(ctx context.Context, userID uuid.UUID) error {
+	return s.update(s.users.RevokeTokens(ctx, userID))
+}
+
+// ResetPassword выдаёт пользователю новый временный пароль и завершает его сеансы
+func (s *Service) ResetPassword(ctx context.Context, userID uuid.UUID) (string, error) {
+	password, hash, err := s.tempPassword()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.users.UpdatePassword(ctx, userID, hash, true); err != nil {
+			return err
+		}
+		return s.users.RevokeTokens(ctx, userID)
+	})
+	if err := s.update(err); err != nil {
+		return "", err
+	}
+	return password, nil
+}
+
+// ChangePassword меняет пароль пользователя на заданный им самим и завершает все его сеансы,
+// включая текущий: после смены пароля нужно войти заново
+func (s *Service) ChangePassword(ctx context.Context, userID uuid.UUID, current, next string) error {
+	if utf8.RuneCountInString(next) < MinPasswordLen || len(next) > maxPasswordBytes {
+		return fmt.Errorf("%w: must be at least %d characters and at most %d bytes", ErrInvalidPassword, MinPasswordLen, maxPasswordBytes)
+	}
+
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return domain.ErrUserNotFound
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(current)); err != nil {
+		return ErrWrongPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(next), s.hashCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	return s.update(s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.users.UpdatePassword(ctx, userID, string(hash), false); err != nil {
+			return err
+		}
+		return s.users.RevokeTokens(ctx, userID)
+	}))
+}
+
+// update оборачивает ошибку изменения пользователя, оставляя domain.ErrUserNotFound различимой
+func (s *Service) update(err error) error {
+	if err == nil || errors.Is(err, domain.ErrUserNotFound) {
+		return err
+	}
+	return fmt.Errorf("failed to update user: %w", err)
+}
+
+// tempPassword генерирует временный пароль и его bcrypt-хэш
+func (s *Service) tempPassword() (string, string, error) {
+	alphabetLen := big.NewInt(int64(len(tempPasswordAlphabet)))
+	b := make([]byte, tempPasswordLen)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, alphabetLen)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to generate password: %w", err)
+		}
+		b[i] = tempPasswordAlphabet[n.Int64()]
+	}
+
+	hash, err := bcrypt.GenerateFromPassword(b, s.hashCost)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(b), string(hash), nil
+}
@@ -0,0 +1,166 @@
+package users
+
+import (
+	"backend/internal/domain"
+	"backend/internal/fakes"
+	"backend/internal/usecase/usage"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestService(t *testing.T) (*Service, *fakes.UserRepo, *fakes.UsageRepo) {
+	t.Helper()
+
+	repo, usageRepo := fakes.NewUserRepo(), fakes.NewUsageRepo()
+	usageService, err := usage.NewService(usageRepo, &domain.Quota{DailyRequests: 100})
+	if err != nil {
+		t.Fatalf("usage.NewService() error = %v", err)
+	}
+	svc, err := NewService(repo, usageService, fakes.NewTx(repo))
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+	svc.hashCost = bcrypt.MinCost
+	return svc, repo, usageRepo
+}
+
+func TestService_CreateAndChangePassword(t *testing.T) {
+	svc, repo, _ := newTestService(t)
+	ctx := context.Background()
+
+	user, password, err := svc.Create(ctx, " Anna <ANNA@example.com> ", "")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if user.Email != "anna@example.com" || user.Role != domain.UserRoleUser || !user.IsActive || !user.MustChangePassword {
+		t.Fatalf("user = %+v", user)
+	}
+	if len(password) != tempPasswordLen {
+		t.Fatalf("temporary password %q has length %d", password, len(password))
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(repo.Users[user.ID].PasswordHash), []byte(password)); err != nil {
+		t.Fatalf("stored hash doesn't match temporary password: %v", err)
+	}
+
+	if _, _, err := svc.Create(ctx, "anna@example.com", domain.UserRoleUser); !errors.Is(err, domain.ErrUserExists) {
+		t.Fatalf("Create() duplicate error = %v, want ErrUserExists", err)
+	}
+
+	if err := svc.ChangePassword(ctx, user.ID, "wrong", "новый-пароль-1"); !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("ChangePassword() with wrong current error = %v, want ErrWrongPassword", err)
+	}
+	if err := svc.ChangePassword(ctx, user.ID, password, "short"); !errors.Is(err, ErrInvalidPassword) {
+		t.Fatalf("ChangePassword() with short password error = %v, want ErrInvalidPassword", err)
+	}
+	if err := svc.ChangePassword(ctx, user.ID, password, "новый-пароль-1"); err != nil {
+		t.Fatalf("ChangePassword() error = %v", err)
+	}
+	if repo.Users[user.ID].MustChangePassword {
+		t.Fatal("password is still temporary after change")
+	}
+	if repo.Users[user.ID].TokenVersion != 1 {
+		t.Fatalf("token version = %d after change, want sessions revoked", repo.Users[user.ID].TokenVersion)
+	}
+}
+
+func TestService_CreateErrors(t *testing.T) {
+	svc, _, _ := newTestService(t)
+
+	tests := []struct {
+		name    string
+		email   string
+		role    domain.UserRole
+		wantErr error
+	}{
+		{name: "invalid email", email: "not an email", wantErr: ErrInvalidEmail},
+		{name: "invalid role", email: "a@example.com", role: "owner", wantErr: ErrInvalidRole},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := svc.Create(context.Background(), tt.email, tt.role); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Create() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestService_ResetPasswordRevokesSessions(t *testing.T) {
+	svc, repo, _ := newTestService(t)
+	ctx := context.Background()
+	user, _, err := svc.Create(ctx, "anna@example.com", domain.UserRoleUser)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	repo.Users[user.ID].MustChangePassword = false
+
+	password, err := svc.ResetPassword(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("ResetPassword() error = %v", err)
+	}
+	stored := repo.Users[user.ID]
+	if !stored.MustChangePassword || stored.TokenVersion != 1 {
+		t.Fatalf("after reset: must change = %v, token version = %d", stored.MustChangePassword, stored.TokenVersion)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(stored.PasswordHash), []byte(password)); err != nil {
+		t.Fatalf("stored hash doesn't match new password: %v", err)
+	}
+
+	// Если сеансы не завершились, пароль тоже не меняется
+	repo.RevokeErr = errors.New("db is down")
+	hash := stored.PasswordHash
+	if _, err := svc.ResetPassword(ctx, user.ID); err == nil {
+		t.Fatal("ResetPassword() error = nil, want failure")
+	}
+	if repo.Users[user.ID].PasswordHash != hash {
+		t.Fatal("password changed although sessions were not revoked")
+	}
+
+	repo.RevokeErr = nil
+	if _, err := svc.ResetPassword(ctx, uuid.New()); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Fatalf("ResetPassword() for unknown user error = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestService_SetActiveAndGet(t *testing.T) {
+	svc, repo, usageRepo := newTestService(t)
+	ctx := context.Background()
+	admin, _, err := svc.Create(ctx, "admin@example.com", domain.UserRoleAdmin)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	user, _, err := svc.Create(ctx, "anna@example.com", domain.UserRoleUser)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	if err := svc.SetActive(ctx, admin.ID, admin.ID, false); !errors.Is(err, ErrSelfLockout) {
+		t.Fatalf("SetActive() on self error = %v, want ErrSelfLockout", err)
+	}
+	if err := svc.SetActive(ctx, admin.ID, user.ID, false); err != nil {
+		t.Fatalf("SetActive() error = %v", err)
+	}
+	if err := svc.SetActive(ctx, admin.ID, uuid.New(), false); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Fatalf("SetActive() for unknown user error = %v, want ErrUserNotFound", err)
+	}
+
+	repo.Chats[user.ID] = 2
+	if err := usageRepo.Add(ctx, &domain.Usage{UserID: user.ID, Day: time.Now().UTC().Truncate(24 * time.Hour), Requests: 7}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	overview, err := svc.Get(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if overview.IsActive || overview.Chats != 2 || overview.Usage.Monthly.Requests != 7 {
+		t.Fatalf("overview = %+v, usage = %+v", overview.UserOverview, overview.Usage)
+	}
+	if _, err := svc.Get(ctx, uuid.New()); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Fatalf("Get() for unknown user error = %v, want ErrUserNotFound", err)
+	}
+}
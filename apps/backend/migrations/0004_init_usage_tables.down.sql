@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS app.quotas CASCADE;
+DROP TABLE IF EXISTS app.usage CASCADE;
+ALTER TABLE auth.users DROP COLUMN IF EXISTS role;
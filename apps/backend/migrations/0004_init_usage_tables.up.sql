@@ -0,0 +1,25 @@
+ALTER TABLE auth.users
+    ADD COLUMN role TEXT NOT NULL DEFAULT 'user';
+
+CREATE TABLE app.usage
+(
+    user_id           UUID   NOT NULL REFERENCES app.users (id),
+    day               DATE   NOT NULL,
+    requests          BIGINT NOT NULL DEFAULT 0,
+    prompt_tokens     BIGINT NOT NULL DEFAULT 0,
+    completion_tokens BIGINT NOT NULL DEFAULT 0,
+    document_bytes    BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, day)
+);
+
+CREATE TABLE app.quotas
+(
+    user_id                UUID PRIMARY KEY REFERENCES app.users (id),
+    daily_tokens           BIGINT      NOT NULL DEFAULT 0,
+    monthly_tokens         BIGINT      NOT NULL DEFAULT 0,
+    daily_requests         BIGINT      NOT NULL DEFAULT 0,
+    monthly_requests       BIGINT      NOT NULL DEFAULT 0,
+    daily_document_bytes   BIGINT      NOT NULL DEFAULT 0,
+    monthly_document_bytes BIGINT      NOT NULL DEFAULT 0,
+    updated_at             TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,2 @@
+ALTER TABLE app.messages
+    ADD COLUMN metadata JSONB NOT NULL DEFAULT '{}'::jsonb;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS app.cache (
+    key        TEXT PRIMARY KEY,
+    value      BYTEA NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_cache_expires_at ON app.cache (expires_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS app.idx_messages_parent_id;
+ALTER TABLE app.chats DROP COLUMN IF EXISTS active_message_id;
+ALTER TABLE app.messages DROP COLUMN IF EXISTS parent_id;
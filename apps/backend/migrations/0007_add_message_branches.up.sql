@@ -0,0 +1,22 @@
+ALTER TABLE app.messages
+    ADD COLUMN parent_id UUID REFERENCES app.messages (id) ON DELETE CASCADE;
+
+ALTER TABLE app.chats
+    ADD COLUMN active_message_id UUID REFERENCES app.messages (id) ON DELETE SET NULL;
+
+-- Существующие чаты линейные: родитель сообщения - предыдущее сообщение чата
+UPDATE app.messages m
+SET parent_id = p.prev_id
+FROM (SELECT id, LAG(id) OVER (PARTITION BY chat_id ORDER BY created_at, id) AS prev_id
+      FROM app.messages) p
+WHERE m.id = p.id
+  AND p.prev_id IS NOT NULL;
+
+UPDATE app.chats c
+SET active_message_id = (SELECT m.id
+                         FROM app.messages m
+                         WHERE m.chat_id = c.id
+                         ORDER BY m.created_at DESC, m.id DESC
+                         LIMIT 1);
+
+CREATE INDEX IF NOT EXISTS idx_messages_parent_id ON app.messages (parent_id);
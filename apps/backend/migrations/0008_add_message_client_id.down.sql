@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS app.idx_messages_chat_client_message_id;
+ALTER TABLE app.messages DROP COLUMN IF EXISTS client_message_id;
@@ -0,0 +1,7 @@
+ALTER TABLE app.messages
+    ADD COLUMN client_message_id TEXT;
+
+-- Ключ идемпотентности клиента уникален в пределах чата
+CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_chat_client_message_id
+    ON app.messages (chat_id, client_message_id)
+    WHERE client_message_id IS NOT NULL;
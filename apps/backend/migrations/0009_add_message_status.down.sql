@@ -0,0 +1 @@
+ALTER TABLE app.messages DROP COLUMN IF EXISTS status;
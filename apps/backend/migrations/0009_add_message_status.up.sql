@@ -0,0 +1,4 @@
+-- Статус генерации ответа на сообщение пользователя; существующие сообщения считаются завершёнными
+ALTER TABLE app.messages
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'complete'
+        CHECK (status IN ('pending', 'complete', 'failed'));
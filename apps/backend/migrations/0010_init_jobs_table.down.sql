@@ -0,0 +1,2 @@
+ALTER TABLE app.chats DROP COLUMN IF EXISTS summary;
+DROP TABLE IF EXISTS app.jobs;
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS app.jobs
+(
+    id           UUID PRIMARY KEY,
+    kind         TEXT        NOT NULL,
+    user_id      UUID        NOT NULL REFERENCES app.users (id),
+    payload      JSONB       NOT NULL DEFAULT '{}'::jsonb,
+    status       TEXT        NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'running', 'done', 'failed')),
+    result       JSONB,
+    error        TEXT        NOT NULL DEFAULT '',
+    attempts     INT         NOT NULL DEFAULT 0,
+    max_attempts INT         NOT NULL DEFAULT 3,
+    dedupe_key   TEXT,
+    run_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    locked_until TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Выборка воркером: ожидающие задачи по времени запуска и зависшие по истечению аренды
+CREATE INDEX IF NOT EXISTS idx_jobs_pending_run_at ON app.jobs (run_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_jobs_running_locked_until ON app.jobs (locked_until) WHERE status = 'running';
+
+-- Одна ожидающая задача на ключ: повторная постановка (например, названия чата) не плодит дубли
+CREATE UNIQUE INDEX IF NOT EXISTS idx_jobs_pending_dedupe_key ON app.jobs (dedupe_key) WHERE status = 'pending';
+
+ALTER TABLE app.chats
+    ADD COLUMN summary TEXT;
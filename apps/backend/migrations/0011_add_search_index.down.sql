@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS app.idx_chats_search_vector;
+DROP INDEX IF EXISTS app.idx_messages_search_vector;
+
+ALTER TABLE app.chats DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE app.messages DROP COLUMN IF EXISTS search_vector;
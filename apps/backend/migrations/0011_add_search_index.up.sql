@@ -0,0 +1,11 @@
+-- Полнотекстовый поиск по чатам пользователя: русская морфология, название чата весит больше текста сообщений
+ALTER TABLE app.messages
+    ADD COLUMN search_vector tsvector
+        GENERATED ALWAYS AS (to_tsvector('russian', content)) STORED;
+
+ALTER TABLE app.chats
+    ADD COLUMN search_vector tsvector
+        GENERATED ALWAYS AS (setweight(to_tsvector('russian', coalesce(title, '')), 'A')) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_messages_search_vector ON app.messages USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_chats_search_vector ON app.chats USING GIN (search_vector);
@@ -0,0 +1,16 @@
+-- Ссылки на снимок чата только для чтения. Хранится хэш токена: утечка базы не раскрывает ссылки
+CREATE TABLE IF NOT EXISTS app.chat_shares
+(
+    id         UUID PRIMARY KEY,
+    chat_id    UUID        NOT NULL REFERENCES app.chats (id) ON DELETE CASCADE,
+    user_id    UUID        NOT NULL REFERENCES app.users (id),
+    token_hash BYTEA       NOT NULL UNIQUE,
+    -- Последнее сообщение снимка; ссылка показывает ветку до него включительно
+    message_id UUID REFERENCES app.messages (id) ON DELETE CASCADE,
+    mask_pii   BOOLEAN     NOT NULL DEFAULT false,
+    expires_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_shares_chat_id ON app.chat_shares (chat_id);
@@ -0,0 +1,4 @@
+ALTER TABLE app.chats DROP COLUMN IF EXISTS org_id;
+DROP TABLE IF EXISTS app.org_invites;
+DROP TABLE IF EXISTS app.memberships;
+DROP TABLE IF EXISTS app.orgs;
@@ -0,0 +1,40 @@
+-- Организации: общие чаты и документы сотрудников одной компании
+CREATE TABLE IF NOT EXISTS app.orgs
+(
+    id         UUID PRIMARY KEY,
+    name       TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS app.memberships
+(
+    org_id     UUID        NOT NULL REFERENCES app.orgs (id) ON DELETE CASCADE,
+    user_id    UUID        NOT NULL REFERENCES app.users (id) ON DELETE CASCADE,
+    role       TEXT        NOT NULL CHECK (role IN ('owner', 'member', 'viewer')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (org_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_memberships_user_id ON app.memberships (user_id);
+
+-- Приглашения по email. Хранится хэш токена из письма, как у ссылок на чаты
+CREATE TABLE IF NOT EXISTS app.org_invites
+(
+    id          UUID PRIMARY KEY,
+    org_id      UUID        NOT NULL REFERENCES app.orgs (id) ON DELETE CASCADE,
+    email       TEXT        NOT NULL,
+    role        TEXT        NOT NULL CHECK (role IN ('owner', 'member', 'viewer')),
+    token_hash  BYTEA       NOT NULL UNIQUE,
+    invited_by  UUID        NOT NULL REFERENCES app.users (id),
+    expires_at  TIMESTAMPTZ NOT NULL,
+    accepted_at TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_org_invites_org_id ON app.org_invites (org_id);
+
+-- Чат без организации - личный чат пользователя user_id
+ALTER TABLE app.chats
+    ADD COLUMN IF NOT EXISTS org_id UUID REFERENCES app.orgs (id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_chats_org_id ON app.chats (org_id) WHERE org_id IS NOT NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE auth.users
+    DROP COLUMN IF EXISTS must_change_password,
+    DROP COLUMN IF EXISTS token_version;
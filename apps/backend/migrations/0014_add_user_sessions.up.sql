@@ -0,0 +1,4 @@
+-- token_version входит в токен; увеличение версии отзывает все выданные токены пользователя
+ALTER TABLE auth.users
+    ADD COLUMN IF NOT EXISTS token_version        INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS must_change_password BOOLEAN NOT NULL DEFAULT FALSE;
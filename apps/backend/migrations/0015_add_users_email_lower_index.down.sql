@@ -0,0 +1 @@
+DROP INDEX IF EXISTS auth.users_email_lower_idx;
@@ -0,0 +1,2 @@
+-- Email уникален без учёта регистра: вход ищет пользователя по lower(email)
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_idx ON auth.users (lower(email));
@@ -0,0 +1,46 @@
+// Package migrations встраивает SQL-миграции в бинарник, чтобы сервис знал,
+// какую версию схемы он ожидает увидеть в базе.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var FS embed.FS
+
+// LatestVersion возвращает номер последней up-миграции (0004_init_usage_tables.up.sql -> 4)
+func LatestVersion() (int64, error) {
+	entries, err := fs.ReadDir(FS, ".")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest int64
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasSuffix(name, ".up.sql") {
+			continue
+		}
+
+		prefix, _, ok := strings.Cut(name, "_")
+		if !ok {
+			return 0, fmt.Errorf("migration %q has no version prefix", name)
+		}
+
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("migration %q: %w", name, err)
+		}
+
+		if version > latest {
+			latest = version
+		}
+	}
+
+	return latest, nil
+}